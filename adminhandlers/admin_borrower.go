@@ -211,5 +211,34 @@ func BorrowerGetDetails(c echo.Context) error {
 		return returnInvalidResponse(http.StatusNotFound, err, fmt.Sprintf("Nasabah %v tidak ditemukan", borrowerID))
 	}
 
+	PresignImages(c, "core_view_image", "borrower", fmt.Sprint(borrower.ID), &borrower.ImageProfile, &borrower.IdCardImage, &borrower.TaxIDImage)
+
 	return c.JSON(http.StatusOK, borrower)
 }
+
+// BorrowerCreditHistory track record of borrower across all loans, with anonymised counts of loans with other banks
+func BorrowerCreditHistory(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "core_borrower_credit_history")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	borrowerID, _ := strconv.ParseUint(c.Param("borrower_id"), 10, 64)
+	borrower := models.Borrower{}
+	err = borrower.FindbyID(borrowerID)
+	if err != nil {
+		NLog("warning", "BorrowerCreditHistory", map[string]interface{}{"message": fmt.Sprintf("borrower %v not found", borrowerID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusNotFound, err, fmt.Sprintf("Nasabah %v tidak ditemukan", borrowerID))
+	}
+
+	history, err := models.BorrowerCreditHistory(borrower, true)
+	if err != nil {
+		NLog("error", "BorrowerCreditHistory", map[string]interface{}{"message": fmt.Sprintf("error summarizing borrower %v credit history", borrowerID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, "Gagal memuat riwayat kredit nasabah")
+	}
+
+	return c.JSON(http.StatusOK, history)
+}
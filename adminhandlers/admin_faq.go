@@ -96,8 +96,7 @@ func FAQNew(c echo.Context) error {
 	marshal, _ := json.Marshal(faqPayload)
 	json.Unmarshal(marshal, &faq)
 
-	err = faq.Create()
-	middlewares.SubmitKafkaPayload(faq, "faq_create")
+	err = middlewares.CreateWithOutbox(&faq, "faq_create")
 	if err != nil {
 		NLog("error", "FAQNew", fmt.Sprintf("error create : %v", err), c.Get("user").(*jwt.Token), "", true)
 
@@ -170,9 +169,9 @@ func FAQPatch(c echo.Context) error {
 		faq.Description = faqPayload.Description
 	}
 
-	err = middlewares.SubmitKafkaPayload(faq, "faq_update")
+	err = middlewares.SaveWithOutbox(&faq, "faq_update")
 	if err != nil {
-		NLog("error", "FAQPatch", fmt.Sprintf("kafka error : %v", err), c.Get("user").(*jwt.Token), "", true)
+		NLog("error", "FAQPatch", fmt.Sprintf("error update : %v", err), c.Get("user").(*jwt.Token), "", true)
 
 		return returnInvalidResponse(http.StatusInternalServerError, err, fmt.Sprintf("Gagal update FAQ %v", faqID))
 	}
@@ -200,7 +199,7 @@ func FAQDelete(c echo.Context) error {
 		return returnInvalidResponse(http.StatusNotFound, err, "Tidak memiliki hak akses")
 	}
 
-	err = middlewares.SubmitKafkaPayload(faq, "faq_delete")
+	err = middlewares.DeleteWithOutbox(&faq, "faq_delete")
 	if err != nil {
 		NLog("error", "FAQDelete", fmt.Sprintf("delete FAQ %v error : %v", faqID, err), c.Get("user").(*jwt.Token), "", false)
 
@@ -5,7 +5,6 @@ import (
 	"asira_lender/middlewares"
 	"asira_lender/models"
 	"database/sql"
-	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"math"
@@ -186,6 +185,8 @@ func AgentDetails(c echo.Context) error {
 		return returnInvalidResponse(http.StatusNotFound, err, fmt.Sprintf("Agen %v tidak ditemukan", id))
 	}
 
+	presignImageThumbnails(c, "core_view_image", "agent", fmt.Sprint(agent.ID), &agent.Image, &agent.Thumbnails)
+
 	return c.JSON(http.StatusOK, agent)
 }
 
@@ -235,16 +236,15 @@ func AgentNew(c echo.Context) error {
 	json.Unmarshal(marshal, &agent)
 
 	if len(agentPayload.Image) > 0 {
-		unbased, _ := base64.StdEncoding.DecodeString(agentPayload.Image)
 		filename := "agt" + strconv.FormatInt(time.Now().Unix(), 10)
-		url, err := asira.App.S3.UploadJPEG(unbased, filename)
+		url, thumbnails, err := uploadImage(agentPayload.Image, filename)
 		if err != nil {
 			NLog("error", "AgentNew", map[string]interface{}{"message": fmt.Sprintf("error uploading image when creating agent : %v", agent.ID), "error": err}, c.Get("user").(*jwt.Token), "", false)
 
-			return returnInvalidResponse(http.StatusInternalServerError, err, "Gagal membuat agent baru")
+			return imageUploadResponse(err, "Gagal membuat agent baru")
 		}
 
-		agent.Image = url
+		agent.Image, agent.Thumbnails = imageKeys(url, thumbnails)
 	}
 
 	if agentPayload.AgentProvider != 0 {
@@ -254,10 +254,9 @@ func AgentNew(c echo.Context) error {
 		}
 	}
 
-	err = agent.Create()
-	middlewares.SubmitKafkaPayload(agent, "agent_create")
+	err = middlewares.CreateWithOutbox(&agent, "agent_create")
 	if err != nil {
-		NLog("error", "AgentNew", map[string]interface{}{"message": fmt.Sprintf("error submitting to kafka after creating agent : %v", agent.ID), "agent": agent, "error": err}, c.Get("user").(*jwt.Token), "", false)
+		NLog("error", "AgentNew", map[string]interface{}{"message": "error creating agent", "agent": agent, "error": err}, c.Get("user").(*jwt.Token), "", false)
 
 		return returnInvalidResponse(http.StatusInternalServerError, err, "Gagal membuat agent baru")
 	}
@@ -341,28 +340,25 @@ func AgentPatch(c echo.Context) error {
 		agent.Status = agentPayload.Status
 	}
 	if len(agentPayload.Image) > 0 {
-		unbased, _ := base64.StdEncoding.DecodeString(agentPayload.Image)
 		filename := "agt" + strconv.FormatInt(time.Now().Unix(), 10)
-		url, err := asira.App.S3.UploadJPEG(unbased, filename)
+		url, thumbnails, err := uploadImage(agentPayload.Image, filename)
 		if err != nil {
 			NLog("error", "AgentPatch", map[string]interface{}{"message": fmt.Sprintf("error uploading image to agent %v", id), "error": err}, c.Get("user").(*jwt.Token), "", false)
 
-			return returnInvalidResponse(http.StatusInternalServerError, err, "Gagal membuat agent baru")
+			return imageUploadResponse(err, "Gagal membuat agent baru")
 		}
 
-		i := strings.Split(agent.Image, "/")
-		delImage := i[len(i)-1]
-		err = asira.App.S3.DeleteObject(delImage)
+		err = deleteImage(agent.Image, agent.Thumbnails)
 		if err != nil {
 			NLog("error", "AgentPatch", map[string]interface{}{"message": fmt.Sprintf("error deleting old image of agent %v", id), "error": err}, c.Get("user").(*jwt.Token), "", false)
 		}
 
-		agent.Image = url
+		agent.Image, agent.Thumbnails = imageKeys(url, thumbnails)
 	}
 
-	err = middlewares.SubmitKafkaPayload(agent, "agent_update")
+	err = middlewares.SaveWithOutbox(&agent, "agent_update")
 	if err != nil {
-		NLog("error", "AgentPatch", map[string]interface{}{"message": fmt.Sprintf("error kafka submit agent %v", agent.ID), "error": err, "agent": agent}, c.Get("user").(*jwt.Token), "", false)
+		NLog("error", "AgentPatch", map[string]interface{}{"message": fmt.Sprintf("error updating agent %v", agent.ID), "error": err, "agent": agent}, c.Get("user").(*jwt.Token), "", false)
 
 		return returnInvalidResponse(http.StatusInternalServerError, err, "Gagal mengubah agent baru")
 	}
@@ -390,9 +386,9 @@ func AgentDelete(c echo.Context) error {
 		return returnInvalidResponse(http.StatusNotFound, err, fmt.Sprintf("Agen %v tidak ditemukan", id))
 	}
 
-	err = middlewares.SubmitKafkaPayload(agent, "agent_delete")
+	err = middlewares.DeleteWithOutbox(&agent, "agent_delete")
 	if err != nil {
-		NLog("error", "AgentDelete", map[string]interface{}{"message": fmt.Sprintf("error submit kafka agent : %v", agent.ID), "error": err, "agent": agent}, c.Get("user").(*jwt.Token), "", false)
+		NLog("error", "AgentDelete", map[string]interface{}{"message": fmt.Sprintf("error deleting agent %v", agent.ID), "error": err, "agent": agent}, c.Get("user").(*jwt.Token), "", false)
 
 		return returnInvalidResponse(http.StatusInternalServerError, err, "Gagal mengubah agen baru")
 	}
@@ -122,10 +122,9 @@ func AgentProviderNew(c echo.Context) error {
 		return returnInvalidResponse(http.StatusUnprocessableEntity, validate, "Hambatan validasi")
 	}
 
-	err = agentProvider.Create()
-	middlewares.SubmitKafkaPayload(agentProvider, "agent_provider_create")
+	err = middlewares.CreateWithOutbox(&agentProvider, "agent_provider_create")
 	if err != nil {
-		NLog("warning", "AgentProviderNew", map[string]interface{}{"message": "error kafka submit create new provider", "error": err}, c.Get("user").(*jwt.Token), "", false)
+		NLog("warning", "AgentProviderNew", map[string]interface{}{"message": "error create new provider", "error": err}, c.Get("user").(*jwt.Token), "", false)
 
 		return returnInvalidResponse(http.StatusInternalServerError, err, "Gagal membuat tipe bank baru")
 	}
@@ -170,9 +169,9 @@ func AgentProviderPatch(c echo.Context) error {
 		return returnInvalidResponse(http.StatusUnprocessableEntity, validate, "validation error")
 	}
 
-	err = middlewares.SubmitKafkaPayload(agentProvider, "agent_provider_update")
+	err = middlewares.SaveWithOutbox(&agentProvider, "agent_provider_update")
 	if err != nil {
-		NLog("error", "AgentProviderPatch", map[string]interface{}{"message": fmt.Sprintf("error submitting to kafka after patching provider %v", id), "error": err}, c.Get("user").(*jwt.Token), "", false)
+		NLog("error", "AgentProviderPatch", map[string]interface{}{"message": fmt.Sprintf("error patching provider %v", id), "error": err}, c.Get("user").(*jwt.Token), "", false)
 
 		return returnInvalidResponse(http.StatusInternalServerError, err, "Gagal membuat tipe bank baru")
 	}
@@ -0,0 +1,281 @@
+package adminhandlers
+
+import (
+	"asira_lender/models"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/jinzhu/gorm/dialects/postgres"
+	"github.com/labstack/echo"
+	"github.com/thedevsaddam/govalidator"
+)
+
+// ApprovalPolicyPayload to handle post and patch
+type ApprovalPolicyPayload struct {
+	Name      string                `json:"name"`
+	Product   uint64                `json:"product"`
+	MinAmount float64               `json:"min_amount"`
+	MaxAmount float64               `json:"max_amount"`
+	Steps     []models.ApprovalStep `json:"steps"`
+	Status    string                `json:"status"`
+}
+
+// ApprovalPolicyList lists loan approval policies of bank
+func ApprovalPolicyList(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "core_approval_policy_list")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	bankID, _ := strconv.ParseUint(c.Param("bank_id"), 10, 64)
+
+	// pagination parameters
+	rows, err := strconv.Atoi(c.QueryParam("rows"))
+	page, err := strconv.Atoi(c.QueryParam("page"))
+	orderby := strings.Split(c.QueryParam("orderby"), ",")
+	sort := strings.Split(c.QueryParam("sort"), ",")
+
+	type Filter struct {
+		Bank   uint64 `json:"bank"`
+		Name   string `json:"name" condition:"LIKE"`
+		Status string `json:"status"`
+	}
+
+	policy := models.ApprovalPolicy{}
+	result, err := policy.PagedFindFilter(page, rows, orderby, sort, &Filter{
+		Bank:   bankID,
+		Name:   c.QueryParam("name"),
+		Status: c.QueryParam("status"),
+	})
+	if err != nil {
+		NLog("warning", "ApprovalPolicyList", map[string]interface{}{"message": fmt.Sprintf("error listing approval policies of bank %v", bankID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, "Pencarian tidak ditemukan")
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// ApprovalPolicyNew adds loan approval policy to bank
+func ApprovalPolicyNew(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "core_approval_policy_new")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	bankID, _ := strconv.ParseUint(c.Param("bank_id"), 10, 64)
+	bank := models.Bank{}
+	err = bank.FindbyID(bankID)
+	if err != nil {
+		NLog("warning", "ApprovalPolicyNew", map[string]interface{}{"message": fmt.Sprintf("bank %v not found", bankID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusNotFound, err, fmt.Sprintf("Bank %v tidak ditemukan", bankID))
+	}
+
+	policyPayload := ApprovalPolicyPayload{}
+	payloadRules := govalidator.MapData{
+		"name":       []string{"required"},
+		"product":    []string{},
+		"min_amount": []string{},
+		"max_amount": []string{},
+		"steps":      []string{"required"},
+		"status":     []string{"required", "active_inactive"},
+	}
+
+	validate := validateRequestPayload(c, payloadRules, &policyPayload)
+	if validate == nil {
+		validate = validateApprovalPolicy(policyPayload)
+	}
+	if validate != nil {
+		NLog("warning", "ApprovalPolicyNew", map[string]interface{}{"message": "error validate new approval policy", "error": validate}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusUnprocessableEntity, validate, "Hambatan validasi")
+	}
+
+	steps, _ := json.Marshal(policyPayload.Steps)
+	policy := models.ApprovalPolicy{
+		Bank:      bank.ID,
+		Product:   policyPayload.Product,
+		Name:      policyPayload.Name,
+		MinAmount: policyPayload.MinAmount,
+		MaxAmount: policyPayload.MaxAmount,
+		Steps:     postgres.Jsonb{RawMessage: steps},
+		Status:    policyPayload.Status,
+	}
+
+	err = policy.Create()
+	if err != nil {
+		NLog("error", "ApprovalPolicyNew", map[string]interface{}{"message": "error create approval policy", "error": err, "policy": policy}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, "Gagal membuat kebijakan persetujuan baru")
+	}
+
+	NAudittrail(models.ApprovalPolicy{}, policy, c.Get("user").(*jwt.Token), "approval policy", fmt.Sprint(policy.ID), "create")
+
+	return c.JSON(http.StatusCreated, policy)
+}
+
+// ApprovalPolicyDetail get loan approval policy of bank by id
+func ApprovalPolicyDetail(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "core_approval_policy_detail")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	policy, err := bankApprovalPolicy(c, "ApprovalPolicyDetail")
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, policy)
+}
+
+// ApprovalPolicyPatch edit loan approval policy of bank by id. loans already in approval keep their steps
+func ApprovalPolicyPatch(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "core_approval_policy_patch")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	policy, err := bankApprovalPolicy(c, "ApprovalPolicyPatch")
+	if err != nil {
+		return err
+	}
+	origin := policy
+
+	policyPayload := ApprovalPolicyPayload{
+		Product:   policy.Product,
+		MinAmount: policy.MinAmount,
+		MaxAmount: policy.MaxAmount,
+		Steps:     policy.StepList(),
+	}
+	payloadRules := govalidator.MapData{
+		"name":       []string{},
+		"product":    []string{},
+		"min_amount": []string{},
+		"max_amount": []string{},
+		"steps":      []string{},
+		"status":     []string{"active_inactive"},
+	}
+
+	validate := validateRequestPayload(c, payloadRules, &policyPayload)
+	if validate == nil {
+		validate = validateApprovalPolicy(policyPayload)
+	}
+	if validate != nil {
+		NLog("warning", "ApprovalPolicyPatch", map[string]interface{}{"message": "validation error", "error": validate}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusUnprocessableEntity, validate, "Hambatan validasi")
+	}
+
+	if len(policyPayload.Name) > 0 {
+		policy.Name = policyPayload.Name
+	}
+	if len(policyPayload.Status) > 0 {
+		policy.Status = policyPayload.Status
+	}
+	steps, _ := json.Marshal(policyPayload.Steps)
+	policy.Product = policyPayload.Product
+	policy.MinAmount = policyPayload.MinAmount
+	policy.MaxAmount = policyPayload.MaxAmount
+	policy.Steps = postgres.Jsonb{RawMessage: steps}
+
+	err = policy.Save()
+	if err != nil {
+		NLog("error", "ApprovalPolicyPatch", map[string]interface{}{"message": fmt.Sprintf("error update approval policy %v", policy.ID), "error": err, "policy": policy}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, fmt.Sprintf("Gagal update kebijakan persetujuan %v", policy.ID))
+	}
+
+	NAudittrail(origin, policy, c.Get("user").(*jwt.Token), "approval policy", fmt.Sprint(policy.ID), "update")
+
+	return c.JSON(http.StatusOK, policy)
+}
+
+// ApprovalPolicyDelete removes loan approval policy of bank by id
+func ApprovalPolicyDelete(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "core_approval_policy_delete")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	policy, err := bankApprovalPolicy(c, "ApprovalPolicyDelete")
+	if err != nil {
+		return err
+	}
+	origin := policy
+
+	err = policy.Delete()
+	if err != nil {
+		NLog("error", "ApprovalPolicyDelete", map[string]interface{}{"message": fmt.Sprintf("error delete approval policy %v", policy.ID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, fmt.Sprintf("Gagal delete kebijakan persetujuan %v", policy.ID))
+	}
+
+	NAudittrail(origin, policy, c.Get("user").(*jwt.Token), "approval policy", fmt.Sprint(policy.ID), "delete")
+
+	return c.JSON(http.StatusOK, policy)
+}
+
+// bankApprovalPolicy finds :policy_id of :bank_id. returned error is ready to be sent as response
+func bankApprovalPolicy(c echo.Context, tag string) (policy models.ApprovalPolicy, err error) {
+	bankID, _ := strconv.ParseUint(c.Param("bank_id"), 10, 64)
+	policyID, _ := strconv.ParseUint(c.Param("policy_id"), 10, 64)
+
+	type Filter struct {
+		ID   uint64 `json:"id"`
+		Bank uint64 `json:"bank"`
+	}
+	err = policy.SingleFindFilter(&Filter{
+		ID:   policyID,
+		Bank: bankID,
+	})
+	if err != nil {
+		NLog("warning", tag, map[string]interface{}{"message": fmt.Sprintf("approval policy %v of bank %v not found", policyID, bankID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return policy, returnInvalidResponse(http.StatusNotFound, err, fmt.Sprintf("Kebijakan persetujuan %v tidak ditemukan", policyID))
+	}
+
+	return policy, nil
+}
+
+func validateApprovalPolicy(payload ApprovalPolicyPayload) interface{} {
+	errors := map[string][]string{}
+
+	if payload.MinAmount < 0 || payload.MaxAmount < 0 {
+		errors["min_amount"] = append(errors["min_amount"], "The amount fields may not be negative")
+	}
+	if payload.MaxAmount > 0 && payload.MaxAmount < payload.MinAmount {
+		errors["max_amount"] = append(errors["max_amount"], "The max_amount field must be greater than min_amount")
+	}
+	if payload.Product > 0 {
+		product := models.Product{}
+		if err := product.FindbyID(payload.Product); err != nil {
+			errors["product"] = append(errors["product"], fmt.Sprintf("Product %v not found", payload.Product))
+		}
+	}
+	if len(payload.Steps) < 1 {
+		errors["steps"] = append(errors["steps"], "The steps field is required")
+	}
+	for i, step := range payload.Steps {
+		role := models.Roles{}
+		if len(step.Name) < 1 || step.Approvers < 1 || role.FindbyID(step.Role) != nil {
+			errors["steps"] = append(errors["steps"], fmt.Sprintf("Step %v must have name, existing role and at least 1 approver", i+1))
+		}
+	}
+
+	if len(errors) > 0 {
+		return errors
+	}
+
+	return nil
+}
@@ -0,0 +1,379 @@
+package adminhandlers
+
+import (
+	"asira_lender/models"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/jinzhu/gorm/dialects/postgres"
+	"github.com/labstack/echo"
+	"github.com/thedevsaddam/govalidator"
+)
+
+// AutoDecisionRulePayload to handle post and patch
+type AutoDecisionRulePayload struct {
+	Product        uint64                         `json:"product"`
+	Name           string                         `json:"name"`
+	Priority       int                            `json:"priority"`
+	Conditions     []models.AutoDecisionCondition `json:"conditions"`
+	Action         string                         `json:"action"`
+	DisburseOffset int                            `json:"disburse_offset"`
+	RejectReason   string                         `json:"reject_reason"`
+	Status         string                         `json:"status"`
+}
+
+// AutoDecisionRuleList lists auto decision rules
+func AutoDecisionRuleList(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "core_auto_decision_rule_list")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	// pagination parameters
+	rows, err := strconv.Atoi(c.QueryParam("rows"))
+	page, err := strconv.Atoi(c.QueryParam("page"))
+	orderby := strings.Split(c.QueryParam("orderby"), ",")
+	sort := strings.Split(c.QueryParam("sort"), ",")
+
+	type Filter struct {
+		Product string `json:"product"`
+		Name    string `json:"name" condition:"LIKE"`
+		Action  string `json:"action"`
+		Status  string `json:"status"`
+	}
+
+	rule := models.AutoDecisionRule{}
+	result, err := rule.PagedFindFilter(page, rows, orderby, sort, &Filter{
+		Product: c.QueryParam("product"),
+		Name:    c.QueryParam("name"),
+		Action:  c.QueryParam("action"),
+		Status:  c.QueryParam("status"),
+	})
+	if err != nil {
+		NLog("warning", "AutoDecisionRuleList", map[string]interface{}{"message": "error listing auto decision rules", "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, "Pencarian tidak ditemukan")
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// AutoDecisionRuleNew adds auto decision rule of product
+func AutoDecisionRuleNew(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "core_auto_decision_rule_new")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	rulePayload := AutoDecisionRulePayload{}
+	payloadRules := govalidator.MapData{
+		"product":         []string{"required"},
+		"name":            []string{"required"},
+		"priority":        []string{},
+		"conditions":      []string{"required"},
+		"action":          []string{"required", "in:approve,reject"},
+		"disburse_offset": []string{},
+		"reject_reason":   []string{},
+		"status":          []string{"required", "active_inactive"},
+	}
+
+	validate := validateRequestPayload(c, payloadRules, &rulePayload)
+	if validate == nil {
+		validate = validateAutoDecisionRule(rulePayload)
+	}
+	if validate != nil {
+		NLog("warning", "AutoDecisionRuleNew", map[string]interface{}{"message": "error validate new auto decision rule", "error": validate}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusUnprocessableEntity, validate, "Hambatan validasi")
+	}
+
+	conditions, _ := json.Marshal(rulePayload.Conditions)
+	rule := models.AutoDecisionRule{
+		Product:        rulePayload.Product,
+		Name:           rulePayload.Name,
+		Priority:       rulePayload.Priority,
+		Conditions:     postgres.Jsonb{RawMessage: conditions},
+		Action:         rulePayload.Action,
+		DisburseOffset: rulePayload.DisburseOffset,
+		RejectReason:   rulePayload.RejectReason,
+		Status:         rulePayload.Status,
+	}
+
+	err = rule.Create()
+	if err != nil {
+		NLog("error", "AutoDecisionRuleNew", map[string]interface{}{"message": "error create auto decision rule", "error": err, "rule": rule}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, "Gagal membuat aturan keputusan otomatis baru")
+	}
+
+	NAudittrail(models.AutoDecisionRule{}, rule, c.Get("user").(*jwt.Token), "auto decision rule", fmt.Sprint(rule.ID), "create")
+
+	return c.JSON(http.StatusCreated, rule)
+}
+
+// AutoDecisionRuleDetail get auto decision rule by id
+func AutoDecisionRuleDetail(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "core_auto_decision_rule_detail")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	ruleID, _ := strconv.ParseUint(c.Param("rule_id"), 10, 64)
+	rule := models.AutoDecisionRule{}
+	err = rule.FindbyID(ruleID)
+	if err != nil {
+		NLog("warning", "AutoDecisionRuleDetail", map[string]interface{}{"message": fmt.Sprintf("auto decision rule %v not found", ruleID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusNotFound, err, fmt.Sprintf("Aturan keputusan otomatis %v tidak ditemukan", ruleID))
+	}
+
+	return c.JSON(http.StatusOK, rule)
+}
+
+// AutoDecisionRulePatch edit auto decision rule by id
+func AutoDecisionRulePatch(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "core_auto_decision_rule_patch")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	ruleID, _ := strconv.ParseUint(c.Param("rule_id"), 10, 64)
+	rule := models.AutoDecisionRule{}
+	err = rule.FindbyID(ruleID)
+	if err != nil {
+		NLog("warning", "AutoDecisionRulePatch", map[string]interface{}{"message": fmt.Sprintf("auto decision rule %v not found", ruleID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusNotFound, err, fmt.Sprintf("Aturan keputusan otomatis %v tidak ditemukan", ruleID))
+	}
+	origin := rule
+
+	rulePayload := AutoDecisionRulePayload{
+		Product:        rule.Product,
+		Name:           rule.Name,
+		Priority:       rule.Priority,
+		Conditions:     rule.ConditionList(),
+		Action:         rule.Action,
+		DisburseOffset: rule.DisburseOffset,
+		RejectReason:   rule.RejectReason,
+		Status:         rule.Status,
+	}
+	payloadRules := govalidator.MapData{
+		"product":         []string{},
+		"name":            []string{},
+		"priority":        []string{},
+		"conditions":      []string{},
+		"action":          []string{"in:approve,reject"},
+		"disburse_offset": []string{},
+		"reject_reason":   []string{},
+		"status":          []string{"active_inactive"},
+	}
+
+	validate := validateRequestPayload(c, payloadRules, &rulePayload)
+	if validate == nil {
+		validate = validateAutoDecisionRule(rulePayload)
+	}
+	if validate != nil {
+		NLog("warning", "AutoDecisionRulePatch", map[string]interface{}{"message": "validation error", "error": validate}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusUnprocessableEntity, validate, "Hambatan validasi")
+	}
+
+	conditions, _ := json.Marshal(rulePayload.Conditions)
+	rule.Product = rulePayload.Product
+	rule.Name = rulePayload.Name
+	rule.Priority = rulePayload.Priority
+	rule.Conditions = postgres.Jsonb{RawMessage: conditions}
+	rule.Action = rulePayload.Action
+	rule.DisburseOffset = rulePayload.DisburseOffset
+	rule.RejectReason = rulePayload.RejectReason
+	rule.Status = rulePayload.Status
+
+	err = rule.Save()
+	if err != nil {
+		NLog("error", "AutoDecisionRulePatch", map[string]interface{}{"message": fmt.Sprintf("error update auto decision rule %v", rule.ID), "error": err, "rule": rule}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, fmt.Sprintf("Gagal update aturan keputusan otomatis %v", rule.ID))
+	}
+
+	NAudittrail(origin, rule, c.Get("user").(*jwt.Token), "auto decision rule", fmt.Sprint(rule.ID), "update")
+
+	return c.JSON(http.StatusOK, rule)
+}
+
+// AutoDecisionRuleDelete removes auto decision rule by id
+func AutoDecisionRuleDelete(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "core_auto_decision_rule_delete")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	ruleID, _ := strconv.ParseUint(c.Param("rule_id"), 10, 64)
+	rule := models.AutoDecisionRule{}
+	err = rule.FindbyID(ruleID)
+	if err != nil {
+		NLog("warning", "AutoDecisionRuleDelete", map[string]interface{}{"message": fmt.Sprintf("auto decision rule %v not found", ruleID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusNotFound, err, fmt.Sprintf("Aturan keputusan otomatis %v tidak ditemukan", ruleID))
+	}
+	origin := rule
+
+	err = rule.Delete()
+	if err != nil {
+		NLog("error", "AutoDecisionRuleDelete", map[string]interface{}{"message": fmt.Sprintf("error delete auto decision rule %v", rule.ID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, fmt.Sprintf("Gagal delete aturan keputusan otomatis %v", rule.ID))
+	}
+
+	NAudittrail(origin, rule, c.Get("user").(*jwt.Token), "auto decision rule", fmt.Sprint(rule.ID), "delete")
+
+	return c.JSON(http.StatusOK, rule)
+}
+
+// AutoDecisionRuleTest shows which rule would decide the loan and the facts it is evaluated on, without deciding it
+func AutoDecisionRuleTest(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "core_auto_decision_rule_test")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	type TestPayload struct {
+		LoanID uint64 `json:"loan_id"`
+	}
+	testPayload := TestPayload{}
+	payloadRules := govalidator.MapData{
+		"loan_id": []string{"required"},
+	}
+
+	validate := validateRequestPayload(c, payloadRules, &testPayload)
+	if validate != nil {
+		NLog("warning", "AutoDecisionRuleTest", map[string]interface{}{"message": "validation error", "error": validate}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusUnprocessableEntity, validate, "Hambatan validasi")
+	}
+
+	loan := models.Loan{}
+	err = loan.FindbyID(testPayload.LoanID)
+	if err != nil {
+		NLog("warning", "AutoDecisionRuleTest", map[string]interface{}{"message": fmt.Sprintf("loan %v not found", testPayload.LoanID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusNotFound, err, fmt.Sprintf("Pinjaman %v tidak ditemukan", testPayload.LoanID))
+	}
+
+	rule, facts, matched, err := models.MatchAutoDecisionRule(loan)
+	if err != nil {
+		NLog("error", "AutoDecisionRuleTest", map[string]interface{}{"message": fmt.Sprintf("error evaluating auto decision rules of loan %v", loan.ID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, "Terjadi kesalahan")
+	}
+
+	response := map[string]interface{}{
+		"loan":    loan.ID,
+		"matched": matched,
+		"facts":   facts,
+		"rule":    nil,
+	}
+	if matched {
+		response["rule"] = rule
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// AutoDecisionList lists decisions made by auto decision rules
+func AutoDecisionList(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "core_auto_decision_list")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	// pagination parameters
+	rows, err := strconv.Atoi(c.QueryParam("rows"))
+	page, err := strconv.Atoi(c.QueryParam("page"))
+	orderby := strings.Split(c.QueryParam("orderby"), ",")
+	sort := strings.Split(c.QueryParam("sort"), ",")
+
+	type Filter struct {
+		Loan   string `json:"loan"`
+		Rule   string `json:"rule"`
+		Action string `json:"action"`
+	}
+
+	decision := models.LoanAutoDecision{}
+	result, err := decision.PagedFindFilter(page, rows, orderby, sort, &Filter{
+		Loan:   c.QueryParam("loan"),
+		Rule:   c.QueryParam("rule"),
+		Action: c.QueryParam("action"),
+	})
+	if err != nil {
+		NLog("warning", "AutoDecisionList", map[string]interface{}{"message": "error listing auto decisions", "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, "Pencarian tidak ditemukan")
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+func validateAutoDecisionRule(payload AutoDecisionRulePayload) interface{} {
+	errors := map[string][]string{}
+
+	product := models.Product{}
+	if err := product.FindbyID(payload.Product); err != nil {
+		errors["product"] = append(errors["product"], fmt.Sprintf("Product %v not found", payload.Product))
+	}
+
+	if len(payload.Conditions) < 1 {
+		errors["conditions"] = append(errors["conditions"], "The conditions field is required")
+	}
+	for i, condition := range payload.Conditions {
+		operators := []string{}
+		for _, fact := range models.AutoDecisionNumericFacts {
+			if fact == condition.Fact {
+				operators = []string{"lt", "lte", "gt", "gte", "eq"}
+			}
+		}
+		for _, fact := range models.AutoDecisionTextFacts {
+			if fact == condition.Fact {
+				operators = []string{"empty", "not_empty", "in"}
+			}
+		}
+		if len(operators) < 1 {
+			errors["conditions"] = append(errors["conditions"], fmt.Sprintf("Condition %v fact %v is not supported", i+1, condition.Fact))
+			continue
+		}
+
+		valid := false
+		for _, operator := range operators {
+			valid = valid || operator == condition.Operator
+		}
+		if !valid {
+			errors["conditions"] = append(errors["conditions"], fmt.Sprintf("Condition %v operator of fact %v must be one of %v", i+1, condition.Fact, strings.Join(operators, ", ")))
+		}
+		if condition.Operator == "in" && len(condition.Values) < 1 {
+			errors["conditions"] = append(errors["conditions"], fmt.Sprintf("Condition %v needs values", i+1))
+		}
+	}
+
+	if payload.DisburseOffset < 0 {
+		errors["disburse_offset"] = append(errors["disburse_offset"], "The disburse_offset field may not be negative")
+	}
+	if payload.Action == "reject" && len(payload.RejectReason) < 1 {
+		errors["reject_reason"] = append(errors["reject_reason"], "The reject_reason field is required to reject loans")
+	}
+
+	if len(errors) > 0 {
+		return errors
+	}
+
+	return nil
+}
@@ -4,7 +4,6 @@ import (
 	"asira_lender/asira"
 	"asira_lender/middlewares"
 	"asira_lender/models"
-	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -171,22 +170,20 @@ func BankNew(c echo.Context) error {
 	json.Unmarshal(marshal, &bank)
 
 	if len(bankPayload.Image) > 0 {
-		unbased, _ := base64.StdEncoding.DecodeString(bankPayload.Image)
 		filename := "agt" + strconv.FormatInt(time.Now().Unix(), 10)
-		url, err := asira.App.S3.UploadJPEG(unbased, filename)
+		url, thumbnails, err := uploadImage(bankPayload.Image, filename)
 		if err != nil {
 			NLog("error", "BankNew", map[string]interface{}{"message": fmt.Sprintf("error upload image bank %v", bank.ID), "error": err}, c.Get("user").(*jwt.Token), "", false)
 
-			return returnInvalidResponse(http.StatusInternalServerError, err, "Gagal membuat bank baru")
+			return imageUploadResponse(err, "Gagal membuat bank baru")
 		}
 
-		bank.Image = url
+		bank.Image, bank.Thumbnails = imageKeys(url, thumbnails)
 	}
 
-	err = bank.Create()
-	middlewares.SubmitKafkaPayload(bank, "bank_create")
+	err = middlewares.CreateWithOutbox(&bank, "bank_create")
 	if err != nil {
-		NLog("error", "BankNew", map[string]interface{}{"message": fmt.Sprintf("error submitting kafka bank %v", bank.ID), "error": err, "bank": bank}, c.Get("user").(*jwt.Token), "", false)
+		NLog("error", "BankNew", map[string]interface{}{"message": "error create bank", "error": err, "bank": bank}, c.Get("user").(*jwt.Token), "", false)
 
 		return returnInvalidResponse(http.StatusInternalServerError, err, "Gagal membuat bank baru")
 	}
@@ -221,6 +218,8 @@ func BankDetail(c echo.Context) error {
 		return returnInvalidResponse(http.StatusNotFound, err, fmt.Sprintf("Bank type %v tidak ditemukan", bankID))
 	}
 
+	presignImageThumbnails(c, "core_view_image", "bank", fmt.Sprint(bank.ID), &bank.Image, &bank.Thumbnails)
+
 	return c.JSON(http.StatusOK, bank)
 }
 
@@ -292,26 +291,23 @@ func BankPatch(c echo.Context) error {
 		bank.Phone = bankPayload.Phone
 	}
 	if len(bankPayload.Image) > 0 {
-		unbased, _ := base64.StdEncoding.DecodeString(bankPayload.Image)
 		filename := "agt" + strconv.FormatInt(time.Now().Unix(), 10)
-		url, err := asira.App.S3.UploadJPEG(unbased, filename)
+		url, thumbnails, err := uploadImage(bankPayload.Image, filename)
 		if err != nil {
-			return returnInvalidResponse(http.StatusInternalServerError, err, "Gagal membuat bank baru")
+			return imageUploadResponse(err, "Gagal membuat bank baru")
 		}
 
-		i := strings.Split(bank.Image, "/")
-		delImage := i[len(i)-1]
-		err = asira.App.S3.DeleteObject(delImage)
+		err = deleteImage(bank.Image, bank.Thumbnails)
 		if err != nil {
-			log.Printf("failed to delete image %v from s3 bucket", delImage)
+			log.Printf("failed to delete image %v from s3 bucket", bank.Image)
 		}
 
-		bank.Image = url
+		bank.Image, bank.Thumbnails = imageKeys(url, thumbnails)
 	}
 
-	err = middlewares.SubmitKafkaPayload(bank, "bank_update")
+	err = middlewares.SaveWithOutbox(&bank, "bank_update")
 	if err != nil {
-		NLog("error", "BankPatch", map[string]interface{}{"message": fmt.Sprintf("error submitting bank %v", bank.ID), "error": err, "bank": bank}, c.Get("user").(*jwt.Token), "", false)
+		NLog("error", "BankPatch", map[string]interface{}{"message": fmt.Sprintf("error updating bank %v", bank.ID), "error": err, "bank": bank}, c.Get("user").(*jwt.Token), "", false)
 
 		return returnInvalidResponse(http.StatusInternalServerError, err, fmt.Sprintf("Gagal update bank %v", bankID))
 	}
@@ -339,9 +335,9 @@ func BankDelete(c echo.Context) error {
 		return returnInvalidResponse(http.StatusNotFound, err, fmt.Sprintf("Bank type %v tidak ditemukan", bankID))
 	}
 
-	err = middlewares.SubmitKafkaPayload(bank, "bank_delete")
+	err = middlewares.DeleteWithOutbox(&bank, "bank_delete")
 	if err != nil {
-		NLog("error", "BankDelete", map[string]interface{}{"message": fmt.Sprintf("error submitting kafka bank %v", bankID), "error": err, "bank": bank}, c.Get("user").(*jwt.Token), "", false)
+		NLog("error", "BankDelete", map[string]interface{}{"message": fmt.Sprintf("error deleting bank %v", bankID), "error": err, "bank": bank}, c.Get("user").(*jwt.Token), "", false)
 
 		return returnInvalidResponse(http.StatusInternalServerError, err, fmt.Sprintf("Gagal update bank tipe %v", bankID))
 	}
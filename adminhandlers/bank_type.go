@@ -80,8 +80,7 @@ func BankTypeNew(c echo.Context) error {
 	marshal, _ := json.Marshal(bankTypePayload)
 	json.Unmarshal(marshal, &bankType)
 
-	err = bankType.Create()
-	middlewares.SubmitKafkaPayload(bankType, "bank_type_create")
+	err = middlewares.CreateWithOutbox(&bankType, "bank_type_create")
 	if err != nil {
 		return returnInvalidResponse(http.StatusInternalServerError, err, "Gagal membuat tipe bank baru")
 	}
@@ -151,9 +150,9 @@ func BankTypePatch(c echo.Context) error {
 		bankType.Description = bankTypePayload.Description
 	}
 
-	err = middlewares.SubmitKafkaPayload(bankType, "bank_type_update")
+	err = middlewares.SaveWithOutbox(&bankType, "bank_type_update")
 	if err != nil {
-		NLog("error", "BankTypePatch", map[string]interface{}{"message": fmt.Sprintf("error submit kafka for bank type %v", bankType.ID), "error": err, "bank type": bankType}, c.Get("user").(*jwt.Token), "", false)
+		NLog("error", "BankTypePatch", map[string]interface{}{"message": fmt.Sprintf("error updating bank type %v", bankType.ID), "error": err, "bank type": bankType}, c.Get("user").(*jwt.Token), "", false)
 
 		return returnInvalidResponse(http.StatusInternalServerError, err, fmt.Sprintf("Gagal update bank tipe %v", bankID))
 	}
@@ -181,9 +180,9 @@ func BankTypeDelete(c echo.Context) error {
 		return returnInvalidResponse(http.StatusNotFound, err, fmt.Sprintf("Bank type %v tidak ditemukan", bankID))
 	}
 
-	err = middlewares.SubmitKafkaPayload(bankType, "bank_type_delete")
+	err = middlewares.DeleteWithOutbox(&bankType, "bank_type_delete")
 	if err != nil {
-		NLog("error", "BankTypeDelete", map[string]interface{}{"message": fmt.Sprintf("error submit kafka bank type %v", bankID), "error": err, "bank type": bankType}, c.Get("user").(*jwt.Token), "", false)
+		NLog("error", "BankTypeDelete", map[string]interface{}{"message": fmt.Sprintf("error deleting bank type %v", bankID), "error": err, "bank type": bankType}, c.Get("user").(*jwt.Token), "", false)
 
 		return returnInvalidResponse(http.StatusInternalServerError, err, fmt.Sprintf("Gagal update bank tipe %v", bankID))
 	}
@@ -0,0 +1,172 @@
+package adminhandlers
+
+import (
+	"asira_lender/models"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/labstack/echo"
+	"github.com/thedevsaddam/govalidator"
+)
+
+// DocumentTypePayload to handle post and patch
+type DocumentTypePayload struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Status      string `json:"status"`
+}
+
+// DocumentTypeList lists all document type
+func DocumentTypeList(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "core_document_type_list")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	// pagination parameters
+	rows, err := strconv.Atoi(c.QueryParam("rows"))
+	page, err := strconv.Atoi(c.QueryParam("page"))
+	orderby := strings.Split(c.QueryParam("orderby"), ",")
+	sort := strings.Split(c.QueryParam("sort"), ",")
+
+	type Filter struct {
+		ID     []string `json:"id"`
+		Name   string   `json:"name" condition:"LIKE"`
+		Status string   `json:"status"`
+	}
+
+	documentType := models.DocumentType{}
+	result, err := documentType.PagedFindFilter(page, rows, orderby, sort, &Filter{
+		ID:     customSplit(c.QueryParam("id"), ","),
+		Name:   c.QueryParam("name"),
+		Status: c.QueryParam("status"),
+	})
+	if err != nil {
+		NLog("warning", "DocumentTypeList", map[string]interface{}{"message": "error listing document type", "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, "Pencarian tidak ditemukan")
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// DocumentTypeNew add new document type
+func DocumentTypeNew(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "core_document_type_new")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	documentTypePayload := DocumentTypePayload{}
+
+	payloadRules := govalidator.MapData{
+		"name":        []string{"required"},
+		"description": []string{},
+		"status":      []string{"required", "active_inactive"},
+	}
+
+	validate := validateRequestPayload(c, payloadRules, &documentTypePayload)
+	if validate != nil {
+		NLog("warning", "DocumentTypeNew", map[string]interface{}{"message": "error validate new document type", "error": validate}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusUnprocessableEntity, validate, "Hambatan validasi")
+	}
+
+	documentType := models.DocumentType{
+		Name:        documentTypePayload.Name,
+		Description: documentTypePayload.Description,
+		Status:      documentTypePayload.Status,
+	}
+
+	err = documentType.Create()
+	if err != nil {
+		NLog("error", "DocumentTypeNew", map[string]interface{}{"message": "error create document type", "error": err, "document type": documentType}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, "Gagal membuat tipe dokumen baru")
+	}
+
+	NAudittrail(models.DocumentType{}, documentType, c.Get("user").(*jwt.Token), "document type", fmt.Sprint(documentType.ID), "create")
+
+	return c.JSON(http.StatusCreated, documentType)
+}
+
+// DocumentTypeDetail get document type detail by id
+func DocumentTypeDetail(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "core_document_type_detail")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	documentTypeID, _ := strconv.ParseUint(c.Param("document_type_id"), 10, 64)
+
+	documentType := models.DocumentType{}
+	err = documentType.FindbyID(documentTypeID)
+	if err != nil {
+		NLog("warning", "DocumentTypeDetail", map[string]interface{}{"message": fmt.Sprintf("document type %v not found", documentTypeID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusNotFound, err, fmt.Sprintf("Tipe dokumen %v tidak ditemukan", documentTypeID))
+	}
+
+	return c.JSON(http.StatusOK, documentType)
+}
+
+// DocumentTypePatch edit document type by id
+func DocumentTypePatch(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "core_document_type_patch")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	documentTypeID, _ := strconv.ParseUint(c.Param("document_type_id"), 10, 64)
+
+	documentType := models.DocumentType{}
+	err = documentType.FindbyID(documentTypeID)
+	if err != nil {
+		NLog("warning", "DocumentTypePatch", map[string]interface{}{"message": fmt.Sprintf("document type %v not found", documentTypeID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusNotFound, err, fmt.Sprintf("Tipe dokumen %v tidak ditemukan", documentTypeID))
+	}
+	origin := documentType
+
+	documentTypePayload := DocumentTypePayload{}
+	payloadRules := govalidator.MapData{
+		"name":        []string{},
+		"description": []string{},
+		"status":      []string{"active_inactive"},
+	}
+
+	validate := validateRequestPayload(c, payloadRules, &documentTypePayload)
+	if validate != nil {
+		NLog("warning", "DocumentTypePatch", map[string]interface{}{"message": "validation error", "error": validate}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusUnprocessableEntity, validate, "Hambatan validasi")
+	}
+
+	if len(documentTypePayload.Name) > 0 {
+		documentType.Name = documentTypePayload.Name
+	}
+	if len(documentTypePayload.Description) > 0 {
+		documentType.Description = documentTypePayload.Description
+	}
+	if len(documentTypePayload.Status) > 0 {
+		documentType.Status = documentTypePayload.Status
+	}
+
+	err = documentType.Save()
+	if err != nil {
+		NLog("error", "DocumentTypePatch", map[string]interface{}{"message": fmt.Sprintf("error update document type %v", documentTypeID), "error": err, "document type": documentType}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, fmt.Sprintf("Gagal update tipe dokumen %v", documentTypeID))
+	}
+
+	NAudittrail(origin, documentType, c.Get("user").(*jwt.Token), "document type", fmt.Sprint(documentType.ID), "update")
+
+	return c.JSON(http.StatusOK, documentType)
+}
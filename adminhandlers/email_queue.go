@@ -0,0 +1,100 @@
+package adminhandlers
+
+import (
+	"asira_lender/models"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/labstack/echo"
+)
+
+// EmailQueueList lists queued emails, filter status=failed to find undelivered emails
+func EmailQueueList(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "core_email_queue_list")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	// pagination parameters
+	rows, err := strconv.Atoi(c.QueryParam("rows"))
+	page, err := strconv.Atoi(c.QueryParam("page"))
+	orderby := strings.Split(c.QueryParam("orderby"), ",")
+	sort := strings.Split(c.QueryParam("sort"), ",")
+
+	type Filter struct {
+		Status []string `json:"status"`
+		Event  []string `json:"event"`
+	}
+
+	queue := models.EmailQueue{}
+	result, err := queue.PagedFindFilter(page, rows, orderby, sort, &Filter{
+		Status: customSplit(c.QueryParam("status"), ","),
+		Event:  customSplit(c.QueryParam("event"), ","),
+	})
+	if err != nil {
+		NLog("warning", "EmailQueueList", map[string]interface{}{"message": "error listing email queue", "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, "Pencarian tidak ditemukan")
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// EmailQueueDetail get queued email by id
+func EmailQueueDetail(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "core_email_queue_detail")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	queueID, _ := strconv.ParseUint(c.Param("email_id"), 10, 64)
+
+	queue := models.EmailQueue{}
+	err = queue.FindbyID(queueID)
+	if err != nil {
+		NLog("warning", "EmailQueueDetail", map[string]interface{}{"message": fmt.Sprintf("email %v not found", queueID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusNotFound, err, fmt.Sprintf("Email %v tidak ditemukan", queueID))
+	}
+
+	return c.JSON(http.StatusOK, queue)
+}
+
+// EmailQueueResend puts failed email back to the queue
+func EmailQueueResend(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "core_email_queue_resend")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	queueID, _ := strconv.ParseUint(c.Param("email_id"), 10, 64)
+
+	queue := models.EmailQueue{}
+	err = queue.FindbyID(queueID)
+	if err != nil {
+		NLog("warning", "EmailQueueResend", map[string]interface{}{"message": fmt.Sprintf("email %v not found", queueID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusNotFound, err, fmt.Sprintf("Email %v tidak ditemukan", queueID))
+	}
+	if queue.Status != "failed" {
+		return returnInvalidResponse(http.StatusUnprocessableEntity, "", fmt.Sprintf("Email %v berstatus %v, hanya email gagal yang dapat dikirim ulang", queueID, queue.Status))
+	}
+	origin := queue
+
+	err = queue.Requeue()
+	if err != nil {
+		NLog("error", "EmailQueueResend", map[string]interface{}{"message": fmt.Sprintf("error requeue email %v", queueID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, fmt.Sprintf("Gagal mengirim ulang email %v", queueID))
+	}
+
+	NAudittrail(origin, queue, c.Get("user").(*jwt.Token), "email queue", fmt.Sprint(queue.ID), "resend")
+
+	return c.JSON(http.StatusOK, queue)
+}
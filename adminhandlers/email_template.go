@@ -0,0 +1,203 @@
+package adminhandlers
+
+import (
+	"asira_lender/email"
+	"asira_lender/models"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/labstack/echo"
+	"github.com/thedevsaddam/govalidator"
+)
+
+// EmailTemplatePayload to handle post and patch
+type EmailTemplatePayload struct {
+	Event       string `json:"event"`
+	Language    string `json:"language"`
+	Subject     string `json:"subject"`
+	HTMLBody    string `json:"html_body"`
+	TextBody    string `json:"text_body"`
+	Description string `json:"description"`
+}
+
+// EmailTemplateList lists all email templates
+func EmailTemplateList(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "core_email_template_list")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	// pagination parameters
+	rows, err := strconv.Atoi(c.QueryParam("rows"))
+	page, err := strconv.Atoi(c.QueryParam("page"))
+	orderby := strings.Split(c.QueryParam("orderby"), ",")
+	sort := strings.Split(c.QueryParam("sort"), ",")
+
+	type Filter struct {
+		Event    []string `json:"event"`
+		Language string   `json:"language"`
+	}
+
+	emailTemplate := models.EmailTemplate{}
+	result, err := emailTemplate.PagedFindFilter(page, rows, orderby, sort, &Filter{
+		Event:    customSplit(c.QueryParam("event"), ","),
+		Language: c.QueryParam("language"),
+	})
+	if err != nil {
+		NLog("warning", "EmailTemplateList", map[string]interface{}{"message": "error listing email template", "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, "Pencarian tidak ditemukan")
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// EmailTemplateNew add email template for event and language
+func EmailTemplateNew(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "core_email_template_new")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	templatePayload := EmailTemplatePayload{}
+	payloadRules := govalidator.MapData{
+		"event":       []string{"required"},
+		"language":    []string{"required", "in:" + strings.Join(email.Languages, ",")},
+		"subject":     []string{"required"},
+		"html_body":   []string{"required"},
+		"text_body":   []string{"required"},
+		"description": []string{},
+	}
+
+	validate := validateRequestPayload(c, payloadRules, &templatePayload)
+	if validate != nil {
+		NLog("warning", "EmailTemplateNew", map[string]interface{}{"message": "validation error", "error": validate}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusUnprocessableEntity, validate, "Hambatan validasi")
+	}
+
+	emailTemplate := models.EmailTemplate{
+		Event:       templatePayload.Event,
+		Language:    templatePayload.Language,
+		Subject:     templatePayload.Subject,
+		HTMLBody:    templatePayload.HTMLBody,
+		TextBody:    templatePayload.TextBody,
+		Description: templatePayload.Description,
+	}
+	if err = emailTemplate.Template().Validate(emailTemplate.Event); err != nil {
+		NLog("warning", "EmailTemplateNew", map[string]interface{}{"message": "invalid template", "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusUnprocessableEntity, err.Error(), "Template email tidak valid")
+	}
+
+	type Filter struct {
+		Event    string `json:"event"`
+		Language string `json:"language"`
+	}
+	existing := models.EmailTemplate{}
+	if err = existing.SingleFindFilter(&Filter{Event: emailTemplate.Event, Language: emailTemplate.Language}); err == nil {
+		return returnInvalidResponse(http.StatusUnprocessableEntity, "", fmt.Sprintf("Template email %v bahasa %v sudah ada", emailTemplate.Event, emailTemplate.Language))
+	}
+
+	err = emailTemplate.Create()
+	if err != nil {
+		NLog("error", "EmailTemplateNew", map[string]interface{}{"message": "error create email template", "error": err, "email template": emailTemplate}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, "Gagal membuat template email baru")
+	}
+
+	NAudittrail(models.EmailTemplate{}, emailTemplate, c.Get("user").(*jwt.Token), "email template", fmt.Sprint(emailTemplate.ID), "create")
+
+	return c.JSON(http.StatusCreated, emailTemplate)
+}
+
+// EmailTemplateDetail get email template by id
+func EmailTemplateDetail(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "core_email_template_detail")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	templateID, _ := strconv.ParseUint(c.Param("template_id"), 10, 64)
+
+	emailTemplate := models.EmailTemplate{}
+	err = emailTemplate.FindbyID(templateID)
+	if err != nil {
+		NLog("warning", "EmailTemplateDetail", map[string]interface{}{"message": fmt.Sprintf("email template %v not found", templateID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusNotFound, err, fmt.Sprintf("Template email %v tidak ditemukan", templateID))
+	}
+
+	return c.JSON(http.StatusOK, emailTemplate)
+}
+
+// EmailTemplatePatch edit email template content by id
+func EmailTemplatePatch(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "core_email_template_patch")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	templateID, _ := strconv.ParseUint(c.Param("template_id"), 10, 64)
+
+	emailTemplate := models.EmailTemplate{}
+	err = emailTemplate.FindbyID(templateID)
+	if err != nil {
+		NLog("warning", "EmailTemplatePatch", map[string]interface{}{"message": fmt.Sprintf("email template %v not found", templateID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusNotFound, err, fmt.Sprintf("Template email %v tidak ditemukan", templateID))
+	}
+	origin := emailTemplate
+
+	templatePayload := EmailTemplatePayload{}
+	payloadRules := govalidator.MapData{
+		"subject":     []string{},
+		"html_body":   []string{},
+		"text_body":   []string{},
+		"description": []string{},
+	}
+
+	validate := validateRequestPayload(c, payloadRules, &templatePayload)
+	if validate != nil {
+		NLog("warning", "EmailTemplatePatch", map[string]interface{}{"message": "validation error", "error": validate}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusUnprocessableEntity, validate, "Hambatan validasi")
+	}
+
+	if len(templatePayload.Subject) > 0 {
+		emailTemplate.Subject = templatePayload.Subject
+	}
+	if len(templatePayload.HTMLBody) > 0 {
+		emailTemplate.HTMLBody = templatePayload.HTMLBody
+	}
+	if len(templatePayload.TextBody) > 0 {
+		emailTemplate.TextBody = templatePayload.TextBody
+	}
+	if len(templatePayload.Description) > 0 {
+		emailTemplate.Description = templatePayload.Description
+	}
+
+	if err = emailTemplate.Template().Validate(emailTemplate.Event); err != nil {
+		NLog("warning", "EmailTemplatePatch", map[string]interface{}{"message": "invalid template", "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusUnprocessableEntity, err.Error(), "Template email tidak valid")
+	}
+
+	err = emailTemplate.Save()
+	if err != nil {
+		NLog("error", "EmailTemplatePatch", map[string]interface{}{"message": fmt.Sprintf("error update email template %v", templateID), "error": err, "email template": emailTemplate}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, fmt.Sprintf("Gagal update template email %v", templateID))
+	}
+
+	NAudittrail(origin, emailTemplate, c.Get("user").(*jwt.Token), "email template", fmt.Sprint(emailTemplate.ID), "update")
+
+	return c.JSON(http.StatusOK, emailTemplate)
+}
@@ -0,0 +1,160 @@
+package adminhandlers
+
+import (
+	"asira_lender/models"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/labstack/echo"
+	"github.com/thedevsaddam/govalidator"
+)
+
+// FraudFlagReviewPayload to handle fraud flag review
+type FraudFlagReviewPayload struct {
+	Status string `json:"status"`
+	Note   string `json:"note"`
+}
+
+// FraudFlagList lists fraud flags waiting for review
+func FraudFlagList(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "core_fraud_flag_list")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	// pagination parameters
+	rows, err := strconv.Atoi(c.QueryParam("rows"))
+	page, err := strconv.Atoi(c.QueryParam("page"))
+	orderby := strings.Split(c.QueryParam("orderby"), ",")
+	sort := strings.Split(c.QueryParam("sort"), ",")
+
+	type Filter struct {
+		Borrower string `json:"borrower"`
+		Agent    string `json:"agent"`
+		Kind     string `json:"kind"`
+		Status   string `json:"status"`
+	}
+
+	flag := models.FraudFlag{}
+	result, err := flag.PagedFindFilter(page, rows, orderby, sort, &Filter{
+		Borrower: c.QueryParam("borrower"),
+		Agent:    c.QueryParam("agent"),
+		Kind:     c.QueryParam("kind"),
+		Status:   c.QueryParam("status"),
+	})
+	if err != nil {
+		NLog("warning", "FraudFlagList", map[string]interface{}{"message": "error listing fraud flags", "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, "Pencarian tidak ditemukan")
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// FraudFlagDetail get fraud flag by id
+func FraudFlagDetail(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "core_fraud_flag_detail")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	flagID, _ := strconv.ParseUint(c.Param("flag_id"), 10, 64)
+	flag := models.FraudFlag{}
+	err = flag.FindbyID(flagID)
+	if err != nil {
+		NLog("warning", "FraudFlagDetail", map[string]interface{}{"message": fmt.Sprintf("fraud flag %v not found", flagID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusNotFound, err, fmt.Sprintf("Indikasi fraud %v tidak ditemukan", flagID))
+	}
+
+	return c.JSON(http.StatusOK, flag)
+}
+
+// FraudFlagReview confirms or dismisses fraud flag by id
+func FraudFlagReview(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "core_fraud_flag_review")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	flagID, _ := strconv.ParseUint(c.Param("flag_id"), 10, 64)
+	flag := models.FraudFlag{}
+	err = flag.FindbyID(flagID)
+	if err != nil {
+		NLog("warning", "FraudFlagReview", map[string]interface{}{"message": fmt.Sprintf("fraud flag %v not found", flagID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusNotFound, err, fmt.Sprintf("Indikasi fraud %v tidak ditemukan", flagID))
+	}
+	origin := flag
+
+	reviewPayload := FraudFlagReviewPayload{}
+	payloadRules := govalidator.MapData{
+		"status": []string{"required", "in:confirmed,dismissed"},
+		"note":   []string{},
+	}
+
+	validate := validateRequestPayload(c, payloadRules, &reviewPayload)
+	if validate != nil {
+		NLog("warning", "FraudFlagReview", map[string]interface{}{"message": "validation error", "error": validate}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusUnprocessableEntity, validate, "Hambatan validasi")
+	}
+
+	user := c.Get("user").(*jwt.Token)
+	claims := user.Claims.(jwt.MapClaims)
+	userID, _ := strconv.ParseUint(claims["jti"].(string), 10, 64)
+	now := time.Now()
+
+	flag.Status = reviewPayload.Status
+	flag.ReviewNote = reviewPayload.Note
+	flag.ReviewedBy = userID
+	flag.ReviewedAt = &now
+
+	err = flag.Save()
+	if err != nil {
+		NLog("error", "FraudFlagReview", map[string]interface{}{"message": fmt.Sprintf("error review fraud flag %v", flag.ID), "error": err, "flag": flag}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, fmt.Sprintf("Gagal meninjau indikasi fraud %v", flag.ID))
+	}
+
+	NAudittrail(origin, flag, c.Get("user").(*jwt.Token), "fraud flag", fmt.Sprint(flag.ID), "review")
+
+	return c.JSON(http.StatusOK, flag)
+}
+
+// BorrowerFraudCheck checks borrower data against other borrowers, for borrowers registered before duplicate detection
+func BorrowerFraudCheck(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "core_fraud_flag_scan")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	borrowerID, _ := strconv.ParseUint(c.Param("borrower_id"), 10, 64)
+	borrower := models.Borrower{}
+	err = borrower.FindbyID(borrowerID)
+	if err != nil {
+		NLog("warning", "BorrowerFraudCheck", map[string]interface{}{"message": fmt.Sprintf("borrower %v not found", borrowerID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusNotFound, err, fmt.Sprintf("Nasabah %v tidak ditemukan", borrowerID))
+	}
+
+	flags, err := models.DetectBorrowerDuplicates(borrower)
+	if err != nil {
+		NLog("error", "BorrowerFraudCheck", map[string]interface{}{"message": fmt.Sprintf("error checking borrower %v duplicates", borrowerID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, fmt.Sprintf("Gagal memeriksa data nasabah %v", borrowerID))
+	}
+	if flags == nil {
+		flags = []models.FraudFlag{}
+	}
+
+	return c.JSON(http.StatusOK, flags)
+}
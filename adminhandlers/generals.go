@@ -2,9 +2,9 @@ package adminhandlers
 
 import (
 	"asira_lender/asira"
+	"asira_lender/custommodule"
 	"asira_lender/models"
 	"encoding/json"
-	"flag"
 	"fmt"
 	"log"
 	"math/rand"
@@ -183,7 +183,7 @@ func NLog(level string, tag string, message interface{}, jwttoken *jwt.Token, no
 
 	jMarshal, _ := json.Marshal(message)
 
-	if flag.Lookup("test.v") == nil {
+	if asira.App.Northstar.Send {
 		err = asira.App.Northstar.SubmitKafkaLog(northstarlib.Log{
 			Level:    level,
 			Tag:      tag,
@@ -221,7 +221,7 @@ func NAudittrail(ori interface{}, new interface{}, jwttoken *jwt.Token, entity s
 	oriMarshal, _ := json.Marshal(ori)
 	newMarshal, _ := json.Marshal(new)
 
-	if flag.Lookup("test.v") == nil {
+	if asira.App.Northstar.Send {
 		err = asira.App.Northstar.SubmitKafkaLog(northstarlib.Audittrail{
 			Client:   asira.App.Northstar.Secret,
 			UserID:   uid,
@@ -239,3 +239,37 @@ func NAudittrail(ori interface{}, new interface{}, jwttoken *jwt.Token, entity s
 		log.Printf("error northstar log : %v", err)
 	}
 }
+
+// PresignImages replaces stored image keys with short lived pre-signed urls.
+// images are emptied when user does not have the view permission, every view is audited
+func PresignImages(c echo.Context, permission string, entity string, entityID string, images ...*string) {
+	if err := validatePermission(c, permission); err != nil {
+		for _, image := range images {
+			*image = ""
+		}
+		return
+	}
+
+	expire := asira.App.Config.GetInt(fmt.Sprintf("%s.s3.presign_expire", asira.App.ENV))
+	if expire <= 0 {
+		expire = 15
+	}
+
+	var keys []string
+	for _, image := range images {
+		if len(*image) < 1 {
+			continue
+		}
+		keys = append(keys, *image)
+
+		url, err := custommodule.PresignObject(asira.App.Storage, *image, time.Duration(expire)*time.Minute)
+		if err != nil {
+			NLog("error", "PresignImages", map[string]interface{}{"message": fmt.Sprintf("error presign %v %v image", entity, entityID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+		}
+		*image = url
+	}
+
+	if len(keys) > 0 {
+		NAudittrail(nil, map[string]interface{}{"images": keys}, c.Get("user").(*jwt.Token), entity, entityID, "view_image")
+	}
+}
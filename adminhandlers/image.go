@@ -0,0 +1,132 @@
+package adminhandlers
+
+import (
+	"asira_lender/asira"
+	"asira_lender/custommodule"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/jinzhu/gorm/dialects/postgres"
+	"github.com/labstack/echo"
+)
+
+// imageConfig upload limits from config
+func imageConfig() custommodule.ImageConfig {
+	conf := custommodule.ImageConfig{
+		MaxSize:      asira.App.Config.GetInt(fmt.Sprintf("%s.image.max_size", asira.App.ENV)),
+		MaxDimension: asira.App.Config.GetInt(fmt.Sprintf("%s.image.max_dimension", asira.App.ENV)),
+	}
+	if sizes, ok := asira.App.Config.Get(fmt.Sprintf("%s.image.thumbnails", asira.App.ENV)).([]interface{}); ok {
+		for _, size := range sizes {
+			if v, ok := size.(int); ok {
+				conf.Thumbnails = append(conf.Thumbnails, v)
+			}
+		}
+	}
+	if conf.MaxSize <= 0 {
+		conf.MaxSize = 2 * 1024 * 1024
+	}
+	if conf.MaxDimension <= 0 {
+		conf.MaxDimension = 6000
+	}
+	if len(conf.Thumbnails) < 1 {
+		conf.Thumbnails = []int{128, 512}
+	}
+
+	return conf
+}
+
+// uploadImage validates base64 encoded image and stores it along with its thumbnails
+func uploadImage(based string, filename string) (url string, thumbnails *postgres.Jsonb, err error) {
+	// accept data uri as well
+	if i := strings.Index(based, ";base64,"); i >= 0 {
+		based = based[i+len(";base64,"):]
+	}
+
+	unbased, err := base64.StdEncoding.DecodeString(based)
+	if err != nil {
+		return "", thumbnails, custommodule.InvalidImageError{Reason: "base64 tidak valid"}
+	}
+
+	img, err := custommodule.ProcessImage(unbased, imageConfig())
+	if err != nil {
+		return "", thumbnails, err
+	}
+
+	url, thumbs, err := custommodule.StoreImage(asira.App.Storage, img, filename)
+	if err != nil {
+		return "", thumbnails, err
+	}
+
+	marshal, _ := json.Marshal(thumbs)
+
+	return url, &postgres.Jsonb{RawMessage: marshal}, nil
+}
+
+// deleteImage removes stored image and its thumbnails
+func deleteImage(image string, thumbnails *postgres.Jsonb) (err error) {
+	if len(image) > 0 {
+		err = asira.App.Storage.Delete(custommodule.ObjectKey(image))
+	}
+
+	thumbs := map[string]string{}
+	if thumbnails != nil {
+		json.Unmarshal(thumbnails.RawMessage, &thumbs)
+	}
+	for _, thumb := range thumbs {
+		if derr := asira.App.Storage.Delete(custommodule.ObjectKey(thumb)); derr != nil {
+			err = derr
+		}
+	}
+
+	return err
+}
+
+// imageKeys converts stored urls into object keys
+func imageKeys(url string, thumbnails *postgres.Jsonb) (string, *postgres.Jsonb) {
+	thumbs := map[string]string{}
+	if thumbnails != nil {
+		json.Unmarshal(thumbnails.RawMessage, &thumbs)
+	}
+	for size, thumb := range thumbs {
+		thumbs[size] = custommodule.ObjectKey(thumb)
+	}
+	marshal, _ := json.Marshal(thumbs)
+
+	return custommodule.ObjectKey(url), &postgres.Jsonb{RawMessage: marshal}
+}
+
+// presignImageThumbnails presigns stored image and its thumbnails keys, see PresignImages
+func presignImageThumbnails(c echo.Context, permission string, entity string, entityID string, image *string, thumbnails **postgres.Jsonb) {
+	thumbs := map[string]string{}
+	if *thumbnails != nil {
+		json.Unmarshal((*thumbnails).RawMessage, &thumbs)
+	}
+	sizes := []string{}
+	images := []*string{image}
+	for size, thumbnail := range thumbs {
+		thumbnail := thumbnail
+		sizes = append(sizes, size)
+		images = append(images, &thumbnail)
+	}
+	PresignImages(c, permission, entity, entityID, images...)
+	if len(sizes) > 0 {
+		for i, size := range sizes {
+			thumbs[size] = *images[i+1]
+		}
+		marshal, _ := json.Marshal(thumbs)
+		*thumbnails = &postgres.Jsonb{RawMessage: marshal}
+	}
+}
+
+// imageUploadResponse invalid image is a client error, anything else is a storage failure
+func imageUploadResponse(err error, message string) error {
+	if _, ok := err.(custommodule.InvalidImageError); ok {
+		return returnInvalidResponse(http.StatusUnprocessableEntity, err.Error(), "Gambar tidak valid")
+	}
+
+	return returnInvalidResponse(http.StatusInternalServerError, err, message)
+}
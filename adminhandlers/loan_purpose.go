@@ -94,13 +94,7 @@ func LoanPurposeNew(c echo.Context) error {
 	marshal, _ := json.Marshal(purposePayload)
 	json.Unmarshal(marshal, &purpose)
 
-	err = purpose.Create()
-	if err != nil {
-		NLog("error", "LoanPurposeNew", map[string]interface{}{"message": "error create loan purpose", "error": err}, c.Get("user").(*jwt.Token), "", true)
-
-		return returnInvalidResponse(http.StatusInternalServerError, err, "Gagal membuat loan purpose baru")
-	}
-	err = middlewares.SubmitKafkaPayload(purpose, "loan_purpose_create")
+	err = middlewares.CreateWithOutbox(&purpose, "loan_purpose_create")
 	if err != nil {
 		NLog("error", "LoanPurposeNew", map[string]interface{}{"message": "error create loan purpose", "error": err}, c.Get("user").(*jwt.Token), "", true)
 
@@ -172,9 +166,9 @@ func LoanPurposePatch(c echo.Context) error {
 		purpose.Status = purposePayload.Status
 	}
 
-	err = middlewares.SubmitKafkaPayload(purpose, "loan_purpose_update")
+	err = middlewares.SaveWithOutbox(&purpose, "loan_purpose_update")
 	if err != nil {
-		NLog("error", "LoanPurposePatch", map[string]interface{}{"message": fmt.Sprintf("kafka error on loan purpose %v", loanPurposeID), "loan purpose": purpose, "error": err}, c.Get("user").(*jwt.Token), "", true)
+		NLog("error", "LoanPurposePatch", map[string]interface{}{"message": fmt.Sprintf("error updating loan purpose %v", loanPurposeID), "loan purpose": purpose, "error": err}, c.Get("user").(*jwt.Token), "", true)
 
 		return returnInvalidResponse(http.StatusInternalServerError, err, fmt.Sprintf("Gagal update loan purpose %v", loanPurposeID))
 	}
@@ -202,7 +196,7 @@ func LoanPurposeDelete(c echo.Context) error {
 		return returnInvalidResponse(http.StatusNotFound, err, "Tidak memiliki hak akses")
 	}
 
-	err = middlewares.SubmitKafkaPayload(purpose, "loan_purpose_delete")
+	err = middlewares.DeleteWithOutbox(&purpose, "loan_purpose_delete")
 	if err != nil {
 		NLog("error", "LoanPurposeDelete", map[string]interface{}{"message": fmt.Sprintf("delete loan purpose %v error", loanPurposeID), "error": err, "loan purpose": purpose}, c.Get("user").(*jwt.Token), "", true)
 
@@ -0,0 +1,30 @@
+package adminhandlers
+
+import (
+	"asira_lender/middlewares"
+	"fmt"
+	"net/http"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/labstack/echo"
+)
+
+// OutboxMetrics get kafka outbox relay lag and counters
+func OutboxMetrics(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "core_outbox_metrics")
+	if err != nil {
+		NLog("warning", "OutboxMetrics", map[string]interface{}{"message": "unauthorized access", "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	stats, err := middlewares.OutboxStats()
+	if err != nil {
+		NLog("error", "OutboxMetrics", map[string]interface{}{"message": "error query outbox metrics", "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, "Gagal mendapatkan metrik outbox")
+	}
+
+	return c.JSON(http.StatusOK, stats)
+}
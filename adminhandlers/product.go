@@ -35,6 +35,9 @@ type ProductPayload struct {
 	Status                   string         `json:"status"`
 	Form                     postgres.Jsonb `json:"form"`
 	Description              string         `json:"description"`
+	MaxDTI                   *float64       `json:"max_dti"`
+	HardDTI                  *float64       `json:"hard_dti"`
+	EarlySettlementFee       *float64       `json:"early_settlement_fee"`
 }
 
 // ProductList get all product list
@@ -154,6 +157,9 @@ func ProductNew(c echo.Context) error {
 		"status":                     []string{"required", "active_inactive"},
 		"form":                       []string{},
 		"description":                []string{},
+		"max_dti":                    []string{"numeric"},
+		"hard_dti":                   []string{"numeric"},
+		"early_settlement_fee":       []string{"numeric"},
 	}
 
 	validate := validateRequestPayload(c, payloadRules, &productPayload)
@@ -166,16 +172,9 @@ func ProductNew(c echo.Context) error {
 	marshal, _ := json.Marshal(productPayload)
 	json.Unmarshal(marshal, &product)
 
-	err = product.Create()
+	err = middlewares.CreateWithOutbox(&product, "product_create")
 	if err != nil {
-		NLog("error", "ProductNew", map[string]interface{}{"message": "create product error", "error": err}, c.Get("user").(*jwt.Token), "", false)
-
-		return returnInvalidResponse(http.StatusInternalServerError, err, "Gagal membuat produk baru")
-	}
-
-	err = middlewares.SubmitKafkaPayload(product, "product_create")
-	if err != nil {
-		NLog("error", "ProductNew", map[string]interface{}{"message": "kafka submit error", "error": err, "product": product}, c.Get("user").(*jwt.Token), "", false)
+		NLog("error", "ProductNew", map[string]interface{}{"message": "create product error", "error": err, "product": product}, c.Get("user").(*jwt.Token), "", false)
 
 		return returnInvalidResponse(http.StatusInternalServerError, err, "Gagal membuat produk baru")
 	}
@@ -243,6 +242,9 @@ func ProductPatch(c echo.Context) error {
 		"status":                     []string{"active_inactive"},
 		"form":                       []string{},
 		"description":                []string{},
+		"max_dti":                    []string{"numeric"},
+		"hard_dti":                   []string{"numeric"},
+		"early_settlement_fee":       []string{"numeric"},
 	}
 	validate := validateRequestPayload(c, payloadRules, &productPayload)
 	if validate != nil {
@@ -299,10 +301,20 @@ func ProductPatch(c echo.Context) error {
 	if len(productPayload.Description) > 0 {
 		product.Description = productPayload.Description
 	}
+	// 0 disables the checks and the fee, so they are set whenever sent
+	if productPayload.MaxDTI != nil {
+		product.MaxDTI = *productPayload.MaxDTI
+	}
+	if productPayload.HardDTI != nil {
+		product.HardDTI = *productPayload.HardDTI
+	}
+	if productPayload.EarlySettlementFee != nil {
+		product.EarlySettlementFee = *productPayload.EarlySettlementFee
+	}
 
-	err = middlewares.SubmitKafkaPayload(product, "product_update")
+	err = middlewares.SaveWithOutbox(&product, "product_update")
 	if err != nil {
-		NLog("error", "ProductPatch", map[string]interface{}{"message": fmt.Sprintf("kafka submit error on product %v", productID), "error": err, "product": product}, c.Get("user").(*jwt.Token), "", false)
+		NLog("error", "ProductPatch", map[string]interface{}{"message": fmt.Sprintf("update error on product %v", productID), "error": err, "product": product}, c.Get("user").(*jwt.Token), "", false)
 
 		return returnInvalidResponse(http.StatusInternalServerError, err, fmt.Sprintf("Gagal update produk %v", productID))
 	}
@@ -330,9 +342,9 @@ func ProductDelete(c echo.Context) error {
 		return returnInvalidResponse(http.StatusNotFound, err, fmt.Sprintf("Product %v tidak ditemukan", productID))
 	}
 
-	err = middlewares.SubmitKafkaPayload(product, "product_delete")
+	err = middlewares.DeleteWithOutbox(&product, "product_delete")
 	if err != nil {
-		NLog("error", "ProductDelete", map[string]interface{}{"message": fmt.Sprintf("kafka submit error product %v", productID), "error": err, "product": product}, c.Get("user").(*jwt.Token), "", false)
+		NLog("error", "ProductDelete", map[string]interface{}{"message": fmt.Sprintf("delete error product %v", productID), "error": err, "product": product}, c.Get("user").(*jwt.Token), "", false)
 
 		return returnInvalidResponse(http.StatusInternalServerError, err, fmt.Sprintf("Gagal delete produk %v", productID))
 	}
@@ -0,0 +1,318 @@
+package adminhandlers
+
+import (
+	"asira_lender/models"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/jinzhu/gorm/dialects/postgres"
+	"github.com/labstack/echo"
+	"github.com/thedevsaddam/govalidator"
+)
+
+// ScorecardPayload to handle post and patch
+type ScorecardPayload struct {
+	Product uint64              `json:"product"`
+	Name    string              `json:"name"`
+	Rules   []models.ScoreRule  `json:"rules"`
+	Grades  []models.ScoreGrade `json:"grades"`
+	Status  string              `json:"status"`
+}
+
+// ScorecardList lists credit scorecards
+func ScorecardList(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "core_scorecard_list")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	// pagination parameters
+	rows, err := strconv.Atoi(c.QueryParam("rows"))
+	page, err := strconv.Atoi(c.QueryParam("page"))
+	orderby := strings.Split(c.QueryParam("orderby"), ",")
+	sort := strings.Split(c.QueryParam("sort"), ",")
+
+	type Filter struct {
+		Product string `json:"product"`
+		Name    string `json:"name" condition:"LIKE"`
+		Status  string `json:"status"`
+	}
+
+	scorecard := models.Scorecard{}
+	result, err := scorecard.PagedFindFilter(page, rows, orderby, sort, &Filter{
+		Product: c.QueryParam("product"),
+		Name:    c.QueryParam("name"),
+		Status:  c.QueryParam("status"),
+	})
+	if err != nil {
+		NLog("warning", "ScorecardList", map[string]interface{}{"message": "error listing scorecards", "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, "Pencarian tidak ditemukan")
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// ScorecardNew adds credit scorecard of product
+func ScorecardNew(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "core_scorecard_new")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	scorecardPayload := ScorecardPayload{}
+	payloadRules := govalidator.MapData{
+		"product": []string{"required"},
+		"name":    []string{"required"},
+		"rules":   []string{"required"},
+		"grades":  []string{"required"},
+		"status":  []string{"required", "active_inactive"},
+	}
+
+	validate := validateRequestPayload(c, payloadRules, &scorecardPayload)
+	if validate == nil {
+		validate = validateScorecard(scorecardPayload)
+	}
+	if validate != nil {
+		NLog("warning", "ScorecardNew", map[string]interface{}{"message": "error validate new scorecard", "error": validate}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusUnprocessableEntity, validate, "Hambatan validasi")
+	}
+
+	rules, _ := json.Marshal(scorecardPayload.Rules)
+	grades, _ := json.Marshal(scorecardPayload.Grades)
+	scorecard := models.Scorecard{
+		Product: scorecardPayload.Product,
+		Name:    scorecardPayload.Name,
+		Rules:   postgres.Jsonb{RawMessage: rules},
+		Grades:  postgres.Jsonb{RawMessage: grades},
+		Status:  scorecardPayload.Status,
+	}
+
+	err = scorecard.Create()
+	if err != nil {
+		NLog("error", "ScorecardNew", map[string]interface{}{"message": "error create scorecard", "error": err, "scorecard": scorecard}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, "Gagal membuat scorecard baru")
+	}
+
+	NAudittrail(models.Scorecard{}, scorecard, c.Get("user").(*jwt.Token), "scorecard", fmt.Sprint(scorecard.ID), "create")
+
+	return c.JSON(http.StatusCreated, scorecard)
+}
+
+// ScorecardDetail get credit scorecard by id
+func ScorecardDetail(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "core_scorecard_detail")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	scorecardID, _ := strconv.ParseUint(c.Param("scorecard_id"), 10, 64)
+	scorecard := models.Scorecard{}
+	err = scorecard.FindbyID(scorecardID)
+	if err != nil {
+		NLog("warning", "ScorecardDetail", map[string]interface{}{"message": fmt.Sprintf("scorecard %v not found", scorecardID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusNotFound, err, fmt.Sprintf("Scorecard %v tidak ditemukan", scorecardID))
+	}
+
+	return c.JSON(http.StatusOK, scorecard)
+}
+
+// ScorecardPatch edit credit scorecard by id. scored loans keep their score until rescored
+func ScorecardPatch(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "core_scorecard_patch")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	scorecardID, _ := strconv.ParseUint(c.Param("scorecard_id"), 10, 64)
+	scorecard := models.Scorecard{}
+	err = scorecard.FindbyID(scorecardID)
+	if err != nil {
+		NLog("warning", "ScorecardPatch", map[string]interface{}{"message": fmt.Sprintf("scorecard %v not found", scorecardID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusNotFound, err, fmt.Sprintf("Scorecard %v tidak ditemukan", scorecardID))
+	}
+	origin := scorecard
+
+	scorecardPayload := ScorecardPayload{
+		Product: scorecard.Product,
+		Rules:   scorecard.RuleList(),
+		Grades:  scorecard.GradeList(),
+	}
+	payloadRules := govalidator.MapData{
+		"product": []string{},
+		"name":    []string{},
+		"rules":   []string{},
+		"grades":  []string{},
+		"status":  []string{"active_inactive"},
+	}
+
+	validate := validateRequestPayload(c, payloadRules, &scorecardPayload)
+	if validate == nil {
+		validate = validateScorecard(scorecardPayload)
+	}
+	if validate != nil {
+		NLog("warning", "ScorecardPatch", map[string]interface{}{"message": "validation error", "error": validate}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusUnprocessableEntity, validate, "Hambatan validasi")
+	}
+
+	if len(scorecardPayload.Name) > 0 {
+		scorecard.Name = scorecardPayload.Name
+	}
+	if len(scorecardPayload.Status) > 0 {
+		scorecard.Status = scorecardPayload.Status
+	}
+	rules, _ := json.Marshal(scorecardPayload.Rules)
+	grades, _ := json.Marshal(scorecardPayload.Grades)
+	scorecard.Product = scorecardPayload.Product
+	scorecard.Rules = postgres.Jsonb{RawMessage: rules}
+	scorecard.Grades = postgres.Jsonb{RawMessage: grades}
+
+	err = scorecard.Save()
+	if err != nil {
+		NLog("error", "ScorecardPatch", map[string]interface{}{"message": fmt.Sprintf("error update scorecard %v", scorecard.ID), "error": err, "scorecard": scorecard}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, fmt.Sprintf("Gagal update scorecard %v", scorecard.ID))
+	}
+
+	NAudittrail(origin, scorecard, c.Get("user").(*jwt.Token), "scorecard", fmt.Sprint(scorecard.ID), "update")
+
+	return c.JSON(http.StatusOK, scorecard)
+}
+
+// ScorecardDelete removes credit scorecard by id
+func ScorecardDelete(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "core_scorecard_delete")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	scorecardID, _ := strconv.ParseUint(c.Param("scorecard_id"), 10, 64)
+	scorecard := models.Scorecard{}
+	err = scorecard.FindbyID(scorecardID)
+	if err != nil {
+		NLog("warning", "ScorecardDelete", map[string]interface{}{"message": fmt.Sprintf("scorecard %v not found", scorecardID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusNotFound, err, fmt.Sprintf("Scorecard %v tidak ditemukan", scorecardID))
+	}
+	origin := scorecard
+
+	err = scorecard.Delete()
+	if err != nil {
+		NLog("error", "ScorecardDelete", map[string]interface{}{"message": fmt.Sprintf("error delete scorecard %v", scorecard.ID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, fmt.Sprintf("Gagal delete scorecard %v", scorecard.ID))
+	}
+
+	NAudittrail(origin, scorecard, c.Get("user").(*jwt.Token), "scorecard", fmt.Sprint(scorecard.ID), "delete")
+
+	return c.JSON(http.StatusOK, scorecard)
+}
+
+// LoanScore recomputes loan score with current active scorecard of its product
+func LoanScore(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "core_loan_score")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	loanID, _ := strconv.ParseUint(c.Param("loan_id"), 10, 64)
+	loan := models.Loan{}
+	err = loan.FindbyID(loanID)
+	if err != nil {
+		NLog("warning", "LoanScore", map[string]interface{}{"message": fmt.Sprintf("loan %v not found", loanID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusNotFound, err, fmt.Sprintf("Pinjaman %v tidak ditemukan", loanID))
+	}
+	origin := loan
+
+	scored, err := models.ScoreLoan(&loan)
+	if err != nil {
+		NLog("error", "LoanScore", map[string]interface{}{"message": fmt.Sprintf("error scoring loan %v", loanID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, fmt.Sprintf("Gagal menghitung skor pinjaman %v", loanID))
+	}
+	if !scored {
+		return returnInvalidResponse(http.StatusUnprocessableEntity, fmt.Sprintf("product %v has no active scorecard", loan.Product), fmt.Sprintf("Produk pinjaman %v tidak memiliki scorecard aktif", loanID))
+	}
+
+	err = loan.Save()
+	if err != nil {
+		NLog("error", "LoanScore", map[string]interface{}{"message": fmt.Sprintf("error saving loan %v score", loanID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, fmt.Sprintf("Gagal menyimpan skor pinjaman %v", loanID))
+	}
+
+	NAudittrail(origin, loan, c.Get("user").(*jwt.Token), "loan", fmt.Sprint(loan.ID), "score")
+
+	return c.JSON(http.StatusOK, loan)
+}
+
+func validateScorecard(payload ScorecardPayload) interface{} {
+	errors := map[string][]string{}
+
+	product := models.Product{}
+	if err := product.FindbyID(payload.Product); err != nil {
+		errors["product"] = append(errors["product"], fmt.Sprintf("Product %v not found", payload.Product))
+	}
+
+	if len(payload.Rules) < 1 {
+		errors["rules"] = append(errors["rules"], "The rules field is required")
+	}
+	for i, rule := range payload.Rules {
+		numeric := false
+		for _, factor := range models.ScoreNumericFactors {
+			numeric = numeric || factor == rule.Factor
+		}
+		text := false
+		for _, factor := range models.ScoreTextFactors {
+			text = text || factor == rule.Factor
+		}
+
+		switch {
+		case !numeric && !text:
+			errors["rules"] = append(errors["rules"], fmt.Sprintf("Rule %v factor %v is not supported", i+1, rule.Factor))
+		case rule.Weight <= 0 || len(rule.Bands) < 1:
+			errors["rules"] = append(errors["rules"], fmt.Sprintf("Rule %v must have positive weight and at least 1 band", i+1))
+		}
+		for _, band := range rule.Bands {
+			if text && len(band.Values) < 1 {
+				errors["rules"] = append(errors["rules"], fmt.Sprintf("Rule %v bands of factor %v need values", i+1, rule.Factor))
+				break
+			}
+			if numeric && (len(band.Values) > 0 || (band.Max > 0 && band.Max <= band.Min)) {
+				errors["rules"] = append(errors["rules"], fmt.Sprintf("Rule %v bands of factor %v need max greater than min", i+1, rule.Factor))
+				break
+			}
+		}
+	}
+
+	if len(payload.Grades) < 1 {
+		errors["grades"] = append(errors["grades"], "The grades field is required")
+	}
+	for i, grade := range payload.Grades {
+		if len(grade.Grade) < 1 {
+			errors["grades"] = append(errors["grades"], fmt.Sprintf("Grade %v must have name", i+1))
+		}
+	}
+
+	if len(errors) > 0 {
+		return errors
+	}
+
+	return nil
+}
@@ -1,10 +1,8 @@
 package adminhandlers
 
 import (
-	"asira_lender/asira"
 	"asira_lender/middlewares"
 	"asira_lender/models"
-	"encoding/base64"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -101,32 +99,25 @@ func ServiceNew(c echo.Context) error {
 		return returnInvalidResponse(http.StatusUnprocessableEntity, validate, "Hambatan validasi")
 	}
 
-	unbased, _ := base64.StdEncoding.DecodeString(servicePayload.Image)
 	filename := "svc" + strconv.FormatInt(time.Now().Unix(), 10)
-	url, err := asira.App.S3.UploadJPEG(unbased, filename)
+	url, thumbnails, err := uploadImage(servicePayload.Image, filename)
 	if err != nil {
 		NLog("error", "ServiceNew", map[string]interface{}{"message": "upload image error", "error": err}, c.Get("user").(*jwt.Token), "", false)
 
-		return returnInvalidResponse(http.StatusInternalServerError, err, "Gagal membuat layanan bank baru")
+		return imageUploadResponse(err, "Gagal membuat layanan bank baru")
 	}
 
 	service := models.Service{
 		Name:        servicePayload.Name,
 		Image:       url,
+		Thumbnails:  thumbnails,
 		Status:      servicePayload.Status,
 		Description: servicePayload.Description,
 	}
 
-	err = service.Create()
+	err = middlewares.CreateWithOutbox(&service, "service_create")
 	if err != nil {
-		NLog("error", "ServiceNew", map[string]interface{}{"message": "service create error", "error": err}, c.Get("user").(*jwt.Token), "", false)
-
-		return returnInvalidResponse(http.StatusInternalServerError, err, "Gagal membuat layanan baru")
-	}
-
-	middlewares.SubmitKafkaPayload(service, "service_create")
-	if err != nil {
-		NLog("error", "ServiceNew", map[string]interface{}{"message": "kafka submit error", "error": err, "service": service}, c.Get("user").(*jwt.Token), "", false)
+		NLog("error", "ServiceNew", map[string]interface{}{"message": "service create error", "error": err, "service": service}, c.Get("user").(*jwt.Token), "", false)
 
 		return returnInvalidResponse(http.StatusInternalServerError, err, "Gagal membuat layanan baru")
 	}
@@ -194,16 +185,16 @@ func ServicePatch(c echo.Context) error {
 		service.Name = servicePayload.Name
 	}
 	if len(servicePayload.Image) > 0 {
-		unbased, _ := base64.StdEncoding.DecodeString(servicePayload.Image)
 		filename := "svc" + strconv.FormatInt(time.Now().Unix(), 10)
-		url, err := asira.App.S3.UploadJPEG(unbased, filename)
+		url, thumbnails, err := uploadImage(servicePayload.Image, filename)
 		if err != nil {
 			NLog("error", "ServicePatch", map[string]interface{}{"message": "error upload image", "error": err}, c.Get("user").(*jwt.Token), "", false)
 
-			return returnInvalidResponse(http.StatusInternalServerError, err, "Gagal membuat layanan bank baru")
+			return imageUploadResponse(err, "Gagal membuat layanan bank baru")
 		}
 
 		service.Image = url
+		service.Thumbnails = thumbnails
 	}
 	if len(servicePayload.Status) > 0 {
 		service.Status = servicePayload.Status
@@ -213,9 +204,9 @@ func ServicePatch(c echo.Context) error {
 		service.Description = servicePayload.Description
 	}
 
-	err = middlewares.SubmitKafkaPayload(service, "service_update")
+	err = middlewares.SaveWithOutbox(&service, "service_update")
 	if err != nil {
-		NLog("error", "ServicePatch", map[string]interface{}{"message": "kafka submit error", "error": err, "service": service}, c.Get("user").(*jwt.Token), "", false)
+		NLog("error", "ServicePatch", map[string]interface{}{"message": "service update error", "error": err, "service": service}, c.Get("user").(*jwt.Token), "", false)
 
 		return returnInvalidResponse(http.StatusInternalServerError, err, fmt.Sprintf("Gagal update layanan %v", serviceID))
 	}
@@ -243,9 +234,9 @@ func ServiceDelete(c echo.Context) error {
 		return returnInvalidResponse(http.StatusNotFound, err, fmt.Sprintf("Layanan %v tidak ditemukan", serviceID))
 	}
 
-	err = middlewares.SubmitKafkaPayload(service, "service_delete")
+	err = middlewares.DeleteWithOutbox(&service, "service_delete")
 	if err != nil {
-		NLog("error", "ServiceDelete", map[string]interface{}{"message": "error submit kafka", "error": err, "service": service}, c.Get("user").(*jwt.Token), "", false)
+		NLog("error", "ServiceDelete", map[string]interface{}{"message": "service delete error", "error": err, "service": service}, c.Get("user").(*jwt.Token), "", false)
 
 		return returnInvalidResponse(http.StatusInternalServerError, err, fmt.Sprintf("Gagal delete layanan %v", serviceID))
 	}
@@ -240,10 +240,11 @@ func UserNew(c echo.Context) error {
 	}
 
 	to := newUser.Email
-	subject := "[NO REPLY] - Password Aplikasi ASIRA"
-	message := "Selamat Pagi,\n\nIni adalah password anda untuk login " + tempPW + " \n\n\n Ayannah Solusi Nusantara Team"
-
-	err = email.SendMail(to, subject, message)
+	err = models.SendEmail("", email.UserPasswordData{
+		Username: newUser.Username,
+		Email:    newUser.Email,
+		Password: tempPW,
+	}, to)
 	if err != nil {
 		NLog("error", "UserNew", map[string]interface{}{"message": fmt.Sprintf("error sending password to email : %v", to), "error": err}, c.Get("user").(*jwt.Token), "", false)
 
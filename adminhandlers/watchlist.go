@@ -0,0 +1,390 @@
+package adminhandlers
+
+import (
+	"asira_lender/models"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/labstack/echo"
+	"github.com/thedevsaddam/govalidator"
+)
+
+// WatchlistPayload to handle post and patch
+type WatchlistPayload struct {
+	Bank   uint64 `json:"bank"`
+	Kind   string `json:"kind"`
+	Value  string `json:"value"`
+	Reason string `json:"reason"`
+	Status string `json:"status"`
+}
+
+// maxWatchlistImport max rows of one watchlist csv import
+const maxWatchlistImport = 5000
+
+// WatchlistList lists watchlist entries
+func WatchlistList(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "core_watchlist_list")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	// pagination parameters
+	rows, err := strconv.Atoi(c.QueryParam("rows"))
+	page, err := strconv.Atoi(c.QueryParam("page"))
+	orderby := strings.Split(c.QueryParam("orderby"), ",")
+	sort := strings.Split(c.QueryParam("sort"), ",")
+
+	type Filter struct {
+		Bank   string `json:"bank"`
+		Kind   string `json:"kind"`
+		Value  string `json:"value" condition:"LIKE"`
+		Status string `json:"status"`
+	}
+
+	watchlist := models.Watchlist{}
+	result, err := watchlist.PagedFindFilter(page, rows, orderby, sort, &Filter{
+		Bank:   c.QueryParam("bank"),
+		Kind:   c.QueryParam("kind"),
+		Value:  c.QueryParam("value"),
+		Status: c.QueryParam("status"),
+	})
+	if err != nil {
+		NLog("warning", "WatchlistList", map[string]interface{}{"message": "error listing watchlist", "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, "Pencarian tidak ditemukan")
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// WatchlistNew adds watchlist entry
+func WatchlistNew(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "core_watchlist_new")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	watchlistPayload := WatchlistPayload{}
+	payloadRules := govalidator.MapData{
+		"bank":   []string{},
+		"kind":   []string{"required", "in:" + strings.Join(models.WatchlistKinds, ",")},
+		"value":  []string{"required"},
+		"reason": []string{},
+		"status": []string{"required", "active_inactive"},
+	}
+
+	validate := validateRequestPayload(c, payloadRules, &watchlistPayload)
+	if validate == nil {
+		validate = validateWatchlist(watchlistPayload, models.Watchlist{})
+	}
+	if validate != nil {
+		NLog("warning", "WatchlistNew", map[string]interface{}{"message": "error validate new watchlist", "error": validate}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusUnprocessableEntity, validate, "Hambatan validasi")
+	}
+
+	watchlist := models.Watchlist{
+		Bank:   watchlistBank(watchlistPayload.Bank),
+		Kind:   watchlistPayload.Kind,
+		Value:  models.NormalizeWatchlistValue(watchlistPayload.Value),
+		Reason: watchlistPayload.Reason,
+		Status: watchlistPayload.Status,
+	}
+
+	err = watchlist.Create()
+	if err != nil {
+		NLog("error", "WatchlistNew", map[string]interface{}{"message": "error create watchlist", "error": err, "watchlist": watchlist}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, "Gagal membuat watchlist baru")
+	}
+
+	NAudittrail(models.Watchlist{}, watchlist, c.Get("user").(*jwt.Token), "watchlist", fmt.Sprint(watchlist.ID), "create")
+
+	return c.JSON(http.StatusCreated, watchlist)
+}
+
+// WatchlistDetail get watchlist entry by id
+func WatchlistDetail(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "core_watchlist_detail")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	watchlistID, _ := strconv.ParseUint(c.Param("watchlist_id"), 10, 64)
+	watchlist := models.Watchlist{}
+	err = watchlist.FindbyID(watchlistID)
+	if err != nil {
+		NLog("warning", "WatchlistDetail", map[string]interface{}{"message": fmt.Sprintf("watchlist %v not found", watchlistID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusNotFound, err, fmt.Sprintf("Watchlist %v tidak ditemukan", watchlistID))
+	}
+
+	return c.JSON(http.StatusOK, watchlist)
+}
+
+// WatchlistPatch edit watchlist entry by id
+func WatchlistPatch(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "core_watchlist_patch")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	watchlistID, _ := strconv.ParseUint(c.Param("watchlist_id"), 10, 64)
+	watchlist := models.Watchlist{}
+	err = watchlist.FindbyID(watchlistID)
+	if err != nil {
+		NLog("warning", "WatchlistPatch", map[string]interface{}{"message": fmt.Sprintf("watchlist %v not found", watchlistID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusNotFound, err, fmt.Sprintf("Watchlist %v tidak ditemukan", watchlistID))
+	}
+	origin := watchlist
+
+	watchlistPayload := WatchlistPayload{
+		Bank:   uint64(watchlist.Bank.Int64),
+		Kind:   watchlist.Kind,
+		Value:  watchlist.Value,
+		Reason: watchlist.Reason,
+		Status: watchlist.Status,
+	}
+	payloadRules := govalidator.MapData{
+		"bank":   []string{},
+		"kind":   []string{"in:" + strings.Join(models.WatchlistKinds, ",")},
+		"value":  []string{},
+		"reason": []string{},
+		"status": []string{"active_inactive"},
+	}
+
+	validate := validateRequestPayload(c, payloadRules, &watchlistPayload)
+	if validate == nil {
+		validate = validateWatchlist(watchlistPayload, origin)
+	}
+	if validate != nil {
+		NLog("warning", "WatchlistPatch", map[string]interface{}{"message": "validation error", "error": validate}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusUnprocessableEntity, validate, "Hambatan validasi")
+	}
+
+	watchlist.Bank = watchlistBank(watchlistPayload.Bank)
+	watchlist.Kind = watchlistPayload.Kind
+	watchlist.Value = models.NormalizeWatchlistValue(watchlistPayload.Value)
+	watchlist.Reason = watchlistPayload.Reason
+	watchlist.Status = watchlistPayload.Status
+
+	err = watchlist.Save()
+	if err != nil {
+		NLog("error", "WatchlistPatch", map[string]interface{}{"message": fmt.Sprintf("error update watchlist %v", watchlist.ID), "error": err, "watchlist": watchlist}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, fmt.Sprintf("Gagal update watchlist %v", watchlist.ID))
+	}
+
+	NAudittrail(origin, watchlist, c.Get("user").(*jwt.Token), "watchlist", fmt.Sprint(watchlist.ID), "update")
+
+	return c.JSON(http.StatusOK, watchlist)
+}
+
+// WatchlistDelete removes watchlist entry by id
+func WatchlistDelete(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "core_watchlist_delete")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	watchlistID, _ := strconv.ParseUint(c.Param("watchlist_id"), 10, 64)
+	watchlist := models.Watchlist{}
+	err = watchlist.FindbyID(watchlistID)
+	if err != nil {
+		NLog("warning", "WatchlistDelete", map[string]interface{}{"message": fmt.Sprintf("watchlist %v not found", watchlistID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusNotFound, err, fmt.Sprintf("Watchlist %v tidak ditemukan", watchlistID))
+	}
+	origin := watchlist
+
+	err = watchlist.Delete()
+	if err != nil {
+		NLog("error", "WatchlistDelete", map[string]interface{}{"message": fmt.Sprintf("error delete watchlist %v", watchlist.ID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, fmt.Sprintf("Gagal delete watchlist %v", watchlist.ID))
+	}
+
+	NAudittrail(origin, watchlist, c.Get("user").(*jwt.Token), "watchlist", fmt.Sprint(watchlist.ID), "delete")
+
+	return c.JSON(http.StatusOK, watchlist)
+}
+
+// WatchlistImport adds active watchlist entries from csv file with header kind,value,reason,bank.
+// reason and bank columns are optional, entries already listed are skipped
+func WatchlistImport(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "core_watchlist_import")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	type RowError struct {
+		Line    int    `json:"line"`
+		Message string `json:"message"`
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		NLog("warning", "WatchlistImport", map[string]interface{}{"message": "csv file not found", "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusUnprocessableEntity, map[string][]string{"file": []string{"The file field is required"}}, "Hambatan validasi")
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		NLog("error", "WatchlistImport", map[string]interface{}{"message": "error opening csv file", "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, "Gagal membaca file")
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	columns := map[string]int{}
+	for i, column := range header {
+		columns[strings.ToLower(strings.TrimSpace(column))] = i
+	}
+	_, hasKind := columns["kind"]
+	_, hasValue := columns["value"]
+	if err != nil || !hasKind || !hasValue {
+		NLog("warning", "WatchlistImport", map[string]interface{}{"message": "invalid csv header", "error": err, "header": header}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusUnprocessableEntity, map[string][]string{"file": []string{"The file must be csv with kind and value columns"}}, "Hambatan validasi")
+	}
+	column := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var (
+		imported []models.Watchlist
+		skipped  int
+		errors   []RowError
+	)
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if line-1 > maxWatchlistImport {
+			errors = append(errors, RowError{Line: line, Message: fmt.Sprintf("Import dibatasi %v baris", maxWatchlistImport)})
+			break
+		}
+		if err != nil {
+			errors = append(errors, RowError{Line: line, Message: err.Error()})
+			continue
+		}
+
+		payload := WatchlistPayload{
+			Kind:   column(record, "kind"),
+			Value:  column(record, "value"),
+			Reason: column(record, "reason"),
+			Status: "active",
+		}
+		if bank := column(record, "bank"); len(bank) > 0 {
+			payload.Bank, err = strconv.ParseUint(bank, 10, 64)
+			if err != nil {
+				errors = append(errors, RowError{Line: line, Message: fmt.Sprintf("Bank %v tidak valid", bank)})
+				continue
+			}
+		}
+
+		if validate := validateWatchlist(payload, models.Watchlist{}); validate != nil {
+			if _, duplicate := validate.(map[string][]string)["duplicate"]; duplicate {
+				skipped++
+				continue
+			}
+			var messages []string
+			for _, fieldErrors := range validate.(map[string][]string) {
+				messages = append(messages, fieldErrors...)
+			}
+			errors = append(errors, RowError{Line: line, Message: strings.Join(messages, ", ")})
+			continue
+		}
+
+		watchlist := models.Watchlist{
+			Bank:   watchlistBank(payload.Bank),
+			Kind:   payload.Kind,
+			Value:  models.NormalizeWatchlistValue(payload.Value),
+			Reason: payload.Reason,
+			Status: payload.Status,
+		}
+		if err = watchlist.Create(); err != nil {
+			NLog("error", "WatchlistImport", map[string]interface{}{"message": fmt.Sprintf("error create watchlist of line %v", line), "error": err, "watchlist": watchlist}, c.Get("user").(*jwt.Token), "", false)
+
+			errors = append(errors, RowError{Line: line, Message: "Gagal membuat watchlist"})
+			continue
+		}
+		NAudittrail(models.Watchlist{}, watchlist, c.Get("user").(*jwt.Token), "watchlist", fmt.Sprint(watchlist.ID), "import")
+
+		imported = append(imported, watchlist)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"imported": len(imported),
+		"skipped":  skipped,
+		"failed":   len(errors),
+		"errors":   errors,
+		"data":     imported,
+	})
+}
+
+func watchlistBank(bank uint64) sql.NullInt64 {
+	return sql.NullInt64{
+		Int64: int64(bank),
+		Valid: bank > 0,
+	}
+}
+
+func validateWatchlist(payload WatchlistPayload, origin models.Watchlist) interface{} {
+	errors := map[string][]string{}
+
+	valid := false
+	for _, kind := range models.WatchlistKinds {
+		valid = valid || kind == payload.Kind
+	}
+	if !valid {
+		errors["kind"] = append(errors["kind"], fmt.Sprintf("The kind field must be one of %v", strings.Join(models.WatchlistKinds, ", ")))
+	}
+
+	value := models.NormalizeWatchlistValue(payload.Value)
+	if len(value) < 1 {
+		errors["value"] = append(errors["value"], "The value field is required")
+	}
+
+	if payload.Bank > 0 {
+		bank := models.Bank{}
+		if err := bank.FindbyID(payload.Bank); err != nil {
+			errors["bank"] = append(errors["bank"], fmt.Sprintf("Bank %v not found", payload.Bank))
+		}
+	}
+
+	bank := watchlistBank(payload.Bank)
+	changed := origin.ID == 0 || origin.Bank != bank || origin.Kind != payload.Kind || origin.Value != value
+	if len(errors) < 1 && changed && models.WatchlistExists(bank, payload.Kind, value) {
+		errors["duplicate"] = append(errors["duplicate"], fmt.Sprintf("%v %v is already listed", payload.Kind, value))
+	}
+
+	if len(errors) > 0 {
+		return errors
+	}
+
+	return nil
+}
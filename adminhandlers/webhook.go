@@ -0,0 +1,343 @@
+package adminhandlers
+
+import (
+	"asira_lender/middlewares"
+	"asira_lender/models"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/labstack/echo"
+	"github.com/lib/pq"
+	"github.com/thedevsaddam/govalidator"
+)
+
+// WebhookPayload to handle post and patch
+type WebhookPayload struct {
+	URL         string   `json:"url"`
+	Secret      string   `json:"secret"`
+	Events      []string `json:"events"`
+	Description string   `json:"description"`
+	Status      string   `json:"status"`
+}
+
+// WebhookList lists webhooks of bank
+func WebhookList(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "core_webhook_list")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	bankID, _ := strconv.ParseUint(c.Param("bank_id"), 10, 64)
+
+	// pagination parameters
+	rows, err := strconv.Atoi(c.QueryParam("rows"))
+	page, err := strconv.Atoi(c.QueryParam("page"))
+	orderby := strings.Split(c.QueryParam("orderby"), ",")
+	sort := strings.Split(c.QueryParam("sort"), ",")
+
+	type Filter struct {
+		Bank   uint64 `json:"bank"`
+		Status string `json:"status"`
+	}
+
+	webhook := models.Webhook{}
+	result, err := webhook.PagedFindFilter(page, rows, orderby, sort, &Filter{
+		Bank:   bankID,
+		Status: c.QueryParam("status"),
+	})
+	if err != nil {
+		NLog("warning", "WebhookList", map[string]interface{}{"message": fmt.Sprintf("error listing webhooks of bank %v", bankID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, "Pencarian tidak ditemukan")
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// WebhookNew subscribes bank system to loan events
+func WebhookNew(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "core_webhook_new")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	bankID, _ := strconv.ParseUint(c.Param("bank_id"), 10, 64)
+	bank := models.Bank{}
+	err = bank.FindbyID(bankID)
+	if err != nil {
+		NLog("warning", "WebhookNew", map[string]interface{}{"message": fmt.Sprintf("bank %v not found", bankID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusNotFound, err, fmt.Sprintf("Bank %v tidak ditemukan", bankID))
+	}
+
+	webhookPayload := WebhookPayload{}
+	payloadRules := govalidator.MapData{
+		"url":         []string{"required", "url"},
+		"secret":      []string{},
+		"events":      []string{"required"},
+		"description": []string{},
+		"status":      []string{"required", "active_inactive"},
+	}
+
+	validate := validateRequestPayload(c, payloadRules, &webhookPayload)
+	if validate == nil {
+		validate = validateWebhookEvents(webhookPayload.Events)
+	}
+	if validate != nil {
+		NLog("warning", "WebhookNew", map[string]interface{}{"message": "error validate new webhook", "error": validate}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusUnprocessableEntity, validate, "Hambatan validasi")
+	}
+
+	if len(webhookPayload.Secret) < 1 {
+		webhookPayload.Secret = newWebhookSecret()
+	}
+
+	webhook := models.Webhook{
+		Bank:        bank.ID,
+		URL:         webhookPayload.URL,
+		Secret:      webhookPayload.Secret,
+		Events:      pq.StringArray(webhookPayload.Events),
+		Description: webhookPayload.Description,
+		Status:      webhookPayload.Status,
+	}
+
+	err = webhook.Create()
+	if err != nil {
+		NLog("error", "WebhookNew", map[string]interface{}{"message": "error create webhook", "error": err, "webhook": webhook}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, "Gagal membuat webhook baru")
+	}
+
+	NAudittrail(models.Webhook{}, webhook, c.Get("user").(*jwt.Token), "webhook", fmt.Sprint(webhook.ID), "create")
+
+	return c.JSON(http.StatusCreated, webhook)
+}
+
+// WebhookDetail get webhook of bank by id
+func WebhookDetail(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "core_webhook_detail")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	webhook, err := bankWebhook(c, "WebhookDetail")
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, webhook)
+}
+
+// WebhookPatch edit webhook of bank by id
+func WebhookPatch(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "core_webhook_patch")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	webhook, err := bankWebhook(c, "WebhookPatch")
+	if err != nil {
+		return err
+	}
+	origin := webhook
+
+	webhookPayload := WebhookPayload{}
+	payloadRules := govalidator.MapData{
+		"url":         []string{"url"},
+		"secret":      []string{},
+		"events":      []string{},
+		"description": []string{},
+		"status":      []string{"active_inactive"},
+	}
+
+	validate := validateRequestPayload(c, payloadRules, &webhookPayload)
+	if validate == nil && len(webhookPayload.Events) > 0 {
+		validate = validateWebhookEvents(webhookPayload.Events)
+	}
+	if validate != nil {
+		NLog("warning", "WebhookPatch", map[string]interface{}{"message": "validation error", "error": validate}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusUnprocessableEntity, validate, "Hambatan validasi")
+	}
+
+	if len(webhookPayload.URL) > 0 {
+		webhook.URL = webhookPayload.URL
+	}
+	if len(webhookPayload.Secret) > 0 {
+		webhook.Secret = webhookPayload.Secret
+	}
+	if len(webhookPayload.Events) > 0 {
+		webhook.Events = pq.StringArray(webhookPayload.Events)
+	}
+	if len(webhookPayload.Description) > 0 {
+		webhook.Description = webhookPayload.Description
+	}
+	if len(webhookPayload.Status) > 0 {
+		webhook.Status = webhookPayload.Status
+	}
+
+	err = webhook.Save()
+	if err != nil {
+		NLog("error", "WebhookPatch", map[string]interface{}{"message": fmt.Sprintf("error update webhook %v", webhook.ID), "error": err, "webhook": webhook}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, fmt.Sprintf("Gagal update webhook %v", webhook.ID))
+	}
+
+	NAudittrail(origin, webhook, c.Get("user").(*jwt.Token), "webhook", fmt.Sprint(webhook.ID), "update")
+
+	return c.JSON(http.StatusOK, webhook)
+}
+
+// WebhookDelete removes webhook of bank by id
+func WebhookDelete(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "core_webhook_delete")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	webhook, err := bankWebhook(c, "WebhookDelete")
+	if err != nil {
+		return err
+	}
+	origin := webhook
+
+	err = webhook.Delete()
+	if err != nil {
+		NLog("error", "WebhookDelete", map[string]interface{}{"message": fmt.Sprintf("error delete webhook %v", webhook.ID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, fmt.Sprintf("Gagal delete webhook %v", webhook.ID))
+	}
+
+	NAudittrail(origin, webhook, c.Get("user").(*jwt.Token), "webhook", fmt.Sprint(webhook.ID), "delete")
+
+	return c.JSON(http.StatusOK, webhook)
+}
+
+// WebhookTest sends test event to webhook right away and returns the recorded delivery
+func WebhookTest(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "core_webhook_test")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	webhook, err := bankWebhook(c, "WebhookTest")
+	if err != nil {
+		return err
+	}
+
+	delivery := models.NewWebhookDelivery(webhook, models.WebhookTest, "", map[string]interface{}{
+		"message": "test event",
+	})
+	err = delivery.Create()
+	if err != nil {
+		NLog("error", "WebhookTest", map[string]interface{}{"message": fmt.Sprintf("error create test delivery of webhook %v", webhook.ID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, "Gagal mengirim test webhook")
+	}
+
+	err = middlewares.DeliverWebhook(webhook, &delivery)
+	if err != nil {
+		NLog("warning", "WebhookTest", map[string]interface{}{"message": fmt.Sprintf("test delivery of webhook %v failed", webhook.ID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+	}
+
+	return c.JSON(http.StatusOK, delivery)
+}
+
+// WebhookDeliveryList lists delivery logs of webhook
+func WebhookDeliveryList(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "core_webhook_delivery_list")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	webhook, err := bankWebhook(c, "WebhookDeliveryList")
+	if err != nil {
+		return err
+	}
+
+	// pagination parameters
+	rows, err := strconv.Atoi(c.QueryParam("rows"))
+	page, err := strconv.Atoi(c.QueryParam("page"))
+	orderby := strings.Split(c.QueryParam("orderby"), ",")
+	sort := strings.Split(c.QueryParam("sort"), ",")
+
+	type Filter struct {
+		Webhook uint64   `json:"webhook"`
+		Status  []string `json:"status"`
+		Event   []string `json:"event"`
+	}
+
+	delivery := models.WebhookDelivery{}
+	result, err := delivery.PagedFindFilter(page, rows, orderby, sort, &Filter{
+		Webhook: webhook.ID,
+		Status:  customSplit(c.QueryParam("status"), ","),
+		Event:   customSplit(c.QueryParam("event"), ","),
+	})
+	if err != nil {
+		NLog("warning", "WebhookDeliveryList", map[string]interface{}{"message": fmt.Sprintf("error listing deliveries of webhook %v", webhook.ID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, "Pencarian tidak ditemukan")
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// bankWebhook finds :webhook_id of :bank_id. returned error is ready to be sent as response
+func bankWebhook(c echo.Context, tag string) (webhook models.Webhook, err error) {
+	bankID, _ := strconv.ParseUint(c.Param("bank_id"), 10, 64)
+	webhookID, _ := strconv.ParseUint(c.Param("webhook_id"), 10, 64)
+
+	type Filter struct {
+		ID   uint64 `json:"id"`
+		Bank uint64 `json:"bank"`
+	}
+	err = webhook.SingleFindFilter(&Filter{
+		ID:   webhookID,
+		Bank: bankID,
+	})
+	if err != nil {
+		NLog("warning", tag, map[string]interface{}{"message": fmt.Sprintf("webhook %v of bank %v not found", webhookID, bankID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return webhook, returnInvalidResponse(http.StatusNotFound, err, fmt.Sprintf("Webhook %v tidak ditemukan", webhookID))
+	}
+
+	return webhook, nil
+}
+
+func validateWebhookEvents(events []string) interface{} {
+	for _, event := range events {
+		valid := false
+		for _, webhookEvent := range models.WebhookEvents {
+			if event == webhookEvent {
+				valid = true
+			}
+		}
+		if !valid {
+			return map[string][]string{
+				"events": []string{fmt.Sprintf("The events field must be one of %s", strings.Join(models.WebhookEvents, ","))},
+			}
+		}
+	}
+
+	return nil
+}
+
+func newWebhookSecret() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -27,16 +28,17 @@ var App *Application
 type (
 	// Application main variable of the app
 	Application struct {
-		Name       string          `json:"name"`
-		Port       string          `json:"port"`
-		Version    string          `json:"version"`
-		ENV        string          `json:"env"`
-		Config     viper.Viper     `json:"prog_config"`
-		DB         *gorm.DB        `json:"db"`
-		Kafka      KafkaInstance   `json:"kafka"`
-		S3         custommodule.S3 `json:"s3"`
-		Cron       cron.Cron       `json:"cron"`
-		Permission viper.Viper     `json:"prog_permission"`
+		Name       string                  `json:"name"`
+		Port       string                  `json:"port"`
+		Version    string                  `json:"version"`
+		ENV        string                  `json:"env"`
+		Config     viper.Viper             `json:"prog_config"`
+		DB         *gorm.DB                `json:"db"`
+		Kafka      KafkaInstance           `json:"kafka"`
+		Bus        custommodule.MessageBus `json:"bus"`
+		Storage    custommodule.Storage    `json:"storage"`
+		Cron       cron.Cron               `json:"cron"`
+		Permission viper.Viper             `json:"prog_permission"`
 		Northstar  northstarlib.NorthstarLib
 	}
 
@@ -66,7 +68,10 @@ func init() {
 	}
 
 	App.KafkaInit()
-	App.S3init()
+	App.BusInit()
+	if err = App.S3init(); err != nil {
+		log.Printf("Storage init error : %v", err)
+	}
 	App.CronInit()
 	App.NorthstarInit()
 
@@ -81,6 +86,9 @@ func (x *Application) Close() (err error) {
 		return err
 	}
 	x.Cron.Stop()
+	if err = x.Bus.Close(); err != nil {
+		return err
+	}
 
 	return nil
 }
@@ -177,6 +185,24 @@ func (x *Application) KafkaInit() {
 	x.Kafka.Host = strings.Join([]string{kafkaConf["host"].(string), kafkaConf["port"].(string)}, ":")
 }
 
+// BusInit load message bus driver
+func (x *Application) BusInit() {
+	driver := x.Config.GetString(fmt.Sprintf("%s.messagebus.driver", x.ENV))
+
+	switch driver {
+	default:
+		x.Bus = custommodule.NewKafkaBus(x.Kafka.Host, x.Kafka.Config)
+		break
+	case "memory":
+		loopback := true
+		if key := fmt.Sprintf("%s.messagebus.loopback", x.ENV); x.Config.IsSet(key) {
+			loopback = x.Config.GetBool(key)
+		}
+		x.Bus = custommodule.NewMemoryBus(loopback)
+		break
+	}
+}
+
 // LoadPermissions loads general configs
 func (x *Application) LoadPermissions() error {
 	var conf *viper.Viper
@@ -200,11 +226,30 @@ func (x *Application) LoadPermissions() error {
 	return nil
 }
 
-// S3init load config for s3
+// S3init load object storage
 func (x *Application) S3init() (err error) {
-	s3conf := x.Config.GetStringMap(fmt.Sprintf("%s.s3", x.ENV))
+	key := func(k string) string {
+		return fmt.Sprintf("%s.s3.%s", x.ENV, k)
+	}
 
-	x.S3, err = custommodule.NewS3(s3conf["access_key"].(string), s3conf["secret_key"].(string), s3conf["host"].(string), s3conf["bucket_name"].(string), s3conf["region"].(string))
+	driver := x.Config.GetString(key("driver"))
+
+	switch driver {
+	default:
+		x.Storage, err = custommodule.NewS3(x.Config.GetString(key("access_key")), x.Config.GetString(key("secret_key")), x.Config.GetString(key("host")), x.Config.GetString(key("bucket_name")), x.Config.GetString(key("region")), x.Config.GetBool(key("insecure_skip_verify")))
+		break
+	case "filesystem":
+		root := x.Config.GetString(key("root"))
+		if len(root) < 1 {
+			root = filepath.Join(os.TempDir(), x.Name+"_files")
+		}
+		baseURL := x.Config.GetString(key("base_url"))
+		if len(baseURL) < 1 {
+			baseURL = "/files"
+		}
+		x.Storage, err = custommodule.NewFileStorage(root, baseURL, x.Config.GetString(fmt.Sprintf("%s.passphrase", x.ENV)))
+		break
+	}
 
 	return err
 }
@@ -214,6 +259,10 @@ func (x *Application) CronInit() (err error) {
 	x.Cron.TZ = x.Config.GetString(fmt.Sprintf("%s.database.timezone", x.ENV))
 	cron.DB = x.DB
 	x.Cron.Time = x.Config.GetString(fmt.Sprintf("%s.cron.time", x.ENV))
+	x.Cron.DedupRetention = x.Config.GetInt(fmt.Sprintf("%s.kafka.dedup.retention", x.ENV))
+	if x.Cron.DedupRetention <= 0 {
+		x.Cron.DedupRetention = 168
+	}
 	x.Cron.New()
 	x.Cron.Start()
 
@@ -10,9 +10,10 @@ import (
 
 //Cron main type
 type Cron struct {
-	Cron *cron.Cron
-	TZ   string
-	Time string
+	Cron           *cron.Cron
+	TZ             string
+	Time           string
+	DedupRetention int
 }
 
 // DB instance
@@ -26,6 +27,9 @@ func (c *Cron) New() {
 	format := fmt.Sprintf("CRON_TZ=%s %s", c.TZ, c.Time)
 	cron.AddFunc(format, AutoLoanDisburseConfirm())
 	log.Printf("CRON # : %s\n", format)
+	if c.DedupRetention > 0 {
+		cron.AddFunc("@hourly", PurgeConsumedEvents(c.DedupRetention))
+	}
 
 	c.Cron = cron
 }
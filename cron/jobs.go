@@ -11,8 +11,18 @@ func AutoLoanDisburseConfirm() func() {
 			Where("disburse_date IS NOT NULL").
 			Where("disburse_date != ?", "0001-01-01 00:00:00+00").
 			Where("NOW() > disburse_date + make_interval(days => 2)").
+			Where("disburse_status = ?", "processing").
 			Update("disburse_status", "confirmed").Error
 
 		log.Printf("AutoLoanDisburseConfirm cron executed. error : %v", err)
 	}
 }
+
+// PurgeConsumedEvents removes kafka dedup records older than retention hours
+func PurgeConsumedEvents(retention int) func() {
+	return func() {
+		db := DB.Exec("DELETE FROM consumed_events WHERE created_at < NOW() - make_interval(hours => ?)", retention)
+
+		log.Printf("PurgeConsumedEvents cron executed. deleted : %v error : %v", db.RowsAffected, db.Error)
+	}
+}
@@ -0,0 +1,114 @@
+package custommodule
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FileStorage stores objects in local directory, served under BaseURL
+type FileStorage struct {
+	Root    string
+	BaseURL string
+	Secret  string
+}
+
+// NewFileStorage create new local filesystem storage
+func NewFileStorage(root string, baseURL string, secret string) (*FileStorage, error) {
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, err
+	}
+
+	return &FileStorage{
+		Root:    root,
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+		Secret:  secret,
+	}, nil
+}
+
+func (x *FileStorage) path(key string) (string, error) {
+	clean := filepath.Clean("/" + key)
+	if clean == "/" {
+		return "", fmt.Errorf("invalid object key")
+	}
+
+	return filepath.Join(x.Root, clean), nil
+}
+
+// Put writes object to disk
+func (x *FileStorage) Put(key string, b []byte, contentType string) (string, error) {
+	path, err := x.path(key)
+	if err != nil {
+		return "", err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+	if err = ioutil.WriteFile(path, b, 0644); err != nil {
+		return "", err
+	}
+
+	return x.URL(key), nil
+}
+
+// Get reads object from disk
+func (x *FileStorage) Get(key string) ([]byte, error) {
+	path, err := x.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return ioutil.ReadFile(path)
+}
+
+// Delete removes object from disk
+func (x *FileStorage) Delete(key string) error {
+	path, err := x.path(key)
+	if err != nil {
+		return err
+	}
+
+	return os.Remove(path)
+}
+
+// Presign creates url signed with storage secret
+func (x *FileStorage) Presign(key string, expire time.Duration) (string, error) {
+	expires := time.Now().Add(expire).Unix()
+
+	return fmt.Sprintf("%s?expires=%d&signature=%s", x.URL(key), expires, x.sign(key, expires)), nil
+}
+
+// Verify checks presigned url signature and expiry
+func (x *FileStorage) Verify(key string, expires string, signature string) error {
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid expiry")
+	}
+	if time.Now().Unix() > exp {
+		return fmt.Errorf("url expired")
+	}
+	if !hmac.Equal([]byte(signature), []byte(x.sign(key, exp))) {
+		return fmt.Errorf("invalid signature")
+	}
+
+	return nil
+}
+
+func (x *FileStorage) sign(key string, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(x.Secret))
+	mac.Write([]byte(fmt.Sprintf("%s:%d", key, expires)))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// URL permanent url of object
+func (x *FileStorage) URL(key string) string {
+	return x.BaseURL + "/" + strings.TrimPrefix(key, "/")
+}
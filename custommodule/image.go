@@ -0,0 +1,289 @@
+package custommodule
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+)
+
+type (
+	// ImageConfig upload limits and thumbnail sizes
+	ImageConfig struct {
+		MaxSize      int
+		MaxDimension int
+		Thumbnails   []int
+	}
+
+	// ProcessedImage validated image ready to be stored
+	ProcessedImage struct {
+		ContentType string
+		Ext         string
+		Original    []byte
+		// Thumbnails encoded thumbnail per max width/height
+		Thumbnails map[int][]byte
+	}
+
+	// InvalidImageError upload is not an acceptable image
+	InvalidImageError struct {
+		Reason string
+	}
+)
+
+func (e InvalidImageError) Error() string {
+	return e.Reason
+}
+
+// ProcessImage validates image bytes, normalises exif orientation and generates thumbnails
+func ProcessImage(b []byte, conf ImageConfig) (result ProcessedImage, err error) {
+	if len(b) < 1 {
+		return result, InvalidImageError{"gambar kosong"}
+	}
+	if conf.MaxSize > 0 && len(b) > conf.MaxSize {
+		return result, InvalidImageError{fmt.Sprintf("ukuran gambar melebihi %d byte", conf.MaxSize)}
+	}
+
+	result.ContentType, result.Ext = DetectContentType(b)
+	if result.ContentType != "image/jpeg" && result.ContentType != "image/png" {
+		return result, InvalidImageError{fmt.Sprintf("tipe gambar %s tidak didukung", result.ContentType)}
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(b))
+	if err != nil {
+		return result, InvalidImageError{"gambar tidak dapat dibaca"}
+	}
+	if conf.MaxDimension > 0 && (cfg.Width > conf.MaxDimension || cfg.Height > conf.MaxDimension) {
+		return result, InvalidImageError{fmt.Sprintf("dimensi gambar melebihi %d piksel", conf.MaxDimension)}
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(b))
+	if err != nil {
+		return result, InvalidImageError{"gambar tidak dapat dibaca"}
+	}
+
+	result.Original = b
+	if result.ContentType == "image/jpeg" {
+		if orientation := jpegOrientation(b); orientation > 1 {
+			img = orient(img, orientation)
+			if result.Original, err = encodeImage(img, result.ContentType); err != nil {
+				return result, err
+			}
+		}
+	}
+
+	result.Thumbnails = map[int][]byte{}
+	for _, size := range conf.Thumbnails {
+		if size <= 0 {
+			continue
+		}
+		if result.Thumbnails[size], err = encodeImage(thumbnail(img, size), result.ContentType); err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+// StoreImage puts original image and its thumbnails under filename, returns original url and thumbnail urls by size
+func StoreImage(s Storage, img ProcessedImage, filename string) (url string, thumbnails map[string]string, err error) {
+	url, err = s.Put(filename+img.Ext, img.Original, img.ContentType)
+	if err != nil {
+		return "", nil, err
+	}
+
+	thumbnails = map[string]string{}
+	for size, b := range img.Thumbnails {
+		thumb, err := s.Put(fmt.Sprintf("%s_%d%s", filename, size, img.Ext), b, img.ContentType)
+		if err != nil {
+			return "", nil, err
+		}
+		thumbnails[fmt.Sprint(size)] = thumb
+	}
+
+	return url, thumbnails, nil
+}
+
+func encodeImage(img image.Image, contentType string) ([]byte, error) {
+	buf := new(bytes.Buffer)
+
+	var err error
+	if contentType == "image/png" {
+		err = png.Encode(buf, img)
+	} else {
+		err = jpeg.Encode(buf, img, &jpeg.Options{Quality: 85})
+	}
+
+	return buf.Bytes(), err
+}
+
+// thumbnail scales image down to fit in size x size with bilinear sampling. smaller images are kept as is
+func thumbnail(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w <= size && h <= size {
+		return img
+	}
+
+	dw, dh := size, h*size/w
+	if h > w {
+		dw, dh = w*size/h, size
+	}
+	if dw < 1 {
+		dw = 1
+	}
+	if dh < 1 {
+		dh = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	xRatio := float64(w-1) / float64(maxInt(dw-1, 1))
+	yRatio := float64(h-1) / float64(maxInt(dh-1, 1))
+	for y := 0; y < dh; y++ {
+		sy := float64(y) * yRatio
+		y0 := int(sy)
+		y1 := minInt(y0+1, h-1)
+		fy := sy - float64(y0)
+		for x := 0; x < dw; x++ {
+			sx := float64(x) * xRatio
+			x0 := int(sx)
+			x1 := minInt(x0+1, w-1)
+			fx := sx - float64(x0)
+
+			c00 := color.RGBA64Model.Convert(img.At(bounds.Min.X+x0, bounds.Min.Y+y0)).(color.RGBA64)
+			c10 := color.RGBA64Model.Convert(img.At(bounds.Min.X+x1, bounds.Min.Y+y0)).(color.RGBA64)
+			c01 := color.RGBA64Model.Convert(img.At(bounds.Min.X+x0, bounds.Min.Y+y1)).(color.RGBA64)
+			c11 := color.RGBA64Model.Convert(img.At(bounds.Min.X+x1, bounds.Min.Y+y1)).(color.RGBA64)
+
+			lerp := func(a, b, c, d uint16) uint16 {
+				top := float64(a)*(1-fx) + float64(b)*fx
+				bottom := float64(c)*(1-fx) + float64(d)*fx
+				return uint16(top*(1-fy) + bottom*fy)
+			}
+			dst.Set(x, y, color.RGBA64{
+				R: lerp(c00.R, c10.R, c01.R, c11.R),
+				G: lerp(c00.G, c10.G, c01.G, c11.G),
+				B: lerp(c00.B, c10.B, c01.B, c11.B),
+				A: lerp(c00.A, c10.A, c01.A, c11.A),
+			})
+		}
+	}
+
+	return dst
+}
+
+// orient applies exif orientation so the image is stored upright
+func orient(img image.Image, orientation int) image.Image {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2:
+				sx, sy = w-1-x, y
+			case 3:
+				sx, sy = w-1-x, h-1-y
+			case 4:
+				sx, sy = x, h-1-y
+			case 5:
+				sx, sy = y, x
+			case 6:
+				sx, sy = y, h-1-x
+			case 7:
+				sx, sy = w-1-y, h-1-x
+			case 8:
+				sx, sy = w-1-y, x
+			default:
+				sx, sy = x, y
+			}
+			dst.Set(x, y, img.At(bounds.Min.X+sx, bounds.Min.Y+sy))
+		}
+	}
+
+	return dst
+}
+
+// jpegOrientation reads exif orientation tag, 1 when not present
+func jpegOrientation(b []byte) int {
+	if len(b) < 4 || b[0] != 0xFF || b[1] != 0xD8 {
+		return 1
+	}
+
+	i := 2
+	for i+4 <= len(b) {
+		if b[i] != 0xFF {
+			return 1
+		}
+		marker := b[i+1]
+		length := int(binary.BigEndian.Uint16(b[i+2 : i+4]))
+		// start of scan, no more metadata
+		if marker == 0xDA || length < 2 || i+2+length > len(b) {
+			return 1
+		}
+
+		segment := b[i+4 : i+2+length]
+		if marker == 0xE1 && len(segment) > 14 && string(segment[:6]) == "Exif\x00\x00" {
+			return exifOrientation(segment[6:])
+		}
+		i += 2 + length
+	}
+
+	return 1
+}
+
+func exifOrientation(tiff []byte) int {
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	offset := int(order.Uint32(tiff[4:8]))
+	if offset+2 > len(tiff) {
+		return 1
+	}
+	count := int(order.Uint16(tiff[offset : offset+2]))
+	for n := 0; n < count; n++ {
+		entry := offset + 2 + n*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:entry+2]) == 0x0112 {
+			orientation := int(order.Uint16(tiff[entry+8 : entry+10]))
+			if orientation < 1 || orientation > 8 {
+				return 1
+			}
+			return orientation
+		}
+	}
+
+	return 1
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
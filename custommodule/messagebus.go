@@ -0,0 +1,24 @@
+package custommodule
+
+import (
+	"log"
+)
+
+type (
+	// MessageBus publish and subscribe messages between asira services
+	MessageBus interface {
+		Publish(topic string, message []byte) error
+		Subscribe(topic string, handler MessageHandler) error
+		Close() error
+	}
+
+	// MessageHandler process a single consumed message
+	MessageHandler func(message []byte) error
+)
+
+// handleMessage runs handler and logs its error, a failing message does not stop the subscription
+func handleMessage(handler MessageHandler, message []byte) {
+	if err := handler(message); err != nil {
+		log.Printf("%v . message : %v", err, string(message))
+	}
+}
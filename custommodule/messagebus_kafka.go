@@ -0,0 +1,111 @@
+package custommodule
+
+import (
+	"log"
+	"sync"
+
+	"github.com/Shopify/sarama"
+)
+
+// KafkaBus message bus backed by kafka broker
+type KafkaBus struct {
+	Host     string
+	Config   *sarama.Config
+	producer sarama.SyncProducer
+	consumer sarama.Consumer
+	mutex    sync.Mutex
+}
+
+// NewKafkaBus create new kafka message bus
+func NewKafkaBus(host string, config *sarama.Config) *KafkaBus {
+	return &KafkaBus{
+		Host:   host,
+		Config: config,
+	}
+}
+
+// Publish sends message to topic and waits for broker ack
+func (k *KafkaBus) Publish(topic string, message []byte) (err error) {
+	k.mutex.Lock()
+	if k.producer == nil {
+		k.producer, err = sarama.NewSyncProducer([]string{k.Host}, k.Config)
+		if err != nil {
+			k.producer = nil
+			k.mutex.Unlock()
+			return err
+		}
+	}
+	producer := k.producer
+	k.mutex.Unlock()
+
+	_, _, err = producer.SendMessage(&sarama.ProducerMessage{
+		Topic: topic,
+		Value: sarama.ByteEncoder(message),
+	})
+	if err != nil {
+		log.Printf("Fail producing topic : %s error : %v", topic, err)
+		return err
+	}
+	log.Printf("Produced topic : %s", topic)
+
+	return nil
+}
+
+// Subscribe consumes topic partition 0 from the oldest offset in background
+func (k *KafkaBus) Subscribe(topic string, handler MessageHandler) (err error) {
+	k.mutex.Lock()
+	if k.consumer == nil {
+		k.consumer, err = sarama.NewConsumer([]string{k.Host}, k.Config)
+		if err != nil {
+			k.consumer = nil
+			k.mutex.Unlock()
+			return err
+		}
+	}
+	consumer := k.consumer
+	k.mutex.Unlock()
+
+	partitionConsumer, err := consumer.ConsumePartition(topic, 0, sarama.OffsetOldest)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		defer partitionConsumer.Close()
+		for {
+			select {
+			case err, ok := <-partitionConsumer.Errors():
+				if !ok {
+					return
+				}
+				log.Printf("error occured when listening kafka : %v", err)
+			case msg, ok := <-partitionConsumer.Messages():
+				if !ok {
+					return
+				}
+				handleMessage(handler, msg.Value)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Close producer and consumer
+func (k *KafkaBus) Close() (err error) {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	if k.producer != nil {
+		err = k.producer.Close()
+		k.producer = nil
+	}
+	if k.consumer != nil {
+		if cerr := k.consumer.Close(); cerr != nil {
+			err = cerr
+		}
+		k.consumer = nil
+	}
+
+	return err
+}
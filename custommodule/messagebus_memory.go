@@ -0,0 +1,64 @@
+package custommodule
+
+import (
+	"sync"
+)
+
+// MemoryBus in process message bus for local development and tests.
+// messages are delivered synchronously before Publish returns
+type MemoryBus struct {
+	// Loopback delivers every message to all subscribers regardless of topic,
+	// so a service consumes what it produces
+	Loopback bool
+	handlers map[string][]MessageHandler
+	mutex    sync.RWMutex
+}
+
+// NewMemoryBus create new in memory message bus
+func NewMemoryBus(loopback bool) *MemoryBus {
+	return &MemoryBus{
+		Loopback: loopback,
+		handlers: map[string][]MessageHandler{},
+	}
+}
+
+// Publish delivers message to subscribers
+func (m *MemoryBus) Publish(topic string, message []byte) error {
+	var handlers []MessageHandler
+
+	m.mutex.RLock()
+	if m.Loopback {
+		for _, h := range m.handlers {
+			handlers = append(handlers, h...)
+		}
+	} else {
+		handlers = append(handlers, m.handlers[topic]...)
+	}
+	m.mutex.RUnlock()
+
+	for _, handler := range handlers {
+		handleMessage(handler, message)
+	}
+
+	return nil
+}
+
+// Subscribe registers handler for topic
+func (m *MemoryBus) Subscribe(topic string, handler MessageHandler) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.handlers[topic] = append(m.handlers[topic], handler)
+
+	return nil
+}
+
+// Close removes all subscribers
+func (m *MemoryBus) Close() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.handlers = map[string][]MessageHandler{}
+
+	return nil
+}
@@ -3,9 +3,10 @@ package custommodule
 import (
 	"bytes"
 	"crypto/tls"
-	"flag"
+	"io/ioutil"
 	"net/http"
-	"os"
+	"sync"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
@@ -17,21 +18,25 @@ import (
 type S3 struct {
 	Config *aws.Config
 	Bucket string
+	client *s3.S3
+	bucket sync.Once
 }
 
 // NewS3 create new S3 instance
-func NewS3(accesskey string, secretkey string, host string, bucketname string, region string) (S3, error) {
-	result := S3{}
+func NewS3(accesskey string, secretkey string, host string, bucketname string, region string, insecure bool) (*S3, error) {
+	result := &S3{}
 	creds := credentials.NewStaticCredentials(accesskey, secretkey, "")
 	_, err := creds.Get()
 	if err != nil {
-		return S3{}, err
+		return nil, err
 	}
 
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	client := http.DefaultClient
+	if insecure {
+		client = &http.Client{Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		}}
 	}
-	client := &http.Client{Transport: tr}
 
 	result.Bucket = bucketname
 	result.Config = &aws.Config{
@@ -42,58 +47,76 @@ func NewS3(accesskey string, secretkey string, host string, bucketname string, r
 		HTTPClient:       client,
 	}
 
-	return result, err
-}
+	sess, err := session.NewSession(result.Config)
+	if err != nil {
+		return nil, err
+	}
+	result.client = s3.New(sess)
 
-// UploadJPEG uploads jpeg to s3
-func (x *S3) UploadJPEG(b []byte, filename string) (string, error) {
-	var err error
-	if flag.Lookup("test.v") == nil {
-		session, _ := session.NewSession(x.Config)
+	return result, nil
+}
 
-		s3Client := s3.New(session)
-		s3Client.CreateBucket(&s3.CreateBucketInput{
+// ensureBucket creates bucket once, error is ignored when bucket already exists
+func (x *S3) ensureBucket() {
+	x.bucket.Do(func() {
+		x.client.CreateBucket(&s3.CreateBucketInput{
 			Bucket: aws.String(x.Bucket),
 		})
+	})
+}
 
-		file, _ := os.Create("files/" + filename + ".jpeg")
-		defer file.Close()
-		file.Write(b)
-		file.Sync()
-		fileinfo, _ := file.Stat()
-
-		open, _ := os.Open("files/" + filename + ".jpeg")
-		defer open.Close()
-		defer os.Remove("files/" + filename + ".jpeg")
-		buffer := make([]byte, fileinfo.Size())
-		open.Read(buffer)
+// Put uploads object to s3
+func (x *S3) Put(key string, b []byte, contentType string) (string, error) {
+	x.ensureBucket()
 
-		_, err = s3Client.PutObject(&s3.PutObjectInput{
-			Bucket: aws.String(x.Bucket),
-			Key:    aws.String(fileinfo.Name()),
-			Body:   bytes.NewReader(buffer),
-		})
+	_, err := x.client.PutObject(&s3.PutObjectInput{
+		Bucket:      aws.String(x.Bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(b),
+		ContentType: aws.String(contentType),
+	})
+	if err != nil {
+		return "", err
 	}
 
-	return *x.Config.Endpoint + "/" + x.Bucket + "/" + filename + ".jpeg", err
+	return x.URL(key), nil
 }
 
-// DeleteObject delete object from s3
-func (x *S3) DeleteObject(objectName string) error {
-	var err error
-	if flag.Lookup("test.v") == nil {
-		session, _ := session.NewSession(x.Config)
+// Get downloads object from s3
+func (x *S3) Get(key string) ([]byte, error) {
+	out, err := x.client.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(x.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer out.Body.Close()
 
-		s3Client := s3.New(session)
-		s3Client.CreateBucket(&s3.CreateBucketInput{
-			Bucket: aws.String(x.Bucket),
-		})
+	return ioutil.ReadAll(out.Body)
+}
 
-		_, err = s3Client.DeleteObject(&s3.DeleteObjectInput{
-			Bucket: aws.String(x.Bucket),
-			Key:    aws.String(objectName),
-		})
-	}
+// Delete delete object from s3
+func (x *S3) Delete(key string) error {
+	_, err := x.client.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(x.Bucket),
+		Key:    aws.String(key),
+	})
 
 	return err
 }
+
+// Presign creates pre-signed get url
+func (x *S3) Presign(key string, expire time.Duration) (string, error) {
+	req, _ := x.client.GetObjectRequest(&s3.GetObjectInput{
+		Bucket: aws.String(x.Bucket),
+		Key:    aws.String(key),
+	})
+
+	return req.Presign(expire)
+}
+
+// URL permanent url of object
+func (x *S3) URL(key string) string {
+	return *x.Config.Endpoint + "/" + x.Bucket + "/" + key
+}
@@ -0,0 +1,74 @@
+package custommodule
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Storage object storage backend
+type Storage interface {
+	// Put stores object and returns its public url
+	Put(key string, b []byte, contentType string) (string, error)
+	Get(key string) ([]byte, error)
+	Delete(key string) error
+	// Presign returns url to the object that is valid until expire passed
+	Presign(key string, expire time.Duration) (string, error)
+	// URL returns permanent url of the object
+	URL(key string) string
+}
+
+// StorageExtensions file extension of supported content types
+var StorageExtensions = map[string]string{
+	"image/jpeg":      ".jpeg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+}
+
+// DetectContentType sniff content type and file extension from object bytes
+func DetectContentType(b []byte) (contentType string, ext string) {
+	contentType = strings.Split(http.DetectContentType(b), ";")[0]
+	if ext, ok := StorageExtensions[contentType]; ok {
+		return contentType, ext
+	}
+
+	return "application/octet-stream", ".bin"
+}
+
+// PutFile stores object under filename with extension detected from its content
+func PutFile(s Storage, b []byte, filename string) (string, error) {
+	if len(b) < 1 {
+		return "", fmt.Errorf("empty file")
+	}
+	contentType, ext := DetectContentType(b)
+
+	return s.Put(filename+ext, b, contentType)
+}
+
+// ObjectKey get object key from its url
+func ObjectKey(url string) string {
+	url = strings.Split(url, "?")[0]
+	i := strings.Split(url, "/")
+
+	return i[len(i)-1]
+}
+
+// PresignObject creates pre-signed url from stored object key or url of this storage.
+// urls outside this storage are returned as is
+func PresignObject(s Storage, value string, expire time.Duration) (string, error) {
+	if len(value) < 1 {
+		return "", nil
+	}
+
+	key := value
+	if prefix := s.URL(""); strings.HasPrefix(value, prefix) {
+		key = strings.TrimPrefix(value, prefix)
+	} else if strings.Contains(value, "://") {
+		return value, nil
+	}
+
+	return s.Presign(key, expire)
+}
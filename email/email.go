@@ -2,27 +2,47 @@ package email
 
 import (
 	"asira_lender/asira"
-	"flag"
 	"fmt"
+	"io"
 
 	"gopkg.in/gomail.v2"
 )
 
-// SendMail sends email
-func SendMail(to string, subject, message string) error {
+// SendMessage sends rendered email as multipart text and html with attachments to every recipient
+func SendMessage(msg Message, recipients ...string) error {
+	if len(recipients) < 1 {
+		return fmt.Errorf("no recipient")
+	}
+
 	Config := asira.App.Config.GetStringMap(fmt.Sprintf("%s.mailer", asira.App.ENV))
 	mailer := gomail.NewMessage()
-	mailer.SetHeader("From", Config["email"].(string))
-	mailer.SetHeader("To", to)
-	mailer.SetHeader("Subject", subject)
-	mailer.SetBody("text/plain", message)
+	mailer.SetHeader("From", fmt.Sprint(Config["email"]))
+	mailer.SetHeader("To", recipients...)
+	mailer.SetHeader("Subject", msg.Subject)
+	mailer.SetBody("text/plain", msg.Text)
+	if len(msg.HTML) > 0 {
+		mailer.AddAlternative("text/html", msg.HTML)
+	}
+	for _, attachment := range msg.Attachments {
+		data := attachment.Data
+		settings := []gomail.FileSetting{gomail.SetCopyFunc(func(w io.Writer) error {
+			_, err := w.Write(data)
+			return err
+		})}
+		if len(attachment.ContentType) > 0 {
+			settings = append(settings, gomail.SetHeader(map[string][]string{"Content-Type": {attachment.ContentType}}))
+		}
+		mailer.Attach(attachment.Filename, settings...)
+	}
 
-	dialer := gomail.NewPlainDialer(Config["host"].(string),
-		Config["port"].(int),
-		Config["email"].(string),
-		Config["password"].(string))
+	// mailer send config is turned off for unit testing
+	if send, ok := Config["send"].(bool); !ok || send {
+		port, _ := Config["port"].(int)
+		dialer := gomail.NewPlainDialer(fmt.Sprint(Config["host"]),
+			port,
+			fmt.Sprint(Config["email"]),
+			fmt.Sprint(Config["password"]))
 
-	if flag.Lookup("test.v") == nil {
 		err := dialer.DialAndSend(mailer)
 		if err != nil {
 			return err
@@ -31,3 +51,13 @@ func SendMail(to string, subject, message string) error {
 
 	return nil
 }
+
+// DefaultLanguage email language from config, id if not set
+func DefaultLanguage() string {
+	language := asira.App.Config.GetString(fmt.Sprintf("%s.mailer.language", asira.App.ENV))
+	if len(language) < 1 {
+		return Languages[0]
+	}
+
+	return language
+}
@@ -0,0 +1,207 @@
+package email
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	texttemplate "text/template"
+)
+
+type (
+	// Template email template source of an event in a language
+	Template struct {
+		Subject string
+		HTML    string
+		Text    string
+	}
+
+	// Message rendered email
+	Message struct {
+		Subject     string
+		HTML        string
+		Text        string
+		Attachments []Attachment
+	}
+
+	// Attachment file attached to email
+	Attachment struct {
+		Filename    string
+		ContentType string
+		Data        []byte
+	}
+
+	// TemplateData typed data for an email event
+	TemplateData interface {
+		Event() string
+	}
+
+	// UserPasswordData sent when admin creates new user
+	UserPasswordData struct {
+		Username string
+		Email    string
+		Password string
+	}
+
+	// AgentPasswordData sent when new agent is registered
+	AgentPasswordData struct {
+		Name     string
+		Username string
+		Password string
+	}
+
+	// ResetPasswordData sent on forgot password request
+	ResetPasswordData struct {
+		Email string
+		Link  string
+	}
+
+	// LenderNotificationData sent to bank representatives on loan and borrower events
+	LenderNotificationData struct {
+		Username string
+		Title    string
+		Message  string
+		Link     string
+	}
+)
+
+// email events
+const (
+	EventUserPassword  = "user_password"
+	EventAgentPassword = "agent_password"
+	EventResetPassword = "reset_password"
+	EventLenderNotify  = "lender_notification"
+)
+
+// Languages supported template languages, first one is the fallback
+var Languages = []string{"id", "en"}
+
+// Event name
+func (d UserPasswordData) Event() string { return EventUserPassword }
+
+// Event name
+func (d AgentPasswordData) Event() string { return EventAgentPassword }
+
+// Event name
+func (d ResetPasswordData) Event() string { return EventResetPassword }
+
+// Event name
+func (d LenderNotificationData) Event() string { return EventLenderNotify }
+
+// SampleData example data per event, used to validate edited templates
+var SampleData = map[string]TemplateData{
+	EventUserPassword:  UserPasswordData{Username: "username", Email: "user@domain.com", Password: "password"},
+	EventAgentPassword: AgentPasswordData{Name: "Agent", Username: "agent", Password: "password"},
+	EventResetPassword: ResetPasswordData{Email: "user@domain.com", Link: "https://domain.com/ubahpassword?token=token"},
+	EventLenderNotify:  LenderNotificationData{Username: "username", Title: "Pengajuan pinjaman baru", Message: "Pinjaman 1 diajukan", Link: "https://domain.com"},
+}
+
+// DefaultTemplates built in templates by event and language. used for seeding and when template is missing from database
+var DefaultTemplates = map[string]map[string]Template{
+	EventUserPassword: {
+		"id": {
+			Subject: "[NO REPLY] - Password Aplikasi ASIRA",
+			Text:    "Selamat Pagi,\n\nIni adalah password anda untuk login {{.Password}} \n\n\n Ayannah Solusi Nusantara Team",
+			HTML:    "<p>Selamat Pagi,</p><p>Ini adalah password anda untuk login <strong>{{.Password}}</strong></p><p>Ayannah Solusi Nusantara Team</p>",
+		},
+		"en": {
+			Subject: "[NO REPLY] - ASIRA Application Password",
+			Text:    "Good Morning,\n\nThis is your login password {{.Password}} \n\n\n Ayannah Solusi Nusantara Team",
+			HTML:    "<p>Good Morning,</p><p>This is your login password <strong>{{.Password}}</strong></p><p>Ayannah Solusi Nusantara Team</p>",
+		},
+	},
+	EventAgentPassword: {
+		"id": {
+			Subject: "Selamat Bergabung dengan Asira",
+			Text:    "Selamat bergabung dengan asira sebagai Agent. anda dapat login menggunakan username dengan password : {{.Password}}",
+			HTML:    "<p>Selamat bergabung dengan asira sebagai Agent.</p><p>Anda dapat login menggunakan username <strong>{{.Username}}</strong> dengan password : <strong>{{.Password}}</strong></p>",
+		},
+		"en": {
+			Subject: "Welcome to Asira",
+			Text:    "Welcome to asira as an Agent. you can login using your username with password : {{.Password}}",
+			HTML:    "<p>Welcome to asira as an Agent.</p><p>You can login using username <strong>{{.Username}}</strong> with password : <strong>{{.Password}}</strong></p>",
+		},
+	},
+	EventResetPassword: {
+		"id": {
+			Subject: "Forgot Password Request",
+			Text:    "link reset password : {{.Link}}",
+			HTML:    "<p>Klik link berikut untuk reset password anda : <a href=\"{{.Link}}\">{{.Link}}</a></p>",
+		},
+		"en": {
+			Subject: "Forgot Password Request",
+			Text:    "reset password link : {{.Link}}",
+			HTML:    "<p>Click the following link to reset your password : <a href=\"{{.Link}}\">{{.Link}}</a></p>",
+		},
+	},
+	EventLenderNotify: {
+		"id": {
+			Subject: "[ASIRA] {{.Title}}",
+			Text:    "Halo {{.Username}},\n\n{{.Message}}\n\nBuka dashboard : {{.Link}}",
+			HTML:    "<p>Halo {{.Username}},</p><p>{{.Message}}</p><p><a href=\"{{.Link}}\">Buka dashboard</a></p>",
+		},
+		"en": {
+			Subject: "[ASIRA] {{.Title}}",
+			Text:    "Hello {{.Username}},\n\n{{.Message}}\n\nOpen dashboard : {{.Link}}",
+			HTML:    "<p>Hello {{.Username}},</p><p>{{.Message}}</p><p><a href=\"{{.Link}}\">Open dashboard</a></p>",
+		},
+	},
+}
+
+// DefaultTemplate built in template of event, falls back to first language
+func DefaultTemplate(event string, language string) (Template, error) {
+	templates, ok := DefaultTemplates[event]
+	if !ok {
+		return Template{}, fmt.Errorf("unknown email event %v", event)
+	}
+	if t, ok := templates[language]; ok {
+		return t, nil
+	}
+
+	return templates[Languages[0]], nil
+}
+
+// Render executes subject and text with text/template and html body with html/template
+func (t Template) Render(data TemplateData) (msg Message, err error) {
+	if msg.Subject, err = renderText("subject", t.Subject, data); err != nil {
+		return msg, err
+	}
+	if msg.Text, err = renderText("text", t.Text, data); err != nil {
+		return msg, err
+	}
+
+	tmpl, err := htmltemplate.New("html").Option("missingkey=error").Parse(t.HTML)
+	if err != nil {
+		return msg, err
+	}
+	buf := new(bytes.Buffer)
+	if err = tmpl.Execute(buf, data); err != nil {
+		return msg, err
+	}
+	msg.HTML = buf.String()
+
+	return msg, nil
+}
+
+// Validate parses and renders template against event sample data
+func (t Template) Validate(event string) error {
+	data, ok := SampleData[event]
+	if !ok {
+		return fmt.Errorf("unknown email event %v", event)
+	}
+	_, err := t.Render(data)
+
+	return err
+}
+
+func renderText(name string, source string, data TemplateData) (string, error) {
+	tmpl, err := texttemplate.New(name).Option("missingkey=error").Parse(source)
+	if err != nil {
+		return "", err
+	}
+	buf := new(bytes.Buffer)
+	if err = tmpl.Execute(buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
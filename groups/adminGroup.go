@@ -24,10 +24,13 @@ func AdminGroup(e *echo.Echo) {
 	// Borrowers
 	g.GET("/borrower", adminhandlers.BorrowerGetAll)
 	g.GET("/borrower/:borrower_id", adminhandlers.BorrowerGetDetails)
+	g.GET("/borrower/:borrower_id/credit_history", adminhandlers.BorrowerCreditHistory)
+	g.POST("/borrower/:borrower_id/fraud_check", adminhandlers.BorrowerFraudCheck)
 
 	// Loans
 	g.GET("/loan", adminhandlers.LoanGetAll)
 	g.GET("/loan/:loan_id", adminhandlers.LoanGetDetails)
+	g.POST("/loan/:loan_id/score", adminhandlers.LoanScore)
 
 	// Bank Types
 	g.GET("/bank_types", adminhandlers.BankTypeList)
@@ -36,6 +39,23 @@ func AdminGroup(e *echo.Echo) {
 	g.PATCH("/bank_types/:bank_id", adminhandlers.BankTypePatch)
 	g.DELETE("/bank_types/:bank_id", adminhandlers.BankTypeDelete)
 
+	// Document Types
+	g.GET("/document_types", adminhandlers.DocumentTypeList)
+	g.POST("/document_types", adminhandlers.DocumentTypeNew)
+	g.GET("/document_types/:document_type_id", adminhandlers.DocumentTypeDetail)
+	g.PATCH("/document_types/:document_type_id", adminhandlers.DocumentTypePatch)
+
+	// Email Templates
+	g.GET("/email_templates", adminhandlers.EmailTemplateList)
+	g.POST("/email_templates", adminhandlers.EmailTemplateNew)
+	g.GET("/email_templates/:template_id", adminhandlers.EmailTemplateDetail)
+	g.PATCH("/email_templates/:template_id", adminhandlers.EmailTemplatePatch)
+
+	// Email Queue
+	g.GET("/emails", adminhandlers.EmailQueueList)
+	g.GET("/emails/:email_id", adminhandlers.EmailQueueDetail)
+	g.POST("/emails/:email_id/resend", adminhandlers.EmailQueueResend)
+
 	// Banks
 	g.GET("/banks", adminhandlers.BankList)
 	g.POST("/banks", adminhandlers.BankNew)
@@ -43,6 +63,22 @@ func AdminGroup(e *echo.Echo) {
 	g.PATCH("/banks/:bank_id", adminhandlers.BankPatch)
 	g.DELETE("/banks/:bank_id", adminhandlers.BankDelete)
 
+	// Bank webhooks
+	g.GET("/banks/:bank_id/webhooks", adminhandlers.WebhookList)
+	g.POST("/banks/:bank_id/webhooks", adminhandlers.WebhookNew)
+	g.GET("/banks/:bank_id/webhooks/:webhook_id", adminhandlers.WebhookDetail)
+	g.PATCH("/banks/:bank_id/webhooks/:webhook_id", adminhandlers.WebhookPatch)
+	g.DELETE("/banks/:bank_id/webhooks/:webhook_id", adminhandlers.WebhookDelete)
+	g.POST("/banks/:bank_id/webhooks/:webhook_id/test", adminhandlers.WebhookTest)
+	g.GET("/banks/:bank_id/webhooks/:webhook_id/deliveries", adminhandlers.WebhookDeliveryList)
+
+	// Bank loan approval policies
+	g.GET("/banks/:bank_id/approval_policies", adminhandlers.ApprovalPolicyList)
+	g.POST("/banks/:bank_id/approval_policies", adminhandlers.ApprovalPolicyNew)
+	g.GET("/banks/:bank_id/approval_policies/:policy_id", adminhandlers.ApprovalPolicyDetail)
+	g.PATCH("/banks/:bank_id/approval_policies/:policy_id", adminhandlers.ApprovalPolicyPatch)
+	g.DELETE("/banks/:bank_id/approval_policies/:policy_id", adminhandlers.ApprovalPolicyDelete)
+
 	// Services
 	g.GET("/services", adminhandlers.ServiceList)
 	g.POST("/services", adminhandlers.ServiceNew)
@@ -57,6 +93,35 @@ func AdminGroup(e *echo.Echo) {
 	g.PATCH("/products/:id", adminhandlers.ProductPatch)
 	g.DELETE("/products/:id", adminhandlers.ProductDelete)
 
+	// Credit scorecards
+	g.GET("/scorecards", adminhandlers.ScorecardList)
+	g.POST("/scorecards", adminhandlers.ScorecardNew)
+	g.GET("/scorecards/:scorecard_id", adminhandlers.ScorecardDetail)
+	g.PATCH("/scorecards/:scorecard_id", adminhandlers.ScorecardPatch)
+	g.DELETE("/scorecards/:scorecard_id", adminhandlers.ScorecardDelete)
+
+	// Auto decision rules
+	g.GET("/auto_decision_rules", adminhandlers.AutoDecisionRuleList)
+	g.POST("/auto_decision_rules", adminhandlers.AutoDecisionRuleNew)
+	g.POST("/auto_decision_rules/test", adminhandlers.AutoDecisionRuleTest)
+	g.GET("/auto_decision_rules/:rule_id", adminhandlers.AutoDecisionRuleDetail)
+	g.PATCH("/auto_decision_rules/:rule_id", adminhandlers.AutoDecisionRulePatch)
+	g.DELETE("/auto_decision_rules/:rule_id", adminhandlers.AutoDecisionRuleDelete)
+	g.GET("/auto_decisions", adminhandlers.AutoDecisionList)
+
+	// Fraud flags
+	g.GET("/fraud_flags", adminhandlers.FraudFlagList)
+	g.GET("/fraud_flags/:flag_id", adminhandlers.FraudFlagDetail)
+	g.PATCH("/fraud_flags/:flag_id", adminhandlers.FraudFlagReview)
+
+	// Watchlist
+	g.GET("/watchlists", adminhandlers.WatchlistList)
+	g.POST("/watchlists", adminhandlers.WatchlistNew)
+	g.POST("/watchlists/import", adminhandlers.WatchlistImport)
+	g.GET("/watchlists/:watchlist_id", adminhandlers.WatchlistDetail)
+	g.PATCH("/watchlists/:watchlist_id", adminhandlers.WatchlistPatch)
+	g.DELETE("/watchlists/:watchlist_id", adminhandlers.WatchlistDelete)
+
 	// Loan Purpose
 	g.GET("/loan_purposes", adminhandlers.LoanPurposeList)
 	g.POST("/loan_purposes", adminhandlers.LoanPurposeNew)
@@ -95,6 +160,7 @@ func AdminGroup(e *echo.Echo) {
 
 	// Reports
 	g.GET("/reports/convenience_fee", reports.ConvenienceFeeReport)
+	g.GET("/reports/write_off", reports.WriteOffReport)
 
 	// FAQ
 	g.GET("/faq", adminhandlers.FAQList)
@@ -102,4 +168,7 @@ func AdminGroup(e *echo.Echo) {
 	g.GET("/faq/:faq_id", adminhandlers.FAQDetail)
 	g.PATCH("/faq/:faq_id", adminhandlers.FAQPatch)
 	g.DELETE("/faq/:faq_id", adminhandlers.FAQDelete)
+
+	// Outbox
+	g.GET("/outbox/metrics", adminhandlers.OutboxMetrics)
 }
@@ -17,10 +17,33 @@ func LenderGroup(e *echo.Echo) {
 	g.PATCH("/profile", handlers.LenderProfileEdit)
 	g.POST("/first_login", handlers.UserFirstLoginChangePassword)
 
+	// Notifications endpoints
+	g.GET("/notifications", handlers.LenderNotificationList)
+	g.GET("/notifications/unread_count", handlers.LenderNotificationUnreadCount)
+	g.PATCH("/notifications/read_all", handlers.LenderNotificationReadAll)
+	g.PATCH("/notifications/:notification_id/read", handlers.LenderNotificationRead)
+	g.GET("/notification_preferences", handlers.LenderNotificationPreferences)
+	g.PATCH("/notification_preferences", handlers.LenderNotificationPreferencePatch)
+
+	// Live events stream
+	g.GET("/events", handlers.LenderEvents)
+
 	// Loans endpoints
 	g.GET("/loanrequest_list", handlers.LenderLoanRequestList)
 	g.GET("/loanrequest_list/:loan_id/detail", handlers.LenderLoanRequestListDetail)
 	g.GET("/loanrequest_list/:loan_id/detail/:approve_reject", handlers.LenderLoanApproveReject)
+	g.POST("/loanrequest_list/approve_reject/bulk", handlers.LenderLoanApproveRejectBulk)
+	g.GET("/loanrequest_list/:loan_id/approval", handlers.LenderLoanApprovalDetail)
+	g.POST("/loanrequest_list/:loan_id/restructure", handlers.LenderLoanRestructure)
+	g.GET("/loanrequest_list/:loan_id/restructures", handlers.LenderLoanRestructureList)
+	g.GET("/loanrequest_list/:loan_id/payoff_quote", handlers.LenderLoanPayoffQuote)
+	g.POST("/loanrequest_list/:loan_id/early_settlement", handlers.LenderLoanEarlySettlement)
+	g.POST("/loanrequest_list/:loan_id/write_off", handlers.LenderLoanWriteOff)
+	g.GET("/loanrequest_list/:loan_id/write_off", handlers.LenderLoanWriteOffDetail)
+	g.PATCH("/loanrequest_list/:loan_id/write_off", handlers.LenderLoanWriteOffDecision)
+	g.POST("/loanrequest_list/:loan_id/recoveries", handlers.LenderLoanRecovery)
+	g.GET("/write_offs", handlers.LenderLoanWriteOffList)
+	g.GET("/approval_tasks", handlers.LenderApprovalTaskList)
 	g.GET("/loanrequest_list/:loan_id/detail/confirm_disbursement", handlers.LenderLoanConfirmDisbursement)
 	g.GET("/loanrequest_list/:loan_id/detail/change_disburse_date", handlers.LenderLoanChangeDisburseDate)
 	g.GET("/loanrequest_list/download", handlers.LenderLoanRequestListDownload)
@@ -28,12 +51,34 @@ func LenderGroup(e *echo.Echo) {
 	g.PATCH("/loanrequest_list/:loan_id/detail/installment_approve/bulk", handlers.LenderLoanInstallmentsApproveBulk)
 	g.PATCH("/loanrequest_list/:loan_id/change_payment_status", handlers.LenderLoanEditPaymentStatus)
 
+	// Disbursement batches
+	g.GET("/disbursement_batches", handlers.LenderDisbursementBatchList)
+	g.POST("/disbursement_batches", handlers.LenderDisbursementBatchNew)
+	g.GET("/disbursement_batches/:batch_id", handlers.LenderDisbursementBatchDetail)
+	g.GET("/disbursement_batches/:batch_id/file", handlers.LenderDisbursementBatchFile)
+	g.POST("/disbursement_batches/:batch_id/result", handlers.LenderDisbursementBatchResult)
+
+	// Bank statement reconciliation
+	g.GET("/bank_statements", handlers.LenderBankStatementList)
+	g.POST("/bank_statements", handlers.LenderBankStatementUpload)
+	g.GET("/bank_statements/:statement_id", handlers.LenderBankStatementDetail)
+	g.POST("/bank_statements/:statement_id/confirm", handlers.LenderBankStatementConfirm)
+
 	// Borrowers endpoints
 	g.GET("/borrower_list", handlers.LenderBorrowerList)
 	g.GET("/borrower_list/:borrower_id/detail", handlers.LenderBorrowerListDetail)
+	g.GET("/borrower_list/:borrower_id/credit_history", handlers.LenderBorrowerCreditHistory)
 	g.GET("/borrower_list/download", handlers.LenderBorrowerListDownload)
 	g.GET("/borrower_list/:borrower_id/:approval", handlers.LenderApproveRejectProspectiveBorrower)
 
+	// Borrower documents endpoints
+	g.GET("/document_types", handlers.LenderDocumentTypeList)
+	g.GET("/borrower_list/:borrower_id/documents", handlers.LenderBorrowerDocumentList)
+	g.POST("/borrower_list/:borrower_id/documents", handlers.LenderBorrowerDocumentNew)
+	g.GET("/borrower_list/:borrower_id/documents/:document_id", handlers.LenderBorrowerDocumentDetail)
+	g.PATCH("/borrower_list/:borrower_id/documents/:document_id", handlers.LenderBorrowerDocumentPatch)
+	g.PATCH("/borrower_list/:borrower_id/documents/:document_id/verify", handlers.LenderBorrowerDocumentVerify)
+
 	// services owned by bank (lender)
 	g.GET("/services", handlers.LenderServiceList)
 	g.GET("/services/:service_id", handlers.LenderServiceLListDetail)
@@ -3,7 +3,6 @@ package handlers
 import (
 	"asira_lender/adminhandlers"
 	"asira_lender/asira"
-	"flag"
 	"fmt"
 	"strings"
 	"time"
@@ -11,7 +10,6 @@ import (
 	jwt "github.com/dgrijalva/jwt-go"
 	"github.com/labstack/echo"
 	"github.com/thedevsaddam/govalidator"
-	"gopkg.in/gomail.v2"
 )
 
 type (
@@ -146,30 +144,3 @@ func validatePermission(c echo.Context, permission string) error {
 
 	return fmt.Errorf("Permission Denied")
 }
-
-// SendMail sends email, reci
-func SendMail(subject string, message string, recipients ...string) error {
-	if flag.Lookup("test.v") == nil {
-		Config := asira.App.Config.GetStringMap(fmt.Sprintf("%s.mailer", asira.App.ENV))
-		mailer := gomail.NewMessage()
-		mailer.SetHeader("From", Config["email"].(string))
-		mailer.SetHeader("To", recipients[0])
-		if len(recipients) > 1 {
-			mailer.SetHeader("Cc", recipients[1])
-		}
-		mailer.SetHeader("Subject", subject)
-		mailer.SetBody("text/plain", message)
-
-		dialer := gomail.NewPlainDialer(Config["host"].(string),
-			Config["port"].(int),
-			Config["email"].(string),
-			Config["password"].(string))
-
-		err := dialer.DialAndSend(mailer)
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
-}
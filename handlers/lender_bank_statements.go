@@ -0,0 +1,292 @@
+package handlers
+
+import (
+	"asira_lender/adminhandlers"
+	"asira_lender/asira"
+	"asira_lender/middlewares"
+	"asira_lender/models"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/labstack/echo"
+)
+
+type (
+	// BankStatementConfirmLine confirmation of one statement credit. installment defaults to the matched one,
+	// ignore leaves the credit out of reconciliation
+	BankStatementConfirmLine struct {
+		Line        uint64 `json:"line"`
+		Installment uint64 `json:"installment"`
+		Ignore      bool   `json:"ignore"`
+	}
+
+	// BankStatementConfirmPayload to handle statement confirmation
+	BankStatementConfirmPayload struct {
+		Lines []BankStatementConfirmLine `json:"lines"`
+	}
+)
+
+// LenderBankStatementUpload reads credits of uploaded bank statement and matches them to outstanding installments of lender bank
+func LenderBankStatementUpload(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "lender_bank_statement_upload")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	bankRep := models.BankRepresentatives{}
+	bankRep.FindbyUserID(int(lenderUserID(c)))
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		adminhandlers.NLog("warning", "LenderBankStatementUpload", map[string]interface{}{"message": "statement file not found", "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusUnprocessableEntity, map[string][]string{"file": []string{"The file field is required"}}, "Hambatan validasi")
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		adminhandlers.NLog("error", "LenderBankStatementUpload", map[string]interface{}{"message": "error opening statement file", "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, "Gagal membaca file")
+	}
+	defer file.Close()
+	content, err := ioutil.ReadAll(file)
+	if err != nil {
+		adminhandlers.NLog("error", "LenderBankStatementUpload", map[string]interface{}{"message": "error reading statement file", "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, "Gagal membaca file")
+	}
+
+	format := strings.ToLower(c.FormValue("format"))
+	if len(format) < 1 {
+		format = statementFormat(fileHeader.Filename, content)
+	}
+	if format != models.StatementFormatCSV && format != models.StatementFormatMT940 {
+		return returnInvalidResponse(http.StatusUnprocessableEntity, map[string][]string{"format": []string{"The format field must be csv or mt940"}}, "Hambatan validasi")
+	}
+
+	lines, account, err := models.ParseBankStatement(format, bytes.NewReader(content))
+	if err != nil || len(lines) < 1 {
+		adminhandlers.NLog("warning", "LenderBankStatementUpload", map[string]interface{}{"message": "invalid statement file", "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		message := fmt.Sprintf("The file must be %v bank statement with credits", format)
+		if err != nil {
+			message = err.Error()
+		}
+		return returnInvalidResponse(http.StatusUnprocessableEntity, map[string][]string{"file": []string{message}}, "Hambatan validasi")
+	}
+
+	statement := models.BankStatement{
+		Bank:          bankRep.BankID,
+		FileName:      fileHeader.Filename,
+		Format:        format,
+		AccountNumber: account,
+		UploadedBy:    lenderUserID(c),
+	}
+	if err = models.ReconcileBankStatement(&statement, lines); err != nil {
+		adminhandlers.NLog("error", "LenderBankStatementUpload", map[string]interface{}{"message": "error reconciling bank statement", "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, "Gagal memproses mutasi")
+	}
+
+	adminhandlers.NAudittrail(models.BankStatement{}, statement, c.Get("user").(*jwt.Token), "bank_statement", fmt.Sprint(statement.ID), "create")
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"statement": statement,
+		"lines":     lines,
+	})
+}
+
+// LenderBankStatementList lists uploaded bank statements of lender bank
+func LenderBankStatementList(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "lender_bank_statement_list")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	bankRep := models.BankRepresentatives{}
+	bankRep.FindbyUserID(int(lenderUserID(c)))
+
+	// pagination parameters
+	rows, err := strconv.Atoi(c.QueryParam("rows"))
+	page, err := strconv.Atoi(c.QueryParam("page"))
+	orderby := strings.Split(c.QueryParam("orderby"), ",")
+	sort := strings.Split(c.QueryParam("sort"), ",")
+
+	type Filter struct {
+		Bank   uint64 `json:"bank"`
+		Format string `json:"format"`
+		Status string `json:"status"`
+	}
+
+	statement := models.BankStatement{}
+	result, err := statement.PagedFindFilter(page, rows, orderby, sort, &Filter{
+		Bank:   bankRep.BankID,
+		Format: c.QueryParam("format"),
+		Status: c.QueryParam("status"),
+	})
+	if err != nil {
+		adminhandlers.NLog("warning", "LenderBankStatementList", map[string]interface{}{"message": "error listing bank statements", "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, "Pencarian tidak ditemukan")
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// LenderBankStatementDetail bank statement with its credits, filtered by status query
+func LenderBankStatementDetail(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "lender_bank_statement_list")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	statement, err := lenderBankStatement(c, "LenderBankStatementDetail")
+	if err != nil {
+		return err
+	}
+
+	lines, err := statement.Lines(c.QueryParam("status"))
+	if err != nil {
+		adminhandlers.NLog("warning", "LenderBankStatementDetail", map[string]interface{}{"message": fmt.Sprintf("error listing bank statement %v lines", statement.ID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"statement": statement,
+		"lines":     lines,
+	})
+}
+
+// LenderBankStatementConfirm applies confirmed statement credits as installment payments, or ignores them
+func LenderBankStatementConfirm(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "lender_bank_statement_confirm")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	type LineError struct {
+		Line    uint64 `json:"line"`
+		Message string `json:"message"`
+	}
+
+	statement, err := lenderBankStatement(c, "LenderBankStatementConfirm")
+	if err != nil {
+		return err
+	}
+	origin := statement
+
+	confirmPayload := BankStatementConfirmPayload{}
+	x, _ := ioutil.ReadAll(c.Request().Body)
+	if err = json.Unmarshal(x, &confirmPayload); err != nil || len(confirmPayload.Lines) < 1 {
+		return returnInvalidResponse(http.StatusUnprocessableEntity, map[string][]string{"lines": []string{"The lines field is required"}}, "Hambatan validasi")
+	}
+
+	var (
+		resolved     []models.BankStatementLine
+		installments []models.Installment
+		errors       []LineError
+	)
+	for _, confirm := range confirmPayload.Lines {
+		line := models.BankStatementLine{}
+		if err = asira.App.DB.Where("id = ? AND statement = ?", confirm.Line, statement.ID).First(&line).Error; err != nil {
+			errors = append(errors, LineError{confirm.Line, "Mutasi tidak ditemukan"})
+			continue
+		}
+		if line.Status != "matched" && line.Status != "ambiguous" && line.Status != "unmatched" {
+			errors = append(errors, LineError{confirm.Line, fmt.Sprintf("Mutasi sudah berstatus %v", line.Status)})
+			continue
+		}
+
+		if confirm.Ignore {
+			if err = statement.ResolveLine(&line, nil, middlewares.SubmitKafkaPayloadTx); err != nil {
+				if approvalError, ok := err.(models.ApprovalError); ok {
+					errors = append(errors, LineError{confirm.Line, approvalError.Message})
+					continue
+				}
+				adminhandlers.NLog("error", "LenderBankStatementConfirm", map[string]interface{}{"message": fmt.Sprintf("error ignoring bank statement line %v", line.ID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+				errors = append(errors, LineError{confirm.Line, "Gagal memproses mutasi"})
+				continue
+			}
+			resolved = append(resolved, line)
+			continue
+		}
+
+		installmentID := confirm.Installment
+		if installmentID == 0 {
+			installmentID = line.Installment
+		}
+		if installmentID == 0 {
+			errors = append(errors, LineError{confirm.Line, "Cicilan untuk mutasi belum dipilih"})
+			continue
+		}
+		outstanding, err := models.FindOutstandingInstallment(statement.Bank, installmentID)
+		if err != nil || outstanding.ID == 0 {
+			errors = append(errors, LineError{confirm.Line, fmt.Sprintf("Cicilan %v tidak ditemukan atau sudah dibayar", installmentID)})
+			continue
+		}
+
+		if err = statement.ResolveLine(&line, &outstanding, middlewares.SubmitKafkaPayloadTx); err != nil {
+			if approvalError, ok := err.(models.ApprovalError); ok {
+				errors = append(errors, LineError{confirm.Line, approvalError.Message})
+				continue
+			}
+			adminhandlers.NLog("error", "LenderBankStatementConfirm", map[string]interface{}{"message": fmt.Sprintf("error applying bank statement line %v", line.ID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+			errors = append(errors, LineError{confirm.Line, "Gagal memproses mutasi"})
+			continue
+		}
+		resolved = append(resolved, line)
+		installments = append(installments, outstanding.Installment)
+	}
+
+	adminhandlers.NAudittrail(origin, statement, c.Get("user").(*jwt.Token), "bank_statement", fmt.Sprint(statement.ID), "confirm")
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"statement":    statement,
+		"lines":        resolved,
+		"installments": installments,
+		"errors":       errors,
+	})
+}
+
+// lenderBankStatement finds bank statement of :statement_id of lender bank. returned error is ready to be sent as response
+func lenderBankStatement(c echo.Context, tag string) (statement models.BankStatement, err error) {
+	bankRep := models.BankRepresentatives{}
+	bankRep.FindbyUserID(int(lenderUserID(c)))
+
+	statementID, _ := strconv.ParseUint(c.Param("statement_id"), 10, 64)
+	err = statement.FindbyID(statementID)
+	if err != nil || statement.Bank != bankRep.BankID {
+		adminhandlers.NLog("warning", tag, map[string]interface{}{"message": fmt.Sprintf("bank statement %v not found", statementID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return statement, returnInvalidResponse(http.StatusNotFound, err, fmt.Sprintf("Mutasi %v tidak ditemukan", statementID))
+	}
+
+	return statement, nil
+}
+
+// statementFormat guesses bank statement format of uploaded file
+func statementFormat(name string, content []byte) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".sta", ".mt940", ".940":
+		return models.StatementFormatMT940
+	case ".csv":
+		return models.StatementFormatCSV
+	}
+	if bytes.Contains(content, []byte(":61:")) {
+		return models.StatementFormatMT940
+	}
+
+	return models.StatementFormatCSV
+}
@@ -0,0 +1,455 @@
+package handlers
+
+import (
+	"asira_lender/adminhandlers"
+	"asira_lender/asira"
+	"asira_lender/custommodule"
+	"asira_lender/models"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/labstack/echo"
+	"github.com/thedevsaddam/govalidator"
+)
+
+type (
+	// BorrowerDocumentPayload handles request body
+	BorrowerDocumentPayload struct {
+		DocumentType uint64 `json:"document_type"`
+		Loan         uint64 `json:"loan"`
+		File         string `json:"file"`
+		Note         string `json:"note"`
+	}
+	// BorrowerDocumentVerifyPayload handles verify request body
+	BorrowerDocumentVerifyPayload struct {
+		Status       string `json:"status"`
+		RejectReason string `json:"reject_reason"`
+	}
+	// InvalidDocumentError upload is not an acceptable document
+	InvalidDocumentError struct {
+		Reason string
+	}
+)
+
+func (e InvalidDocumentError) Error() string {
+	return e.Reason
+}
+
+// documentContentTypes accepted borrower document types
+var documentContentTypes = []string{"application/pdf", "image/jpeg", "image/png"}
+
+// LenderDocumentTypeList lists active document types lender can request
+func LenderDocumentTypeList(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "lender_borrower_document_list")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	// pagination parameters
+	rows, err := strconv.Atoi(c.QueryParam("rows"))
+	page, err := strconv.Atoi(c.QueryParam("page"))
+	orderby := strings.Split(c.QueryParam("orderby"), ",")
+	sort := strings.Split(c.QueryParam("sort"), ",")
+
+	type Filter struct {
+		Name   string `json:"name" condition:"LIKE"`
+		Status string `json:"status"`
+	}
+
+	documentType := models.DocumentType{}
+	result, err := documentType.PagedFindFilter(page, rows, orderby, sort, &Filter{
+		Name:   c.QueryParam("name"),
+		Status: "active",
+	})
+	if err != nil {
+		adminhandlers.NLog("warning", "LenderDocumentTypeList", map[string]interface{}{"message": "error listing document type", "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, "Pencarian tidak ditemukan")
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// LenderBorrowerDocumentList lists documents of lender's borrower
+func LenderBorrowerDocumentList(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "lender_borrower_document_list")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	bankRep, borrower, err := lenderDocumentBorrower(c, "LenderBorrowerDocumentList")
+	if err != nil {
+		return err
+	}
+
+	// pagination parameters
+	rows, err := strconv.Atoi(c.QueryParam("rows"))
+	page, err := strconv.Atoi(c.QueryParam("page"))
+	orderby := strings.Split(c.QueryParam("orderby"), ",")
+	sort := strings.Split(c.QueryParam("sort"), ",")
+
+	type Filter struct {
+		Borrower     uint64   `json:"borrower"`
+		Bank         uint64   `json:"bank"`
+		Loan         []string `json:"loan"`
+		DocumentType []string `json:"document_type"`
+		Status       []string `json:"status"`
+	}
+
+	document := models.BorrowerDocument{}
+	result, err := document.PagedFindFilter(page, rows, orderby, sort, &Filter{
+		Borrower:     borrower.ID,
+		Bank:         bankRep.BankID,
+		Loan:         customSplit(c.QueryParam("loan"), ","),
+		DocumentType: customSplit(c.QueryParam("document_type"), ","),
+		Status:       customSplit(c.QueryParam("status"), ","),
+	})
+	if err != nil {
+		adminhandlers.NLog("warning", "LenderBorrowerDocumentList", map[string]interface{}{"message": fmt.Sprintf("error listing borrower %v documents", borrower.ID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, "Pencarian tidak ditemukan")
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// LenderBorrowerDocumentNew attach document to borrower. without file the document is requested from borrower
+func LenderBorrowerDocumentNew(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "lender_borrower_document_new")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	bankRep, borrower, err := lenderDocumentBorrower(c, "LenderBorrowerDocumentNew")
+	if err != nil {
+		return err
+	}
+
+	documentPayload := BorrowerDocumentPayload{}
+	payloadRules := govalidator.MapData{
+		"document_type": []string{"required", "numeric"},
+		"loan":          []string{"numeric"},
+		"file":          []string{},
+		"note":          []string{},
+	}
+
+	validate := validateRequestPayload(c, payloadRules, &documentPayload)
+	if validate != nil {
+		adminhandlers.NLog("warning", "LenderBorrowerDocumentNew", map[string]interface{}{"message": "validation error", "error": validate}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusUnprocessableEntity, validate, "Hambatan validasi")
+	}
+
+	documentType := models.DocumentType{}
+	err = documentType.FindbyID(documentPayload.DocumentType)
+	if err != nil || documentType.Status != "active" {
+		adminhandlers.NLog("warning", "LenderBorrowerDocumentNew", map[string]interface{}{"message": fmt.Sprintf("document type %v not found", documentPayload.DocumentType), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusUnprocessableEntity, err, fmt.Sprintf("Tipe dokumen %v tidak ditemukan", documentPayload.DocumentType))
+	}
+
+	if documentPayload.Loan > 0 {
+		loan := models.Loan{}
+		err = loan.FindbyID(documentPayload.Loan)
+		if err != nil || loan.Borrower != borrower.ID {
+			adminhandlers.NLog("warning", "LenderBorrowerDocumentNew", map[string]interface{}{"message": fmt.Sprintf("loan %v not found for borrower %v", documentPayload.Loan, borrower.ID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+			return returnInvalidResponse(http.StatusUnprocessableEntity, err, fmt.Sprintf("Pinjaman %v tidak ditemukan", documentPayload.Loan))
+		}
+	}
+
+	document := models.BorrowerDocument{
+		Borrower:     borrower.ID,
+		Loan:         documentPayload.Loan,
+		Bank:         bankRep.BankID,
+		DocumentType: documentType.ID,
+		Note:         documentPayload.Note,
+		RequestedBy:  lenderUserID(c),
+		Status:       "requested",
+	}
+
+	if len(documentPayload.File) > 0 {
+		document.File, document.ContentType, err = uploadDocument(documentPayload.File, borrower.ID)
+		if err != nil {
+			adminhandlers.NLog("error", "LenderBorrowerDocumentNew", map[string]interface{}{"message": "error upload document", "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+			return documentUploadResponse(err, "Gagal menambahkan dokumen")
+		}
+		document.Status = "pending"
+	}
+
+	err = document.Create()
+	if err != nil {
+		adminhandlers.NLog("error", "LenderBorrowerDocumentNew", map[string]interface{}{"message": "error create borrower document", "error": err, "document": document}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, "Gagal menambahkan dokumen")
+	}
+
+	adminhandlers.NAudittrail(models.BorrowerDocument{}, document, c.Get("user").(*jwt.Token), "borrower document", fmt.Sprint(document.ID), "create")
+
+	return c.JSON(http.StatusCreated, document)
+}
+
+// LenderBorrowerDocumentDetail get borrower document by id
+func LenderBorrowerDocumentDetail(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "lender_borrower_document_detail")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	document, err := lenderBorrowerDocument(c, "LenderBorrowerDocumentDetail")
+	if err != nil {
+		return err
+	}
+
+	adminhandlers.PresignImages(c, "lender_view_image", "borrower document", fmt.Sprint(document.ID), &document.File)
+
+	return c.JSON(http.StatusOK, document)
+}
+
+// LenderBorrowerDocumentPatch update note or replace file of borrower document. new file has to be verified again
+func LenderBorrowerDocumentPatch(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "lender_borrower_document_patch")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	document, err := lenderBorrowerDocument(c, "LenderBorrowerDocumentPatch")
+	if err != nil {
+		return err
+	}
+	origin := document
+
+	documentPayload := BorrowerDocumentPayload{}
+	payloadRules := govalidator.MapData{
+		"file": []string{},
+		"note": []string{},
+	}
+
+	validate := validateRequestPayload(c, payloadRules, &documentPayload)
+	if validate != nil {
+		adminhandlers.NLog("warning", "LenderBorrowerDocumentPatch", map[string]interface{}{"message": "validation error", "error": validate}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusUnprocessableEntity, validate, "Hambatan validasi")
+	}
+
+	if len(documentPayload.Note) > 0 {
+		document.Note = documentPayload.Note
+	}
+	if len(documentPayload.File) > 0 {
+		document.File, document.ContentType, err = uploadDocument(documentPayload.File, document.Borrower)
+		if err != nil {
+			adminhandlers.NLog("error", "LenderBorrowerDocumentPatch", map[string]interface{}{"message": "error upload document", "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+			return documentUploadResponse(err, fmt.Sprintf("Gagal update dokumen %v", document.ID))
+		}
+		document.Status = "pending"
+		document.RejectReason = ""
+		document.VerifiedBy = 0
+		document.VerifiedAt = nil
+	}
+
+	err = document.Save()
+	if err != nil {
+		adminhandlers.NLog("error", "LenderBorrowerDocumentPatch", map[string]interface{}{"message": fmt.Sprintf("error update borrower document %v", document.ID), "error": err, "document": document}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, fmt.Sprintf("Gagal update dokumen %v", document.ID))
+	}
+
+	if len(origin.File) > 0 && origin.File != document.File {
+		if err := asira.App.Storage.Delete(origin.File); err != nil {
+			adminhandlers.NLog("warning", "LenderBorrowerDocumentPatch", map[string]interface{}{"message": fmt.Sprintf("error delete replaced file of borrower document %v", document.ID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+		}
+	}
+
+	adminhandlers.NAudittrail(origin, document, c.Get("user").(*jwt.Token), "borrower document", fmt.Sprint(document.ID), "update")
+
+	return c.JSON(http.StatusOK, document)
+}
+
+// LenderBorrowerDocumentVerify verify or reject uploaded borrower document
+func LenderBorrowerDocumentVerify(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "lender_borrower_document_verify")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	document, err := lenderBorrowerDocument(c, "LenderBorrowerDocumentVerify")
+	if err != nil {
+		return err
+	}
+	origin := document
+
+	verifyPayload := BorrowerDocumentVerifyPayload{}
+	payloadRules := govalidator.MapData{
+		"status":        []string{"required", "in:verified,rejected"},
+		"reject_reason": []string{},
+	}
+
+	validate := validateRequestPayload(c, payloadRules, &verifyPayload)
+	if validate != nil {
+		adminhandlers.NLog("warning", "LenderBorrowerDocumentVerify", map[string]interface{}{"message": "validation error", "error": validate}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusUnprocessableEntity, validate, "Hambatan validasi")
+	}
+	if verifyPayload.Status == "rejected" && len(verifyPayload.RejectReason) < 1 {
+		return returnInvalidResponse(http.StatusUnprocessableEntity, map[string]interface{}{"reject_reason": []string{"alasan penolakan wajib diisi"}}, "Hambatan validasi")
+	}
+	if document.Status != "pending" {
+		adminhandlers.NLog("warning", "LenderBorrowerDocumentVerify", map[string]interface{}{"message": fmt.Sprintf("borrower document %v is %v", document.ID, document.Status)}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusUnprocessableEntity, "", fmt.Sprintf("Dokumen %v tidak dalam status menunggu verifikasi", document.ID))
+	}
+
+	now := time.Now()
+	document.Status = verifyPayload.Status
+	document.RejectReason = ""
+	if verifyPayload.Status == "rejected" {
+		document.RejectReason = verifyPayload.RejectReason
+	}
+	document.VerifiedBy = lenderUserID(c)
+	document.VerifiedAt = &now
+
+	err = document.Save()
+	if err != nil {
+		adminhandlers.NLog("error", "LenderBorrowerDocumentVerify", map[string]interface{}{"message": fmt.Sprintf("error verify borrower document %v", document.ID), "error": err, "document": document}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, fmt.Sprintf("Gagal verifikasi dokumen %v", document.ID))
+	}
+
+	adminhandlers.NLog("info", "LenderBorrowerDocumentVerify", map[string]interface{}{"message": fmt.Sprintf("borrower document %v status changed to %v", document.ID, document.Status)}, c.Get("user").(*jwt.Token), "", false)
+
+	adminhandlers.NAudittrail(origin, document, c.Get("user").(*jwt.Token), "borrower document", fmt.Sprint(document.ID), "verify")
+
+	return c.JSON(http.StatusOK, document)
+}
+
+func lenderUserID(c echo.Context) uint64 {
+	jti := c.Get("user").(*jwt.Token).Claims.(jwt.MapClaims)["jti"].(string)
+	lenderID, _ := strconv.ParseUint(jti, 10, 64)
+
+	return lenderID
+}
+
+// lenderDocumentBorrower finds borrower of :borrower_id owned by lender's bank. returned error is ready to be sent as response
+func lenderDocumentBorrower(c echo.Context, tag string) (bankRep models.BankRepresentatives, borrower models.Borrower, err error) {
+	err = bankRep.FindbyUserID(int(lenderUserID(c)))
+	if err != nil {
+		adminhandlers.NLog("warning", tag, map[string]interface{}{"message": "error finding bank representative", "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return bankRep, borrower, returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	borrowerID, _ := strconv.Atoi(c.Param("borrower_id"))
+
+	type Filter struct {
+		Bank sql.NullInt64 `json:"bank"`
+		ID   int           `json:"id"`
+	}
+	err = borrower.SingleFindFilter(&Filter{
+		Bank: sql.NullInt64{
+			Int64: int64(bankRep.BankID),
+			Valid: true,
+		},
+		ID: borrowerID,
+	})
+	if err != nil {
+		adminhandlers.NLog("warning", tag, map[string]interface{}{"message": fmt.Sprintf("error finding borrower %v", borrowerID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return bankRep, borrower, returnInvalidResponse(http.StatusNotFound, err, fmt.Sprintf("ID %v tidak ditemukan", borrowerID))
+	}
+
+	return bankRep, borrower, nil
+}
+
+// lenderBorrowerDocument finds :document_id of :borrower_id owned by lender's bank
+func lenderBorrowerDocument(c echo.Context, tag string) (document models.BorrowerDocument, err error) {
+	bankRep, borrower, err := lenderDocumentBorrower(c, tag)
+	if err != nil {
+		return document, err
+	}
+
+	documentID, _ := strconv.ParseUint(c.Param("document_id"), 10, 64)
+
+	type Filter struct {
+		ID       uint64 `json:"id"`
+		Borrower uint64 `json:"borrower"`
+		Bank     uint64 `json:"bank"`
+	}
+	err = document.SingleFindFilter(&Filter{
+		ID:       documentID,
+		Borrower: borrower.ID,
+		Bank:     bankRep.BankID,
+	})
+	if err != nil {
+		adminhandlers.NLog("warning", tag, map[string]interface{}{"message": fmt.Sprintf("error finding borrower document %v", documentID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return document, returnInvalidResponse(http.StatusNotFound, err, fmt.Sprintf("Dokumen %v tidak ditemukan", documentID))
+	}
+
+	return document, nil
+}
+
+// uploadDocument validates base64 encoded document and stores it, returns object key and content type
+func uploadDocument(based string, borrowerID uint64) (key string, contentType string, err error) {
+	// accept data uri as well
+	if i := strings.Index(based, ";base64,"); i >= 0 {
+		based = based[i+len(";base64,"):]
+	}
+
+	unbased, err := base64.StdEncoding.DecodeString(based)
+	if err != nil {
+		return "", "", InvalidDocumentError{"base64 tidak valid"}
+	}
+
+	maxSize := asira.App.Config.GetInt(fmt.Sprintf("%s.document.max_size", asira.App.ENV))
+	if maxSize <= 0 {
+		maxSize = 5 * 1024 * 1024
+	}
+	if len(unbased) < 1 {
+		return "", "", InvalidDocumentError{"dokumen kosong"}
+	}
+	if len(unbased) > maxSize {
+		return "", "", InvalidDocumentError{fmt.Sprintf("ukuran dokumen melebihi %d byte", maxSize)}
+	}
+
+	contentType, ext := custommodule.DetectContentType(unbased)
+	accepted := false
+	for _, v := range documentContentTypes {
+		if v == contentType {
+			accepted = true
+			break
+		}
+	}
+	if !accepted {
+		return "", "", InvalidDocumentError{fmt.Sprintf("tipe dokumen %s tidak didukung", contentType)}
+	}
+
+	key = fmt.Sprintf("doc%d_%d%s", borrowerID, time.Now().UnixNano(), ext)
+	if _, err = asira.App.Storage.Put(key, unbased, contentType); err != nil {
+		return "", "", err
+	}
+
+	return key, contentType, nil
+}
+
+// documentUploadResponse invalid document is a client error, anything else is a storage failure
+func documentUploadResponse(err error, message string) error {
+	if _, ok := err.(InvalidDocumentError); ok {
+		return returnInvalidResponse(http.StatusUnprocessableEntity, err.Error(), "Dokumen tidak valid")
+	}
+
+	return returnInvalidResponse(http.StatusInternalServerError, err, message)
+}
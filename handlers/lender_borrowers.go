@@ -80,12 +80,13 @@ type (
 	// BorrowerSelect select for joining
 	BorrowerSelect struct {
 		models.Borrower
-		Category          string `json:"category"`
-		BankName          string `json:"bank_name"`
-		LoanCount         int    `json:"loan_count"`
-		LoanStatus        string `json:"loan_status"`
-		AgentName         string `json:"agent_name"`
-		AgentProviderName string `json:"agent_provider_name"`
+		Category          string             `json:"category"`
+		BankName          string             `json:"bank_name"`
+		LoanCount         int                `json:"loan_count"`
+		LoanStatus        string             `json:"loan_status"`
+		AgentName         string             `json:"agent_name"`
+		AgentProviderName string             `json:"agent_provider_name"`
+		FraudFlags        []models.FraudFlag `json:"fraud_flags,omitempty" gorm:"-"`
 	}
 )
 
@@ -272,9 +273,51 @@ func LenderBorrowerListDetail(c echo.Context) error {
 		return returnInvalidResponse(http.StatusNotFound, err, fmt.Sprintf("ID %v tidak ditemukan.", borrowerID))
 	}
 
+	adminhandlers.PresignImages(c, "lender_view_image", "borrower", fmt.Sprint(borrower.ID), &borrower.ImageProfile, &borrower.IdCardImage, &borrower.TaxIDImage)
+
+	borrower.FraudFlags, err = lenderFraudFlags(borrower.ID, bankRep.BankID)
+	if err != nil {
+		adminhandlers.NLog("warning", "LenderBorrowerListDetail", map[string]interface{}{"message": fmt.Sprintf("error finding borrower %v fraud flags", borrowerID), "error": err}, user.(*jwt.Token), "", false)
+	}
+
 	return c.JSON(http.StatusOK, borrower)
 }
 
+// lenderFraudFlags fraud flags of borrower, with matches of other banks left out
+func lenderFraudFlags(borrowerID uint64, bankID uint64) ([]models.FraudFlag, error) {
+	flags, err := models.BorrowerFraudFlags(borrowerID)
+	if err != nil {
+		return flags, err
+	}
+
+	for i, flag := range flags {
+		if len(flag.Matches) < 1 {
+			continue
+		}
+
+		var visible []int64
+		db := asira.App.DB
+		if flag.Kind == models.FraudAgentLoanBurst {
+			err = db.Table("loans").
+				Joins("INNER JOIN borrowers b ON b.id = loans.borrower").
+				Where("loans.id IN (?) AND b.bank = ?", []int64(flag.Matches), bankID).
+				Order("loans.id asc").
+				Pluck("loans.id", &visible).Error
+		} else {
+			err = db.Table("borrowers").
+				Where("id IN (?) AND bank = ?", []int64(flag.Matches), bankID).
+				Order("id asc").
+				Pluck("id", &visible).Error
+		}
+		if err != nil {
+			return flags, err
+		}
+		flags[i].Matches = visible
+	}
+
+	return flags, nil
+}
+
 // LenderBorrowerListDownload download borrower list csv
 func LenderBorrowerListDownload(c echo.Context) error {
 	defer c.Request().Body.Close()
@@ -433,12 +476,20 @@ func LenderApproveRejectProspectiveBorrower(c echo.Context) error {
 	default:
 		if accNumber := c.QueryParam("account_number"); len(accNumber) > 0 {
 			borrower.BankAccountNumber = accNumber
+			if err = watchlistBlock(c, borrower); err != nil {
+				if _, ok := err.(models.ApprovalError); ok {
+					return returnInvalidResponse(http.StatusUnprocessableEntity, err, err.Error())
+				}
+				adminhandlers.NLog("error", "LenderApproveRejectProspectiveBorrower", map[string]interface{}{"message": "error checking watchlist", "error": err, "borrower": borrower}, user.(*jwt.Token), "", false)
+
+				return returnInvalidResponse(http.StatusInternalServerError, err, "Gagal approve borrower")
+			}
 			borrower.Status = "approved"
-			err = middlewares.SubmitKafkaPayload(borrower, "borrower_update")
+			err = middlewares.SaveWithOutbox(&borrower, "borrower_update")
 			if err != nil {
-				adminhandlers.NLog("error", "LenderApproveRejectProspectiveBorrower", map[string]interface{}{"message": "error submitting borrower kafka", "error": err, "borrower": borrower}, user.(*jwt.Token), "", false)
+				adminhandlers.NLog("error", "LenderApproveRejectProspectiveBorrower", map[string]interface{}{"message": "error saving borrower", "error": err, "borrower": borrower}, user.(*jwt.Token), "", false)
 
-				returnInvalidResponse(http.StatusUnprocessableEntity, err, "Gagal approve borrower")
+				return returnInvalidResponse(http.StatusUnprocessableEntity, err, "Gagal approve borrower")
 			}
 		} else {
 			adminhandlers.NLog("warning", "LenderApproveRejectProspectiveBorrower", map[string]interface{}{"message": "account number empty"}, user.(*jwt.Token), "", false)
@@ -448,11 +499,11 @@ func LenderApproveRejectProspectiveBorrower(c echo.Context) error {
 		break
 	case "reject":
 		borrower.Status = "rejected"
-		err = middlewares.SubmitKafkaPayload(borrower, "borrower_update")
+		err = middlewares.SaveWithOutbox(&borrower, "borrower_update")
 		if err != nil {
-			adminhandlers.NLog("error", "LenderApproveRejectProspectiveBorrower", map[string]interface{}{"message": "error submitting kafka borrower", "error": err, "borrower": borrower}, user.(*jwt.Token), "", false)
+			adminhandlers.NLog("error", "LenderApproveRejectProspectiveBorrower", map[string]interface{}{"message": "error saving borrower", "error": err, "borrower": borrower}, user.(*jwt.Token), "", false)
 
-			returnInvalidResponse(http.StatusUnprocessableEntity, err, "Gagal reject borrower")
+			return returnInvalidResponse(http.StatusUnprocessableEntity, err, "Gagal reject borrower")
 		}
 		break
 	}
@@ -475,3 +526,26 @@ func mapnewBorrowerStruct(m []BorrowerSelect) []BorrowerCSV {
 	}
 	return r
 }
+
+// LenderBorrowerCreditHistory track record of borrower across all loans with lender's bank
+func LenderBorrowerCreditHistory(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "lender_borrower_credit_history")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	_, borrower, err := lenderDocumentBorrower(c, "LenderBorrowerCreditHistory")
+	if err != nil {
+		return err
+	}
+
+	history, err := models.BorrowerCreditHistory(borrower, false)
+	if err != nil {
+		adminhandlers.NLog("error", "LenderBorrowerCreditHistory", map[string]interface{}{"message": fmt.Sprintf("error summarizing borrower %v credit history", borrower.ID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, "Gagal memuat riwayat kredit nasabah")
+	}
+
+	return c.JSON(http.StatusOK, history)
+}
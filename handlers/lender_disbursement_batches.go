@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"asira_lender/adminhandlers"
+	"asira_lender/asira"
+	"asira_lender/middlewares"
+	"asira_lender/models"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/labstack/echo"
+	"github.com/thedevsaddam/govalidator"
+)
+
+// DisbursementBatchPayload to handle new disbursement batch
+type DisbursementBatchPayload struct {
+	Format string `json:"format"`
+}
+
+// LenderDisbursementBatchList lists disbursement batches of lender bank
+func LenderDisbursementBatchList(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "lender_disbursement_batch_list")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	bankRep := models.BankRepresentatives{}
+	bankRep.FindbyUserID(int(lenderUserID(c)))
+
+	// pagination parameters
+	rows, err := strconv.Atoi(c.QueryParam("rows"))
+	page, err := strconv.Atoi(c.QueryParam("page"))
+	orderby := strings.Split(c.QueryParam("orderby"), ",")
+	sort := strings.Split(c.QueryParam("sort"), ",")
+
+	type Filter struct {
+		Bank   uint64 `json:"bank"`
+		Status string `json:"status"`
+	}
+
+	batch := models.DisbursementBatch{}
+	result, err := batch.PagedFindFilter(page, rows, orderby, sort, &Filter{
+		Bank:   bankRep.BankID,
+		Status: c.QueryParam("status"),
+	})
+	if err != nil {
+		adminhandlers.NLog("warning", "LenderDisbursementBatchList", map[string]interface{}{"message": "error listing disbursement batches", "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, "Pencarian tidak ditemukan")
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// LenderDisbursementBatchNew batches approved loans to be disbursed today into a transfer batch file
+func LenderDisbursementBatchNew(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "lender_disbursement_batch_new")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	bankRep := models.BankRepresentatives{}
+	bankRep.FindbyUserID(int(lenderUserID(c)))
+
+	batchPayload := DisbursementBatchPayload{
+		Format: disbursementFormat(),
+	}
+	payloadRules := govalidator.MapData{
+		"format": []string{"in:" + strings.Join(models.DisbursementFormats, ",")},
+	}
+
+	validate := validateRequestPayload(c, payloadRules, &batchPayload)
+	if validate != nil {
+		adminhandlers.NLog("warning", "LenderDisbursementBatchNew", map[string]interface{}{"message": "validation error", "error": validate}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusUnprocessableEntity, validate, "Hambatan validasi")
+	}
+
+	batch, items, skipped, err := models.CollectDisbursementBatch(bankRep.BankID, time.Now(), batchPayload.Format, lenderUserID(c), middlewares.SubmitKafkaPayloadTx)
+	if err != nil {
+		adminhandlers.NLog("error", "LenderDisbursementBatchNew", map[string]interface{}{"message": "error collecting disbursement batch", "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, "Gagal membuat batch pencairan")
+	}
+	if len(items) < 1 {
+		return returnInvalidResponse(http.StatusUnprocessableEntity, map[string]interface{}{"skipped": skipped}, "Tidak ada pinjaman yang dapat dicairkan hari ini")
+	}
+
+	adminhandlers.NAudittrail(models.DisbursementBatch{}, batch, c.Get("user").(*jwt.Token), "disbursement_batch", fmt.Sprint(batch.ID), "create")
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"batch":   batch,
+		"items":   items,
+		"skipped": skipped,
+	})
+}
+
+// LenderDisbursementBatchDetail disbursement batch with its transfers
+func LenderDisbursementBatchDetail(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "lender_disbursement_batch_list")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	batch, err := lenderDisbursementBatch(c, "LenderDisbursementBatchDetail")
+	if err != nil {
+		return err
+	}
+
+	items, err := batch.Items()
+	if err != nil {
+		adminhandlers.NLog("warning", "LenderDisbursementBatchDetail", map[string]interface{}{"message": fmt.Sprintf("error listing disbursement batch %v items", batch.ID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"batch": batch,
+		"items": items,
+	})
+}
+
+// LenderDisbursementBatchFile downloads transfer batch file to be uploaded to bank
+func LenderDisbursementBatchFile(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "lender_disbursement_batch_list")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	batch, err := lenderDisbursementBatch(c, "LenderDisbursementBatchFile")
+	if err != nil {
+		return err
+	}
+
+	items, err := batch.Items()
+	if err != nil {
+		adminhandlers.NLog("error", "LenderDisbursementBatchFile", map[string]interface{}{"message": fmt.Sprintf("error listing disbursement batch %v items", batch.ID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, "Gagal membuat file batch")
+	}
+
+	content, contentType, name, err := batch.File(items)
+	if err != nil {
+		adminhandlers.NLog("error", "LenderDisbursementBatchFile", map[string]interface{}{"message": fmt.Sprintf("error rendering disbursement batch %v file", batch.ID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, "Gagal membuat file batch")
+	}
+
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", name))
+
+	return c.Blob(http.StatusOK, contentType, content)
+}
+
+// LenderDisbursementBatchResult reads bank result file of batch, confirming or failing each pending transfer
+func LenderDisbursementBatchResult(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "lender_disbursement_batch_result")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	batch, err := lenderDisbursementBatch(c, "LenderDisbursementBatchResult")
+	if err != nil {
+		return err
+	}
+	origin := batch
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		adminhandlers.NLog("warning", "LenderDisbursementBatchResult", map[string]interface{}{"message": "result file not found", "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusUnprocessableEntity, map[string][]string{"file": []string{"The file field is required"}}, "Hambatan validasi")
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		adminhandlers.NLog("error", "LenderDisbursementBatchResult", map[string]interface{}{"message": "error opening result file", "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, "Gagal membaca file")
+	}
+	defer file.Close()
+
+	results, err := models.ParseDisbursementResult(batch.Format, file)
+	if err != nil || len(results) < 1 {
+		adminhandlers.NLog("warning", "LenderDisbursementBatchResult", map[string]interface{}{"message": "invalid result file", "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusUnprocessableEntity, map[string][]string{"file": []string{fmt.Sprintf("The file must be %v result file of batch transfers", batch.Format)}}, "Hambatan validasi")
+	}
+
+	origins, loans, unmatched, err := batch.ApplyResults(results, middlewares.SubmitKafkaPayloadTx)
+	if err != nil {
+		adminhandlers.NLog("error", "LenderDisbursementBatchResult", map[string]interface{}{"message": fmt.Sprintf("error applying disbursement batch %v result", batch.ID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, "Gagal memproses hasil pencairan")
+	}
+
+	for i, loan := range loans {
+		middlewares.LoanWebhooks(origins[i], loan)
+
+		adminhandlers.NAudittrail(origins[i], loan, c.Get("user").(*jwt.Token), "loan", fmt.Sprint(loan.ID), fmt.Sprintf("disbursement %v", loan.DisburseStatus))
+	}
+
+	adminhandlers.NAudittrail(origin, batch, c.Get("user").(*jwt.Token), "disbursement_batch", fmt.Sprint(batch.ID), "result")
+
+	items, _ := batch.Items()
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"batch":     batch,
+		"items":     items,
+		"unmatched": unmatched,
+	})
+}
+
+// lenderDisbursementBatch finds disbursement batch of :batch_id of lender bank. returned error is ready to be sent as response
+func lenderDisbursementBatch(c echo.Context, tag string) (batch models.DisbursementBatch, err error) {
+	bankRep := models.BankRepresentatives{}
+	bankRep.FindbyUserID(int(lenderUserID(c)))
+
+	batchID, _ := strconv.ParseUint(c.Param("batch_id"), 10, 64)
+	err = batch.FindbyID(batchID)
+	if err != nil || batch.Bank != bankRep.BankID {
+		adminhandlers.NLog("warning", tag, map[string]interface{}{"message": fmt.Sprintf("disbursement batch %v not found", batchID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return batch, returnInvalidResponse(http.StatusNotFound, err, fmt.Sprintf("Batch pencairan %v tidak ditemukan", batchID))
+	}
+
+	return batch, nil
+}
+
+func disbursementFormat() string {
+	format := asira.App.Config.GetString(fmt.Sprintf("%s.disbursement.format", asira.App.ENV))
+	for _, f := range models.DisbursementFormats {
+		if f == format {
+			return format
+		}
+	}
+
+	return models.DisbursementFormatCSV
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"asira_lender/adminhandlers"
+	"asira_lender/middlewares"
+	"asira_lender/models"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/labstack/echo"
+)
+
+// liveRetry reconnection delay in milliseconds suggested to event stream clients
+const liveRetry = 3000
+
+// LenderEvents streams loan and borrower changes of lender's bank as server-sent events.
+// reconnecting clients send Last-Event-ID header (or last_event_id query) to replay missed events
+func LenderEvents(c echo.Context) error {
+	defer c.Request().Body.Close()
+
+	// events of an entity are only streamed to users allowed to list it
+	loanErr := validatePermission(c, "lender_loan_request_list")
+	borrowerErr := validatePermission(c, "lender_borrower_list")
+	if loanErr != nil && borrowerErr != nil {
+		return returnInvalidResponse(http.StatusForbidden, loanErr, fmt.Sprintf("%s", loanErr))
+	}
+	entities := map[string]bool{
+		"loan":     loanErr == nil,
+		"borrower": borrowerErr == nil,
+	}
+
+	bankRep := models.BankRepresentatives{}
+	err := bankRep.FindbyUserID(int(lenderUserID(c)))
+	if err != nil {
+		adminhandlers.NLog("warning", "LenderEvents", map[string]interface{}{"message": "error finding bank representative", "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusForbidden, err, "Akun tidak terdaftar sebagai perwakilan bank")
+	}
+
+	lastEventID := c.Request().Header.Get("Last-Event-ID")
+	if len(lastEventID) < 1 {
+		lastEventID = c.QueryParam("last_event_id")
+	}
+	lastID, _ := strconv.ParseUint(lastEventID, 10, 64)
+
+	subscription, missed, complete := middlewares.SubscribeLiveEvents(bankRep.BankID, lastID)
+	defer subscription.Unsubscribe()
+
+	response := c.Response()
+	response.Header().Set(echo.HeaderContentType, "text/event-stream")
+	response.Header().Set("Cache-Control", "no-cache")
+	response.Header().Set("Connection", "keep-alive")
+	response.Header().Set("X-Accel-Buffering", "no")
+	response.WriteHeader(http.StatusOK)
+
+	fmt.Fprintf(response, "retry: %d\n\n", liveRetry)
+	if !complete {
+		// some missed events are gone, client should reload its lists
+		fmt.Fprintf(response, "event: reload\ndata: {}\n\n")
+	}
+	for _, event := range missed {
+		if entities[event.Entity] {
+			writeLiveEvent(response, event)
+		}
+	}
+	response.Flush()
+
+	ticker := time.NewTicker(middlewares.LiveHeartbeat())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.Request().Context().Done():
+			return nil
+		case event, ok := <-subscription.Events:
+			if !ok {
+				return nil
+			}
+			if !entities[event.Entity] {
+				continue
+			}
+			writeLiveEvent(response, event)
+			response.Flush()
+		case <-ticker.C:
+			fmt.Fprintf(response, ": heartbeat %d\n\n", time.Now().Unix())
+			response.Flush()
+		}
+	}
+}
+
+func writeLiveEvent(response *echo.Response, event middlewares.LiveEvent) {
+	data, _ := json.Marshal(event)
+	fmt.Fprintf(response, "id: %d\nevent: %s_%s\ndata: %s\n\n", event.ID, event.Entity, event.Mode, data)
+}
@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"asira_lender/adminhandlers"
+	"asira_lender/asira"
+	"asira_lender/middlewares"
+	"asira_lender/models"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ayannahindonesia/basemodel"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/labstack/echo"
+)
+
+// ApprovalTask loan waiting for current lender user decision
+type ApprovalTask struct {
+	models.LoanApproval
+	BorrowerName string  `json:"borrower_name"`
+	LoanAmount   float64 `json:"loan_amount"`
+	Product      string  `json:"product"`
+}
+
+// LenderApprovalTaskList lists loans whose current approval step waits for current lender user
+func LenderApprovalTaskList(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "lender_loan_approval_task_list")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	user := models.User{}
+	err = user.FindbyID(lenderUserID(c))
+	if err != nil {
+		adminhandlers.NLog("warning", "LenderApprovalTaskList", map[string]interface{}{"message": "error finding user", "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusForbidden, err, "Akun tidak ditemukan")
+	}
+	bankRep := models.BankRepresentatives{}
+	bankRep.FindbyUserID(int(user.ID))
+
+	var (
+		totalRows int
+		offset    int
+		rows      int
+		page      int
+		lastPage  int
+		tasks     []ApprovalTask
+	)
+
+	// pagination parameters
+	rows, _ = strconv.Atoi(c.QueryParam("rows"))
+	if rows > 0 {
+		page, _ = strconv.Atoi(c.QueryParam("page"))
+		if page <= 0 {
+			page = 1
+		}
+		offset = (page * rows) - rows
+	}
+
+	db := asira.App.DB.Table("loan_approvals").
+		Select("loan_approvals.*, b.fullname as borrower_name, l.loan_amount, p.name as product").
+		Joins("INNER JOIN loans l ON l.id = loan_approvals.loan").
+		Joins("INNER JOIN borrowers b ON b.id = l.borrower").
+		Joins("LEFT JOIN products p ON p.id = l.product").
+		Where("loan_approvals.deleted_at IS NULL").
+		Where("loan_approvals.bank = ?", bankRep.BankID).
+		Where("loan_approvals.status = ?", "pending").
+		Where("l.status = ?", "processing").
+		Where("l.otp_verified = ?", true).
+		Where("loan_approvals.step_role IN (?)", []int64(user.Roles)).
+		Where("NOT EXISTS (SELECT 1 FROM loan_approval_actions a WHERE a.loan_approval = loan_approvals.id AND a.user_id = ?)", user.ID)
+
+	db.Count(&totalRows)
+
+	if rows > 0 {
+		db = db.Limit(rows).Offset(offset)
+		lastPage = int(math.Ceil(float64(totalRows) / float64(rows)))
+	}
+	err = db.Order("loan_approvals.id ASC").Scan(&tasks).Error
+	if err != nil {
+		adminhandlers.NLog("warning", "LenderApprovalTaskList", map[string]interface{}{"message": "error listing approval tasks", "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, "Pencarian tidak ditemukan")
+	}
+
+	result := basemodel.PagedFindResult{
+		TotalData:   totalRows,
+		Rows:        rows,
+		CurrentPage: page,
+		LastPage:    lastPage,
+		From:        offset + 1,
+		To:          offset + rows,
+		Data:        tasks,
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// LenderLoanApprovalDetail shows approval chain progress of loan and decisions made so far
+func LenderLoanApprovalDetail(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "lender_loan_approval_detail")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	bankRep := models.BankRepresentatives{}
+	bankRep.FindbyUserID(int(lenderUserID(c)))
+
+	loanID, _ := strconv.ParseUint(c.Param("loan_id"), 10, 64)
+
+	type Filter struct {
+		Loan uint64 `json:"loan"`
+		Bank uint64 `json:"bank"`
+	}
+	approval := models.LoanApproval{}
+	err = approval.SingleFindFilter(&Filter{
+		Loan: loanID,
+		Bank: bankRep.BankID,
+	})
+	if err != nil {
+		adminhandlers.NLog("warning", "LenderLoanApprovalDetail", map[string]interface{}{"message": fmt.Sprintf("approval of loan %v not found", loanID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusNotFound, err, fmt.Sprintf("Persetujuan pinjaman %v tidak ditemukan", loanID))
+	}
+
+	actions, err := approval.Actions()
+	if err != nil {
+		adminhandlers.NLog("warning", "LenderLoanApprovalDetail", map[string]interface{}{"message": fmt.Sprintf("error finding approval actions of loan %v", loanID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, "Terjadi kesalahan")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"approval": approval,
+		"actions":  actions,
+	})
+}
+
+// decideLoanByPolicy applies lender user decision to loan. loans under an approval policy of the bank are
+// only decided when the last step approves or any step rejects, until then the decision is recorded on the approval.
+// loans above hard dti limit of their product can not be approved. returned approval is nil for loans decided directly
+func decideLoanByPolicy(c echo.Context, bankID uint64, loan *models.Loan, action string, disburseDate time.Time, reason string) (approval *models.LoanApproval, err error) {
+	if action == "approve" {
+		affordability, err := models.LoanAffordability(*loan)
+		if err != nil {
+			return nil, err
+		}
+		if affordability.Blocked {
+			return nil, models.ApprovalError{Message: fmt.Sprintf("Pinjaman %v tidak dapat disetujui, %v", loan.ID, affordability.Warning)}
+		}
+
+		borrower := models.Borrower{}
+		if err = borrower.FindbyID(loan.Borrower); err != nil {
+			return nil, err
+		}
+		if err = watchlistBlock(c, borrower); err != nil {
+			return nil, err
+		}
+	}
+
+	loanApproval, required, err := models.StartLoanApproval(*loan, bankID)
+	if err != nil {
+		return nil, err
+	}
+	if !required {
+		return nil, models.DecideLoan(loan, action, disburseDate, reason, middlewares.SubmitKafkaPayloadTx)
+	}
+
+	user := models.User{}
+	if err = user.FindbyID(lenderUserID(c)); err != nil {
+		return &loanApproval, err
+	}
+
+	var proposedDate *time.Time
+	if action == "approve" {
+		proposedDate = &disburseDate
+	}
+	_, err = loanApproval.Act(user, action, reason, proposedDate, loan, middlewares.SubmitKafkaPayloadTx)
+
+	return &loanApproval, err
+}
+
+// watchlistBlock prevents approving borrower matching active watchlist entries, unless lender user may override the watchlist
+func watchlistBlock(c echo.Context, borrower models.Borrower) error {
+	matches, err := models.WatchlistMatches(borrower)
+	if err != nil || len(matches) < 1 {
+		return err
+	}
+
+	var kinds []string
+	for _, match := range matches {
+		kinds = append(kinds, match.Kind)
+	}
+
+	if validatePermission(c, "lender_watchlist_override") == nil {
+		adminhandlers.NLog("info", "watchlistBlock", map[string]interface{}{"message": fmt.Sprintf("watchlist match of borrower %v overridden", borrower.ID), "watchlist": matches}, c.Get("user").(*jwt.Token), "", false)
+
+		return nil
+	}
+
+	return models.ApprovalError{Message: fmt.Sprintf("Nasabah %v terdaftar di watchlist (%v) dan tidak dapat disetujui", borrower.ID, strings.Join(kinds, ", "))}
+}
+
+// approvalMessage describes loan status after lender user decision
+func approvalMessage(loan models.Loan, approval *models.LoanApproval) string {
+	if approval == nil || approval.Status != "pending" {
+		return fmt.Sprintf("loan %v is %v", loan.ID, loan.Status)
+	}
+
+	steps := approval.StepList()
+	step := approval.CurrentStep
+	if step >= len(steps) {
+		step = len(steps) - 1
+	}
+
+	return fmt.Sprintf("loan %v menunggu persetujuan tahap %v (%v)", loan.ID, step+1, steps[step].Name)
+}
@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"asira_lender/adminhandlers"
+	"asira_lender/middlewares"
+	"asira_lender/models"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/labstack/echo"
+	"github.com/thedevsaddam/govalidator"
+)
+
+// LoanRestructurePayload to handle loan restructure
+type LoanRestructurePayload struct {
+	Tenor             int     `json:"tenor"`
+	Interest          float64 `json:"interest"`
+	GracePeriod       int     `json:"grace_period"`
+	CapitaliseArrears bool    `json:"capitalise_arrears"`
+	FirstDueDate      string  `json:"first_due_date"`
+	Reason            string  `json:"reason"`
+	Preview           bool    `json:"preview"`
+}
+
+// LenderLoanRestructure replaces unpaid installments of approved loan with a new schedule. preview returns
+// the schedule without applying it
+func LenderLoanRestructure(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "lender_loan_restructure")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	bankRep := models.BankRepresentatives{}
+	bankRep.FindbyUserID(int(lenderUserID(c)))
+
+	loanID, _ := strconv.ParseUint(c.Param("loan_id"), 10, 64)
+	loan, err := lenderBankLoan(bankRep.BankID, loanID)
+	if err != nil {
+		adminhandlers.NLog("warning", "LenderLoanRestructure", map[string]interface{}{"message": fmt.Sprintf("loan %v not found", loanID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusNotFound, err, fmt.Sprintf("Pinjaman %v tidak ditemukan", loanID))
+	}
+	if loan.Status != "approved" || loan.PaymentStatus == "terbayar" || loan.PaymentStatus == "hapus_buku" {
+		return returnInvalidResponse(http.StatusUnprocessableEntity, "", fmt.Sprintf("Pinjaman %v berstatus %v dan tidak dapat direstrukturisasi", loanID, loan.Status))
+	}
+	origin := loan
+
+	restructurePayload := LoanRestructurePayload{
+		Interest: loan.Interest,
+	}
+	payloadRules := govalidator.MapData{
+		"tenor":              []string{"required"},
+		"interest":           []string{},
+		"grace_period":       []string{},
+		"capitalise_arrears": []string{},
+		"first_due_date":     []string{"required", "date"},
+		"reason":             []string{"required"},
+		"preview":            []string{},
+	}
+
+	validate := validateRequestPayload(c, payloadRules, &restructurePayload)
+	if validate == nil {
+		validate = validateLoanRestructure(restructurePayload)
+	}
+	if validate != nil {
+		adminhandlers.NLog("warning", "LenderLoanRestructure", map[string]interface{}{"message": "validation error", "error": validate}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusUnprocessableEntity, validate, "Hambatan validasi")
+	}
+
+	firstDueDate, _ := time.Parse("2006-01-02", restructurePayload.FirstDueDate)
+	restructure := models.LoanRestructure{
+		Loan:              loan.ID,
+		Tenor:             restructurePayload.Tenor,
+		Interest:          restructurePayload.Interest,
+		GracePeriod:       restructurePayload.GracePeriod,
+		CapitaliseArrears: restructurePayload.CapitaliseArrears,
+		FirstDueDate:      firstDueDate,
+		PreviousPlan:      loan.LayawayPlan,
+		Reason:            restructurePayload.Reason,
+		RequestedBy:       lenderUserID(c),
+	}
+
+	if restructurePayload.Preview {
+		installments, err := models.LoanInstallments(loan)
+		if err != nil {
+			adminhandlers.NLog("error", "LenderLoanRestructure", map[string]interface{}{"message": fmt.Sprintf("error finding loan %v installments", loanID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+			return returnInvalidResponse(http.StatusInternalServerError, err, "Terjadi kesalahan")
+		}
+		_, closed, schedule, err := restructure.Schedule(installments, time.Now())
+		if _, ok := err.(models.ApprovalError); ok {
+			return returnInvalidResponse(http.StatusUnprocessableEntity, err, err.Error())
+		}
+
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"restructure":  restructure,
+			"closed":       closed,
+			"installments": schedule,
+		})
+	}
+
+	_, created, err := models.RestructureLoan(&loan, &restructure, middlewares.SubmitKafkaPayloadTx)
+	if _, ok := err.(models.ApprovalError); ok {
+		return returnInvalidResponse(http.StatusUnprocessableEntity, err, err.Error())
+	}
+	if err != nil {
+		adminhandlers.NLog("error", "LenderLoanRestructure", map[string]interface{}{"message": fmt.Sprintf("error restructuring loan %v", loanID), "error": err, "restructure": restructure}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, fmt.Sprintf("Gagal restrukturisasi pinjaman %v", loanID))
+	}
+
+	middlewares.LoanWebhooks(origin, loan)
+
+	adminhandlers.NAudittrail(origin, loan, c.Get("user").(*jwt.Token), "loan", fmt.Sprint(loan.ID), "restructure")
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"restructure":  restructure,
+		"loan":         loan,
+		"installments": created,
+	})
+}
+
+// LenderLoanRestructureList lists restructures of loan
+func LenderLoanRestructureList(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "lender_loan_request_detail")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	bankRep := models.BankRepresentatives{}
+	bankRep.FindbyUserID(int(lenderUserID(c)))
+
+	loanID, _ := strconv.ParseUint(c.Param("loan_id"), 10, 64)
+	loan, err := lenderBankLoan(bankRep.BankID, loanID)
+	if err != nil {
+		adminhandlers.NLog("warning", "LenderLoanRestructureList", map[string]interface{}{"message": fmt.Sprintf("loan %v not found", loanID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusNotFound, err, fmt.Sprintf("Pinjaman %v tidak ditemukan", loanID))
+	}
+
+	// pagination parameters
+	rows, err := strconv.Atoi(c.QueryParam("rows"))
+	page, err := strconv.Atoi(c.QueryParam("page"))
+	orderby := strings.Split(c.QueryParam("orderby"), ",")
+	sort := strings.Split(c.QueryParam("sort"), ",")
+
+	type Filter struct {
+		Loan uint64 `json:"loan"`
+	}
+
+	restructure := models.LoanRestructure{}
+	result, err := restructure.PagedFindFilter(page, rows, orderby, sort, &Filter{
+		Loan: loan.ID,
+	})
+	if err != nil {
+		adminhandlers.NLog("warning", "LenderLoanRestructureList", map[string]interface{}{"message": fmt.Sprintf("error listing loan %v restructures", loanID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, "Pencarian tidak ditemukan")
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+func validateLoanRestructure(payload LoanRestructurePayload) interface{} {
+	errors := map[string][]string{}
+
+	if payload.Tenor < 1 {
+		errors["tenor"] = append(errors["tenor"], "The tenor field must be at least 1")
+	}
+	if payload.GracePeriod < 0 {
+		errors["grace_period"] = append(errors["grace_period"], "The grace_period field may not be negative")
+	}
+	if payload.Interest < 0 {
+		errors["interest"] = append(errors["interest"], "The interest field may not be negative")
+	}
+	firstDueDate, err := time.Parse("2006-01-02", payload.FirstDueDate)
+	if err != nil || firstDueDate.Before(time.Now().Truncate(24*time.Hour)) {
+		errors["first_due_date"] = append(errors["first_due_date"], "The first_due_date field must be a date from today")
+	}
+
+	if len(errors) > 0 {
+		return errors
+	}
+
+	return nil
+}
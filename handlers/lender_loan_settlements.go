@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"asira_lender/adminhandlers"
+	"asira_lender/middlewares"
+	"asira_lender/models"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/labstack/echo"
+	"github.com/thedevsaddam/govalidator"
+)
+
+// LoanSettlementPayload to handle early settlement
+type LoanSettlementPayload struct {
+	Date       string  `json:"date"`
+	PaidAmount float64 `json:"paid_amount"`
+	Note       string  `json:"note"`
+}
+
+// LenderLoanPayoffQuote quotes full payoff of approved loan at date query, today when empty
+func LenderLoanPayoffQuote(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "lender_loan_payoff_quote")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	loan, err := lenderPayoffLoan(c, "LenderLoanPayoffQuote")
+	if err != nil {
+		return err
+	}
+
+	date, err := payoffDate(c.QueryParam("date"))
+	if err != nil {
+		return returnInvalidResponse(http.StatusUnprocessableEntity, map[string][]string{"date": []string{"The date field must be a valid date"}}, "Hambatan validasi")
+	}
+
+	quote, err := models.LoanPayoffQuote(loan, date)
+	if err != nil {
+		adminhandlers.NLog("error", "LenderLoanPayoffQuote", map[string]interface{}{"message": fmt.Sprintf("error quoting loan %v payoff", loan.ID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, "Terjadi kesalahan")
+	}
+
+	return c.JSON(http.StatusOK, quote)
+}
+
+// LenderLoanEarlySettlement settles approved loan in full, marking remaining installments paid and loan paid off
+func LenderLoanEarlySettlement(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "lender_loan_early_settlement")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	loan, err := lenderPayoffLoan(c, "LenderLoanEarlySettlement")
+	if err != nil {
+		return err
+	}
+	origin := loan
+
+	settlementPayload := LoanSettlementPayload{}
+	payloadRules := govalidator.MapData{
+		"date":        []string{"date"},
+		"paid_amount": []string{"required", "numeric"},
+		"note":        []string{},
+	}
+
+	validate := validateRequestPayload(c, payloadRules, &settlementPayload)
+	if validate != nil {
+		adminhandlers.NLog("warning", "LenderLoanEarlySettlement", map[string]interface{}{"message": "validation error", "error": validate}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusUnprocessableEntity, validate, "Hambatan validasi")
+	}
+
+	date, err := payoffDate(settlementPayload.Date)
+	if err != nil || date.After(time.Now()) {
+		return returnInvalidResponse(http.StatusUnprocessableEntity, map[string][]string{"date": []string{"The date field may not be in the future"}}, "Hambatan validasi")
+	}
+
+	quote, err := models.LoanPayoffQuote(loan, date)
+	if err != nil {
+		adminhandlers.NLog("error", "LenderLoanEarlySettlement", map[string]interface{}{"message": fmt.Sprintf("error quoting loan %v payoff", loan.ID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, "Terjadi kesalahan")
+	}
+	if settlementPayload.PaidAmount < quote.Total {
+		return returnInvalidResponse(http.StatusUnprocessableEntity, map[string][]string{"paid_amount": []string{fmt.Sprintf("The paid_amount field must be at least %v", quote.Total)}}, "Hambatan validasi")
+	}
+
+	settlement := models.LoanSettlement{
+		PayoffQuote: quote,
+		PaidAmount:  settlementPayload.PaidAmount,
+		Note:        settlementPayload.Note,
+		SettledBy:   lenderUserID(c),
+	}
+	if len(settlement.Note) < 1 {
+		settlement.Note = "pelunasan dipercepat"
+	}
+
+	settled, err := models.SettleLoan(&loan, &settlement, middlewares.SubmitKafkaPayloadTx)
+	if _, ok := err.(models.ApprovalError); ok {
+		return returnInvalidResponse(http.StatusUnprocessableEntity, err, err.Error())
+	}
+	if err != nil {
+		adminhandlers.NLog("error", "LenderLoanEarlySettlement", map[string]interface{}{"message": fmt.Sprintf("error settling loan %v", loan.ID), "error": err, "settlement": settlement}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, fmt.Sprintf("Gagal melunasi pinjaman %v", loan.ID))
+	}
+
+	middlewares.LoanWebhooks(origin, loan)
+
+	adminhandlers.NAudittrail(origin, loan, c.Get("user").(*jwt.Token), "loan", fmt.Sprint(loan.ID), "early settlement")
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"settlement":   settlement,
+		"loan":         loan,
+		"installments": settled,
+	})
+}
+
+// lenderPayoffLoan finds approved loan of :loan_id not paid off yet. returned error is ready to be sent as response
+func lenderPayoffLoan(c echo.Context, tag string) (loan models.Loan, err error) {
+	bankRep := models.BankRepresentatives{}
+	bankRep.FindbyUserID(int(lenderUserID(c)))
+
+	loanID, _ := strconv.ParseUint(c.Param("loan_id"), 10, 64)
+	loan, err = lenderBankLoan(bankRep.BankID, loanID)
+	if err != nil {
+		adminhandlers.NLog("warning", tag, map[string]interface{}{"message": fmt.Sprintf("loan %v not found", loanID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return loan, returnInvalidResponse(http.StatusNotFound, err, fmt.Sprintf("Pinjaman %v tidak ditemukan", loanID))
+	}
+	if loan.Status != "approved" || loan.PaymentStatus == "terbayar" || loan.PaymentStatus == "hapus_buku" {
+		return loan, returnInvalidResponse(http.StatusUnprocessableEntity, "", fmt.Sprintf("Pinjaman %v tidak memiliki sisa pembayaran", loanID))
+	}
+
+	return loan, nil
+}
+
+func payoffDate(date string) (time.Time, error) {
+	if len(date) < 1 {
+		now := time.Now()
+		return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local), nil
+	}
+
+	return time.ParseInLocation("2006-01-02", date, time.Local)
+}
@@ -0,0 +1,300 @@
+package handlers
+
+import (
+	"asira_lender/adminhandlers"
+	"asira_lender/asira"
+	"asira_lender/middlewares"
+	"asira_lender/models"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/labstack/echo"
+	"github.com/thedevsaddam/govalidator"
+)
+
+type (
+	// LoanWriteOffPayload to handle write off request
+	LoanWriteOffPayload struct {
+		Reason string `json:"reason"`
+	}
+
+	// LoanWriteOffDecisionPayload to handle write off approval
+	LoanWriteOffDecisionPayload struct {
+		Action string `json:"action"`
+		Note   string `json:"note"`
+	}
+
+	// LoanRecoveryPayload to handle recovery payment
+	LoanRecoveryPayload struct {
+		Amount float64 `json:"amount"`
+		Date   string  `json:"date"`
+		Note   string  `json:"note"`
+	}
+)
+
+// LenderLoanWriteOff requests write off of non performing loan, pending until approved by another user
+func LenderLoanWriteOff(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "lender_loan_write_off")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	bankRep := models.BankRepresentatives{}
+	bankRep.FindbyUserID(int(lenderUserID(c)))
+
+	loanID, _ := strconv.ParseUint(c.Param("loan_id"), 10, 64)
+	loan, err := lenderBankLoan(bankRep.BankID, loanID)
+	if err != nil {
+		adminhandlers.NLog("warning", "LenderLoanWriteOff", map[string]interface{}{"message": fmt.Sprintf("loan %v not found", loanID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusNotFound, err, fmt.Sprintf("Pinjaman %v tidak ditemukan", loanID))
+	}
+	if loan.Status != "approved" || loan.PaymentStatus == "terbayar" || loan.PaymentStatus == "hapus_buku" {
+		return returnInvalidResponse(http.StatusUnprocessableEntity, "", fmt.Sprintf("Pinjaman %v tidak dapat dihapus buku", loanID))
+	}
+
+	writeOffPayload := LoanWriteOffPayload{}
+	payloadRules := govalidator.MapData{
+		"reason": []string{"required"},
+	}
+
+	validate := validateRequestPayload(c, payloadRules, &writeOffPayload)
+	if validate != nil {
+		adminhandlers.NLog("warning", "LenderLoanWriteOff", map[string]interface{}{"message": "validation error", "error": validate}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusUnprocessableEntity, validate, "Hambatan validasi")
+	}
+
+	writeOff, err := models.NewLoanWriteOff(loan, bankRep.BankID, time.Now())
+	if err != nil {
+		adminhandlers.NLog("error", "LenderLoanWriteOff", map[string]interface{}{"message": fmt.Sprintf("error computing loan %v write off", loanID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, "Terjadi kesalahan")
+	}
+	if minDPD := writeOffMinDaysPastDue(); writeOff.DaysPastDue < minDPD {
+		return returnInvalidResponse(http.StatusUnprocessableEntity, map[string][]string{"days_past_due": []string{fmt.Sprintf("Pinjaman baru terlambat %v hari, hapus buku membutuhkan minimal %v hari", writeOff.DaysPastDue, minDPD)}}, "Hambatan validasi")
+	}
+	writeOff.Reason = writeOffPayload.Reason
+	writeOff.RequestedBy = lenderUserID(c)
+
+	err = writeOff.Request()
+	if approvalError, ok := err.(models.ApprovalError); ok {
+		return returnInvalidResponse(http.StatusUnprocessableEntity, "", approvalError.Message)
+	}
+	if err != nil {
+		adminhandlers.NLog("error", "LenderLoanWriteOff", map[string]interface{}{"message": fmt.Sprintf("error creating loan %v write off", loanID), "error": err, "write_off": writeOff}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, "Gagal membuat hapus buku")
+	}
+
+	adminhandlers.NAudittrail(models.LoanWriteOff{}, writeOff, c.Get("user").(*jwt.Token), "loan_write_off", fmt.Sprint(writeOff.ID), "create")
+
+	return c.JSON(http.StatusCreated, writeOff)
+}
+
+// LenderLoanWriteOffDecision approves or rejects pending write off of loan. approved loans are written off
+func LenderLoanWriteOffDecision(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "lender_loan_write_off_approve")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	loan, writeOff, err := lenderLoanWriteOff(c, "LenderLoanWriteOffDecision")
+	if err != nil {
+		return err
+	}
+	origin := loan
+	originWriteOff := writeOff
+
+	decisionPayload := LoanWriteOffDecisionPayload{}
+	payloadRules := govalidator.MapData{
+		"action": []string{"required", "in:approve,reject"},
+		"note":   []string{},
+	}
+
+	validate := validateRequestPayload(c, payloadRules, &decisionPayload)
+	if validate != nil {
+		adminhandlers.NLog("warning", "LenderLoanWriteOffDecision", map[string]interface{}{"message": "validation error", "error": validate}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusUnprocessableEntity, validate, "Hambatan validasi")
+	}
+
+	err = writeOff.Decide(&loan, lenderUserID(c), decisionPayload.Action, decisionPayload.Note, middlewares.SubmitKafkaPayloadTx)
+	if err != nil {
+		if approvalError, ok := err.(models.ApprovalError); ok {
+			return returnInvalidResponse(http.StatusUnprocessableEntity, err, approvalError.Message)
+		}
+		adminhandlers.NLog("error", "LenderLoanWriteOffDecision", map[string]interface{}{"message": fmt.Sprintf("error deciding loan %v write off", loan.ID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, "Gagal memutuskan hapus buku")
+	}
+
+	adminhandlers.NAudittrail(originWriteOff, writeOff, c.Get("user").(*jwt.Token), "loan_write_off", fmt.Sprint(writeOff.ID), decisionPayload.Action)
+
+	if writeOff.Status == "approved" {
+		adminhandlers.NAudittrail(origin, loan, c.Get("user").(*jwt.Token), "loan", fmt.Sprint(loan.ID), "write off")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"write_off": writeOff,
+		"loan":      loan,
+	})
+}
+
+// LenderLoanWriteOffDetail latest write off of loan with its recoveries
+func LenderLoanWriteOffDetail(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "lender_loan_request_detail")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	_, writeOff, err := lenderLoanWriteOff(c, "LenderLoanWriteOffDetail")
+	if err != nil {
+		return err
+	}
+
+	recoveries, err := writeOff.Recoveries()
+	if err != nil {
+		adminhandlers.NLog("warning", "LenderLoanWriteOffDetail", map[string]interface{}{"message": fmt.Sprintf("error listing write off %v recoveries", writeOff.ID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"write_off":  writeOff,
+		"recoveries": recoveries,
+	})
+}
+
+// LenderLoanRecovery records recovery payment on written off loan
+func LenderLoanRecovery(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "lender_loan_recovery")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	_, writeOff, err := lenderLoanWriteOff(c, "LenderLoanRecovery")
+	if err != nil {
+		return err
+	}
+	origin := writeOff
+
+	recoveryPayload := LoanRecoveryPayload{}
+	payloadRules := govalidator.MapData{
+		"amount": []string{"required", "numeric"},
+		"date":   []string{"date"},
+		"note":   []string{},
+	}
+
+	validate := validateRequestPayload(c, payloadRules, &recoveryPayload)
+	if validate != nil {
+		adminhandlers.NLog("warning", "LenderLoanRecovery", map[string]interface{}{"message": "validation error", "error": validate}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusUnprocessableEntity, validate, "Hambatan validasi")
+	}
+
+	date, err := payoffDate(recoveryPayload.Date)
+	if err != nil || date.After(time.Now()) {
+		return returnInvalidResponse(http.StatusUnprocessableEntity, map[string][]string{"date": []string{"The date field may not be in the future"}}, "Hambatan validasi")
+	}
+	if recoveryPayload.Amount <= 0 {
+		return returnInvalidResponse(http.StatusUnprocessableEntity, map[string][]string{"amount": []string{"The amount field must be greater than 0"}}, "Hambatan validasi")
+	}
+
+	recovery := models.LoanRecovery{
+		Amount:     recoveryPayload.Amount,
+		Date:       date,
+		Note:       recoveryPayload.Note,
+		RecordedBy: lenderUserID(c),
+	}
+	err = writeOff.Recover(&recovery)
+	if err != nil {
+		if approvalError, ok := err.(models.ApprovalError); ok {
+			return returnInvalidResponse(http.StatusUnprocessableEntity, err, approvalError.Message)
+		}
+		adminhandlers.NLog("error", "LenderLoanRecovery", map[string]interface{}{"message": fmt.Sprintf("error recording write off %v recovery", writeOff.ID), "error": err, "recovery": recovery}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, "Gagal mencatat pemulihan")
+	}
+
+	adminhandlers.NAudittrail(origin, writeOff, c.Get("user").(*jwt.Token), "loan_write_off", fmt.Sprint(writeOff.ID), "recovery")
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"recovery":  recovery,
+		"write_off": writeOff,
+	})
+}
+
+// LenderLoanWriteOffList lists write offs of lender bank, filtered by status and loan
+func LenderLoanWriteOffList(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "lender_loan_request_list")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	bankRep := models.BankRepresentatives{}
+	bankRep.FindbyUserID(int(lenderUserID(c)))
+
+	// pagination parameters
+	rows, err := strconv.Atoi(c.QueryParam("rows"))
+	page, err := strconv.Atoi(c.QueryParam("page"))
+	orderby := strings.Split(c.QueryParam("orderby"), ",")
+	sort := strings.Split(c.QueryParam("sort"), ",")
+
+	type Filter struct {
+		Bank   uint64 `json:"bank"`
+		Loan   string `json:"loan"`
+		Status string `json:"status"`
+	}
+
+	writeOff := models.LoanWriteOff{}
+	result, err := writeOff.PagedFindFilter(page, rows, orderby, sort, &Filter{
+		Bank:   bankRep.BankID,
+		Loan:   c.QueryParam("loan"),
+		Status: c.QueryParam("status"),
+	})
+	if err != nil {
+		adminhandlers.NLog("warning", "LenderLoanWriteOffList", map[string]interface{}{"message": "error listing write offs", "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, "Pencarian tidak ditemukan")
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// lenderLoanWriteOff finds loan of :loan_id and its latest write off. returned error is ready to be sent as response
+func lenderLoanWriteOff(c echo.Context, tag string) (loan models.Loan, writeOff models.LoanWriteOff, err error) {
+	bankRep := models.BankRepresentatives{}
+	bankRep.FindbyUserID(int(lenderUserID(c)))
+
+	loanID, _ := strconv.ParseUint(c.Param("loan_id"), 10, 64)
+	loan, err = lenderBankLoan(bankRep.BankID, loanID)
+	if err != nil {
+		adminhandlers.NLog("warning", tag, map[string]interface{}{"message": fmt.Sprintf("loan %v not found", loanID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return loan, writeOff, returnInvalidResponse(http.StatusNotFound, err, fmt.Sprintf("Pinjaman %v tidak ditemukan", loanID))
+	}
+
+	err = asira.App.DB.Where("loan = ?", loan.ID).Order("id desc").First(&writeOff).Error
+	if err != nil {
+		return loan, writeOff, returnInvalidResponse(http.StatusNotFound, err, fmt.Sprintf("Hapus buku pinjaman %v tidak ditemukan", loanID))
+	}
+
+	return loan, writeOff, nil
+}
+
+func writeOffMinDaysPastDue() int {
+	minDPD := asira.App.Config.GetInt(fmt.Sprintf("%s.write_off.min_days_past_due", asira.App.ENV))
+	if minDPD <= 0 {
+		minDPD = 90
+	}
+
+	return minDPD
+}
@@ -45,15 +45,16 @@ type (
 	// LoanSelect select custom type
 	LoanSelect struct {
 		models.Loan
-		BorrowerName      string               `json:"borrower_name"`
-		BankName          string               `json:"bank_name"`
-		BankAccount       string               `json:"bank_account"`
-		Service           string               `json:"service"`
-		Product           string               `json:"product"`
-		Category          string               `json:"category"`
-		AgentName         string               `json:"agent_name"`
-		AgentProviderName string               `json:"agent_provider_name"`
-		Installments      []models.Installment `json:"installment_details"`
+		BorrowerName      string                `json:"borrower_name"`
+		BankName          string                `json:"bank_name"`
+		BankAccount       string                `json:"bank_account"`
+		Service           string                `json:"service"`
+		Product           string                `json:"product"`
+		Category          string                `json:"category"`
+		AgentName         string                `json:"agent_name"`
+		AgentProviderName string                `json:"agent_provider_name"`
+		Installments      []models.Installment  `json:"installment_details"`
+		Affordability     *models.Affordability `json:"affordability,omitempty"`
 	}
 )
 
@@ -183,6 +184,18 @@ func LenderLoanRequestList(c echo.Context) error {
 		if agentProviderName := c.QueryParam("agent_provider_name"); len(agentProviderName) > 0 {
 			db = db.Where("LOWER(ap.name) LIKE ?", "%"+strings.ToLower(agentProviderName)+"%")
 		}
+		if minScore := c.QueryParam("min_score"); len(minScore) > 0 {
+			db = db.Where("loans.credit_score >= ?", minScore)
+		}
+		if maxScore := c.QueryParam("max_score"); len(maxScore) > 0 {
+			db = db.Where("loans.credit_score <= ?", maxScore)
+		}
+		if riskGrade := customSplit(c.QueryParam("risk_grade"), ","); len(riskGrade) > 0 {
+			db = db.Where("loans.risk_grade IN (?)", riskGrade)
+		}
+		if dtiExceeded := c.QueryParam("dti_exceeded"); len(dtiExceeded) > 0 {
+			db = db.Where("loans.dti_exceeded = ?", dtiExceeded == "true")
+		}
 	}
 
 	if order := strings.Split(c.QueryParam("orderby"), ","); len(order) > 0 {
@@ -276,6 +289,19 @@ func LenderLoanRequestListDetail(c echo.Context) error {
 	}
 	loan.Installments = installments
 
+	// product column of loan select holds product name, affordability needs loan as stored
+	storedLoan := models.Loan{}
+	affordability := models.Affordability{}
+	err = storedLoan.FindbyID(loan.ID)
+	if err == nil {
+		affordability, err = models.LoanAffordability(storedLoan)
+	}
+	if err != nil {
+		adminhandlers.NLog("warning", "LenderLoanRequestListDetail", map[string]interface{}{"message": fmt.Sprintf("error computing loan %v affordability", loanID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+	} else {
+		loan.Affordability = &affordability
+	}
+
 	return c.JSON(http.StatusOK, loan)
 }
 
@@ -287,41 +313,23 @@ func LenderLoanApproveReject(c echo.Context) error {
 		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
 	}
 
-	user := c.Get("user")
-	token := user.(*jwt.Token)
-	claims := token.Claims.(jwt.MapClaims)
-
-	lenderID, _ := strconv.Atoi(claims["jti"].(string))
 	bankRep := models.BankRepresentatives{}
-	bankRep.FindbyUserID(lenderID)
+	bankRep.FindbyUserID(int(lenderUserID(c)))
 
-	loanID, _ := strconv.Atoi(c.Param("loan_id"))
-
-	db := asira.App.DB
-	loan := models.Loan{}
-
-	err = db.Table("loans").
-		Select("*").
-		Joins("INNER JOIN borrowers b ON b.id = loans.borrower").
-		Joins("INNER JOIN banks ba ON b.bank = ba.id").
-		Where("loans.otp_verified = ?", true).
-		Where("ba.id = ?", bankRep.BankID).
-		Where("loans.id = ?", loanID).
-		Limit(1).
-		Find(&loan).Error
+	loanID, _ := strconv.ParseUint(c.Param("loan_id"), 10, 64)
 
+	loan, err := lenderBankLoan(bankRep.BankID, loanID)
 	if err != nil {
 		adminhandlers.NLog("warning", "LenderLoanApproveReject", map[string]interface{}{"message": fmt.Sprintf("error while finding loan %v", loanID), "error": err}, c.Get("user").(*jwt.Token), "", false)
 
-		return returnInvalidResponse(http.StatusInternalServerError, err, fmt.Sprintf("Pinjaman %v tidak ditemukan", loanID))
+		return returnInvalidResponse(http.StatusNotFound, err, fmt.Sprintf("Pinjaman %v tidak ditemukan", loanID))
 	}
 	origin := loan
-	if loan.ID == 0 {
-		adminhandlers.NLog("warning", "LenderLoanApproveReject", map[string]interface{}{"message": "loan id is 0"}, c.Get("user").(*jwt.Token), "", false)
-
-		return returnInvalidResponse(http.StatusNotFound, "", fmt.Sprintf("Pinjaman %v tidak ditemukan", loanID))
+	if loan.Status != "processing" {
+		return returnInvalidResponse(http.StatusUnprocessableEntity, "", fmt.Sprintf("Pinjaman %v berstatus %v dan tidak dapat diproses", loanID, loan.Status))
 	}
 
+	var approval *models.LoanApproval
 	status := c.Param("approve_reject")
 	switch status {
 	default:
@@ -335,13 +343,12 @@ func LenderLoanApproveReject(c echo.Context) error {
 
 			return returnInvalidResponse(http.StatusBadRequest, "", "Terjadi kesalahan")
 		}
-		loan.Status = "approved"
-		loan.DisburseDate = disburseDate
-		loan.ApprovalDate = time.Now()
-
-		err = middlewares.SubmitKafkaPayload(loan, "loan_update")
+		approval, err = decideLoanByPolicy(c, bankRep.BankID, &loan, "approve", disburseDate, "")
+		if _, ok := err.(models.ApprovalError); ok {
+			return returnInvalidResponse(http.StatusUnprocessableEntity, err, err.Error())
+		}
 		if err != nil {
-			adminhandlers.NLog("error", "LenderLoanApproveReject", map[string]interface{}{"message": "error submitting kafka", "error": err}, c.Get("user").(*jwt.Token), "", false)
+			adminhandlers.NLog("error", "LenderLoanApproveReject", map[string]interface{}{"message": "error deciding loan", "error": err}, c.Get("user").(*jwt.Token), "", false)
 
 			return returnInvalidResponse(http.StatusBadRequest, err, "Gagal approve pinjaman")
 		}
@@ -350,21 +357,150 @@ func LenderLoanApproveReject(c echo.Context) error {
 		if len(reason) < 1 {
 			return returnInvalidResponse(http.StatusBadRequest, "", "Harap mengisi alasan menolak")
 		}
-		loan.Status = "rejected"
-		loan.RejectReason = reason
-		loan.ApprovalDate = time.Now()
-
-		err = middlewares.SubmitKafkaPayload(loan, "loan_update")
+		approval, err = decideLoanByPolicy(c, bankRep.BankID, &loan, "reject", time.Time{}, reason)
+		if _, ok := err.(models.ApprovalError); ok {
+			return returnInvalidResponse(http.StatusUnprocessableEntity, err, err.Error())
+		}
 		if err != nil {
-			adminhandlers.NLog("error", "LenderLoanApproveReject", map[string]interface{}{"message": "error submitting kafka", "error": err, "loan": loan}, c.Get("user").(*jwt.Token), "", false)
+			adminhandlers.NLog("error", "LenderLoanApproveReject", map[string]interface{}{"message": "error deciding loan", "error": err, "loan": loan}, c.Get("user").(*jwt.Token), "", false)
 
 			return returnInvalidResponse(http.StatusBadRequest, err, "Gagal reject pinjaman")
 		}
 	}
 
+	middlewares.LoanWebhooks(origin, loan)
+
 	adminhandlers.NAudittrail(origin, loan, c.Get("user").(*jwt.Token), "loan", fmt.Sprint(loan.ID), "approve borrower")
 
-	return c.JSON(http.StatusOK, map[string]interface{}{"message": fmt.Sprintf("loan %v is %v", loanID, loan.Status)})
+	return c.JSON(http.StatusOK, map[string]interface{}{"message": approvalMessage(loan, approval), "approval": approval})
+}
+
+// maxBulkLoans max loans processed in one bulk request
+const maxBulkLoans = 100
+
+// LenderLoanApproveRejectBulk approves or rejects several loans at once and reports result of each loan
+func LenderLoanApproveRejectBulk(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "lender_loan_approve_reject")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	type (
+		BulkPayload struct {
+			LoanIDs      []uint64 `json:"loan_ids"`
+			Action       string   `json:"action"`
+			DisburseDate string   `json:"disburse_date"`
+			Reason       string   `json:"reason"`
+		}
+		BulkResult struct {
+			LoanID  uint64 `json:"loan_id"`
+			Success bool   `json:"success"`
+			Status  string `json:"status"`
+			Message string `json:"message"`
+		}
+	)
+
+	bulkPayload := BulkPayload{}
+	payloadRules := govalidator.MapData{
+		"loan_ids":      []string{"required"},
+		"action":        []string{"required", "in:approve,reject"},
+		"disburse_date": []string{"date"},
+		"reason":        []string{},
+	}
+
+	validate := validateRequestPayload(c, payloadRules, &bulkPayload)
+	if validate == nil {
+		switch {
+		case len(bulkPayload.LoanIDs) > maxBulkLoans:
+			validate = map[string][]string{"loan_ids": []string{fmt.Sprintf("The loan_ids field may not contain more than %v loans", maxBulkLoans)}}
+		case bulkPayload.Action == "approve" && len(bulkPayload.DisburseDate) < 1:
+			validate = map[string][]string{"disburse_date": []string{"The disburse_date field is required to approve loans"}}
+		case bulkPayload.Action == "reject" && len(bulkPayload.Reason) < 1:
+			validate = map[string][]string{"reason": []string{"The reason field is required to reject loans"}}
+		}
+	}
+	if validate != nil {
+		adminhandlers.NLog("warning", "LenderLoanApproveRejectBulk", map[string]interface{}{"message": "validation error", "error": validate}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusUnprocessableEntity, validate, "Hambatan validasi")
+	}
+	disburseDate, _ := time.Parse("2006-01-02", bulkPayload.DisburseDate)
+
+	bankRep := models.BankRepresentatives{}
+	bankRep.FindbyUserID(int(lenderUserID(c)))
+
+	var (
+		results   []BulkResult
+		succeeded int
+		processed = map[uint64]bool{}
+	)
+	for _, loanID := range bulkPayload.LoanIDs {
+		if processed[loanID] {
+			continue
+		}
+		processed[loanID] = true
+		result := BulkResult{LoanID: loanID}
+
+		loan, err := lenderBankLoan(bankRep.BankID, loanID)
+		switch {
+		case err != nil:
+			result.Message = fmt.Sprintf("Pinjaman %v tidak ditemukan", loanID)
+		case loan.Status != "processing":
+			result.Status = loan.Status
+			result.Message = fmt.Sprintf("Pinjaman %v berstatus %v dan tidak dapat diproses", loanID, loan.Status)
+		default:
+			origin := loan
+			approval, err := decideLoanByPolicy(c, bankRep.BankID, &loan, bulkPayload.Action, disburseDate, bulkPayload.Reason)
+			if _, ok := err.(models.ApprovalError); ok {
+				result.Status = origin.Status
+				result.Message = err.Error()
+				break
+			}
+			if err != nil {
+				adminhandlers.NLog("error", "LenderLoanApproveRejectBulk", map[string]interface{}{"message": "error deciding loan", "error": err, "loan": loan}, c.Get("user").(*jwt.Token), "", false)
+
+				result.Status = origin.Status
+				result.Message = fmt.Sprintf("Gagal %v pinjaman %v", bulkPayload.Action, loanID)
+				break
+			}
+
+			middlewares.LoanWebhooks(origin, loan)
+			adminhandlers.NAudittrail(origin, loan, c.Get("user").(*jwt.Token), "loan", fmt.Sprint(loan.ID), fmt.Sprintf("bulk %v loan", bulkPayload.Action))
+
+			result.Success = true
+			result.Status = loan.Status
+			result.Message = approvalMessage(loan, approval)
+			succeeded++
+		}
+
+		results = append(results, result)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"action":    bulkPayload.Action,
+		"total":     len(results),
+		"succeeded": succeeded,
+		"failed":    len(results) - succeeded,
+		"results":   results,
+	})
+}
+
+// lenderBankLoan finds otp verified loan of borrower registered to the bank
+func lenderBankLoan(bankID uint64, loanID uint64) (loan models.Loan, err error) {
+	err = asira.App.DB.Table("loans").
+		Select("loans.*").
+		Joins("INNER JOIN borrowers b ON b.id = loans.borrower").
+		Where("loans.otp_verified = ?", true).
+		Where("b.bank = ?", bankID).
+		Where("loans.id = ?", loanID).
+		Limit(1).
+		Find(&loan).Error
+	if err == nil && loan.ID == 0 {
+		err = fmt.Errorf("loan %v not found", loanID)
+	}
+
+	return loan, err
 }
 
 // LenderLoanRequestListDownload download loans csv
@@ -486,7 +622,7 @@ func LenderLoanConfirmDisbursement(c echo.Context) error {
 	loan := models.Loan{}
 
 	err = db.Table("loans").
-		Select("*").
+		Select("loans.*").
 		Joins("INNER JOIN borrowers b ON b.id = loans.borrower").
 		Joins("INNER JOIN banks ba ON b.bank = ba.id").
 		Where("loans.otp_verified = ?", true).
@@ -511,14 +647,16 @@ func LenderLoanConfirmDisbursement(c echo.Context) error {
 
 	loan.DisburseStatus = "confirmed"
 
-	err = middlewares.SubmitKafkaPayload(loan, "loan_update")
+	err = middlewares.SaveWithOutbox(&loan, "loan_update")
 	if err != nil {
-		adminhandlers.NLog("error", "LenderLoanConfirmDisbursement", map[string]interface{}{"message": "error submitting kafka", "error": err, "loan": loan}, c.Get("user").(*jwt.Token), "", false)
+		adminhandlers.NLog("error", "LenderLoanConfirmDisbursement", map[string]interface{}{"message": "error saving loan", "error": err, "loan": loan}, c.Get("user").(*jwt.Token), "", false)
 
 		return returnInvalidResponse(http.StatusBadRequest, err, "Gagal confirm disbursement pinjaman")
 	}
 	adminhandlers.NLog("info", "LenderLoanConfirmDisbursement", map[string]interface{}{"message": fmt.Sprintf("confirmed loan %v", loan.ID)}, c.Get("user").(*jwt.Token), "", false)
 
+	middlewares.LoanWebhooks(origin, loan)
+
 	adminhandlers.NAudittrail(origin, loan, c.Get("user").(*jwt.Token), "loan", fmt.Sprint(loan.ID), "confirm loan")
 
 	return c.JSON(http.StatusOK, map[string]interface{}{"message": fmt.Sprintf("loan %v disbursement is %v", loanID, loan.DisburseStatus)})
@@ -542,13 +680,13 @@ func LenderLoanChangeDisburseDate(c echo.Context) error {
 	loan := models.Loan{}
 
 	err = db.Table("loans").
-		Select("*").
+		Select("loans.*").
 		Joins("INNER JOIN borrowers b ON b.id = loans.borrower").
 		Joins("INNER JOIN banks ba ON b.bank = ba.id").
 		Where("loans.otp_verified = ?", true).
 		Where("ba.id = ?", bankRep.BankID).
 		Where("loans.id = ?", loanID).
-		Where("loans.disburse_status = ?", "processing").
+		Where("loans.disburse_status IN (?)", []string{"processing", "failed"}).
 		Limit(1).
 		Find(&loan).Error
 
@@ -568,10 +706,12 @@ func LenderLoanChangeDisburseDate(c echo.Context) error {
 
 	loan.DisburseDate = disburseDate
 	loan.DisburseDateChanged = true
+	// failed transfers are disbursed again on the new date
+	loan.DisburseStatus = "processing"
 
-	err = middlewares.SubmitKafkaPayload(loan, "loan_update")
+	err = middlewares.SaveWithOutbox(&loan, "loan_update")
 	if err != nil {
-		adminhandlers.NLog("error", "LenderLoanChangeDisburseDate", map[string]interface{}{"message": "error submitting kafka", "error": err, "loan": loan}, c.Get("user").(*jwt.Token), "", false)
+		adminhandlers.NLog("error", "LenderLoanChangeDisburseDate", map[string]interface{}{"message": "error saving loan", "error": err, "loan": loan}, c.Get("user").(*jwt.Token), "", false)
 
 		return returnInvalidResponse(http.StatusBadRequest, err, "Gagal confirm disbursement pinjaman")
 	}
@@ -670,11 +810,11 @@ func LenderLoanInstallmentsApprove(c echo.Context) error {
 		installment.DueDate = &parsedTime
 	}
 
-	err = middlewares.SubmitKafkaPayload(installment, "installment_update")
+	err = middlewares.SaveWithOutbox(&installment, "installment_update")
 	if err != nil {
-		adminhandlers.NLog("error", "LenderLoanInstallmentsApprove", map[string]interface{}{"message": "error submitting kafka installment", "error": err, "installment": installment}, user.(*jwt.Token), "", false)
+		adminhandlers.NLog("error", "LenderLoanInstallmentsApprove", map[string]interface{}{"message": "error saving installment", "error": err, "installment": installment}, user.(*jwt.Token), "", false)
 
-		returnInvalidResponse(http.StatusUnprocessableEntity, err, "Gagal update installment")
+		return returnInvalidResponse(http.StatusUnprocessableEntity, err, "Gagal update installment")
 	}
 
 	return c.JSON(http.StatusOK, installment)
@@ -757,11 +897,11 @@ func LenderLoanInstallmentsApproveBulk(c echo.Context) error {
 			installment.DueDate = &parsedTime
 		}
 
-		err = middlewares.SubmitKafkaPayload(installment, "installment_update")
+		err = middlewares.SaveWithOutbox(&installment, "installment_update")
 		if err != nil {
-			adminhandlers.NLog("error", "LenderLoanInstallmentsApproveBulk", map[string]interface{}{"message": "error submitting kafka installment", "error": err, "installment": installment}, user.(*jwt.Token), "", false)
+			adminhandlers.NLog("error", "LenderLoanInstallmentsApproveBulk", map[string]interface{}{"message": "error saving installment", "error": err, "installment": installment}, user.(*jwt.Token), "", false)
 
-			returnInvalidResponse(http.StatusUnprocessableEntity, err, "Gagal update installment")
+			return returnInvalidResponse(http.StatusUnprocessableEntity, err, "Gagal update installment")
 		}
 
 		installments = append(installments, installment)
@@ -812,7 +952,7 @@ func LenderLoanEditPaymentStatus(c echo.Context) error {
 	var loan models.Loan
 	db := asira.App.DB
 	err = db.Table("loans").
-		Select("*").
+		Select("loans.*").
 		Joins("INNER JOIN borrowers b ON b.id = loans.borrower").
 		Joins("INNER JOIN banks ba ON b.bank = ba.id").
 		Where("loans.otp_verified = ?", true).
@@ -828,15 +968,18 @@ func LenderLoanEditPaymentStatus(c echo.Context) error {
 		return returnInvalidResponse(http.StatusInternalServerError, err, fmt.Sprintf("Pinjaman %v tidak ditemukan", loanID))
 	}
 
+	origin := loan
 	loan.PaymentStatus = loanPayload.PaymentStatus
 	loan.PaymentNote = loanPayload.PaymentNote
 
-	err = middlewares.SubmitKafkaPayload(loan, "loan_update")
+	err = middlewares.SaveWithOutbox(&loan, "loan_update")
 	if err != nil {
-		adminhandlers.NLog("error", LogTag, map[string]interface{}{"message": "error submitting kafka update loan", "error": err, "loan": loan, "payload": loanPayload}, user.(*jwt.Token), "", false)
+		adminhandlers.NLog("error", LogTag, map[string]interface{}{"message": "error saving loan", "error": err, "loan": loan, "payload": loanPayload}, user.(*jwt.Token), "", false)
 
-		returnInvalidResponse(http.StatusUnprocessableEntity, err, "Gagal update loan")
+		return returnInvalidResponse(http.StatusUnprocessableEntity, err, "Gagal update loan")
 	}
 
+	middlewares.LoanWebhooks(origin, loan)
+
 	return c.JSON(http.StatusOK, loan)
 }
@@ -0,0 +1,265 @@
+package handlers
+
+import (
+	"asira_lender/adminhandlers"
+	"asira_lender/asira"
+	"asira_lender/models"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ayannahindonesia/basemodel"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/labstack/echo"
+	"github.com/thedevsaddam/govalidator"
+)
+
+// NotificationPreferencePayload handles preference request body
+type NotificationPreferencePayload struct {
+	Event string `json:"event"`
+	InApp *bool  `json:"in_app"`
+	Email *bool  `json:"email"`
+}
+
+// LenderNotificationList lists notifications of current lender user
+func LenderNotificationList(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "lender_notification_list")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	var (
+		totalRows     int
+		offset        int
+		rows          int
+		page          int
+		lastPage      int
+		notifications []models.Notification
+	)
+
+	// pagination parameters
+	rows, _ = strconv.Atoi(c.QueryParam("rows"))
+	if rows > 0 {
+		page, _ = strconv.Atoi(c.QueryParam("page"))
+		if page <= 0 {
+			page = 1
+		}
+		offset = (page * rows) - rows
+	}
+
+	db := asira.App.DB.Model(&models.Notification{}).
+		Where("user_id = ?", lenderUserID(c))
+
+	if event := customSplit(c.QueryParam("event"), ","); len(event) > 0 {
+		db = db.Where("event IN (?)", event)
+	}
+	switch c.QueryParam("unread") {
+	case "true":
+		db = db.Where("read_at IS NULL")
+		break
+	case "false":
+		db = db.Where("read_at IS NOT NULL")
+		break
+	}
+
+	db.Count(&totalRows)
+
+	if rows > 0 {
+		db = db.Limit(rows).Offset(offset)
+		lastPage = int(math.Ceil(float64(totalRows) / float64(rows)))
+	}
+	err = db.Order("id DESC").Find(&notifications).Error
+	if err != nil {
+		adminhandlers.NLog("warning", "LenderNotificationList", map[string]interface{}{"message": "error listing notifications", "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, "Pencarian tidak ditemukan")
+	}
+
+	result := basemodel.PagedFindResult{
+		TotalData:   totalRows,
+		Rows:        rows,
+		CurrentPage: page,
+		LastPage:    lastPage,
+		From:        offset + 1,
+		To:          offset + rows,
+		Data:        notifications,
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// LenderNotificationUnreadCount counts unread notifications of current lender user, total and per event
+func LenderNotificationUnreadCount(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "lender_notification_list")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	type EventCount struct {
+		Event string `json:"event"`
+		Total int    `json:"total"`
+	}
+	var counts []EventCount
+
+	err = asira.App.DB.Model(&models.Notification{}).
+		Select("event, COUNT(id) as total").
+		Where("user_id = ?", lenderUserID(c)).
+		Where("read_at IS NULL").
+		Group("event").
+		Scan(&counts).Error
+	if err != nil {
+		adminhandlers.NLog("warning", "LenderNotificationUnreadCount", map[string]interface{}{"message": "error counting unread notifications", "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, "Terjadi kesalahan")
+	}
+
+	unread := 0
+	events := map[string]int{}
+	for _, event := range models.NotificationEvents {
+		events[event] = 0
+	}
+	for _, count := range counts {
+		events[count.Event] = count.Total
+		unread += count.Total
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"unread": unread,
+		"events": events,
+	})
+}
+
+// LenderNotificationRead marks notification as read
+func LenderNotificationRead(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "lender_notification_read")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	notificationID, _ := strconv.ParseUint(c.Param("notification_id"), 10, 64)
+
+	type Filter struct {
+		ID     uint64 `json:"id"`
+		UserID uint64 `json:"user_id"`
+	}
+	notification := models.Notification{}
+	err = notification.SingleFindFilter(&Filter{
+		ID:     notificationID,
+		UserID: lenderUserID(c),
+	})
+	if err != nil {
+		adminhandlers.NLog("warning", "LenderNotificationRead", map[string]interface{}{"message": fmt.Sprintf("notification %v not found", notificationID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusNotFound, err, fmt.Sprintf("Notifikasi %v tidak ditemukan", notificationID))
+	}
+
+	if notification.ReadAt == nil {
+		now := time.Now()
+		notification.ReadAt = &now
+		err = notification.Save()
+		if err != nil {
+			adminhandlers.NLog("error", "LenderNotificationRead", map[string]interface{}{"message": fmt.Sprintf("error marking notification %v as read", notificationID), "error": err}, c.Get("user").(*jwt.Token), "", false)
+
+			return returnInvalidResponse(http.StatusInternalServerError, err, fmt.Sprintf("Gagal update notifikasi %v", notificationID))
+		}
+	}
+
+	return c.JSON(http.StatusOK, notification)
+}
+
+// LenderNotificationReadAll marks all notifications of current lender user as read
+func LenderNotificationReadAll(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "lender_notification_read")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	db := asira.App.DB.Model(&models.Notification{}).
+		Where("user_id = ?", lenderUserID(c)).
+		Where("read_at IS NULL")
+	if event := customSplit(c.QueryParam("event"), ","); len(event) > 0 {
+		db = db.Where("event IN (?)", event)
+	}
+
+	db = db.Updates(map[string]interface{}{"read_at": time.Now()})
+	if db.Error != nil {
+		adminhandlers.NLog("error", "LenderNotificationReadAll", map[string]interface{}{"message": "error marking notifications as read", "error": db.Error}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, db.Error, "Gagal update notifikasi")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"updated": db.RowsAffected,
+	})
+}
+
+// LenderNotificationPreferences lists current lender user preference of every event
+func LenderNotificationPreferences(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "lender_notification_preference")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	userID := lenderUserID(c)
+	preferences := []models.NotificationPreference{}
+	for _, event := range models.NotificationEvents {
+		preferences = append(preferences, models.FindPreference(userID, event))
+	}
+
+	return c.JSON(http.StatusOK, preferences)
+}
+
+// LenderNotificationPreferencePatch sets channels current lender user receives for an event
+func LenderNotificationPreferencePatch(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "lender_notification_preference")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	preferencePayload := NotificationPreferencePayload{}
+	payloadRules := govalidator.MapData{
+		"event":  []string{"required", "in:" + strings.Join(models.NotificationEvents, ",")},
+		"in_app": []string{},
+		"email":  []string{},
+	}
+
+	validate := validateRequestPayload(c, payloadRules, &preferencePayload)
+	if validate != nil {
+		adminhandlers.NLog("warning", "LenderNotificationPreferencePatch", map[string]interface{}{"message": "validation error", "error": validate}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusUnprocessableEntity, validate, "Hambatan validasi")
+	}
+
+	preference := models.FindPreference(lenderUserID(c), preferencePayload.Event)
+	origin := preference
+	if preferencePayload.InApp != nil {
+		preference.InApp = *preferencePayload.InApp
+	}
+	if preferencePayload.Email != nil {
+		preference.Email = *preferencePayload.Email
+	}
+
+	if preference.ID > 0 {
+		err = preference.Save()
+	} else {
+		err = preference.Create()
+	}
+	if err != nil {
+		adminhandlers.NLog("error", "LenderNotificationPreferencePatch", map[string]interface{}{"message": "error saving notification preference", "error": err, "preference": preference}, c.Get("user").(*jwt.Token), "", false)
+
+		return returnInvalidResponse(http.StatusInternalServerError, err, "Gagal menyimpan preferensi notifikasi")
+	}
+
+	adminhandlers.NAudittrail(origin, preference, c.Get("user").(*jwt.Token), "notification preference", fmt.Sprint(preference.ID), "update")
+
+	return c.JSON(http.StatusOK, preference)
+}
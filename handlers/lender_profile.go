@@ -67,6 +67,8 @@ func LenderProfile(c echo.Context) error {
 		return returnInvalidResponse(http.StatusForbidden, err, "Tidak memiliki hak akses")
 	}
 
+	adminhandlers.PresignImages(c, "lender_view_image", "bank", fmt.Sprint(bankRep.BankID), &temporal.Image)
+
 	return c.JSON(http.StatusOK, temporal)
 }
 
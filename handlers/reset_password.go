@@ -3,6 +3,7 @@ package handlers
 import (
 	"asira_lender/adminhandlers"
 	"asira_lender/asira"
+	"asira_lender/email"
 	"asira_lender/models"
 	"crypto/aes"
 	"crypto/cipher"
@@ -137,17 +138,20 @@ func UserResetPasswordRequest(c echo.Context) error {
 		return returnInvalidResponse(http.StatusUnprocessableEntity, err, "Terjadi kesalahan")
 	}
 
-	message := ""
+	link := ""
 	switch c.QueryParam("system") {
 	case "core":
-		message = fmt.Sprintf("link reset password : %v", asira.App.Config.GetString(fmt.Sprintf("%s.core_url", asira.App.ENV))+"/ubahpassword?token="+token)
+		link = asira.App.Config.GetString(fmt.Sprintf("%s.core_url", asira.App.ENV)) + "/ubahpassword?token=" + token
 		break
 	default:
-		message = fmt.Sprintf("link reset password : %v", asira.App.Config.GetString(fmt.Sprintf("%s.dashboard_url", asira.App.ENV))+"/ubahpassword?token="+token)
+		link = asira.App.Config.GetString(fmt.Sprintf("%s.dashboard_url", asira.App.ENV)) + "/ubahpassword?token=" + token
 		break
 	}
 
-	err = SendMail("Forgot Password Request", message, resetRequestPayload.Email)
+	err = models.SendEmail(c.QueryParam("lang"), email.ResetPasswordData{
+		Email: resetRequestPayload.Email,
+		Link:  link,
+	}, resetRequestPayload.Email)
 	if err != nil {
 		adminhandlers.NLog("error", "UserFirstLoginChangePassword", map[string]interface{}{"message": fmt.Sprintf("fail sending email to %v", resetRequestPayload.Email)}, c.Get("user").(*jwt.Token), "", false)
 
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"asira_lender/asira"
+	"asira_lender/custommodule"
+	"net/http"
+
+	"github.com/labstack/echo"
+)
+
+// StorageFile serves objects of local filesystem storage. only pre-signed urls are served
+func StorageFile(c echo.Context) error {
+	defer c.Request().Body.Close()
+
+	storage, ok := asira.App.Storage.(*custommodule.FileStorage)
+	if !ok {
+		return returnInvalidResponse(http.StatusNotFound, nil, "File tidak ditemukan")
+	}
+
+	key := c.Param("*")
+	if len(c.QueryParam("expires")) < 1 || len(c.QueryParam("signature")) < 1 {
+		return returnInvalidResponse(http.StatusForbidden, "missing signature", "Tautan tidak valid")
+	}
+	if err := storage.Verify(key, c.QueryParam("expires"), c.QueryParam("signature")); err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err.Error(), "Tautan tidak valid")
+	}
+
+	b, err := storage.Get(key)
+	if err != nil {
+		return returnInvalidResponse(http.StatusNotFound, nil, "File tidak ditemukan")
+	}
+	contentType, _ := custommodule.DetectContentType(b)
+
+	return c.Blob(http.StatusOK, contentType, b)
+}
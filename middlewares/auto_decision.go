@@ -0,0 +1,148 @@
+package middlewares
+
+import (
+	"asira_lender/asira"
+	"asira_lender/models"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/jinzhu/gorm/dialects/postgres"
+)
+
+// autoDecideLoan decides loan with first matching auto decision rule of its product once the loan is new to lenders,
+// which is when it arrives otp verified. approvals are held for manual review when loan would not pass manual approval
+// checks. decision is saved with its loan update outbox row. returns true when loan was decided
+func autoDecideLoan(origin models.Loan, loan models.Loan) bool {
+	if loan.Status != "processing" || !loan.OTPverified || origin.OTPverified {
+		return false
+	}
+
+	type Filter struct {
+		Loan uint64 `json:"loan"`
+	}
+	decision := models.LoanAutoDecision{}
+	if err := decision.SingleFindFilter(&Filter{Loan: loan.ID}); err == nil {
+		return false
+	}
+
+	rule, facts, matched, err := models.MatchAutoDecisionRule(loan)
+	if err != nil {
+		log.Printf("error evaluating auto decision rules of loan %v : %v", loan.ID, err)
+		return false
+	}
+	if !matched {
+		return false
+	}
+
+	if rule.Action == "approve" {
+		reason, err := autoApproveHold(loan)
+		if err != nil {
+			log.Printf("error checking loan %v before auto approval of rule %v : %v", loan.ID, rule.ID, err)
+			return false
+		}
+		if len(reason) > 0 {
+			log.Printf("loan %v auto approval of rule %v held for manual review : %v", loan.ID, rule.ID, reason)
+			return false
+		}
+	}
+
+	decided := loan
+	switch rule.Action {
+	case "approve":
+		decided.Status = "approved"
+		decided.DisburseDate = time.Now().AddDate(0, 0, rule.DisburseOffset)
+	case "reject":
+		decided.Status = "rejected"
+		decided.RejectReason = rule.RejectReason
+	}
+	decided.ApprovalDate = time.Now()
+
+	factsJSON, _ := json.Marshal(facts)
+	decision = models.LoanAutoDecision{
+		Loan:     loan.ID,
+		Rule:     rule.ID,
+		RuleName: rule.Name,
+		Action:   rule.Action,
+		Facts:    postgres.Jsonb{RawMessage: factsJSON},
+	}
+	if err = saveAutoDecision(&decided, &decision); err != nil {
+		log.Printf("error saving loan %v auto decision of rule %v : %v", loan.ID, rule.ID, err)
+		return false
+	}
+
+	LoanWebhooks(loan, decided)
+
+	return true
+}
+
+// saveAutoDecision saves decided loan, its decision record and loan update outbox row in one transaction
+func saveAutoDecision(loan *models.Loan, decision *models.LoanAutoDecision) (err error) {
+	tx := asira.App.DB.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+
+	if err = tx.Save(loan).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err = tx.Create(decision).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err = SubmitKafkaPayloadTx(tx, *loan, "loan_update"); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// autoApproveHold reason loan can not be approved without a lender, the same checks as manual approval :
+// affordability, watchlist, fraud flags not dismissed yet and approval policy of borrower bank. empty when none
+func autoApproveHold(loan models.Loan) (string, error) {
+	affordability, err := models.LoanAffordability(loan)
+	if err != nil {
+		return "", err
+	}
+	if affordability.Blocked || affordability.Exceeded {
+		return affordability.Warning, nil
+	}
+
+	borrower := models.Borrower{}
+	if err = borrower.FindbyID(loan.Borrower); err != nil {
+		return "", err
+	}
+
+	matches, err := models.WatchlistMatches(borrower)
+	if err != nil {
+		return "", err
+	}
+	if len(matches) > 0 {
+		return fmt.Sprintf("borrower %v matches watchlist %v", borrower.ID, matches[0].ID), nil
+	}
+
+	flags, err := models.BorrowerFraudFlags(borrower.ID)
+	if err != nil {
+		return "", err
+	}
+	if len(flags) > 0 {
+		return fmt.Sprintf("borrower %v has %v fraud flag %v", borrower.ID, flags[0].Status, flags[0].ID), nil
+	}
+
+	if !borrower.Bank.Valid {
+		return fmt.Sprintf("borrower %v has no bank", borrower.ID), nil
+	}
+	policy, err := models.FindApprovalPolicy(uint64(borrower.Bank.Int64), loan.Product, loan.LoanAmount)
+	if err != nil && !gorm.IsRecordNotFoundError(err) {
+		return "", err
+	}
+	if err == nil && len(policy.StepList()) > 0 {
+		return fmt.Sprintf("approval policy %v applies", policy.ID), nil
+	}
+
+	return "", nil
+}
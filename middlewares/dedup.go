@@ -0,0 +1,118 @@
+package middlewares
+
+import (
+	"asira_lender/models"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+type (
+	// KafkaEvent identity of consumed kafka message
+	KafkaEvent struct {
+		EventID  string
+		Model    string
+		EntityID uint64
+		Version  int64
+	}
+)
+
+// newEventID random id attached to every produced payload
+func newEventID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+
+	return hex.EncodeToString(b)
+}
+
+// newKafkaEvent reads event identity from message. messages without event_id are identified by their content hash.
+// version is the payload updated_at, messages without it fall back to their version field
+func newKafkaEvent(model string, raw string, arr map[string]interface{}) KafkaEvent {
+	event := KafkaEvent{Model: model}
+
+	if eventID, ok := arr["event_id"].(string); ok && len(eventID) > 0 {
+		event.EventID = eventID
+	} else {
+		sum := sha256.Sum256([]byte(model + ":" + raw))
+		event.EventID = hex.EncodeToString(sum[:])
+	}
+
+	if id, ok := arr["id"].(float64); ok {
+		event.EntityID = uint64(id)
+	}
+
+	if payload, ok := arr["payload"].(map[string]interface{}); ok {
+		event.Version = payloadVersion(payload)
+	}
+	if version, ok := arr["version"].(float64); ok && event.Version == 0 {
+		event.Version = int64(version)
+	}
+
+	return event
+}
+
+// payloadVersion entity version of payload taken from its updated_at, 0 when payload has none
+func payloadVersion(payload map[string]interface{}) int64 {
+	updatedAt, ok := payload["updated_at"].(string)
+	if !ok {
+		return 0
+	}
+	t, err := time.Parse(time.RFC3339Nano, updatedAt)
+	if err != nil || t.IsZero() {
+		return 0
+	}
+
+	return t.UnixNano()
+}
+
+// Skip checks inside tx whether event is a duplicate or older than the applied entity state
+func (e KafkaEvent) Skip(tx *gorm.DB) (bool, error) {
+	consumed := models.ConsumedEvent{}
+	exists, err := consumed.ExistsTx(tx, e.EventID)
+	if err != nil || exists {
+		return exists, err
+	}
+
+	if e.EntityID == 0 || e.Version == 0 {
+		return false, nil
+	}
+
+	entityVersion := models.EntityVersion{}
+	latest, err := entityVersion.FindVersionTx(tx, e.Model, e.EntityID)
+	if err != nil {
+		return false, err
+	}
+
+	return latest > e.Version, nil
+}
+
+// Done marks event as consumed inside tx applying it
+func (e KafkaEvent) Done(tx *gorm.DB) error {
+	consumed := models.ConsumedEvent{
+		EventID:  e.EventID,
+		Model:    e.Model,
+		EntityID: e.EntityID,
+		Version:  e.Version,
+	}
+	if err := consumed.CreateTx(tx); err != nil {
+		return err
+	}
+
+	if e.EntityID == 0 || e.Version == 0 {
+		return nil
+	}
+
+	entityVersion := models.EntityVersion{
+		Model:    e.Model,
+		EntityID: e.EntityID,
+		Version:  e.Version,
+	}
+
+	return entityVersion.UpsertTx(tx)
+}
@@ -0,0 +1,97 @@
+package middlewares
+
+import (
+	"asira_lender/asira"
+	"asira_lender/email"
+	"asira_lender/models"
+	"fmt"
+	"log"
+	"time"
+)
+
+func init() {
+	// worker is not started when workers are disabled, emails stay pending
+	if !workersEnabled() {
+		return
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			DeliverEmails()
+			time.Sleep(time.Duration(emailQueueConfig("interval", 10)) * time.Second)
+		}
+	}()
+}
+
+func emailQueueConfig(key string, def int) int {
+	value := asira.App.Config.GetInt(fmt.Sprintf("%s.mailer.queue.%s", asira.App.ENV, key))
+	if value <= 0 {
+		value = def
+	}
+
+	return value
+}
+
+// emailBackoff delay before next attempt, doubled on every failure and capped at one hour
+func emailBackoff(attempts int) time.Duration {
+	backoff := time.Duration(emailQueueConfig("backoff", 60)) * time.Second
+	for i := 1; i < attempts && backoff < time.Hour; i++ {
+		backoff *= 2
+	}
+	if backoff > time.Hour {
+		backoff = time.Hour
+	}
+
+	return backoff
+}
+
+// DeliverEmails sends queued emails that are due. failed deliveries are retried with backoff until max attempts
+func DeliverEmails() {
+	var queues []models.EmailQueue
+
+	err := asira.App.DB.Where("status = ? AND next_attempt_at <= ?", "pending", time.Now()).
+		Order("id asc").
+		Limit(emailQueueConfig("batch", 20)).
+		Find(&queues).Error
+	if err != nil {
+		log.Printf("email worker error : %v", err)
+		return
+	}
+
+	maxAttempts := emailQueueConfig("max_attempts", 5)
+	for _, queue := range queues {
+		msg, err := queue.Message()
+		if err == nil {
+			err = email.SendMessage(msg, queue.Recipients...)
+		}
+
+		now := time.Now()
+		queue.Attempts++
+		if err != nil {
+			log.Printf("email %v delivery attempt %v failed : %v", queue.ID, queue.Attempts, err)
+
+			status := "pending"
+			if queue.Attempts >= maxAttempts {
+				status = "failed"
+			}
+			asira.App.DB.Model(&queue).Updates(map[string]interface{}{
+				"status":          status,
+				"attempts":        queue.Attempts,
+				"last_error":      err.Error(),
+				"next_attempt_at": now.Add(emailBackoff(queue.Attempts)),
+			})
+			continue
+		}
+
+		err = asira.App.DB.Model(&queue).Updates(map[string]interface{}{
+			"status":   "sent",
+			"attempts": queue.Attempts,
+			"sent_at":  now,
+		}).Error
+		if err != nil {
+			log.Printf("email %v sent but failed to be marked sent : %v", queue.ID, err)
+		}
+	}
+}
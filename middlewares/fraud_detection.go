@@ -0,0 +1,33 @@
+package middlewares
+
+import (
+	"asira_lender/asira"
+	"asira_lender/models"
+	"fmt"
+	"log"
+	"time"
+)
+
+func fraudConfig(key string, def int) int {
+	value := asira.App.Config.GetInt(fmt.Sprintf("%s.fraud.%s", asira.App.ENV, key))
+	if value <= 0 {
+		value = def
+	}
+
+	return value
+}
+
+// checkBorrowerFraud flags borrowers sharing identity or contact data with borrower
+func checkBorrowerFraud(borrower models.Borrower) {
+	if _, err := models.DetectBorrowerDuplicates(borrower); err != nil {
+		log.Printf("error detecting duplicates of borrower %v : %v", borrower.ID, err)
+	}
+}
+
+// checkLoanFraud flags agent of loan borrower bringing too many loans in a short time
+func checkLoanFraud(loan models.Loan) {
+	window := time.Duration(fraudConfig("agent_burst_window", 60)) * time.Minute
+	if _, _, err := models.DetectAgentLoanBurst(loan, window, fraudConfig("agent_burst_loans", 10)); err != nil {
+		log.Printf("error detecting agent loan burst of loan %v : %v", loan.ID, err)
+	}
+}
@@ -4,21 +4,13 @@ import (
 	"asira_lender/asira"
 	"asira_lender/models"
 	"encoding/json"
-	"flag"
 	"fmt"
 	"log"
 	"strings"
 	"sync"
-
-	"github.com/Shopify/sarama"
 )
 
 type (
-	// AsiraKafkaHandlers type
-	AsiraKafkaHandlers struct {
-		KafkaConsumer     sarama.Consumer
-		PartitionConsumer sarama.PartitionConsumer
-	}
 	// BorrowerInfo borrower info passed to lender
 	BorrowerInfo struct {
 		Info interface{} `json:"borrower_info"`
@@ -31,64 +23,24 @@ type (
 
 var wg sync.WaitGroup
 
-func init() {
-	var err error
-	topic := asira.App.Config.GetString(fmt.Sprintf("%s.kafka.topics.consumes", asira.App.ENV))
-
-	kafka := &AsiraKafkaHandlers{}
-	kafka.KafkaConsumer, err = sarama.NewConsumer([]string{asira.App.Kafka.Host}, asira.App.Kafka.Config)
-	if err != nil {
-		log.Printf("error while creating new kafka consumer : %v", err)
-	}
-
-	kafka.SetPartitionConsumer(topic)
+// workersEnabled whether background workers are started. workers config is turned off for unit testing
+func workersEnabled() bool {
+	key := fmt.Sprintf("%s.workers", asira.App.ENV)
 
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		defer kafka.KafkaConsumer.Close()
-		for {
-			message, err := kafka.Listen()
-			if err != nil {
-				log.Printf("error occured when listening kafka : %v", err)
-			}
-			if message != nil {
-				err := processMessage(message)
-				if err != nil {
-					log.Printf("%v . message : %v", err, string(message))
-				}
-			}
-		}
-	}()
+	return !asira.App.Config.IsSet(key) || asira.App.Config.GetBool(key)
 }
 
-// SetPartitionConsumer func
-func (k *AsiraKafkaHandlers) SetPartitionConsumer(topic string) (err error) {
-	k.PartitionConsumer, err = k.KafkaConsumer.ConsumePartition(topic, 0, sarama.OffsetOldest)
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
+func init() {
+	topic := asira.App.Config.GetString(fmt.Sprintf("%s.kafka.topics.consumes", asira.App.ENV))
 
-// Listen to kafka
-func (k *AsiraKafkaHandlers) Listen() ([]byte, error) {
-	select {
-	case err := <-k.PartitionConsumer.Errors():
-		return nil, err
-	case msg := <-k.PartitionConsumer.Messages():
-		return msg.Value, nil
+	err := asira.App.Bus.Subscribe(topic, processMessage)
+	if err != nil {
+		log.Printf("error while subscribing message bus : %v", err)
 	}
 }
 
 // SubmitKafkaPayload submits payload to kafka
 func SubmitKafkaPayload(i interface{}, model string) (err error) {
-	// skip kafka submit when in unit testing
-	if flag.Lookup("test.v") != nil {
-		return createUnitTestModels(i, model)
-	}
-
 	topic := asira.App.Config.GetString(fmt.Sprintf("%s.kafka.topics.produces", asira.App.ENV))
 
 	var payload interface{}
@@ -97,30 +49,14 @@ func SubmitKafkaPayload(i interface{}, model string) (err error) {
 
 	jMarshal, _ := json.Marshal(payload)
 
-	kafkaProducer, err := sarama.NewAsyncProducer([]string{asira.App.Kafka.Host}, asira.App.Kafka.Config)
-	if err != nil {
-		return err
-	}
-	defer kafkaProducer.Close()
-
-	msg := &sarama.ProducerMessage{
-		Topic: topic,
-		Value: sarama.StringEncoder(model + ":" + string(jMarshal)),
-	}
-
-	select {
-	case kafkaProducer.Input() <- msg:
-		log.Printf("Produced topic : %s", topic)
-	case err := <-kafkaProducer.Errors():
-		log.Printf("Fail producing topic : %s error : %v", topic, err)
-	}
-
-	return nil
+	return asira.App.Bus.Publish(topic, []byte(model+":"+string(jMarshal)))
 }
 
 func kafkaPayloadBuilder(i interface{}, model *string) (payload interface{}) {
 	type KafkaModelPayload struct {
 		ID      float64     `json:"id"`
+		EventID string      `json:"event_id"`
+		Version int64       `json:"version"`
 		Payload interface{} `json:"payload"`
 		Mode    string      `json:"mode"`
 	}
@@ -144,6 +80,8 @@ func kafkaPayloadBuilder(i interface{}, model *string) (payload interface{}) {
 	if modelID, ok := inInterface["id"].(float64); ok {
 		payload = KafkaModelPayload{
 			ID:      modelID,
+			EventID: newEventID(),
+			Version: payloadVersion(inInterface),
 			Payload: i,
 			Mode:    mode,
 		}
@@ -162,8 +100,31 @@ func processMessage(kafkaMessage []byte) (err error) {
 		return err
 	}
 
+	event := newKafkaEvent(data[0], data[1], arr)
+
+	// message is applied and marked consumed in one transaction, a crash in between replays it whole
+	tx := asira.App.DB.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+
+	skip, err := event.Skip(tx)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if skip {
+		tx.Rollback()
+		log.Printf("skipping duplicate or stale %s event %s for id %v", event.Model, event.EventID, event.EntityID)
+		return nil
+	}
+
+	// follow ups of applied message, run once it is committed
+	var applied []func()
+
 	switch data[0] {
 	default:
+		tx.Rollback()
 		return nil
 	case "agent_provider":
 		mod := models.AgentProvider{}
@@ -176,13 +137,13 @@ func processMessage(kafkaMessage []byte) (err error) {
 			err = fmt.Errorf("invalid payload")
 			break
 		case "create":
-			err = mod.FirstOrCreate()
+			err = tx.FirstOrCreate(&mod).Error
 			break
 		case "update":
-			err = mod.Save()
+			err = tx.Save(&mod).Error
 			break
 		case "delete":
-			err = mod.Delete()
+			err = tx.Delete(&mod).Error
 			break
 		}
 		break
@@ -197,13 +158,13 @@ func processMessage(kafkaMessage []byte) (err error) {
 			err = fmt.Errorf("invalid payload")
 			break
 		case "create":
-			err = mod.FirstOrCreate()
+			err = tx.FirstOrCreate(&mod).Error
 			break
 		case "update":
-			err = mod.Save()
+			err = tx.Save(&mod).Error
 			break
 		case "delete":
-			err = mod.Delete()
+			err = tx.Delete(&mod).Error
 			break
 		}
 		break
@@ -218,13 +179,13 @@ func processMessage(kafkaMessage []byte) (err error) {
 			err = fmt.Errorf("invalid payload")
 			break
 		case "create":
-			err = mod.FirstOrCreate()
+			err = tx.FirstOrCreate(&mod).Error
 			break
 		case "update":
-			err = mod.Save()
+			err = tx.Save(&mod).Error
 			break
 		case "delete":
-			err = mod.Delete()
+			err = tx.Delete(&mod).Error
 			break
 		}
 		break
@@ -239,13 +200,13 @@ func processMessage(kafkaMessage []byte) (err error) {
 			err = fmt.Errorf("invalid payload")
 			break
 		case "create":
-			err = mod.FirstOrCreate()
+			err = tx.FirstOrCreate(&mod).Error
 			break
 		case "update":
-			err = mod.Save()
+			err = tx.Save(&mod).Error
 			break
 		case "delete":
-			err = mod.Delete()
+			err = tx.Delete(&mod).Error
 			break
 		}
 		break
@@ -260,13 +221,13 @@ func processMessage(kafkaMessage []byte) (err error) {
 			err = fmt.Errorf("invalid payload")
 			break
 		case "create":
-			err = mod.FirstOrCreate()
+			err = tx.FirstOrCreate(&mod).Error
 			break
 		case "update":
-			err = mod.Save()
+			err = tx.Save(&mod).Error
 			break
 		case "delete":
-			err = mod.Delete()
+			err = tx.Delete(&mod).Error
 			break
 		}
 		break
@@ -281,13 +242,13 @@ func processMessage(kafkaMessage []byte) (err error) {
 			err = fmt.Errorf("invalid payload")
 			break
 		case "create":
-			err = mod.FirstOrCreate()
+			err = tx.FirstOrCreate(&mod).Error
 			break
 		case "update":
-			err = mod.Save()
+			err = tx.Save(&mod).Error
 			break
 		case "delete":
-			err = mod.Delete()
+			err = tx.Delete(&mod).Error
 			break
 		}
 		break
@@ -302,13 +263,13 @@ func processMessage(kafkaMessage []byte) (err error) {
 			err = fmt.Errorf("invalid payload")
 			break
 		case "create":
-			err = mod.FirstOrCreate()
+			err = tx.FirstOrCreate(&mod).Error
 			break
 		case "update":
-			err = mod.Save()
+			err = tx.Save(&mod).Error
 			break
 		case "delete":
-			err = mod.Delete()
+			err = tx.Delete(&mod).Error
 			break
 		}
 		break
@@ -323,13 +284,34 @@ func processMessage(kafkaMessage []byte) (err error) {
 			err = fmt.Errorf("invalid payload")
 			break
 		case "create":
-			err = mod.FirstOrCreate()
+			err = tx.FirstOrCreate(&mod).Error
+			applied = append(applied, func() {
+				assessLoan(&mod)
+				checkLoanFraud(mod)
+				notifyNewLoan(mod)
+				publishLoanEvent("create", mod)
+				if !autoDecideLoan(models.Loan{}, mod) {
+					startLoanApproval(mod)
+				}
+			})
 			break
 		case "update":
-			err = mod.Save()
+			origin := models.Loan{}
+			tx.First(&origin, mod.ID)
+			keepLoanAssessment(origin, &mod)
+
+			err = tx.Save(&mod).Error
+			applied = append(applied, func() {
+				publishLoanEvent("update", mod)
+				LoanWebhooks(origin, mod)
+				// loan verified after it was created is new to lenders now
+				if !autoDecideLoan(origin, mod) && mod.OTPverified && !origin.OTPverified {
+					startLoanApproval(mod)
+				}
+			})
 			break
 		case "delete":
-			err = mod.Delete()
+			err = tx.Delete(&mod).Error
 			break
 		}
 		break
@@ -344,13 +326,22 @@ func processMessage(kafkaMessage []byte) (err error) {
 			err = fmt.Errorf("invalid payload")
 			break
 		case "create":
-			err = mod.FirstOrCreate()
+			err = tx.FirstOrCreate(&mod).Error
+			applied = append(applied, func() {
+				checkBorrowerFraud(mod)
+				notifyNewBorrower(mod)
+				publishBorrowerEvent("create", mod)
+			})
 			break
 		case "update":
-			err = mod.Save()
+			err = tx.Save(&mod).Error
+			applied = append(applied, func() {
+				checkBorrowerFraud(mod)
+				publishBorrowerEvent("update", mod)
+			})
 			break
 		case "delete":
-			err = mod.Delete()
+			err = tx.Delete(&mod).Error
 			break
 		}
 		break
@@ -365,13 +356,13 @@ func processMessage(kafkaMessage []byte) (err error) {
 			err = fmt.Errorf("invalid payload")
 			break
 		case "create":
-			err = mod.FirstOrCreate()
+			err = tx.FirstOrCreate(&mod).Error
 			break
 		case "update":
-			err = mod.Save()
+			err = tx.Save(&mod).Error
 			break
 		case "delete":
-			err = mod.Delete()
+			err = tx.Delete(&mod).Error
 			break
 		}
 	case "installment":
@@ -385,13 +376,13 @@ func processMessage(kafkaMessage []byte) (err error) {
 			err = fmt.Errorf("invalid payload")
 			break
 		case "create":
-			err = mod.FirstOrCreate()
+			err = tx.FirstOrCreate(&mod).Error
 			break
 		case "update":
-			err = mod.Save()
+			err = tx.Save(&mod).Error
 			break
 		case "delete":
-			err = mod.Delete()
+			err = tx.Delete(&mod).Error
 			break
 		}
 		break
@@ -400,202 +391,26 @@ func processMessage(kafkaMessage []byte) (err error) {
 		marshal, _ := json.Marshal(arr["payload"])
 		json.Unmarshal(marshal, &mods)
 
-		for _, mod := range mods {
-			err = mod.FirstOrCreate()
+		for i := range mods {
+			if err = tx.FirstOrCreate(&mods[i]).Error; err != nil {
+				break
+			}
 		}
 	}
-	return err
-}
-
-func createUnitTestModels(i interface{}, model string) error {
-	var (
-		mode string
-		err  error
-	)
-	if strings.HasSuffix(model, "_delete") {
-		mode = "delete"
-		model = strings.TrimSuffix(model, "_delete")
-	} else if strings.HasSuffix(model, "_create") {
-		mode = "create"
-		model = strings.TrimSuffix(model, "_create")
-	} else if strings.HasSuffix(model, "_update") {
-		mode = "update"
-		model = strings.TrimSuffix(model, "_update")
+	if err == nil {
+		err = event.Done(tx)
+	}
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err = tx.Commit().Error; err != nil {
+		return err
 	}
 
-	switch model {
-	default:
-		return fmt.Errorf("invalid model")
-	case "agent_provider":
-		if x, ok := i.(models.AgentProvider); ok {
-			switch mode {
-			default:
-				return fmt.Errorf("invalid model")
-			case "create":
-				err = x.FirstOrCreate()
-				break
-			case "update":
-				err = x.Save()
-				break
-			case "delete":
-				err = x.Delete()
-				break
-			}
-		}
-		break
-	case "agent":
-		if x, ok := i.(models.Agent); ok {
-			switch mode {
-			default:
-				return fmt.Errorf("invalid model")
-			case "create":
-				err = x.FirstOrCreate()
-				break
-			case "update":
-				err = x.Save()
-				break
-			case "delete":
-				err = x.Delete()
-				break
-			}
-		}
-		break
-	case "bank_type":
-		if x, ok := i.(models.BankType); ok {
-			switch mode {
-			default:
-				return fmt.Errorf("invalid model")
-			case "create":
-				err = x.FirstOrCreate()
-				break
-			case "update":
-				err = x.Save()
-				break
-			case "delete":
-				err = x.Delete()
-				break
-			}
-		}
-		break
-	case "bank":
-		if x, ok := i.(models.Bank); ok {
-			switch mode {
-			default:
-				return fmt.Errorf("invalid model")
-			case "create":
-				err = x.FirstOrCreate()
-				break
-			case "update":
-				err = x.Save()
-				break
-			case "delete":
-				err = x.Delete()
-				break
-			}
-		}
-		break
-	case "loan_purpose":
-		if x, ok := i.(models.LoanPurpose); ok {
-			switch mode {
-			default:
-				return fmt.Errorf("invalid model")
-			case "create":
-				err = x.FirstOrCreate()
-				break
-			case "update":
-				err = x.Save()
-				break
-			case "delete":
-				err = x.Delete()
-				break
-			}
-		}
-		break
-	case "product":
-		if x, ok := i.(models.Product); ok {
-			switch mode {
-			default:
-				return fmt.Errorf("invalid model")
-			case "create":
-				err = x.FirstOrCreate()
-				break
-			case "update":
-				err = x.Save()
-				break
-			case "delete":
-				err = x.Delete()
-				break
-			}
-		}
-		break
-	case "service":
-		if x, ok := i.(models.Service); ok {
-			switch mode {
-			default:
-				return fmt.Errorf("invalid model")
-			case "create":
-				err = x.FirstOrCreate()
-				break
-			case "update":
-				err = x.Save()
-				break
-			case "delete":
-				err = x.Delete()
-				break
-			}
-		}
-		break
-	case "loan":
-		if x, ok := i.(models.Loan); ok {
-			switch mode {
-			default:
-				return fmt.Errorf("invalid model")
-			case "create":
-				err = x.FirstOrCreate()
-				break
-			case "update":
-				err = x.Save()
-				break
-			case "delete":
-				err = x.Delete()
-				break
-			}
-		}
-		break
-	case "borrower":
-		if x, ok := i.(models.Borrower); ok {
-			switch mode {
-			default:
-				return fmt.Errorf("invalid model")
-			case "create":
-				err = x.FirstOrCreate()
-				break
-			case "update":
-				err = x.Save()
-				break
-			case "delete":
-				err = x.Delete()
-				break
-			}
-		}
-		break
-	case "faq":
-		if x, ok := i.(models.FAQ); ok {
-			switch mode {
-			default:
-				return fmt.Errorf("invalid model")
-			case "create":
-				err = x.FirstOrCreate()
-				break
-			case "update":
-				err = x.Save()
-				break
-			case "delete":
-				err = x.Delete()
-				break
-			}
-		}
+	for _, f := range applied {
+		f()
 	}
 
-	return err
+	return nil
 }
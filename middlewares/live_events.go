@@ -0,0 +1,159 @@
+package middlewares
+
+import (
+	"asira_lender/asira"
+	"asira_lender/models"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+type (
+	// LiveEvent loan or borrower change pushed to lender dashboards
+	LiveEvent struct {
+		ID        uint64      `json:"id"`
+		Bank      uint64      `json:"bank"`
+		Entity    string      `json:"entity"`
+		Mode      string      `json:"mode"`
+		EntityID  uint64      `json:"entity_id"`
+		Data      interface{} `json:"data"`
+		CreatedAt time.Time   `json:"created_at"`
+	}
+
+	// LiveSubscription live events of a bank received by one stream
+	LiveSubscription struct {
+		Bank   uint64
+		Events chan LiveEvent
+	}
+)
+
+var (
+	liveMutex sync.Mutex
+	// events before this process started are never kept
+	liveStartedID     = uint64(time.Now().UnixNano())
+	liveLastID        uint64
+	liveEvictedID     uint64
+	liveHistory       []LiveEvent
+	liveSubscriptions = map[*LiveSubscription]bool{}
+)
+
+func liveEventsConfig(key string, def int) int {
+	value := asira.App.Config.GetInt(fmt.Sprintf("%s.live_events.%s", asira.App.ENV, key))
+	if value <= 0 {
+		value = def
+	}
+
+	return value
+}
+
+// LiveHeartbeat interval of heartbeat sent to idle streams
+func LiveHeartbeat() time.Duration {
+	return time.Duration(liveEventsConfig("heartbeat", 15)) * time.Second
+}
+
+// PublishLiveEvent pushes event to streams subscribed to the bank and keeps it for replay.
+// streams too slow to keep up are closed, their clients reconnect and replay what they missed
+func PublishLiveEvent(bank uint64, entity string, mode string, entityID uint64, data interface{}) LiveEvent {
+	liveMutex.Lock()
+	defer liveMutex.Unlock()
+
+	// ids are time based so they keep increasing across restarts
+	id := uint64(time.Now().UnixNano())
+	if id <= liveLastID {
+		id = liveLastID + 1
+	}
+	liveLastID = id
+
+	event := LiveEvent{
+		ID:        id,
+		Bank:      bank,
+		Entity:    entity,
+		Mode:      mode,
+		EntityID:  entityID,
+		Data:      data,
+		CreatedAt: time.Now(),
+	}
+
+	liveHistory = append(liveHistory, event)
+	if size := liveEventsConfig("history", 500); len(liveHistory) > size {
+		liveEvictedID = liveHistory[len(liveHistory)-size-1].ID
+		liveHistory = liveHistory[len(liveHistory)-size:]
+	}
+
+	for subscription := range liveSubscriptions {
+		if subscription.Bank != bank {
+			continue
+		}
+		select {
+		case subscription.Events <- event:
+		default:
+			log.Printf("live event stream of bank %v is lagging, closing it", bank)
+			delete(liveSubscriptions, subscription)
+			close(subscription.Events)
+		}
+	}
+
+	return event
+}
+
+// SubscribeLiveEvents subscribes to live events of the bank. events after lastEventID are returned as missed,
+// complete is false when some of them are no longer kept, or lastEventID is not an event of this process,
+// and client should reload its data
+func SubscribeLiveEvents(bank uint64, lastEventID uint64) (subscription *LiveSubscription, missed []LiveEvent, complete bool) {
+	liveMutex.Lock()
+	defer liveMutex.Unlock()
+
+	complete = true
+	if lastEventID > 0 {
+		complete = lastEventID >= liveStartedID && lastEventID >= liveEvictedID && lastEventID <= liveLastID
+		for _, event := range liveHistory {
+			if event.Bank == bank && event.ID > lastEventID {
+				missed = append(missed, event)
+			}
+		}
+	}
+
+	subscription = &LiveSubscription{
+		Bank:   bank,
+		Events: make(chan LiveEvent, liveEventsConfig("buffer", 64)),
+	}
+	liveSubscriptions[subscription] = true
+
+	return subscription, missed, complete
+}
+
+// Unsubscribe stops receiving live events
+func (s *LiveSubscription) Unsubscribe() {
+	liveMutex.Lock()
+	defer liveMutex.Unlock()
+
+	if liveSubscriptions[s] {
+		delete(liveSubscriptions, s)
+		close(s.Events)
+	}
+}
+
+// publishLoanEvent pushes loan change to the bank of loan's borrower. loans not yet otp verified are not listed to lender
+func publishLoanEvent(mode string, loan models.Loan) {
+	if !loan.OTPverified {
+		return
+	}
+
+	borrower := models.Borrower{}
+	if err := borrower.FindbyID(loan.Borrower); err != nil || !borrower.Bank.Valid {
+		log.Printf("skipping loan %v live event, borrower %v bank not found : %v", loan.ID, loan.Borrower, err)
+		return
+	}
+
+	PublishLiveEvent(uint64(borrower.Bank.Int64), "loan", mode, loan.ID, loan)
+}
+
+// publishBorrowerEvent pushes borrower change to borrower's bank
+func publishBorrowerEvent(mode string, borrower models.Borrower) {
+	if !borrower.Bank.Valid {
+		return
+	}
+
+	PublishLiveEvent(uint64(borrower.Bank.Int64), "borrower", mode, borrower.ID, borrower)
+}
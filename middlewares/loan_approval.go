@@ -0,0 +1,19 @@
+package middlewares
+
+import (
+	"asira_lender/models"
+	"log"
+)
+
+// startLoanApproval puts new loan to approval task queue of its bank when an approval policy applies to it
+func startLoanApproval(loan models.Loan) {
+	borrower := models.Borrower{}
+	if err := borrower.FindbyID(loan.Borrower); err != nil || !borrower.Bank.Valid {
+		log.Printf("skipping loan %v approval, borrower %v bank not found : %v", loan.ID, loan.Borrower, err)
+		return
+	}
+
+	if _, _, err := models.StartLoanApproval(loan, uint64(borrower.Bank.Int64)); err != nil {
+		log.Printf("error starting loan %v approval : %v", loan.ID, err)
+	}
+}
@@ -0,0 +1,39 @@
+package middlewares
+
+import (
+	"asira_lender/models"
+	"log"
+)
+
+// assessLoan stores credit score and affordability of new loan
+func assessLoan(loan *models.Loan) {
+	if _, err := models.ScoreLoan(loan); err != nil {
+		log.Printf("error scoring loan %v : %v", loan.ID, err)
+	}
+
+	affordability, err := models.LoanAffordability(*loan)
+	if err != nil {
+		log.Printf("error computing loan %v affordability : %v", loan.ID, err)
+	} else {
+		loan.DTI = affordability.DTI
+		loan.DTIExceeded = affordability.Exceeded
+	}
+
+	if err = loan.Save(); err != nil {
+		log.Printf("error saving loan %v assessment : %v", loan.ID, err)
+	}
+}
+
+// keepLoanAssessment carries score and dti of origin over loan updates sent without them, they are only known to lender
+func keepLoanAssessment(origin models.Loan, loan *models.Loan) {
+	if loan.Scorecard == 0 && origin.Scorecard > 0 {
+		loan.Scorecard = origin.Scorecard
+		loan.CreditScore = origin.CreditScore
+		loan.RiskGrade = origin.RiskGrade
+		loan.ScoreFactors = origin.ScoreFactors
+	}
+	if loan.DTI == 0 && origin.DTI > 0 {
+		loan.DTI = origin.DTI
+		loan.DTIExceeded = origin.DTIExceeded
+	}
+}
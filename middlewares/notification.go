@@ -0,0 +1,88 @@
+package middlewares
+
+import (
+	"asira_lender/asira"
+	"asira_lender/email"
+	"asira_lender/models"
+	"fmt"
+	"log"
+)
+
+// NotifyBank notifies every representative of the bank through the channels each of them prefers
+func NotifyBank(bankID uint64, event string, entity string, entityID uint64, title string, message string) (err error) {
+	var users []models.User
+
+	err = asira.App.DB.Table("users").
+		Select("users.*").
+		Joins("INNER JOIN bank_representatives br ON br.user_id = users.id").
+		Where("br.bank_id = ?", bankID).
+		Where("br.deleted_at IS NULL").
+		Find(&users).Error
+	if err != nil {
+		return err
+	}
+
+	link := asira.App.Config.GetString(fmt.Sprintf("%s.dashboard_url", asira.App.ENV))
+	for _, user := range users {
+		preference := models.FindPreference(user.ID, event)
+
+		if preference.InApp {
+			notification := models.Notification{
+				UserID:   user.ID,
+				Bank:     bankID,
+				Event:    event,
+				Entity:   entity,
+				EntityID: entityID,
+				Title:    title,
+				Message:  message,
+			}
+			if err = notification.Create(); err != nil {
+				log.Printf("error creating %v notification for user %v : %v", event, user.ID, err)
+			}
+		}
+
+		if preference.Email && len(user.Email) > 0 {
+			err = models.SendEmail("", email.LenderNotificationData{
+				Username: user.Username,
+				Title:    title,
+				Message:  message,
+				Link:     link,
+			}, user.Email)
+			if err != nil {
+				log.Printf("error queueing %v notification email for user %v : %v", event, user.ID, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// notifyNewLoan notifies bank of the loan's borrower about new loan request
+func notifyNewLoan(loan models.Loan) {
+	borrower := models.Borrower{}
+	if err := borrower.FindbyID(loan.Borrower); err != nil || !borrower.Bank.Valid {
+		log.Printf("skipping new loan %v notification, borrower %v bank not found : %v", loan.ID, loan.Borrower, err)
+		return
+	}
+
+	err := NotifyBank(uint64(borrower.Bank.Int64), models.NotificationNewLoan, "loan", loan.ID,
+		"Pengajuan pinjaman baru",
+		fmt.Sprintf("Nasabah %v mengajukan pinjaman %v sebesar %.0f", borrower.Fullname, loan.ID, loan.LoanAmount))
+	if err != nil {
+		log.Printf("error notifying new loan %v : %v", loan.ID, err)
+	}
+}
+
+// notifyNewBorrower notifies borrower's bank about new borrower
+func notifyNewBorrower(borrower models.Borrower) {
+	if !borrower.Bank.Valid {
+		return
+	}
+
+	err := NotifyBank(uint64(borrower.Bank.Int64), models.NotificationNewBorrower, "borrower", borrower.ID,
+		"Nasabah baru",
+		fmt.Sprintf("Nasabah baru %v terdaftar", borrower.Fullname))
+	if err != nil {
+		log.Printf("error notifying new borrower %v : %v", borrower.ID, err)
+	}
+}
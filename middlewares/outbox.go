@@ -0,0 +1,254 @@
+package middlewares
+
+import (
+	"asira_lender/asira"
+	"asira_lender/models"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/jinzhu/gorm/dialects/postgres"
+)
+
+type (
+	// OutboxMetrics relay metrics
+	OutboxMetrics struct {
+		Pending          int        `json:"pending"`
+		Failed           int        `json:"failed"`
+		OldestPendingAt  *time.Time `json:"oldest_pending_at"`
+		LagSeconds       float64    `json:"lag_seconds"`
+		RelayedTotal     int64      `json:"relayed_total"`
+		FailedTotal      int64      `json:"failed_total"`
+		LastRelayAt      *time.Time `json:"last_relay_at"`
+		LastError        string     `json:"last_error"`
+		LastErrorAt      *time.Time `json:"last_error_at"`
+		RelayIntervalSec int        `json:"relay_interval_seconds"`
+	}
+)
+
+var (
+	outboxMutex   sync.Mutex
+	outboxMetrics OutboxMetrics
+)
+
+func init() {
+	// relay is not started when workers are disabled, rows stay pending
+	if !workersEnabled() {
+		return
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			RelayOutbox()
+			time.Sleep(time.Duration(outboxInterval()) * time.Second)
+		}
+	}()
+}
+
+func outboxInterval() int {
+	interval := asira.App.Config.GetInt(fmt.Sprintf("%s.kafka.outbox.interval", asira.App.ENV))
+	if interval <= 0 {
+		interval = 5
+	}
+
+	return interval
+}
+
+func outboxBatch() int {
+	batch := asira.App.Config.GetInt(fmt.Sprintf("%s.kafka.outbox.batch", asira.App.ENV))
+	if batch <= 0 {
+		batch = 100
+	}
+
+	return batch
+}
+
+// SubmitKafkaPayloadTx writes kafka payload to outbox inside db transaction
+func SubmitKafkaPayloadTx(tx *gorm.DB, i interface{}, model string) (err error) {
+	topic := asira.App.Config.GetString(fmt.Sprintf("%s.kafka.topics.produces", asira.App.ENV))
+
+	payload := kafkaPayloadBuilder(i, &model)
+	if payload == nil {
+		return fmt.Errorf("model %s has no id", model)
+	}
+
+	jMarshal, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	outbox := models.Outbox{
+		Topic:   topic,
+		Model:   model,
+		Payload: postgres.Jsonb{RawMessage: jMarshal},
+		Status:  "pending",
+	}
+
+	return outbox.CreateTx(tx)
+}
+
+// CreateWithOutbox creates model and its kafka outbox row in one transaction
+func CreateWithOutbox(i interface{}, model string) (err error) {
+	tx := asira.App.DB.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+
+	if err = tx.Create(i).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err = SubmitKafkaPayloadTx(tx, i, model); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// SaveWithOutbox saves model and its kafka outbox row in one transaction
+func SaveWithOutbox(i interface{}, model string) (err error) {
+	tx := asira.App.DB.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+
+	if err = tx.Save(i).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err = SubmitKafkaPayloadTx(tx, i, model); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// DeleteWithOutbox deletes model and writes its kafka outbox row in one transaction
+func DeleteWithOutbox(i interface{}, model string) (err error) {
+	tx := asira.App.DB.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+
+	if err = tx.Delete(i).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err = SubmitKafkaPayloadTx(tx, i, model); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// RelayOutbox publishes pending outbox rows to kafka in order. rows are locked until the batch is done
+// so other relay instances skip them instead of publishing them twice
+func RelayOutbox() {
+	var outboxes []models.Outbox
+
+	tx := asira.App.DB.Begin()
+	if tx.Error != nil {
+		outboxFailed(tx.Error)
+		return
+	}
+
+	err := tx.Set("gorm:query_option", "FOR UPDATE SKIP LOCKED").
+		Where("status = ?", "pending").
+		Order("id asc").
+		Limit(outboxBatch()).
+		Find(&outboxes).Error
+	if err != nil {
+		tx.Rollback()
+		outboxFailed(err)
+		return
+	}
+	if len(outboxes) < 1 {
+		tx.Rollback()
+		return
+	}
+
+	for _, outbox := range outboxes {
+		err = asira.App.Bus.Publish(outbox.Topic, []byte(outbox.Model+":"+string(outbox.Payload.RawMessage)))
+		if err != nil {
+			outbox.Attempts++
+			outbox.LastError = err.Error()
+			tx.Model(&outbox).Updates(map[string]interface{}{
+				"attempts":   outbox.Attempts,
+				"last_error": outbox.LastError,
+			})
+			outboxFailed(err)
+
+			// stop the batch so later rows are not published before this one
+			break
+		}
+
+		now := time.Now()
+		err = tx.Model(&outbox).Updates(map[string]interface{}{
+			"status":   "sent",
+			"attempts": outbox.Attempts + 1,
+			"sent_at":  now,
+		}).Error
+		if err != nil {
+			log.Printf("outbox %v published but failed to be marked sent : %v", outbox.ID, err)
+		}
+
+		outboxMutex.Lock()
+		outboxMetrics.RelayedTotal++
+		outboxMetrics.LastRelayAt = &now
+		outboxMutex.Unlock()
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		outboxFailed(err)
+	}
+}
+
+func outboxFailed(err error) {
+	log.Printf("outbox relay error : %v", err)
+
+	now := time.Now()
+	outboxMutex.Lock()
+	outboxMetrics.FailedTotal++
+	outboxMetrics.LastError = err.Error()
+	outboxMetrics.LastErrorAt = &now
+	outboxMutex.Unlock()
+}
+
+// OutboxStats returns current outbox lag and relay metrics
+func OutboxStats() (stats OutboxMetrics, err error) {
+	outboxMutex.Lock()
+	stats = outboxMetrics
+	outboxMutex.Unlock()
+
+	stats.RelayIntervalSec = outboxInterval()
+
+	db := asira.App.DB.Model(&models.Outbox{})
+	if err = db.Where("status = ?", "pending").Count(&stats.Pending).Error; err != nil {
+		return stats, err
+	}
+	if err = asira.App.DB.Model(&models.Outbox{}).Where("status = ? AND attempts > 0", "pending").Count(&stats.Failed).Error; err != nil {
+		return stats, err
+	}
+
+	if stats.Pending > 0 {
+		var oldest models.Outbox
+		if err = asira.App.DB.Where("status = ?", "pending").Order("id asc").First(&oldest).Error; err != nil {
+			return stats, err
+		}
+		stats.OldestPendingAt = &oldest.CreatedAt
+		stats.LagSeconds = time.Since(oldest.CreatedAt).Seconds()
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,171 @@
+package middlewares
+
+import (
+	"asira_lender/asira"
+	"asira_lender/models"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"time"
+)
+
+func init() {
+	// worker is not started when workers are disabled, deliveries stay pending
+	if !workersEnabled() {
+		return
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			DeliverWebhooks()
+			time.Sleep(time.Duration(webhookConfig("interval", 10)) * time.Second)
+		}
+	}()
+}
+
+func webhookConfig(key string, def int) int {
+	value := asira.App.Config.GetInt(fmt.Sprintf("%s.webhook.%s", asira.App.ENV, key))
+	if value <= 0 {
+		value = def
+	}
+
+	return value
+}
+
+// webhookBackoff delay before next attempt, doubled on every failure and capped at one hour
+func webhookBackoff(attempts int) time.Duration {
+	backoff := time.Duration(webhookConfig("backoff", 60)) * time.Second
+	for i := 1; i < attempts && backoff < time.Hour; i++ {
+		backoff *= 2
+	}
+	if backoff > time.Hour {
+		backoff = time.Hour
+	}
+
+	return backoff
+}
+
+// SignWebhook hex encoded HMAC-SHA256 of body keyed with webhook secret
+func SignWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// DeliverWebhook posts delivery payload to webhook url and records the attempt.
+// deliveries failing max attempts times are marked failed
+func DeliverWebhook(webhook models.Webhook, delivery *models.WebhookDelivery) error {
+	body := []byte("{}")
+	if delivery.Payload != nil {
+		body = delivery.Payload.RawMessage
+	}
+
+	req, err := http.NewRequest("POST", webhook.URL, bytes.NewReader(body))
+	if err == nil {
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Asira-Event", delivery.Event)
+		req.Header.Set("X-Asira-Delivery", fmt.Sprint(delivery.ID))
+		req.Header.Set("X-Asira-Signature", "sha256="+SignWebhook(webhook.Secret, body))
+
+		client := http.Client{Timeout: time.Duration(webhookConfig("timeout", 10)) * time.Second}
+		var res *http.Response
+		res, err = client.Do(req)
+		if err == nil {
+			defer res.Body.Close()
+			response, _ := ioutil.ReadAll(io.LimitReader(res.Body, 4096))
+			delivery.ResponseCode = res.StatusCode
+			delivery.ResponseBody = string(response)
+			if res.StatusCode < 200 || res.StatusCode > 299 {
+				err = fmt.Errorf("webhook responded with status %v", res.StatusCode)
+			}
+		}
+	}
+
+	now := time.Now()
+	delivery.Attempts++
+	if err != nil {
+		delivery.Status = "pending"
+		if delivery.Attempts >= webhookConfig("max_attempts", 5) {
+			delivery.Status = "failed"
+		}
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = now.Add(webhookBackoff(delivery.Attempts))
+	} else {
+		delivery.Status = "sent"
+		delivery.LastError = ""
+		delivery.DeliveredAt = &now
+	}
+
+	if saveErr := delivery.Save(); saveErr != nil {
+		log.Printf("webhook delivery %v attempt failed to be recorded : %v", delivery.ID, saveErr)
+	}
+
+	return err
+}
+
+// DeliverWebhooks sends webhook deliveries that are due
+func DeliverWebhooks() {
+	var deliveries []models.WebhookDelivery
+
+	err := asira.App.DB.Where("status = ? AND next_attempt_at <= ?", "pending", time.Now()).
+		Order("id asc").
+		Limit(webhookConfig("batch", 20)).
+		Find(&deliveries).Error
+	if err != nil {
+		log.Printf("webhook worker error : %v", err)
+		return
+	}
+
+	for _, delivery := range deliveries {
+		webhook := models.Webhook{}
+		if err = webhook.FindbyID(delivery.Webhook); err != nil {
+			delivery.Status = "failed"
+			delivery.LastError = fmt.Sprintf("webhook %v not found", delivery.Webhook)
+			delivery.Save()
+			continue
+		}
+
+		if err = DeliverWebhook(webhook, &delivery); err != nil {
+			log.Printf("webhook delivery %v attempt %v failed : %v", delivery.ID, delivery.Attempts, err)
+		}
+	}
+}
+
+// LoanWebhooks queues webhook events of loan changes made from origin to loan
+func LoanWebhooks(origin models.Loan, loan models.Loan) {
+	var events []string
+	if loan.Status == "approved" && origin.Status != loan.Status {
+		events = append(events, models.WebhookLoanApproved)
+	}
+	if loan.DisburseStatus == "confirmed" && origin.DisburseStatus != loan.DisburseStatus {
+		events = append(events, models.WebhookLoanDisbursed)
+	}
+	if loan.PaymentStatus == "terbayar" && origin.PaymentStatus != loan.PaymentStatus {
+		events = append(events, models.WebhookLoanPaid)
+	}
+	if len(events) < 1 {
+		return
+	}
+
+	borrower := models.Borrower{}
+	if err := borrower.FindbyID(loan.Borrower); err != nil || !borrower.Bank.Valid {
+		log.Printf("skipping loan %v webhooks, borrower %v bank not found : %v", loan.ID, loan.Borrower, err)
+		return
+	}
+
+	for _, event := range events {
+		_, err := models.QueueWebhooks(uint64(borrower.Bank.Int64), event, fmt.Sprintf("loan:%v", loan.ID), loan)
+		if err != nil {
+			log.Printf("error queueing %v webhooks of loan %v : %v", event, loan.ID, err)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+
+CREATE TABLE "outboxes" (
+    "id" bigserial,
+    "created_at" timestamptz DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" timestamptz DEFAULT CURRENT_TIMESTAMP,
+    "deleted_at" timestamptz,
+    "topic" varchar(255),
+    "model" varchar(255),
+    "payload" jsonb,
+    "status" varchar(255) DEFAULT ('pending'),
+    "attempts" int DEFAULT (0),
+    "last_error" text,
+    "sent_at" timestamptz,
+    PRIMARY KEY ("id")
+) WITH (OIDS = FALSE);
+
+CREATE INDEX "outboxes_status_id" ON "outboxes" ("status", "id");
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+
+DROP TABLE IF EXISTS "outboxes" CASCADE;
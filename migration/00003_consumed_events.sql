@@ -0,0 +1,35 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+
+CREATE TABLE "consumed_events" (
+    "id" bigserial,
+    "created_at" timestamptz DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" timestamptz DEFAULT CURRENT_TIMESTAMP,
+    "deleted_at" timestamptz,
+    "event_id" varchar(255) NOT NULL,
+    "model" varchar(255),
+    "entity_id" bigint,
+    "version" bigint,
+    PRIMARY KEY ("id"),
+    UNIQUE ("event_id")
+) WITH (OIDS = FALSE);
+
+CREATE INDEX "consumed_events_created_at" ON "consumed_events" ("created_at");
+
+CREATE TABLE "entity_versions" (
+    "id" bigserial,
+    "created_at" timestamptz DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" timestamptz DEFAULT CURRENT_TIMESTAMP,
+    "deleted_at" timestamptz,
+    "model" varchar(255) NOT NULL,
+    "entity_id" bigint NOT NULL,
+    "version" bigint,
+    PRIMARY KEY ("id"),
+    UNIQUE ("model", "entity_id")
+) WITH (OIDS = FALSE);
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+
+DROP TABLE IF EXISTS "consumed_events" CASCADE;
+DROP TABLE IF EXISTS "entity_versions" CASCADE;
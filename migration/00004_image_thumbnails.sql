@@ -0,0 +1,13 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+
+ALTER TABLE "agents" ADD COLUMN "thumbnails" jsonb;
+ALTER TABLE "banks" ADD COLUMN "thumbnails" jsonb;
+ALTER TABLE "services" ADD COLUMN "thumbnails" jsonb;
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+
+ALTER TABLE "agents" DROP COLUMN IF EXISTS "thumbnails";
+ALTER TABLE "banks" DROP COLUMN IF EXISTS "thumbnails";
+ALTER TABLE "services" DROP COLUMN IF EXISTS "thumbnails";
@@ -0,0 +1,42 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+
+CREATE TABLE "document_types" (
+    "id" bigserial,
+    "created_at" timestamptz DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" timestamptz DEFAULT CURRENT_TIMESTAMP,
+    "deleted_at" timestamptz,
+    "name" varchar(255),
+    "description" text,
+    "status" varchar(255) DEFAULT ('active'),
+    PRIMARY KEY ("id")
+) WITH (OIDS = FALSE);
+
+CREATE TABLE "borrower_documents" (
+    "id" bigserial,
+    "created_at" timestamptz DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" timestamptz DEFAULT CURRENT_TIMESTAMP,
+    "deleted_at" timestamptz,
+    "borrower" bigint,
+    "loan" bigint DEFAULT (0),
+    "bank" bigint,
+    "document_type" bigint,
+    "file" text,
+    "content_type" varchar(255),
+    "status" varchar(255) DEFAULT ('pending'),
+    "reject_reason" text,
+    "note" text,
+    "requested_by" bigint,
+    "verified_by" bigint,
+    "verified_at" timestamptz,
+    FOREIGN KEY ("document_type") REFERENCES document_types(id),
+    PRIMARY KEY ("id")
+) WITH (OIDS = FALSE);
+
+CREATE INDEX "borrower_documents_borrower_loan" ON "borrower_documents" ("borrower", "loan");
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+
+DROP TABLE IF EXISTS "borrower_documents" CASCADE;
+DROP TABLE IF EXISTS "document_types" CASCADE;
@@ -0,0 +1,22 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+
+CREATE TABLE "email_templates" (
+    "id" bigserial,
+    "created_at" timestamptz DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" timestamptz DEFAULT CURRENT_TIMESTAMP,
+    "deleted_at" timestamptz,
+    "event" varchar(255) NOT NULL,
+    "language" varchar(255) NOT NULL,
+    "subject" text,
+    "html_body" text,
+    "text_body" text,
+    "description" text,
+    PRIMARY KEY ("id"),
+    UNIQUE ("event", "language")
+) WITH (OIDS = FALSE);
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+
+DROP TABLE IF EXISTS "email_templates" CASCADE;
@@ -0,0 +1,28 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+
+CREATE TABLE "email_queues" (
+    "id" bigserial,
+    "created_at" timestamptz DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" timestamptz DEFAULT CURRENT_TIMESTAMP,
+    "deleted_at" timestamptz,
+    "event" varchar(255),
+    "recipients" varchar(255) ARRAY,
+    "subject" text,
+    "text_body" text,
+    "html_body" text,
+    "attachments" jsonb,
+    "status" varchar(255) DEFAULT ('pending'),
+    "attempts" int DEFAULT (0),
+    "last_error" text,
+    "next_attempt_at" timestamptz DEFAULT CURRENT_TIMESTAMP,
+    "sent_at" timestamptz,
+    PRIMARY KEY ("id")
+) WITH (OIDS = FALSE);
+
+CREATE INDEX "email_queues_status_next_attempt_at" ON "email_queues" ("status", "next_attempt_at");
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+
+DROP TABLE IF EXISTS "email_queues" CASCADE;
@@ -0,0 +1,39 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+
+CREATE TABLE "notifications" (
+    "id" bigserial,
+    "created_at" timestamptz DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" timestamptz DEFAULT CURRENT_TIMESTAMP,
+    "deleted_at" timestamptz,
+    "user_id" bigint,
+    "bank" bigint,
+    "event" varchar(255),
+    "entity" varchar(255),
+    "entity_id" bigint,
+    "title" varchar(255),
+    "message" text,
+    "read_at" timestamptz,
+    PRIMARY KEY ("id")
+) WITH (OIDS = FALSE);
+
+CREATE INDEX "notifications_user_id_read_at" ON "notifications" ("user_id", "read_at");
+
+CREATE TABLE "notification_preferences" (
+    "id" bigserial,
+    "created_at" timestamptz DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" timestamptz DEFAULT CURRENT_TIMESTAMP,
+    "deleted_at" timestamptz,
+    "user_id" bigint NOT NULL,
+    "event" varchar(255) NOT NULL,
+    "in_app" boolean DEFAULT TRUE,
+    "email" boolean DEFAULT TRUE,
+    PRIMARY KEY ("id"),
+    UNIQUE ("user_id", "event")
+) WITH (OIDS = FALSE);
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+
+DROP TABLE IF EXISTS "notifications" CASCADE;
+DROP TABLE IF EXISTS "notification_preferences" CASCADE;
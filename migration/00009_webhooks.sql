@@ -0,0 +1,49 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+
+CREATE TABLE "webhooks" (
+    "id" bigserial,
+    "created_at" timestamptz DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" timestamptz DEFAULT CURRENT_TIMESTAMP,
+    "deleted_at" timestamptz,
+    "bank" bigint,
+    "url" text,
+    "secret" varchar(255),
+    "events" varchar(255) ARRAY,
+    "description" text,
+    "status" varchar(255) DEFAULT ('active'),
+    FOREIGN KEY ("bank") REFERENCES banks(id),
+    PRIMARY KEY ("id")
+) WITH (OIDS = FALSE);
+
+CREATE TABLE "webhook_deliveries" (
+    "id" bigserial,
+    "created_at" timestamptz DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" timestamptz DEFAULT CURRENT_TIMESTAMP,
+    "deleted_at" timestamptz,
+    "webhook" bigint,
+    "bank" bigint,
+    "event" varchar(255),
+    "reference" varchar(255),
+    "payload" jsonb,
+    "status" varchar(255) DEFAULT ('pending'),
+    "attempts" int DEFAULT (0),
+    "response_code" int,
+    "response_body" text,
+    "last_error" text,
+    "next_attempt_at" timestamptz DEFAULT CURRENT_TIMESTAMP,
+    "delivered_at" timestamptz,
+    FOREIGN KEY ("webhook") REFERENCES webhooks(id),
+    FOREIGN KEY ("bank") REFERENCES banks(id),
+    PRIMARY KEY ("id")
+) WITH (OIDS = FALSE);
+
+CREATE INDEX "webhooks_bank" ON "webhooks" ("bank");
+CREATE INDEX "webhook_deliveries_webhook_event_reference" ON "webhook_deliveries" ("webhook", "event", "reference");
+CREATE INDEX "webhook_deliveries_status_next_attempt_at" ON "webhook_deliveries" ("status", "next_attempt_at");
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+
+DROP TABLE IF EXISTS "webhook_deliveries" CASCADE;
+DROP TABLE IF EXISTS "webhooks" CASCADE;
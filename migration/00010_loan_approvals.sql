@@ -0,0 +1,66 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+
+CREATE TABLE "approval_policies" (
+    "id" bigserial,
+    "created_at" timestamptz DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" timestamptz DEFAULT CURRENT_TIMESTAMP,
+    "deleted_at" timestamptz,
+    "bank" bigint,
+    "product" bigint DEFAULT (0),
+    "name" varchar(255),
+    "min_amount" bigint DEFAULT (0),
+    "max_amount" bigint DEFAULT (0),
+    "steps" jsonb,
+    "status" varchar(255) DEFAULT ('active'),
+    FOREIGN KEY ("bank") REFERENCES banks(id),
+    PRIMARY KEY ("id")
+) WITH (OIDS = FALSE);
+
+CREATE TABLE "loan_approvals" (
+    "id" bigserial,
+    "created_at" timestamptz DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" timestamptz DEFAULT CURRENT_TIMESTAMP,
+    "deleted_at" timestamptz,
+    "loan" bigint,
+    "bank" bigint,
+    "policy" bigint,
+    "steps" jsonb,
+    "current_step" int DEFAULT (0),
+    "step_role" bigint,
+    "status" varchar(255) DEFAULT ('pending'),
+    "disburse_date" timestamptz,
+    FOREIGN KEY ("loan") REFERENCES loans(id),
+    FOREIGN KEY ("bank") REFERENCES banks(id),
+    FOREIGN KEY ("policy") REFERENCES approval_policies(id),
+    UNIQUE ("loan"),
+    PRIMARY KEY ("id")
+) WITH (OIDS = FALSE);
+
+CREATE TABLE "loan_approval_actions" (
+    "id" bigserial,
+    "created_at" timestamptz DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" timestamptz DEFAULT CURRENT_TIMESTAMP,
+    "deleted_at" timestamptz,
+    "loan_approval" bigint,
+    "loan" bigint,
+    "step" int,
+    "user_id" bigint,
+    "role" bigint,
+    "action" varchar(255),
+    "note" text,
+    FOREIGN KEY ("loan_approval") REFERENCES loan_approvals(id),
+    FOREIGN KEY ("user_id") REFERENCES users(id),
+    UNIQUE ("loan_approval", "user_id"),
+    PRIMARY KEY ("id")
+) WITH (OIDS = FALSE);
+
+CREATE INDEX "approval_policies_bank" ON "approval_policies" ("bank");
+CREATE INDEX "loan_approvals_bank_status_step_role" ON "loan_approvals" ("bank", "status", "step_role");
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+
+DROP TABLE IF EXISTS "loan_approval_actions" CASCADE;
+DROP TABLE IF EXISTS "loan_approvals" CASCADE;
+DROP TABLE IF EXISTS "approval_policies" CASCADE;
@@ -0,0 +1,34 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+
+CREATE TABLE "scorecards" (
+    "id" bigserial,
+    "created_at" timestamptz DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" timestamptz DEFAULT CURRENT_TIMESTAMP,
+    "deleted_at" timestamptz,
+    "product" bigint,
+    "name" varchar(255),
+    "rules" jsonb,
+    "grades" jsonb,
+    "status" varchar(255) DEFAULT ('active'),
+    FOREIGN KEY ("product") REFERENCES products(id),
+    PRIMARY KEY ("id")
+) WITH (OIDS = FALSE);
+
+ALTER TABLE "loans" ADD COLUMN "scorecard" bigint DEFAULT (0);
+ALTER TABLE "loans" ADD COLUMN "credit_score" float8 DEFAULT (0);
+ALTER TABLE "loans" ADD COLUMN "risk_grade" varchar(255);
+ALTER TABLE "loans" ADD COLUMN "score_factors" jsonb;
+
+CREATE INDEX "scorecards_product_status" ON "scorecards" ("product", "status");
+CREATE INDEX "loans_credit_score" ON "loans" ("credit_score");
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+
+DROP INDEX IF EXISTS "loans_credit_score";
+ALTER TABLE "loans" DROP COLUMN IF EXISTS "score_factors";
+ALTER TABLE "loans" DROP COLUMN IF EXISTS "risk_grade";
+ALTER TABLE "loans" DROP COLUMN IF EXISTS "credit_score";
+ALTER TABLE "loans" DROP COLUMN IF EXISTS "scorecard";
+DROP TABLE IF EXISTS "scorecards" CASCADE;
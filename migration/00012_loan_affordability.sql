@@ -0,0 +1,15 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+
+ALTER TABLE "products" ADD COLUMN "max_dti" float8 DEFAULT (0);
+ALTER TABLE "products" ADD COLUMN "hard_dti" float8 DEFAULT (0);
+ALTER TABLE "loans" ADD COLUMN "dti" float8 DEFAULT (0);
+ALTER TABLE "loans" ADD COLUMN "dti_exceeded" boolean DEFAULT FALSE;
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+
+ALTER TABLE "loans" DROP COLUMN IF EXISTS "dti_exceeded";
+ALTER TABLE "loans" DROP COLUMN IF EXISTS "dti";
+ALTER TABLE "products" DROP COLUMN IF EXISTS "hard_dti";
+ALTER TABLE "products" DROP COLUMN IF EXISTS "max_dti";
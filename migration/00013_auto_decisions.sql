@@ -0,0 +1,43 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+
+CREATE TABLE "auto_decision_rules" (
+    "id" bigserial,
+    "created_at" timestamptz DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" timestamptz DEFAULT CURRENT_TIMESTAMP,
+    "deleted_at" timestamptz,
+    "product" bigint,
+    "name" varchar(255),
+    "priority" int DEFAULT (0),
+    "conditions" jsonb,
+    "action" varchar(255),
+    "disburse_offset" int DEFAULT (0),
+    "reject_reason" text,
+    "status" varchar(255) DEFAULT ('active'),
+    FOREIGN KEY ("product") REFERENCES products(id),
+    PRIMARY KEY ("id")
+) WITH (OIDS = FALSE);
+
+CREATE TABLE "loan_auto_decisions" (
+    "id" bigserial,
+    "created_at" timestamptz DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" timestamptz DEFAULT CURRENT_TIMESTAMP,
+    "deleted_at" timestamptz,
+    "loan" bigint,
+    "rule" bigint,
+    "rule_name" varchar(255),
+    "action" varchar(255),
+    "facts" jsonb,
+    FOREIGN KEY ("loan") REFERENCES loans(id),
+    FOREIGN KEY ("rule") REFERENCES auto_decision_rules(id),
+    UNIQUE ("loan"),
+    PRIMARY KEY ("id")
+) WITH (OIDS = FALSE);
+
+CREATE INDEX "auto_decision_rules_product_status" ON "auto_decision_rules" ("product", "status");
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+
+DROP TABLE IF EXISTS "loan_auto_decisions" CASCADE;
+DROP TABLE IF EXISTS "auto_decision_rules" CASCADE;
@@ -0,0 +1,29 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+
+CREATE TABLE "fraud_flags" (
+    "id" bigserial,
+    "created_at" timestamptz DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" timestamptz DEFAULT CURRENT_TIMESTAMP,
+    "deleted_at" timestamptz,
+    "borrower" bigint,
+    "loan" bigint DEFAULT (0),
+    "agent" bigint DEFAULT (0),
+    "kind" varchar(255),
+    "matches" bigint ARRAY,
+    "detail" text,
+    "status" varchar(255) DEFAULT ('open'),
+    "reviewed_by" bigint DEFAULT (0),
+    "review_note" text,
+    "reviewed_at" timestamptz,
+    FOREIGN KEY ("borrower") REFERENCES borrowers(id),
+    PRIMARY KEY ("id")
+) WITH (OIDS = FALSE);
+
+CREATE INDEX "fraud_flags_borrower_kind" ON "fraud_flags" ("borrower", "kind");
+CREATE INDEX "fraud_flags_status" ON "fraud_flags" ("status");
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+
+DROP TABLE IF EXISTS "fraud_flags" CASCADE;
@@ -0,0 +1,23 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+
+CREATE TABLE "watchlists" (
+    "id" bigserial,
+    "created_at" timestamptz DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" timestamptz DEFAULT CURRENT_TIMESTAMP,
+    "deleted_at" timestamptz,
+    "bank" bigint,
+    "kind" varchar(255),
+    "value" varchar(255),
+    "reason" text,
+    "status" varchar(255) DEFAULT ('active'),
+    FOREIGN KEY ("bank") REFERENCES banks(id),
+    PRIMARY KEY ("id")
+) WITH (OIDS = FALSE);
+
+CREATE INDEX "watchlists_kind_value" ON "watchlists" ("kind", "value");
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+
+DROP TABLE IF EXISTS "watchlists" CASCADE;
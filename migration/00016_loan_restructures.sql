@@ -0,0 +1,33 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+
+CREATE TABLE "loan_restructures" (
+    "id" bigserial,
+    "created_at" timestamptz DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" timestamptz DEFAULT CURRENT_TIMESTAMP,
+    "deleted_at" timestamptz,
+    "loan" bigint,
+    "tenor" int,
+    "interest" float8 DEFAULT (0),
+    "grace_period" int DEFAULT (0),
+    "capitalise_arrears" boolean DEFAULT FALSE,
+    "first_due_date" timestamptz,
+    "principal" float8 DEFAULT (0),
+    "arrears" float8 DEFAULT (0),
+    "old_installments" bigint ARRAY,
+    "new_installments" bigint ARRAY,
+    "previous_plan" float8 DEFAULT (0),
+    "new_plan" float8 DEFAULT (0),
+    "reason" text,
+    "requested_by" bigint,
+    FOREIGN KEY ("loan") REFERENCES loans(id),
+    PRIMARY KEY ("id")
+) WITH (OIDS = FALSE);
+
+ALTER TABLE "installments" ADD COLUMN "closed_by" bigint DEFAULT (0);
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+
+ALTER TABLE "installments" DROP COLUMN IF EXISTS "closed_by";
+DROP TABLE IF EXISTS "loan_restructures" CASCADE;
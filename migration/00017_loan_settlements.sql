@@ -0,0 +1,32 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+
+ALTER TABLE "products" ADD COLUMN "early_settlement_fee" float8 DEFAULT (0);
+
+CREATE TABLE "loan_settlements" (
+    "id" bigserial,
+    "created_at" timestamptz DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" timestamptz DEFAULT CURRENT_TIMESTAMP,
+    "deleted_at" timestamptz,
+    "loan" bigint,
+    "date" timestamptz,
+    "remaining_principal" float8 DEFAULT (0),
+    "accrued_interest" float8 DEFAULT (0),
+    "penalties" float8 DEFAULT (0),
+    "underpayment" float8 DEFAULT (0),
+    "settlement_fee_rate" float8 DEFAULT (0),
+    "settlement_fee" float8 DEFAULT (0),
+    "total" float8 DEFAULT (0),
+    "installments" bigint ARRAY,
+    "paid_amount" float8 DEFAULT (0),
+    "note" text,
+    "settled_by" bigint,
+    FOREIGN KEY ("loan") REFERENCES loans(id),
+    PRIMARY KEY ("id")
+) WITH (OIDS = FALSE);
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+
+DROP TABLE IF EXISTS "loan_settlements" CASCADE;
+ALTER TABLE "products" DROP COLUMN IF EXISTS "early_settlement_fee";
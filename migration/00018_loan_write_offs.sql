@@ -0,0 +1,51 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+
+CREATE TABLE "loan_write_offs" (
+    "id" bigserial,
+    "created_at" timestamptz DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" timestamptz DEFAULT CURRENT_TIMESTAMP,
+    "deleted_at" timestamptz,
+    "loan" bigint,
+    "bank" bigint,
+    "days_past_due" int DEFAULT (0),
+    "principal" float8 DEFAULT (0),
+    "interest" float8 DEFAULT (0),
+    "penalties" float8 DEFAULT (0),
+    "amount" float8 DEFAULT (0),
+    "recovered" float8 DEFAULT (0),
+    "installments" bigint ARRAY,
+    "reason" text,
+    "status" varchar(255) DEFAULT ('pending'),
+    "requested_by" bigint,
+    "decided_by" bigint,
+    "decision_note" text,
+    "decided_at" timestamptz,
+    FOREIGN KEY ("loan") REFERENCES loans(id),
+    FOREIGN KEY ("bank") REFERENCES banks(id),
+    PRIMARY KEY ("id")
+) WITH (OIDS = FALSE);
+
+CREATE UNIQUE INDEX "loan_write_offs_pending_loan" ON "loan_write_offs" ("loan") WHERE "status" = 'pending' AND "deleted_at" IS NULL;
+
+CREATE TABLE "loan_recoveries" (
+    "id" bigserial,
+    "created_at" timestamptz DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" timestamptz DEFAULT CURRENT_TIMESTAMP,
+    "deleted_at" timestamptz,
+    "write_off" bigint,
+    "loan" bigint,
+    "amount" float8 DEFAULT (0),
+    "date" timestamptz,
+    "note" text,
+    "recorded_by" bigint,
+    FOREIGN KEY ("write_off") REFERENCES loan_write_offs(id),
+    FOREIGN KEY ("loan") REFERENCES loans(id),
+    PRIMARY KEY ("id")
+) WITH (OIDS = FALSE);
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+
+DROP TABLE IF EXISTS "loan_recoveries" CASCADE;
+DROP TABLE IF EXISTS "loan_write_offs" CASCADE;
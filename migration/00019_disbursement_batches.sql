@@ -0,0 +1,45 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+
+CREATE TABLE "disbursement_batches" (
+    "id" bigserial,
+    "created_at" timestamptz DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" timestamptz DEFAULT CURRENT_TIMESTAMP,
+    "deleted_at" timestamptz,
+    "bank" bigint,
+    "reference" varchar(255),
+    "date" timestamptz,
+    "format" varchar(255),
+    "count" int DEFAULT (0),
+    "total_amount" float8 DEFAULT (0),
+    "confirmed" int DEFAULT (0),
+    "failed" int DEFAULT (0),
+    "status" varchar(255) DEFAULT ('generated'),
+    "created_by" bigint,
+    FOREIGN KEY ("bank") REFERENCES banks(id),
+    PRIMARY KEY ("id")
+) WITH (OIDS = FALSE);
+
+CREATE TABLE "disbursement_batch_items" (
+    "id" bigserial,
+    "created_at" timestamptz DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" timestamptz DEFAULT CURRENT_TIMESTAMP,
+    "deleted_at" timestamptz,
+    "batch" bigint,
+    "loan" bigint,
+    "reference" varchar(255) UNIQUE,
+    "account_number" varchar(255),
+    "account_name" varchar(255),
+    "amount" float8 DEFAULT (0),
+    "status" varchar(255) DEFAULT ('pending'),
+    "message" text,
+    FOREIGN KEY ("batch") REFERENCES disbursement_batches(id),
+    FOREIGN KEY ("loan") REFERENCES loans(id),
+    PRIMARY KEY ("id")
+) WITH (OIDS = FALSE);
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+
+DROP TABLE IF EXISTS "disbursement_batch_items" CASCADE;
+DROP TABLE IF EXISTS "disbursement_batches" CASCADE;
@@ -0,0 +1,51 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+
+CREATE TABLE "bank_statements" (
+    "id" bigserial,
+    "created_at" timestamptz DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" timestamptz DEFAULT CURRENT_TIMESTAMP,
+    "deleted_at" timestamptz,
+    "bank" bigint,
+    "file_name" varchar(255),
+    "format" varchar(255),
+    "account_number" varchar(255),
+    "credits" int DEFAULT (0),
+    "matched" int DEFAULT (0),
+    "ambiguous" int DEFAULT (0),
+    "unmatched" int DEFAULT (0),
+    "duplicates" int DEFAULT (0),
+    "applied" int DEFAULT (0),
+    "status" varchar(255) DEFAULT ('reviewing'),
+    "uploaded_by" bigint,
+    FOREIGN KEY ("bank") REFERENCES banks(id),
+    PRIMARY KEY ("id")
+) WITH (OIDS = FALSE);
+
+CREATE TABLE "bank_statement_lines" (
+    "id" bigserial,
+    "created_at" timestamptz DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" timestamptz DEFAULT CURRENT_TIMESTAMP,
+    "deleted_at" timestamptz,
+    "statement" bigint,
+    "line" int,
+    "date" timestamptz,
+    "amount" float8 DEFAULT (0),
+    "reference" varchar(255),
+    "account_number" varchar(255),
+    "description" text,
+    "status" varchar(255),
+    "installment" bigint,
+    "loan" bigint,
+    "candidates" bigint ARRAY,
+    FOREIGN KEY ("statement") REFERENCES bank_statements(id),
+    PRIMARY KEY ("id")
+) WITH (OIDS = FALSE);
+
+CREATE INDEX "bank_statement_lines_installment" ON "bank_statement_lines" ("installment");
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+
+DROP TABLE IF EXISTS "bank_statement_lines" CASCADE;
+DROP TABLE IF EXISTS "bank_statements" CASCADE;
@@ -2,6 +2,7 @@ package migration
 
 import (
 	"asira_lender/asira"
+	"asira_lender/email"
 	"asira_lender/middlewares"
 	"asira_lender/models"
 	"database/sql"
@@ -344,14 +345,14 @@ func Seed() {
 				Status:      "active",
 				Description: "Ops",
 				System:      "Core",
-				Permissions: pq.StringArray{"core_create_client", "core_view_image", "core_borrower_get_all", "core_borrower_get_details", "core_loan_get_all", "core_loan_get_details", "core_bank_type_list", "core_bank_type_new", "core_bank_type_detail", "core_bank_type_patch", "core_bank_list", "core_bank_new", "core_bank_detail", "core_bank_patch", "core_service_list", "core_service_new", "core_service_detail", "core_service_patch", "core_product_list", "core_product_new", "core_product_detail", "core_product_patch", "core_loan_purpose_list", "core_loan_purpose_new", "core_loan_purpose_detail", "core_loan_purpose_patch", "core_role_list", "core_role_details", "core_role_new", "core_role_patch", "core_role_range", "core_permission_list", "core_user_list", "core_user_details", "core_user_new", "core_user_patch", "convenience_fee_report", "lender_loan_patch_payment_status"},
+				Permissions: pq.StringArray{"core_create_client", "core_view_image", "core_borrower_get_all", "core_borrower_get_details", "core_borrower_credit_history", "core_loan_get_all", "core_loan_get_details", "core_bank_type_list", "core_bank_type_new", "core_bank_type_detail", "core_bank_type_patch", "core_document_type_list", "core_document_type_new", "core_document_type_detail", "core_document_type_patch", "core_email_template_list", "core_email_template_new", "core_email_template_detail", "core_email_template_patch", "core_email_queue_list", "core_email_queue_detail", "core_email_queue_resend", "core_webhook_list", "core_webhook_new", "core_webhook_detail", "core_webhook_patch", "core_webhook_delete", "core_webhook_test", "core_webhook_delivery_list", "core_approval_policy_list", "core_approval_policy_new", "core_approval_policy_detail", "core_approval_policy_patch", "core_approval_policy_delete", "core_scorecard_list", "core_scorecard_new", "core_scorecard_detail", "core_scorecard_patch", "core_scorecard_delete", "core_loan_score", "core_auto_decision_rule_list", "core_auto_decision_rule_new", "core_auto_decision_rule_detail", "core_auto_decision_rule_patch", "core_auto_decision_rule_delete", "core_auto_decision_rule_test", "core_auto_decision_list", "core_fraud_flag_list", "core_fraud_flag_detail", "core_fraud_flag_review", "core_fraud_flag_scan", "core_watchlist_list", "core_watchlist_new", "core_watchlist_detail", "core_watchlist_patch", "core_watchlist_delete", "core_watchlist_import", "core_bank_list", "core_bank_new", "core_bank_detail", "core_bank_patch", "core_service_list", "core_service_new", "core_service_detail", "core_service_patch", "core_product_list", "core_product_new", "core_product_detail", "core_product_patch", "core_loan_purpose_list", "core_loan_purpose_new", "core_loan_purpose_detail", "core_loan_purpose_patch", "core_role_list", "core_role_details", "core_role_new", "core_role_patch", "core_role_range", "core_permission_list", "core_user_list", "core_user_details", "core_user_new", "core_user_patch", "convenience_fee_report", "write_off_report", "lender_loan_patch_payment_status"},
 			},
 			models.Roles{
 				Name:        "Banker",
 				Status:      "active",
 				Description: "ini untuk Finance",
 				System:      "Dashboard",
-				Permissions: pq.StringArray{"lender_profile", "lender_profile_edit", "lender_loan_request_list", "lender_loan_request_detail", "lender_loan_approve_reject", "lender_loan_request_list_download", "lender_borrower_list", "lender_borrower_list_detail", "lender_borrower_list_download", "lender_prospective_borrower_approval", "lender_product_list", "lender_product_list_detail", "lender_loan_installment_approve", "lender_loan_installment_approve_bulk", "lender_service_list", "lender_service_list_detail"},
+				Permissions: pq.StringArray{"lender_profile", "lender_profile_edit", "lender_loan_request_list", "lender_loan_request_detail", "lender_loan_approve_reject", "lender_loan_restructure", "lender_loan_payoff_quote", "lender_loan_early_settlement", "lender_loan_write_off", "lender_loan_write_off_approve", "lender_loan_recovery", "lender_disbursement_batch_list", "lender_disbursement_batch_new", "lender_disbursement_batch_result", "lender_bank_statement_list", "lender_bank_statement_upload", "lender_bank_statement_confirm", "lender_loan_request_list_download", "lender_borrower_list", "lender_borrower_list_detail", "lender_borrower_credit_history", "lender_view_image", "lender_borrower_list_download", "lender_borrower_document_list", "lender_borrower_document_new", "lender_borrower_document_detail", "lender_borrower_document_patch", "lender_borrower_document_verify", "lender_notification_list", "lender_notification_read", "lender_notification_preference", "lender_loan_approval_task_list", "lender_loan_approval_detail", "lender_prospective_borrower_approval", "lender_product_list", "lender_product_list_detail", "lender_loan_installment_approve", "lender_loan_installment_approve_bulk", "lender_service_list", "lender_service_list_detail"},
 			},
 		}
 		for _, role := range roles {
@@ -707,6 +708,40 @@ func Seed() {
 			faq.Create()
 			middlewares.SubmitKafkaPayload(faq, "faq_create")
 		}
+
+		documentTypes := []models.DocumentType{
+			models.DocumentType{
+				Name:        "Slip Gaji",
+				Description: "Slip gaji 3 bulan terakhir",
+				Status:      "active",
+			},
+			models.DocumentType{
+				Name:        "Surat Keterangan Kerja",
+				Description: "Surat keterangan kerja dari perusahaan",
+				Status:      "active",
+			},
+			models.DocumentType{
+				Name:        "Foto Jaminan",
+				Description: "Foto barang jaminan pinjaman",
+				Status:      "active",
+			},
+		}
+		for _, documentType := range documentTypes {
+			documentType.Create()
+		}
+
+		for event, templates := range email.DefaultTemplates {
+			for language, template := range templates {
+				emailTemplate := models.EmailTemplate{
+					Event:    event,
+					Language: language,
+					Subject:  template.Subject,
+					HTMLBody: template.HTML,
+					TextBody: template.Text,
+				}
+				emailTemplate.Create()
+			}
+		}
 	}
 }
 
@@ -1177,14 +1212,14 @@ func TestSeed() {
 				Status:      "active",
 				Description: "Ops",
 				System:      "Core",
-				Permissions: pq.StringArray{"core_create_client", "core_view_image", "core_borrower_get_all", "core_borrower_get_details", "core_loan_get_all", "core_loan_get_details", "core_bank_type_list", "core_bank_type_new", "core_bank_type_detail", "core_bank_type_patch", "core_bank_list", "core_bank_new", "core_bank_detail", "core_bank_patch", "core_service_list", "core_service_new", "core_service_detail", "core_service_patch", "core_product_list", "core_product_new", "core_product_detail", "core_product_patch", "core_loan_purpose_list", "core_loan_purpose_new", "core_loan_purpose_detail", "core_loan_purpose_patch", "core_role_list", "core_role_details", "core_role_new", "core_role_patch", "core_role_range", "core_permission_list", "core_user_list", "core_user_details", "core_user_new", "core_user_patch", "convenience_fee_report"},
+				Permissions: pq.StringArray{"core_create_client", "core_view_image", "core_borrower_get_all", "core_borrower_get_details", "core_borrower_credit_history", "core_loan_get_all", "core_loan_get_details", "core_bank_type_list", "core_bank_type_new", "core_bank_type_detail", "core_bank_type_patch", "core_document_type_list", "core_document_type_new", "core_document_type_detail", "core_document_type_patch", "core_email_template_list", "core_email_template_new", "core_email_template_detail", "core_email_template_patch", "core_email_queue_list", "core_email_queue_detail", "core_email_queue_resend", "core_webhook_list", "core_webhook_new", "core_webhook_detail", "core_webhook_patch", "core_webhook_delete", "core_webhook_test", "core_webhook_delivery_list", "core_approval_policy_list", "core_approval_policy_new", "core_approval_policy_detail", "core_approval_policy_patch", "core_approval_policy_delete", "core_scorecard_list", "core_scorecard_new", "core_scorecard_detail", "core_scorecard_patch", "core_scorecard_delete", "core_loan_score", "core_auto_decision_rule_list", "core_auto_decision_rule_new", "core_auto_decision_rule_detail", "core_auto_decision_rule_patch", "core_auto_decision_rule_delete", "core_auto_decision_rule_test", "core_auto_decision_list", "core_fraud_flag_list", "core_fraud_flag_detail", "core_fraud_flag_review", "core_fraud_flag_scan", "core_watchlist_list", "core_watchlist_new", "core_watchlist_detail", "core_watchlist_patch", "core_watchlist_delete", "core_watchlist_import", "core_bank_list", "core_bank_new", "core_bank_detail", "core_bank_patch", "core_service_list", "core_service_new", "core_service_detail", "core_service_patch", "core_product_list", "core_product_new", "core_product_detail", "core_product_patch", "core_loan_purpose_list", "core_loan_purpose_new", "core_loan_purpose_detail", "core_loan_purpose_patch", "core_role_list", "core_role_details", "core_role_new", "core_role_patch", "core_role_range", "core_permission_list", "core_user_list", "core_user_details", "core_user_new", "core_user_patch", "convenience_fee_report", "write_off_report"},
 			},
 			models.Roles{
 				Name:        "Banker",
 				Status:      "active",
 				Description: "ini untuk Finance",
 				System:      "Dashboard",
-				Permissions: pq.StringArray{"lender_profile", "lender_profile_edit", "lender_loan_request_list", "lender_loan_request_detail", "lender_loan_approve_reject", "lender_loan_request_list_download", "lender_borrower_list", "lender_borrower_list_detail", "lender_borrower_list_download", "lender_prospective_borrower_approval", "lender_product_list", "lender_product_list_detail", "lender_loan_installment_approve", "lender_loan_installment_approve_bulk", "lender_loan_patch_payment_status", "lender_service_list", "lender_service_list_detail"},
+				Permissions: pq.StringArray{"lender_profile", "lender_profile_edit", "lender_loan_request_list", "lender_loan_request_detail", "lender_loan_approve_reject", "lender_loan_restructure", "lender_loan_payoff_quote", "lender_loan_early_settlement", "lender_loan_write_off", "lender_loan_write_off_approve", "lender_loan_recovery", "lender_disbursement_batch_list", "lender_disbursement_batch_new", "lender_disbursement_batch_result", "lender_bank_statement_list", "lender_bank_statement_upload", "lender_bank_statement_confirm", "lender_loan_request_list_download", "lender_borrower_list", "lender_borrower_list_detail", "lender_borrower_credit_history", "lender_view_image", "lender_borrower_list_download", "lender_borrower_document_list", "lender_borrower_document_new", "lender_borrower_document_detail", "lender_borrower_document_patch", "lender_borrower_document_verify", "lender_notification_list", "lender_notification_read", "lender_notification_preference", "lender_loan_approval_task_list", "lender_loan_approval_detail", "lender_prospective_borrower_approval", "lender_product_list", "lender_product_list_detail", "lender_loan_installment_approve", "lender_loan_installment_approve_bulk", "lender_loan_patch_payment_status", "lender_service_list", "lender_service_list_detail"},
 			},
 		}
 		for _, role := range roles {
@@ -1368,6 +1403,109 @@ func TestSeed() {
 			faq.Create()
 		}
 
+		documentTypes := []models.DocumentType{
+			models.DocumentType{
+				Name:        "Slip Gaji",
+				Description: "Slip gaji 3 bulan terakhir",
+				Status:      "active",
+			},
+			models.DocumentType{
+				Name:        "Surat Keterangan Kerja",
+				Description: "Surat keterangan kerja dari perusahaan",
+				Status:      "active",
+			},
+			models.DocumentType{
+				Name:        "Foto Jaminan",
+				Description: "Foto barang jaminan pinjaman",
+				Status:      "active",
+			},
+		}
+		for _, documentType := range documentTypes {
+			documentType.Create()
+		}
+
+		for event, templates := range email.DefaultTemplates {
+			for language, template := range templates {
+				emailTemplate := models.EmailTemplate{
+					Event:    event,
+					Language: language,
+					Subject:  template.Subject,
+					HTMLBody: template.HTML,
+					TextBody: template.Text,
+				}
+				emailTemplate.Create()
+			}
+		}
+
+		borrowerDocuments := []models.BorrowerDocument{
+			models.BorrowerDocument{
+				Borrower:     1,
+				Loan:         1,
+				Bank:         1,
+				DocumentType: 1,
+				Note:         "mohon unggah slip gaji terbaru",
+				RequestedBy:  3,
+				Status:       "requested",
+			},
+		}
+		for _, borrowerDocument := range borrowerDocuments {
+			borrowerDocument.Create()
+		}
+
+		emailQueues := []models.EmailQueue{
+			models.EmailQueue{
+				Event:         "user_password",
+				Recipients:    pq.StringArray{"toib@ayannah.com"},
+				Subject:       "[NO REPLY] - Password Aplikasi ASIRA",
+				TextBody:      "Ini adalah password anda untuk login password",
+				Status:        "failed",
+				Attempts:      5,
+				LastError:     "dial tcp: i/o timeout",
+				NextAttemptAt: time.Now(),
+			},
+		}
+		for _, emailQueue := range emailQueues {
+			emailQueue.Create()
+		}
+
+		notifications := []models.Notification{
+			models.Notification{
+				UserID:   3,
+				Bank:     1,
+				Event:    "new_loan",
+				Entity:   "loan",
+				EntityID: 1,
+				Title:    "Pengajuan pinjaman baru",
+				Message:  "Nasabah Full Name A mengajukan pinjaman 1 sebesar 5000000",
+			},
+			models.Notification{
+				UserID:   3,
+				Bank:     1,
+				Event:    "new_borrower",
+				Entity:   "borrower",
+				EntityID: 1,
+				Title:    "Nasabah baru",
+				Message:  "Nasabah baru Full Name A terdaftar",
+			},
+		}
+		for _, notification := range notifications {
+			notification.Create()
+		}
+
+		webhooks := []models.Webhook{
+			models.Webhook{
+				Bank:        1,
+				URL:         "http://localhost:1/asira/webhook",
+				Secret:      "webhooksecret",
+				Events:      pq.StringArray{"loan_approved", "loan_disbursed", "loan_paid"},
+				Description: "core banking bank A",
+				Status:      "active",
+			},
+		}
+		for _, webhook := range webhooks {
+			webhook.Create()
+		}
+
 	}
 }
 
@@ -1390,6 +1528,33 @@ func Truncate(tableList []string) (err error) {
 				"loan_purposes",
 				"agent_providers",
 				"faqs",
+				"outboxes",
+				"consumed_events",
+				"entity_versions",
+				"document_types",
+				"borrower_documents",
+				"email_templates",
+				"email_queues",
+				"notifications",
+				"notification_preferences",
+				"webhooks",
+				"webhook_deliveries",
+				"approval_policies",
+				"loan_approvals",
+				"loan_approval_actions",
+				"scorecards",
+				"auto_decision_rules",
+				"loan_auto_decisions",
+				"fraud_flags",
+				"watchlists",
+				"loan_restructures",
+				"loan_settlements",
+				"loan_write_offs",
+				"loan_recoveries",
+				"disbursement_batches",
+				"disbursement_batch_items",
+				"bank_statements",
+				"bank_statement_lines",
 			}
 		}
 
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"asira_lender/asira"
+	"fmt"
+	"math"
+)
+
+// Affordability debt to income of borrower once loan is approved, dti is in percent
+type Affordability struct {
+	Income            float64 `json:"income"`
+	LoanInstallment   float64 `json:"loan_installment"`
+	OtherInstallments float64 `json:"other_installments"`
+	DTI               float64 `json:"dti"`
+	MaxDTI            float64 `json:"max_dti"`
+	HardDTI           float64 `json:"hard_dti"`
+	Exceeded          bool    `json:"exceeded"`
+	Blocked           bool    `json:"blocked"`
+	Warning           string  `json:"warning,omitempty"`
+}
+
+// LoanAffordability computes dti of loan from monthly and other income of borrower against layaway plan
+// of the loan plus every other approved loan of borrower not paid yet. borrowers without income are at 100%
+func LoanAffordability(loan Loan) (affordability Affordability, err error) {
+	borrower := Borrower{}
+	if err = borrower.FindbyID(loan.Borrower); err != nil {
+		return affordability, err
+	}
+	product := Product{}
+	if loan.Product > 0 {
+		product.FindbyID(loan.Product)
+	}
+
+	type Sum struct {
+		Total float64
+	}
+	other := Sum{}
+	err = asira.App.DB.Table("loans").
+		Select("COALESCE(SUM(layaway_plan), 0) as total").
+		Where("deleted_at IS NULL").
+		Where("borrower = ? AND id <> ?", loan.Borrower, loan.ID).
+		Where("status = ?", "approved").
+		Where("COALESCE(payment_status, '') NOT IN (?)", []string{"terbayar", "hapus_buku"}).
+		Scan(&other).Error
+	if err != nil {
+		return affordability, err
+	}
+
+	affordability = Affordability{
+		Income:            float64(borrower.MonthlyIncome + borrower.OtherIncome),
+		LoanInstallment:   loan.LayawayPlan,
+		OtherInstallments: other.Total,
+		MaxDTI:            product.MaxDTI,
+		HardDTI:           product.HardDTI,
+	}
+
+	installments := affordability.LoanInstallment + affordability.OtherInstallments
+	switch {
+	case affordability.Income > 0:
+		affordability.DTI = math.Round(installments/affordability.Income*10000) / 100
+	case installments > 0:
+		affordability.DTI = 100
+	}
+
+	affordability.Exceeded = affordability.MaxDTI > 0 && affordability.DTI > affordability.MaxDTI
+	affordability.Blocked = affordability.HardDTI > 0 && affordability.DTI > affordability.HardDTI
+	switch {
+	case affordability.Blocked:
+		affordability.Warning = fmt.Sprintf("DTI %v%% melebihi batas maksimal persetujuan %v%%", affordability.DTI, affordability.HardDTI)
+	case affordability.Exceeded:
+		affordability.Warning = fmt.Sprintf("DTI %v%% melebihi batas DTI produk %v%%", affordability.DTI, affordability.MaxDTI)
+	}
+
+	return affordability, nil
+}
@@ -3,10 +3,10 @@ package models
 import (
 	"asira_lender/email"
 	"database/sql"
-	"fmt"
 	"math/rand"
 
 	"github.com/ayannahindonesia/basemodel"
+	"github.com/jinzhu/gorm/dialects/postgres"
 	"github.com/lib/pq"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -14,16 +14,17 @@ import (
 // Agent main type
 type Agent struct {
 	basemodel.BaseModel
-	Name          string        `json:"name" gorm:"column:name"`
-	Username      string        `json:"username" gorm:"column:username"`
-	Password      string        `json:"password" gorm:"column:password"`
-	Image         string        `json:"image" gorm:"column:image"`
-	Email         string        `json:"email" gorm:"column:email"`
-	Phone         string        `json:"phone" gorm:"column:phone"`
-	Category      string        `json:"category" gorm:"column:category"`
-	AgentProvider sql.NullInt64 `json:"agent_provider" gorm:"column:agent_provider"`
-	Banks         pq.Int64Array `json:"banks" gorm:"column:banks"`
-	Status        string        `json:"status" gorm:"column:status"`
+	Name          string          `json:"name" gorm:"column:name"`
+	Username      string          `json:"username" gorm:"column:username"`
+	Password      string          `json:"password" gorm:"column:password"`
+	Image         string          `json:"image" gorm:"column:image"`
+	Thumbnails    *postgres.Jsonb `json:"thumbnails" gorm:"column:thumbnails;type:jsonb"`
+	Email         string          `json:"email" gorm:"column:email"`
+	Phone         string          `json:"phone" gorm:"column:phone"`
+	Category      string          `json:"category" gorm:"column:category"`
+	AgentProvider sql.NullInt64   `json:"agent_provider" gorm:"column:agent_provider"`
+	Banks         pq.Int64Array   `json:"banks" gorm:"column:banks"`
+	Status        string          `json:"status" gorm:"column:status"`
 }
 
 // BeforeCreate gorm callback
@@ -81,9 +82,11 @@ func (model *Agent) PagedFilterSearch(page int, rows int, order []string, sort [
 
 // SendPasswordEmail sends password to agent
 func (model *Agent) SendPasswordEmail(password string) {
-	message := fmt.Sprintf("Selamat bergabung dengan asira sebagai Agent. anda dapat login menggunakan username dengan password : %v", password)
-
-	email.SendMail(model.Email, "Selamat Bergabung dengan Asira", message)
+	SendEmail("", email.AgentPasswordData{
+		Name:     model.Name,
+		Username: model.Username,
+		Password: password,
+	}, model.Email)
 }
 
 func randString(n int) string {
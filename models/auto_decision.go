@@ -0,0 +1,215 @@
+package models
+
+import (
+	"asira_lender/asira"
+	"encoding/json"
+	"strings"
+
+	"github.com/ayannahindonesia/basemodel"
+	"github.com/jinzhu/gorm/dialects/postgres"
+)
+
+type (
+	// AutoDecisionCondition check on one fact of new loan. numeric facts compare with value using
+	// lt, lte, gt, gte or eq. text facts use empty, not_empty or in values
+	AutoDecisionCondition struct {
+		Fact     string   `json:"fact"`
+		Operator string   `json:"operator"`
+		Value    float64  `json:"value"`
+		Values   []string `json:"values,omitempty"`
+	}
+
+	// AutoDecisionRule decides new loans of product matching every condition without manual review.
+	// rules are evaluated by priority, lowest first, and the first matching rule fires
+	AutoDecisionRule struct {
+		basemodel.BaseModel
+		Product        uint64         `json:"product" gorm:"column:product;foreignkey"`
+		Name           string         `json:"name" gorm:"column:name;type:varchar(255)"`
+		Priority       int            `json:"priority" gorm:"column:priority;type:int"`
+		Conditions     postgres.Jsonb `json:"conditions" gorm:"column:conditions;type:jsonb"`
+		Action         string         `json:"action" gorm:"column:action;type:varchar(255)"`
+		DisburseOffset int            `json:"disburse_offset" gorm:"column:disburse_offset;type:int"` // days from decision to disburse date of approved loans
+		RejectReason   string         `json:"reject_reason" gorm:"column:reject_reason;type:text"`
+		Status         string         `json:"status" gorm:"column:status;type:varchar(255)" sql:"DEFAULT:'active'"`
+	}
+
+	// LoanAutoDecision decision made on loan by auto decision rule and facts it was based on
+	LoanAutoDecision struct {
+		basemodel.BaseModel
+		Loan     uint64         `json:"loan" gorm:"column:loan;foreignkey"`
+		Rule     uint64         `json:"rule" gorm:"column:rule;foreignkey"`
+		RuleName string         `json:"rule_name" gorm:"column:rule_name;type:varchar(255)"`
+		Action   string         `json:"action" gorm:"column:action;type:varchar(255)"`
+		Facts    postgres.Jsonb `json:"facts" gorm:"column:facts;type:jsonb"`
+	}
+)
+
+// AutoDecisionNumericFacts loan facts compared with numeric operators
+var AutoDecisionNumericFacts = []string{
+	"loan_amount",
+	"installment",
+	"credit_score",
+	"dti",
+	"paid_loans",
+	"monthly_income",
+	"total_income",
+}
+
+// AutoDecisionTextFacts loan facts compared with text operators
+var AutoDecisionTextFacts = []string{
+	"bank_account",
+	"risk_grade",
+}
+
+// Create func
+func (model *AutoDecisionRule) Create() error {
+	return basemodel.Create(&model)
+}
+
+// Save func
+func (model *AutoDecisionRule) Save() error {
+	return basemodel.Save(&model)
+}
+
+// Delete func
+func (model *AutoDecisionRule) Delete() error {
+	return basemodel.Delete(&model)
+}
+
+// FindbyID func
+func (model *AutoDecisionRule) FindbyID(id uint64) error {
+	return basemodel.FindbyID(&model, id)
+}
+
+// PagedFindFilter func
+func (model *AutoDecisionRule) PagedFindFilter(page int, rows int, orderby []string, sort []string, filter interface{}) (basemodel.PagedFindResult, error) {
+	rules := []AutoDecisionRule{}
+
+	return basemodel.PagedFindFilter(&rules, page, rows, orderby, sort, filter)
+}
+
+// ConditionList conditions of rule
+func (model *AutoDecisionRule) ConditionList() (conditions []AutoDecisionCondition) {
+	json.Unmarshal(model.Conditions.RawMessage, &conditions)
+
+	return conditions
+}
+
+// Matches true when every condition of rule holds for facts
+func (model *AutoDecisionRule) Matches(facts map[string]interface{}) bool {
+	conditions := model.ConditionList()
+	if len(conditions) < 1 {
+		return false
+	}
+	for _, condition := range conditions {
+		if !condition.holds(facts[condition.Fact]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (condition AutoDecisionCondition) holds(fact interface{}) bool {
+	switch v := fact.(type) {
+	case float64:
+		switch condition.Operator {
+		case "lt":
+			return v < condition.Value
+		case "lte":
+			return v <= condition.Value
+		case "gt":
+			return v > condition.Value
+		case "gte":
+			return v >= condition.Value
+		case "eq":
+			return v == condition.Value
+		}
+	case string:
+		switch condition.Operator {
+		case "empty":
+			return len(strings.TrimSpace(v)) < 1
+		case "not_empty":
+			return len(strings.TrimSpace(v)) > 0
+		case "in":
+			for _, value := range condition.Values {
+				if strings.EqualFold(value, v) {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// Create func
+func (model *LoanAutoDecision) Create() error {
+	return basemodel.Create(&model)
+}
+
+// SingleFindFilter func
+func (model *LoanAutoDecision) SingleFindFilter(filter interface{}) error {
+	return basemodel.SingleFindFilter(&model, filter)
+}
+
+// PagedFindFilter func
+func (model *LoanAutoDecision) PagedFindFilter(page int, rows int, orderby []string, sort []string, filter interface{}) (basemodel.PagedFindResult, error) {
+	decisions := []LoanAutoDecision{}
+
+	return basemodel.PagedFindFilter(&decisions, page, rows, orderby, sort, filter)
+}
+
+// AutoDecisionFacts facts of loan auto decision rules are evaluated against
+func AutoDecisionFacts(loan Loan) (facts map[string]interface{}, err error) {
+	borrower := Borrower{}
+	if err = borrower.FindbyID(loan.Borrower); err != nil {
+		return nil, err
+	}
+
+	var paidLoans int
+	err = asira.App.DB.Model(&Loan{}).
+		Where("borrower = ? AND id <> ?", loan.Borrower, loan.ID).
+		Where("status = ? AND payment_status = ?", "approved", "terbayar").
+		Count(&paidLoans).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"loan_amount":    loan.LoanAmount,
+		"installment":    float64(loan.Installment),
+		"credit_score":   loan.CreditScore,
+		"dti":            loan.DTI,
+		"paid_loans":     float64(paidLoans),
+		"monthly_income": float64(borrower.MonthlyIncome),
+		"total_income":   float64(borrower.MonthlyIncome + borrower.OtherIncome),
+		"bank_account":   borrower.BankAccountNumber,
+		"risk_grade":     loan.RiskGrade,
+	}, nil
+}
+
+// MatchAutoDecisionRule finds first active rule of loan product matching loan facts.
+// matched is false when loan has to be reviewed manually
+func MatchAutoDecisionRule(loan Loan) (rule AutoDecisionRule, facts map[string]interface{}, matched bool, err error) {
+	rules := []AutoDecisionRule{}
+	err = asira.App.DB.Where("product = ? AND status = ?", loan.Product, "active").
+		Order("priority asc, id asc").
+		Find(&rules).Error
+	if err != nil || len(rules) < 1 {
+		return rule, nil, false, err
+	}
+
+	facts, err = AutoDecisionFacts(loan)
+	if err != nil {
+		return rule, nil, false, err
+	}
+
+	for _, rule = range rules {
+		if rule.Matches(facts) {
+			return rule, facts, true, nil
+		}
+	}
+
+	return AutoDecisionRule{}, facts, false, nil
+}
@@ -4,21 +4,23 @@ import (
 	"github.com/lib/pq"
 
 	"github.com/ayannahindonesia/basemodel"
+	"github.com/jinzhu/gorm/dialects/postgres"
 )
 
 // Bank main type
 type Bank struct {
 	basemodel.BaseModel
-	Name     string        `json:"name" gorm:"column:name;type:varchar(255)"`
-	Image    string        `json:"image" gorm:"column:image;type:text"`
-	Type     uint64        `json:"type" gorm:"column:type;type:bigserial"`
-	Address  string        `json:"address" gorm:"column:address;type:text"`
-	Province string        `json:"province" gorm:"column:province;type:varchar(255)"`
-	City     string        `json:"city" gorm:"column:city;type:varchar(255)"`
-	PIC      string        `json:"pic" gorm:"column:pic;type:varchar(255)"`
-	Phone    string        `json:"phone" gorm:"column:phone;type:varchar(255)"`
-	Services pq.Int64Array `json:"services" gorm "column:services"`
-	Products pq.Int64Array `json:"products" gorm "column:products"`
+	Name       string          `json:"name" gorm:"column:name;type:varchar(255)"`
+	Image      string          `json:"image" gorm:"column:image;type:text"`
+	Thumbnails *postgres.Jsonb `json:"thumbnails" gorm:"column:thumbnails;type:jsonb"`
+	Type       uint64          `json:"type" gorm:"column:type;type:bigserial"`
+	Address    string          `json:"address" gorm:"column:address;type:text"`
+	Province   string          `json:"province" gorm:"column:province;type:varchar(255)"`
+	City       string          `json:"city" gorm:"column:city;type:varchar(255)"`
+	PIC        string          `json:"pic" gorm:"column:pic;type:varchar(255)"`
+	Phone      string          `json:"phone" gorm:"column:phone;type:varchar(255)"`
+	Services   pq.Int64Array   `json:"services" gorm "column:services"`
+	Products   pq.Int64Array   `json:"products" gorm "column:products"`
 }
 
 // Create func
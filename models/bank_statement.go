@@ -0,0 +1,574 @@
+package models
+
+import (
+	"asira_lender/asira"
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ayannahindonesia/basemodel"
+	"github.com/jinzhu/gorm"
+	"github.com/lib/pq"
+)
+
+type (
+	// BankStatement bank statement uploaded by lender to reconcile installment repayments
+	BankStatement struct {
+		basemodel.BaseModel
+		Bank          uint64 `json:"bank" gorm:"column:bank;foreignkey"`
+		FileName      string `json:"file_name" gorm:"column:file_name;type:varchar(255)"`
+		Format        string `json:"format" gorm:"column:format;type:varchar(255)"`
+		AccountNumber string `json:"account_number" gorm:"column:account_number;type:varchar(255)"` // statement account, mt940 only
+		Credits       int    `json:"credits" gorm:"column:credits;type:int"`
+		Matched       int    `json:"matched" gorm:"column:matched;type:int"`
+		Ambiguous     int    `json:"ambiguous" gorm:"column:ambiguous;type:int"`
+		Unmatched     int    `json:"unmatched" gorm:"column:unmatched;type:int"`
+		Duplicates    int    `json:"duplicates" gorm:"column:duplicates;type:int"`
+		Applied       int    `json:"applied" gorm:"column:applied;type:int"`
+		Status        string `json:"status" gorm:"column:status;type:varchar(255)" sql:"DEFAULT:'reviewing'"`
+		UploadedBy    uint64 `json:"uploaded_by" gorm:"column:uploaded_by"`
+	}
+
+	// BankStatementLine credit of bank statement and the installment it pays
+	BankStatementLine struct {
+		basemodel.BaseModel
+		Statement     uint64        `json:"statement" gorm:"column:statement;foreignkey"`
+		Line          int           `json:"line" gorm:"column:line;type:int"`
+		Date          time.Time     `json:"date" gorm:"column:date"`
+		Amount        float64       `json:"amount" gorm:"column:amount;type:float8"`
+		Reference     string        `json:"reference" gorm:"column:reference;type:varchar(255)"`
+		AccountNumber string        `json:"account_number" gorm:"column:account_number;type:varchar(255)"` // payer account
+		Description   string        `json:"description" gorm:"column:description;type:text"`
+		Status        string        `json:"status" gorm:"column:status;type:varchar(255)"`
+		Installment   uint64        `json:"installment" gorm:"column:installment"`
+		Loan          uint64        `json:"loan" gorm:"column:loan"`
+		Candidates    pq.Int64Array `json:"candidates" gorm:"column:candidates"` // installments an ambiguous credit may pay
+	}
+
+	// OutstandingInstallment unpaid installment of approved loan with the loan borrower bank account
+	OutstandingInstallment struct {
+		Installment
+		Loan          uint64 `json:"loan"`
+		AccountNumber string `json:"account_number"`
+	}
+)
+
+// bank statement formats
+const (
+	StatementFormatCSV   = "csv"
+	StatementFormatMT940 = "mt940"
+)
+
+// StatementFormats every supported bank statement format
+var StatementFormats = []string{StatementFormatCSV, StatementFormatMT940}
+
+var (
+	// loan id written by borrower in transfer reference, e.g. "LOAN 12" or "pinjaman#12"
+	statementLoanReference = regexp.MustCompile(`(?i)\b(?:loan|pinjaman)\s*[-#:]?\s*(\d+)`)
+	statementAccountNumber = regexp.MustCompile(`\d{6,}`)
+	mt940Transaction       = regexp.MustCompile(`^(\d{6})(\d{4})?(R?[CD])[A-Z]?(\d+(?:,\d*)?)(?:[NFS][A-Z0-9]{3})?([^/]*)(?://(.*))?$`)
+	statementCreditTypes   = map[string]bool{"cr": true, "c": true, "credit": true, "kredit": true, "k": true}
+	statementDateLayouts   = []string{"2006-01-02", "02/01/2006", "02-01-2006", "2006/01/02", "02/01/06"}
+)
+
+// FindbyID func
+func (model *BankStatement) FindbyID(id uint64) error {
+	return basemodel.FindbyID(&model, id)
+}
+
+// PagedFindFilter func
+func (model *BankStatement) PagedFindFilter(page int, rows int, orderby []string, sort []string, filter interface{}) (basemodel.PagedFindResult, error) {
+	statements := []BankStatement{}
+
+	return basemodel.PagedFindFilter(&statements, page, rows, orderby, sort, filter)
+}
+
+// Lines credits of statement, filtered by status when not empty
+func (model *BankStatement) Lines(status string) (lines []BankStatementLine, err error) {
+	db := asira.App.DB.Where("statement = ?", model.ID)
+	if len(status) > 0 {
+		db = db.Where("status = ?", status)
+	}
+	err = db.Order("line asc").Find(&lines).Error
+
+	return lines, err
+}
+
+// ParseBankStatement reads credits of bank statement file. csv files need date and amount, or credit, columns with
+// optional type, reference, account_number and description columns. mt940 credits are read from :61: and :86: fields
+func ParseBankStatement(format string, file io.Reader) (lines []BankStatementLine, account string, err error) {
+	if format == StatementFormatMT940 {
+		return parseMT940(file)
+	}
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, "", err
+	}
+	columns := map[string]int{}
+	for i, column := range header {
+		columns[strings.ToLower(strings.TrimSpace(column))] = i
+	}
+	_, hasDate := columns["date"]
+	_, hasAmount := columns["amount"]
+	_, hasCredit := columns["credit"]
+	if !hasDate || (!hasAmount && !hasCredit) {
+		return nil, "", fmt.Errorf("kolom date dan amount atau credit dibutuhkan")
+	}
+	column := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("baris %v : %v", line, err)
+		}
+
+		var amount float64
+		if hasCredit {
+			amount, err = parseStatementAmount(column(record, "credit"))
+		} else {
+			amount, err = parseStatementAmount(column(record, "amount"))
+			if kind := strings.ToLower(column(record, "type")); len(kind) > 0 && !statementCreditTypes[kind] {
+				continue
+			}
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("baris %v : nominal tidak valid", line)
+		}
+		if amount <= 0 {
+			continue
+		}
+		date, err := parseStatementDate(column(record, "date"))
+		if err != nil {
+			return nil, "", fmt.Errorf("baris %v : tanggal tidak valid", line)
+		}
+
+		lines = append(lines, BankStatementLine{
+			Line:          line,
+			Date:          date,
+			Amount:        amount,
+			Reference:     column(record, "reference"),
+			AccountNumber: column(record, "account_number"),
+			Description:   column(record, "description"),
+		})
+	}
+
+	return lines, "", nil
+}
+
+func parseMT940(file io.Reader) (lines []BankStatementLine, account string, err error) {
+	scanner := bufio.NewScanner(file)
+	var (
+		current *BankStatementLine
+		credit  bool
+		field   string
+	)
+	flush := func() {
+		if current != nil && credit {
+			current.Description = strings.TrimSpace(current.Description)
+			lines = append(lines, *current)
+		}
+		current = nil
+	}
+
+	for number := 1; scanner.Scan(); number++ {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if strings.HasPrefix(text, ":") {
+			if i := strings.Index(text[1:], ":"); i > 0 {
+				field = text[1 : i+1]
+				text = text[i+2:]
+			}
+			switch field {
+			case "25":
+				account = strings.TrimSpace(text)
+			case "61":
+				flush()
+				match := mt940Transaction.FindStringSubmatch(strings.TrimSpace(text))
+				if match == nil {
+					return nil, account, fmt.Errorf("baris %v : transaksi :61: tidak valid", number)
+				}
+				date, err := time.ParseInLocation("060102", match[1], time.Local)
+				if err != nil {
+					return nil, account, fmt.Errorf("baris %v : tanggal tidak valid", number)
+				}
+				amount, _ := strconv.ParseFloat(strings.Replace(match[4], ",", ".", 1), 64)
+				// reversal of debit is a credit as well
+				credit = match[3] == "C" || match[3] == "RD"
+				current = &BankStatementLine{
+					Line:      number,
+					Date:      date,
+					Amount:    amount,
+					Reference: strings.TrimSpace(match[5]),
+				}
+				if strings.EqualFold(current.Reference, "NONREF") {
+					current.Reference = ""
+				}
+			case "86":
+				if current != nil {
+					current.Description = text
+				}
+			default:
+				flush()
+			}
+			continue
+		}
+		if strings.HasPrefix(text, "-") {
+			flush()
+			continue
+		}
+		// :86: continues on the following lines
+		if field == "86" && current != nil {
+			current.Description += " " + strings.TrimSpace(text)
+		}
+	}
+	flush()
+
+	return lines, account, scanner.Err()
+}
+
+// parseStatementAmount reads amounts like 1200000, 1.200.000,00 or 1,200,000.00
+func parseStatementAmount(value string) (float64, error) {
+	value = strings.NewReplacer(" ", "", "Rp", "", "IDR", "").Replace(value)
+	if len(value) < 1 {
+		return 0, nil
+	}
+	dot, comma := strings.LastIndex(value, "."), strings.LastIndex(value, ",")
+	switch {
+	case dot >= 0 && comma >= 0:
+		if comma > dot {
+			value = strings.Replace(strings.Replace(value, ".", "", -1), ",", ".", 1)
+		} else {
+			value = strings.Replace(value, ",", "", -1)
+		}
+	case comma >= 0:
+		if strings.Count(value, ",") == 1 && len(value)-comma-1 != 3 {
+			value = strings.Replace(value, ",", ".", 1)
+		} else {
+			value = strings.Replace(value, ",", "", -1)
+		}
+	case dot >= 0:
+		if strings.Count(value, ".") > 1 || len(value)-dot-1 == 3 {
+			value = strings.Replace(value, ".", "", -1)
+		}
+	}
+
+	return strconv.ParseFloat(value, 64)
+}
+
+func parseStatementDate(value string) (date time.Time, err error) {
+	for _, layout := range statementDateLayouts {
+		if date, err = time.ParseInLocation(layout, value, time.Local); err == nil {
+			return date, nil
+		}
+	}
+
+	return date, err
+}
+
+// BankOutstandingInstallments unpaid installments of approved loans of bank in period order, leaving out installments
+// already paid by an applied statement credit
+func BankOutstandingInstallments(bankID uint64) (installments []OutstandingInstallment, err error) {
+	err = outstandingInstallments(bankID).
+		Order("loans.id asc, installments.period asc").
+		Scan(&installments).Error
+
+	return installments, err
+}
+
+// FindOutstandingInstallment outstanding installment of bank by id
+func FindOutstandingInstallment(bankID uint64, id uint64) (installment OutstandingInstallment, err error) {
+	err = outstandingInstallments(bankID).
+		Where("installments.id = ?", id).
+		Limit(1).
+		Scan(&installment).Error
+
+	return installment, err
+}
+
+func outstandingInstallments(bankID uint64) *gorm.DB {
+	return asira.App.DB.Table("installments").
+		Select("installments.*, loans.id as loan, b.bank_accountnumber as account_number").
+		Joins("INNER JOIN loans ON installments.id = ANY(loans.installment_id)").
+		Joins("INNER JOIN borrowers b ON b.id = loans.borrower").
+		Where("installments.deleted_at IS NULL AND loans.deleted_at IS NULL").
+		Where("b.bank = ?", bankID).
+		Where("loans.status = ? AND loans.otp_verified = ?", "approved", true).
+		Where("COALESCE(loans.payment_status, '') NOT IN (?)", []string{"terbayar", "hapus_buku"}).
+		Where("installments.paid_status = ? AND COALESCE(installments.closed_by, 0) = 0", false).
+		Where("NOT EXISTS (SELECT 1 FROM bank_statement_lines s WHERE s.installment = installments.id AND s.status = ? AND s.deleted_at IS NULL)", "applied")
+}
+
+// installmentDue amount borrower has to transfer for installment
+func installmentDue(installment Installment) float64 {
+	return installment.LoanPayment + installment.InterestPayment + installment.Penalty
+}
+
+// statementMatcher matches credits to the next outstanding installment of loans, each installment is matched once
+type statementMatcher struct {
+	loans    map[uint64][]OutstandingInstallment
+	accounts map[string][]uint64
+	claimed  map[uint64]bool
+	order    []uint64
+}
+
+func newStatementMatcher(installments []OutstandingInstallment) *statementMatcher {
+	matcher := &statementMatcher{
+		loans:    map[uint64][]OutstandingInstallment{},
+		accounts: map[string][]uint64{},
+		claimed:  map[uint64]bool{},
+	}
+	for _, installment := range installments {
+		if _, ok := matcher.loans[installment.Loan]; !ok {
+			matcher.order = append(matcher.order, installment.Loan)
+			if account := strings.TrimSpace(installment.AccountNumber); len(account) > 0 {
+				matcher.accounts[account] = append(matcher.accounts[account], installment.Loan)
+			}
+		}
+		matcher.loans[installment.Loan] = append(matcher.loans[installment.Loan], installment)
+	}
+
+	return matcher
+}
+
+// next earliest installment of loan not matched yet
+func (m *statementMatcher) next(loan uint64) (OutstandingInstallment, bool) {
+	for _, installment := range m.loans[loan] {
+		if !m.claimed[installment.ID] {
+			return installment, true
+		}
+	}
+
+	return OutstandingInstallment{}, false
+}
+
+// identify loans credit refers to by loan reference, or else by payer account number
+func (m *statementMatcher) identify(line BankStatementLine) (loans []uint64) {
+	text := line.Reference + " " + line.Description
+	for _, match := range statementLoanReference.FindAllStringSubmatch(text, -1) {
+		id, _ := strconv.ParseUint(match[1], 10, 64)
+		if _, ok := m.loans[id]; ok {
+			loans = append(loans, id)
+		}
+	}
+	if len(loans) > 0 {
+		return loans
+	}
+
+	if account := strings.TrimSpace(line.AccountNumber); len(account) > 0 {
+		return m.accounts[account]
+	}
+	for _, account := range statementAccountNumber.FindAllString(line.Description, -1) {
+		loans = append(loans, m.accounts[account]...)
+	}
+
+	return loans
+}
+
+func (m *statementMatcher) match(line *BankStatementLine) {
+	line.Status = "unmatched"
+	line.Candidates = pq.Int64Array{}
+
+	loans := m.identify(*line)
+	identified := len(loans) > 0
+	if !identified {
+		// amount alone is never enough to match, it only suggests candidates
+		loans = m.order
+	}
+
+	var candidates, exact []OutstandingInstallment
+	for _, loan := range loans {
+		installment, ok := m.next(loan)
+		if !ok {
+			continue
+		}
+		candidates = append(candidates, installment)
+		if math.Abs(installmentDue(installment.Installment)-line.Amount) < 1 {
+			exact = append(exact, installment)
+		}
+	}
+
+	switch {
+	case identified && len(exact) == 1:
+		line.Status = "matched"
+		line.Installment = exact[0].ID
+		line.Loan = exact[0].Loan
+		m.claimed[exact[0].ID] = true
+		return
+	case len(exact) > 0:
+		candidates = exact
+	case !identified:
+		candidates = nil
+	}
+	for _, candidate := range candidates {
+		line.Candidates = append(line.Candidates, int64(candidate.ID))
+	}
+	if len(line.Candidates) > 0 {
+		line.Status = "ambiguous"
+	}
+}
+
+// ReconcileBankStatement stores statement credits of bank matched to outstanding installments. credits applied
+// before by another statement are marked duplicate
+func ReconcileBankStatement(statement *BankStatement, lines []BankStatementLine) error {
+	installments, err := BankOutstandingInstallments(statement.Bank)
+	if err != nil {
+		return err
+	}
+	matcher := newStatementMatcher(installments)
+
+	tx := asira.App.DB.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+
+	statement.Status = "reviewing"
+	if err = tx.Create(statement).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	for i := range lines {
+		line := &lines[i]
+		line.Statement = statement.ID
+		statement.Credits++
+
+		var applied int
+		tx.Table("bank_statement_lines").
+			Joins("INNER JOIN bank_statements st ON st.id = bank_statement_lines.statement").
+			Where("st.bank = ? AND bank_statement_lines.status = ?", statement.Bank, "applied").
+			Where("bank_statement_lines.date = ? AND bank_statement_lines.amount = ?", line.Date, line.Amount).
+			Where("bank_statement_lines.reference = ? AND bank_statement_lines.account_number = ? AND bank_statement_lines.description = ?", line.Reference, line.AccountNumber, line.Description).
+			Count(&applied)
+		if applied > 0 {
+			line.Status = "duplicate"
+			line.Candidates = pq.Int64Array{}
+		} else {
+			matcher.match(line)
+		}
+
+		switch line.Status {
+		case "matched":
+			statement.Matched++
+		case "ambiguous":
+			statement.Ambiguous++
+		case "unmatched":
+			statement.Unmatched++
+		case "duplicate":
+			statement.Duplicates++
+		}
+
+		if err = tx.Create(line).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if err = tx.Save(statement).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// ResolveLine applies line to installment, or ignores line when installment is nil. statement is completed once
+// no credit is left to resolve. applied installment is paid by line amount in place and its kafka payload written
+// through outbox in the same transaction
+func (model *BankStatement) ResolveLine(line *BankStatementLine, installment *OutstandingInstallment, outbox OutboxWriter) (err error) {
+	tx := asira.App.DB.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+
+	err = tx.Set("gorm:query_option", "FOR UPDATE").First(model, model.ID).Error
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// lock line so one credit is never resolved twice
+	err = tx.Set("gorm:query_option", "FOR UPDATE").Where("statement = ?", model.ID).First(line, line.ID).Error
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if line.Status != "matched" && line.Status != "ambiguous" && line.Status != "unmatched" {
+		tx.Rollback()
+		return ApprovalError{fmt.Sprintf("Mutasi sudah berstatus %v", line.Status)}
+	}
+
+	if installment == nil {
+		line.Status = "ignored"
+	} else {
+		// lock installment so it is never paid by two credits
+		paid := Installment{}
+		if err = tx.Set("gorm:query_option", "FOR UPDATE").First(&paid, installment.ID).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+		var applied int
+		tx.Model(&BankStatementLine{}).Where("installment = ? AND status = ?", installment.ID, "applied").Count(&applied)
+		if applied > 0 || paid.PaidStatus {
+			tx.Rollback()
+			return ApprovalError{fmt.Sprintf("Cicilan %v sudah dibayar oleh mutasi lain", installment.ID)}
+		}
+
+		// same update as lender entering the payment on the installment
+		paidDate := line.Date
+		paid.PaidStatus = true
+		paid.PaidDate = &paidDate
+		paid.PaidAmount = line.Amount
+		if due := paid.LoanPayment + paid.InterestPayment + paid.Penalty; line.Amount < due {
+			paid.Underpayment = due - line.Amount
+		}
+		paid.Note = fmt.Sprintf("rekonsiliasi mutasi %v baris %v", model.ID, line.Line)
+		if err = tx.Save(&paid).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err = outbox(tx, paid, "installment_update"); err != nil {
+			tx.Rollback()
+			return err
+		}
+		installment.Installment = paid
+
+		line.Status = "applied"
+		line.Installment = installment.ID
+		line.Loan = installment.Loan
+		model.Applied++
+	}
+	if err = tx.Save(line).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	var pending int
+	tx.Model(&BankStatementLine{}).Where("statement = ? AND status IN (?)", model.ID, []string{"matched", "ambiguous", "unmatched"}).Count(&pending)
+	if pending < 1 {
+		model.Status = "completed"
+	}
+	if err = tx.Save(model).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
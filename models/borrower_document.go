@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+
+	"github.com/ayannahindonesia/basemodel"
+)
+
+// BorrowerDocument additional document attached to borrower, optionally for a specific loan
+type BorrowerDocument struct {
+	basemodel.BaseModel
+	Borrower     uint64     `json:"borrower" gorm:"column:borrower;foreignkey"`
+	Loan         uint64     `json:"loan" gorm:"column:loan"`
+	Bank         uint64     `json:"bank" gorm:"column:bank;foreignkey"`
+	DocumentType uint64     `json:"document_type" gorm:"column:document_type;foreignkey"`
+	File         string     `json:"file" gorm:"column:file;type:text"`
+	ContentType  string     `json:"content_type" gorm:"column:content_type;type:varchar(255)"`
+	Status       string     `json:"status" gorm:"column:status;type:varchar(255)" sql:"DEFAULT:'pending'"`
+	RejectReason string     `json:"reject_reason" gorm:"column:reject_reason;type:text"`
+	Note         string     `json:"note" gorm:"column:note;type:text"`
+	RequestedBy  uint64     `json:"requested_by" gorm:"column:requested_by"`
+	VerifiedBy   uint64     `json:"verified_by" gorm:"column:verified_by"`
+	VerifiedAt   *time.Time `json:"verified_at" gorm:"column:verified_at"`
+}
+
+// Create func
+func (model *BorrowerDocument) Create() error {
+	return basemodel.Create(&model)
+}
+
+// Save func
+func (model *BorrowerDocument) Save() error {
+	return basemodel.Save(&model)
+}
+
+// Delete func
+func (model *BorrowerDocument) Delete() error {
+	return basemodel.Delete(&model)
+}
+
+// FindbyID func
+func (model *BorrowerDocument) FindbyID(id uint64) error {
+	return basemodel.FindbyID(&model, id)
+}
+
+// SingleFindFilter func
+func (model *BorrowerDocument) SingleFindFilter(filter interface{}) error {
+	return basemodel.SingleFindFilter(&model, filter)
+}
+
+// PagedFindFilter func
+func (model *BorrowerDocument) PagedFindFilter(page int, rows int, orderby []string, sort []string, filter interface{}) (basemodel.PagedFindResult, error) {
+	documents := []BorrowerDocument{}
+
+	return basemodel.PagedFindFilter(&documents, page, rows, orderby, sort, filter)
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"github.com/ayannahindonesia/basemodel"
+	"github.com/jinzhu/gorm"
+)
+
+// ConsumedEvent kafka message already applied by consumer
+type ConsumedEvent struct {
+	basemodel.BaseModel
+	EventID  string `json:"event_id" gorm:"column:event_id;type:varchar(255);unique_index"`
+	Model    string `json:"model" gorm:"column:model;type:varchar(255)"`
+	EntityID uint64 `json:"entity_id" gorm:"column:entity_id;type:bigint"`
+	Version  int64  `json:"version" gorm:"column:version;type:bigint"`
+}
+
+// Create func
+func (model *ConsumedEvent) Create() error {
+	return basemodel.Create(&model)
+}
+
+// CreateTx create inside the given transaction
+func (model *ConsumedEvent) CreateTx(tx *gorm.DB) error {
+	return tx.Create(model).Error
+}
+
+// Exists check whether event id has been consumed
+func (model *ConsumedEvent) Exists(eventID string) (bool, error) {
+	return model.ExistsTx(basemodel.DB, eventID)
+}
+
+// ExistsTx check whether event id has been consumed inside the given transaction
+func (model *ConsumedEvent) ExistsTx(tx *gorm.DB, eventID string) (bool, error) {
+	var count int
+	err := tx.Model(&ConsumedEvent{}).Where("event_id = ?", eventID).Count(&count).Error
+
+	return count > 0, err
+}
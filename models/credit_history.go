@@ -0,0 +1,236 @@
+package models
+
+import (
+	"asira_lender/asira"
+	"math"
+	"time"
+)
+
+type (
+	// CreditHistoryLoan track record of one loan of borrower
+	CreditHistoryLoan struct {
+		ID               uint64    `json:"id"`
+		CreatedAt        time.Time `json:"created_at"`
+		Product          uint64    `json:"product"`
+		Status           string    `json:"status"`
+		PaymentStatus    string    `json:"payment_status"`
+		LoanAmount       float64   `json:"loan_amount"`
+		TotalLoan        float64   `json:"total_loan"`
+		Installment      int       `json:"installment"`
+		DisburseDate     time.Time `json:"disburse_date"`
+		DueDate          time.Time `json:"due_date"`
+		Repaid           float64   `json:"repaid"`
+		Outstanding      float64   `json:"outstanding"`
+		PaidInstallments int       `json:"paid_installments"`
+		LateInstallments int       `json:"late_installments"`
+		MaxDPD           int       `json:"max_dpd"`
+	}
+
+	// CreditHistoryOtherBanks anonymised counts of loans borrower has with other banks, under the same id card number
+	CreditHistoryOtherBanks struct {
+		Banks           int `json:"banks"`
+		Loans           int `json:"loans"`
+		ApprovedLoans   int `json:"approved_loans"`
+		ActiveLoans     int `json:"active_loans"`
+		WrittenOffLoans int `json:"written_off_loans"`
+		MaxDPD          int `json:"max_dpd"`
+	}
+
+	// CreditHistory track record of borrower across all loans. on time ratio is in percent of paid installments
+	CreditHistory struct {
+		Borrower           uint64                   `json:"borrower"`
+		LoanStatus         string                   `json:"loan_status"`
+		TotalLoans         int                      `json:"total_loans"`
+		ApprovedLoans      int                      `json:"approved_loans"`
+		RejectedLoans      int                      `json:"rejected_loans"`
+		WrittenOffLoans    int                      `json:"written_off_loans"`
+		TotalBorrowed      float64                  `json:"total_borrowed"`
+		TotalRepaid        float64                  `json:"total_repaid"`
+		CurrentExposure    float64                  `json:"current_exposure"`
+		PaidInstallments   int                      `json:"paid_installments"`
+		OnTimeInstallments int                      `json:"on_time_installments"`
+		OnTimeRatio        float64                  `json:"on_time_ratio"`
+		MaxDPD             int                      `json:"max_dpd"`
+		Loans              []CreditHistoryLoan      `json:"loans"`
+		OtherBanks         *CreditHistoryOtherBanks `json:"other_banks,omitempty"`
+	}
+)
+
+// BorrowerCreditHistory summarizes every loan of borrower. otherBanks adds anonymised counts of loans
+// of borrowers with the same id card number registered to other banks
+func BorrowerCreditHistory(borrower Borrower, otherBanks bool) (history CreditHistory, err error) {
+	now := time.Now()
+	history = CreditHistory{
+		Borrower:   borrower.ID,
+		LoanStatus: "inactive",
+		Loans:      []CreditHistoryLoan{},
+	}
+
+	loans := []Loan{}
+	err = asira.App.DB.Where("borrower = ?", borrower.ID).
+		Order("created_at desc, id desc").
+		Find(&loans).Error
+	if err != nil {
+		return history, err
+	}
+
+	for _, loan := range loans {
+		record := CreditHistoryLoan{
+			ID:            loan.ID,
+			CreatedAt:     loan.CreatedAt,
+			Product:       loan.Product,
+			Status:        loan.Status,
+			PaymentStatus: loan.PaymentStatus,
+			LoanAmount:    loan.LoanAmount,
+			TotalLoan:     loan.TotalLoan,
+			Installment:   loan.Installment,
+			DisburseDate:  loan.DisburseDate,
+			DueDate:       loan.DueDate,
+		}
+
+		installments := []Installment{}
+		if len(loan.InstallmentID) > 0 {
+			err = asira.App.DB.Where("id IN (?)", []int64(loan.InstallmentID)).
+				Order("period asc").
+				Find(&installments).Error
+			if err != nil {
+				return history, err
+			}
+		}
+		for _, installment := range installments {
+			record.Repaid += installment.PaidAmount
+			dpd := installmentDPD(installment, now)
+			if dpd > record.MaxDPD {
+				record.MaxDPD = dpd
+			}
+			if !installment.PaidStatus {
+				continue
+			}
+			record.PaidInstallments++
+			if dpd > 0 {
+				record.LateInstallments++
+			}
+		}
+
+		switch loan.Status {
+		case "approved":
+			history.ApprovedLoans++
+			history.TotalBorrowed += loan.LoanAmount
+			switch loan.PaymentStatus {
+			case "terbayar":
+				record.Repaid = math.Max(record.Repaid, loan.TotalLoan)
+			case "hapus_buku":
+				// written off loans are out of the active portfolio, kept only as a track record
+				history.WrittenOffLoans++
+			default:
+				record.Outstanding = math.Max(loan.TotalLoan-record.Repaid, 0)
+				if dpd := daysPastDue(loan.DueDate, now); dpd > record.MaxDPD {
+					record.MaxDPD = dpd
+				}
+			}
+		case "rejected":
+			history.RejectedLoans++
+		}
+
+		if (loan.Status == "approved" || loan.Status == "processing") && loan.PaymentStatus == "processing" &&
+			(loan.DueDate.IsZero() || now.Before(loan.DueDate.AddDate(0, 0, 1))) {
+			history.LoanStatus = "active"
+		}
+
+		history.TotalRepaid += record.Repaid
+		history.CurrentExposure += record.Outstanding
+		history.PaidInstallments += record.PaidInstallments
+		history.OnTimeInstallments += record.PaidInstallments - record.LateInstallments
+		if record.MaxDPD > history.MaxDPD {
+			history.MaxDPD = record.MaxDPD
+		}
+		history.Loans = append(history.Loans, record)
+	}
+	history.TotalLoans = len(history.Loans)
+	if history.PaidInstallments > 0 {
+		history.OnTimeRatio = math.Round(float64(history.OnTimeInstallments)/float64(history.PaidInstallments)*10000) / 100
+	}
+
+	if otherBanks {
+		history.OtherBanks, err = otherBanksCreditHistory(borrower, now)
+	}
+
+	return history, err
+}
+
+func otherBanksCreditHistory(borrower Borrower, now time.Time) (*CreditHistoryOtherBanks, error) {
+	counts := CreditHistoryOtherBanks{}
+	if len(borrower.IdCardNumber) < 1 {
+		return &counts, nil
+	}
+
+	loans := []struct {
+		Bank          int64
+		Status        string
+		PaymentStatus string
+		DueDate       time.Time
+	}{}
+	err := asira.App.DB.Table("loans").
+		Select("b.bank, loans.status, loans.payment_status, loans.due_date").
+		Joins("INNER JOIN borrowers b ON b.id = loans.borrower").
+		Where("loans.deleted_at IS NULL").
+		Where("b.id_card_number = ? AND b.id <> ?", borrower.IdCardNumber, borrower.ID).
+		Where("b.bank IS DISTINCT FROM ?", borrower.Bank).
+		Scan(&loans).Error
+	if err != nil {
+		return nil, err
+	}
+
+	banks := map[int64]bool{}
+	for _, loan := range loans {
+		banks[loan.Bank] = true
+		counts.Loans++
+		if loan.Status != "approved" {
+			continue
+		}
+		counts.ApprovedLoans++
+		if loan.PaymentStatus == "hapus_buku" {
+			counts.WrittenOffLoans++
+		}
+		if loan.PaymentStatus == "terbayar" || loan.PaymentStatus == "hapus_buku" {
+			continue
+		}
+		counts.ActiveLoans++
+		if dpd := daysPastDue(loan.DueDate, now); dpd > counts.MaxDPD {
+			counts.MaxDPD = dpd
+		}
+	}
+	counts.Banks = len(banks)
+
+	return &counts, nil
+}
+
+// installmentDPD days installment was paid after due date, or is overdue when not paid yet. installments closed by
+// restructuring are not overdue
+func installmentDPD(installment Installment, now time.Time) int {
+	if installment.DueDate == nil || installment.ClosedBy > 0 {
+		return 0
+	}
+	if installment.PaidStatus && installment.PaidDate != nil {
+		return daysPastDue(*installment.DueDate, *installment.PaidDate)
+	}
+	if installment.PaidStatus {
+		return 0
+	}
+
+	return daysPastDue(*installment.DueDate, now)
+}
+
+func daysPastDue(dueDate time.Time, at time.Time) int {
+	if dueDate.IsZero() {
+		return 0
+	}
+	at = at.In(dueDate.Location())
+	due := time.Date(dueDate.Year(), dueDate.Month(), dueDate.Day(), 0, 0, 0, 0, dueDate.Location())
+	day := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, dueDate.Location())
+	if !day.After(due) {
+		return 0
+	}
+
+	return int(day.Sub(due).Hours() / 24)
+}
@@ -0,0 +1,346 @@
+package models
+
+import (
+	"asira_lender/asira"
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/ayannahindonesia/basemodel"
+)
+
+type (
+	// DisbursementBatch transfer batch file of approved loans disbursed on the same day
+	DisbursementBatch struct {
+		basemodel.BaseModel
+		Bank        uint64    `json:"bank" gorm:"column:bank;foreignkey"`
+		Reference   string    `json:"reference" gorm:"column:reference;type:varchar(255)"`
+		Date        time.Time `json:"date" gorm:"column:date"`
+		Format      string    `json:"format" gorm:"column:format;type:varchar(255)"`
+		Count       int       `json:"count" gorm:"column:count;type:int"`
+		TotalAmount float64   `json:"total_amount" gorm:"column:total_amount;type:float8"`
+		Confirmed   int       `json:"confirmed" gorm:"column:confirmed;type:int"`
+		Failed      int       `json:"failed" gorm:"column:failed;type:int"`
+		Status      string    `json:"status" gorm:"column:status;type:varchar(255)" sql:"DEFAULT:'generated'"`
+		CreatedBy   uint64    `json:"created_by" gorm:"column:created_by"`
+	}
+
+	// DisbursementBatchItem transfer of one loan in batch
+	DisbursementBatchItem struct {
+		basemodel.BaseModel
+		Batch         uint64  `json:"batch" gorm:"column:batch;foreignkey"`
+		Loan          uint64  `json:"loan" gorm:"column:loan;foreignkey"`
+		Reference     string  `json:"reference" gorm:"column:reference;type:varchar(255)"`
+		AccountNumber string  `json:"account_number" gorm:"column:account_number;type:varchar(255)"`
+		AccountName   string  `json:"account_name" gorm:"column:account_name;type:varchar(255)"`
+		Amount        float64 `json:"amount" gorm:"column:amount;type:float8"`
+		Status        string  `json:"status" gorm:"column:status;type:varchar(255)" sql:"DEFAULT:'pending'"`
+		Message       string  `json:"message" gorm:"column:message;type:text"`
+	}
+
+	// DisbursementResult outcome of one transfer read from bank result file
+	DisbursementResult struct {
+		Line      int    `json:"line"`
+		Reference string `json:"reference"`
+		Success   bool   `json:"success"`
+		Message   string `json:"message"`
+	}
+)
+
+// disbursement batch file formats
+const (
+	DisbursementFormatCSV        = "csv"
+	DisbursementFormatFixedWidth = "fixed_width"
+)
+
+// DisbursementFormats every supported batch file format
+var DisbursementFormats = []string{DisbursementFormatCSV, DisbursementFormatFixedWidth}
+
+// disbursementSuccess result statuses of successful transfers in csv result files
+var disbursementSuccess = map[string]bool{"success": true, "ok": true, "confirmed": true, "berhasil": true, "00": true}
+
+// FindbyID func
+func (model *DisbursementBatch) FindbyID(id uint64) error {
+	return basemodel.FindbyID(&model, id)
+}
+
+// PagedFindFilter func
+func (model *DisbursementBatch) PagedFindFilter(page int, rows int, orderby []string, sort []string, filter interface{}) (basemodel.PagedFindResult, error) {
+	batches := []DisbursementBatch{}
+
+	return basemodel.PagedFindFilter(&batches, page, rows, orderby, sort, filter)
+}
+
+// Items transfers of batch
+func (model *DisbursementBatch) Items() (items []DisbursementBatchItem, err error) {
+	err = asira.App.DB.Where("batch = ?", model.ID).Order("id asc").Find(&items).Error
+
+	return items, err
+}
+
+// CollectDisbursementBatch batches approved loans of bank with disburse date on date that are not disbursed yet,
+// marking them in batch. loans of borrowers without bank account are skipped. kafka payloads of collected loans are written
+// through outbox in the same transaction
+func CollectDisbursementBatch(bankID uint64, date time.Time, format string, userID uint64, outbox OutboxWriter) (batch DisbursementBatch, items []DisbursementBatchItem, skipped []uint64, err error) {
+	tx := asira.App.DB.Begin()
+	if tx.Error != nil {
+		return batch, nil, nil, tx.Error
+	}
+
+	// lock loans so one loan never ends up in two batches
+	loans := []Loan{}
+	err = tx.Set("gorm:query_option", "FOR UPDATE OF loans").
+		Table("loans").
+		Select("loans.*").
+		Joins("INNER JOIN borrowers b ON b.id = loans.borrower").
+		Where("loans.deleted_at IS NULL").
+		Where("b.bank = ?", bankID).
+		Where("loans.otp_verified = ?", true).
+		Where("loans.status = ?", "approved").
+		Where("loans.disburse_status IN (?)", []string{"processing", "failed"}).
+		Where("loans.disburse_date::date = ?::date", date.Format("2006-01-02")).
+		Order("loans.id asc").
+		Scan(&loans).Error
+	if err != nil {
+		tx.Rollback()
+		return batch, nil, nil, err
+	}
+
+	batch = DisbursementBatch{
+		Bank:      bankID,
+		Date:      date,
+		Format:    format,
+		Status:    "generated",
+		CreatedBy: userID,
+	}
+	batched := []Loan{}
+	for _, loan := range loans {
+		borrower := Borrower{}
+		if err = tx.First(&borrower, loan.Borrower).Error; err != nil || len(strings.TrimSpace(borrower.BankAccountNumber)) < 1 {
+			skipped = append(skipped, loan.ID)
+			continue
+		}
+		items = append(items, DisbursementBatchItem{
+			Loan:          loan.ID,
+			AccountNumber: strings.TrimSpace(borrower.BankAccountNumber),
+			AccountName:   borrower.Fullname,
+			Amount:        loan.DisburseAmount,
+			Status:        "pending",
+		})
+		batch.Count++
+		batch.TotalAmount += loan.DisburseAmount
+		batched = append(batched, loan)
+	}
+	if len(items) < 1 {
+		tx.Rollback()
+		return batch, nil, skipped, nil
+	}
+
+	if err = tx.Create(&batch).Error; err != nil {
+		tx.Rollback()
+		return batch, nil, nil, err
+	}
+	batch.Reference = fmt.Sprintf("DSB%v%06d", date.Format("20060102"), batch.ID)
+	if err = tx.Save(&batch).Error; err != nil {
+		tx.Rollback()
+		return batch, nil, nil, err
+	}
+
+	for i := range items {
+		items[i].Batch = batch.ID
+		items[i].Reference = fmt.Sprintf("DSB%06dL%08d", batch.ID, items[i].Loan)
+		if err = tx.Create(&items[i]).Error; err != nil {
+			tx.Rollback()
+			return batch, nil, nil, err
+		}
+
+		batched[i].DisburseStatus = "in_batch"
+		if err = tx.Save(&batched[i]).Error; err != nil {
+			tx.Rollback()
+			return batch, nil, nil, err
+		}
+		if err = outbox(tx, batched[i], "loan_update"); err != nil {
+			tx.Rollback()
+			return batch, nil, nil, err
+		}
+	}
+
+	return batch, items, skipped, tx.Commit().Error
+}
+
+// File renders batch transfers in batch format, returns file content, content type and file name
+func (model *DisbursementBatch) File(items []DisbursementBatchItem) (content []byte, contentType string, name string, err error) {
+	buf := &bytes.Buffer{}
+
+	switch model.Format {
+	case DisbursementFormatFixedWidth:
+		// header, one detail record per transfer and trailer. amounts are whole rupiah, zero padded
+		fmt.Fprintf(buf, "H%-20s%s%06d%015.0f\r\n", model.Reference, model.Date.Format("20060102"), model.Count, model.TotalAmount)
+		for _, item := range items {
+			fmt.Fprintf(buf, "D%-20s%-20s%-40s%015.0f\r\n", fixedWidth(item.Reference, 20), fixedWidth(item.AccountNumber, 20), fixedWidth(item.AccountName, 40), item.Amount)
+		}
+		fmt.Fprintf(buf, "T%06d%015.0f\r\n", model.Count, model.TotalAmount)
+
+		return buf.Bytes(), "text/plain", model.Reference + ".txt", nil
+	default:
+		writer := csv.NewWriter(buf)
+		writer.Write([]string{"reference", "loan", "account_number", "account_name", "amount"})
+		for _, item := range items {
+			writer.Write([]string{item.Reference, fmt.Sprint(item.Loan), item.AccountNumber, item.AccountName, fmt.Sprintf("%.0f", item.Amount)})
+		}
+		writer.Flush()
+
+		return buf.Bytes(), "text/csv", model.Reference + ".csv", writer.Error()
+	}
+}
+
+// ParseDisbursementResult reads bank result file of format. csv files have reference, status and optional message columns,
+// fixed width files have detail records of reference, two digit status code where 00 is success, and message
+func ParseDisbursementResult(format string, file io.Reader) (results []DisbursementResult, err error) {
+	switch format {
+	case DisbursementFormatFixedWidth:
+		scanner := bufio.NewScanner(file)
+		for line := 1; scanner.Scan(); line++ {
+			record := strings.TrimRight(scanner.Text(), "\r")
+			if !strings.HasPrefix(record, "D") {
+				continue
+			}
+			if len(record) < 23 {
+				return results, fmt.Errorf("baris %v tidak sesuai format", line)
+			}
+			results = append(results, DisbursementResult{
+				Line:      line,
+				Reference: strings.TrimSpace(record[1:21]),
+				Success:   record[21:23] == "00",
+				Message:   strings.TrimSpace(record[23:]),
+			})
+		}
+
+		return results, scanner.Err()
+	default:
+		reader := csv.NewReader(file)
+		reader.FieldsPerRecord = -1
+		reader.TrimLeadingSpace = true
+
+		header, err := reader.Read()
+		if err != nil {
+			return results, err
+		}
+		columns := map[string]int{}
+		for i, column := range header {
+			columns[strings.ToLower(strings.TrimSpace(column))] = i
+		}
+		_, hasReference := columns["reference"]
+		_, hasStatus := columns["status"]
+		if !hasReference || !hasStatus {
+			return results, fmt.Errorf("kolom reference dan status dibutuhkan")
+		}
+		column := func(record []string, name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		for line := 2; ; line++ {
+			record, err := reader.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return results, err
+			}
+			results = append(results, DisbursementResult{
+				Line:      line,
+				Reference: column(record, "reference"),
+				Success:   disbursementSuccess[strings.ToLower(column(record, "status"))],
+				Message:   column(record, "message"),
+			})
+		}
+
+		return results, nil
+	}
+}
+
+// ApplyResults confirms or fails pending transfers of batch by results. loans of failed transfers can be batched again.
+// returns changed loans with their state before the change, and results not matching a pending transfer of batch.
+// kafka payloads of changed loans are written through outbox in the same transaction
+func (model *DisbursementBatch) ApplyResults(results []DisbursementResult, outbox OutboxWriter) (origins []Loan, loans []Loan, unmatched []DisbursementResult, err error) {
+	tx := asira.App.DB.Begin()
+	if tx.Error != nil {
+		return nil, nil, nil, tx.Error
+	}
+
+	err = tx.Set("gorm:query_option", "FOR UPDATE").First(model, model.ID).Error
+	if err != nil {
+		tx.Rollback()
+		return nil, nil, nil, err
+	}
+
+	for _, result := range results {
+		item := DisbursementBatchItem{}
+		err = tx.Where("batch = ? AND reference = ? AND status = ?", model.ID, result.Reference, "pending").First(&item).Error
+		if err != nil {
+			unmatched = append(unmatched, result)
+			continue
+		}
+		loan := Loan{}
+		if err = tx.First(&loan, item.Loan).Error; err != nil {
+			tx.Rollback()
+			return nil, nil, nil, err
+		}
+		origins = append(origins, loan)
+
+		item.Message = result.Message
+		if result.Success {
+			item.Status = "confirmed"
+			loan.DisburseStatus = "confirmed"
+			model.Confirmed++
+		} else {
+			item.Status = "failed"
+			loan.DisburseStatus = "failed"
+			model.Failed++
+		}
+		if err = tx.Save(&item).Error; err != nil {
+			tx.Rollback()
+			return nil, nil, nil, err
+		}
+		if err = tx.Save(&loan).Error; err != nil {
+			tx.Rollback()
+			return nil, nil, nil, err
+		}
+		if err = outbox(tx, loan, "loan_update"); err != nil {
+			tx.Rollback()
+			return nil, nil, nil, err
+		}
+		loans = append(loans, loan)
+	}
+
+	var pending int
+	tx.Model(&DisbursementBatchItem{}).Where("batch = ? AND status = ?", model.ID, "pending").Count(&pending)
+	switch {
+	case pending < 1:
+		model.Status = "completed"
+	case model.Confirmed+model.Failed > 0:
+		model.Status = "partial"
+	}
+	if err = tx.Save(model).Error; err != nil {
+		tx.Rollback()
+		return nil, nil, nil, err
+	}
+
+	return origins, loans, unmatched, tx.Commit().Error
+}
+
+// fixedWidth cuts value to fit field of width
+func fixedWidth(value string, width int) string {
+	if runes := []rune(value); len(runes) > width {
+		return string(runes[:width])
+	}
+
+	return value
+}
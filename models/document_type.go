@@ -0,0 +1,40 @@
+package models
+
+import (
+	"github.com/ayannahindonesia/basemodel"
+)
+
+// DocumentType catalog of documents lender can request from borrower
+type DocumentType struct {
+	basemodel.BaseModel
+	Name        string `json:"name" gorm:"column:name;type:varchar(255)"`
+	Description string `json:"description" gorm:"column:description;type:text"`
+	Status      string `json:"status" gorm:"column:status;type:varchar(255)" sql:"DEFAULT:'active'"`
+}
+
+// Create func
+func (model *DocumentType) Create() error {
+	return basemodel.Create(&model)
+}
+
+// Save func
+func (model *DocumentType) Save() error {
+	return basemodel.Save(&model)
+}
+
+// Delete func
+func (model *DocumentType) Delete() error {
+	return basemodel.Delete(&model)
+}
+
+// FindbyID func
+func (model *DocumentType) FindbyID(id uint64) error {
+	return basemodel.FindbyID(&model, id)
+}
+
+// PagedFindFilter func
+func (model *DocumentType) PagedFindFilter(page int, rows int, orderby []string, sort []string, filter interface{}) (basemodel.PagedFindResult, error) {
+	documentTypes := []DocumentType{}
+
+	return basemodel.PagedFindFilter(&documentTypes, page, rows, orderby, sort, filter)
+}
@@ -0,0 +1,133 @@
+package models
+
+import (
+	"asira_lender/asira"
+	"asira_lender/email"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/ayannahindonesia/basemodel"
+	"github.com/jinzhu/gorm/dialects/postgres"
+	"github.com/lib/pq"
+)
+
+type (
+	// EmailQueue email waiting to be delivered by the email worker
+	EmailQueue struct {
+		basemodel.BaseModel
+		Event         string          `json:"event" gorm:"column:event;type:varchar(255)"`
+		Recipients    pq.StringArray  `json:"recipients" gorm:"column:recipients"`
+		Subject       string          `json:"subject" gorm:"column:subject;type:text"`
+		TextBody      string          `json:"text_body" gorm:"column:text_body;type:text"`
+		HTMLBody      string          `json:"html_body" gorm:"column:html_body;type:text"`
+		Attachments   *postgres.Jsonb `json:"attachments" gorm:"column:attachments;type:jsonb"`
+		Status        string          `json:"status" gorm:"column:status;type:varchar(255)" sql:"DEFAULT:'pending'"`
+		Attempts      int             `json:"attempts" gorm:"column:attempts;type:int" sql:"DEFAULT:0"`
+		LastError     string          `json:"last_error" gorm:"column:last_error;type:text"`
+		NextAttemptAt time.Time       `json:"next_attempt_at" gorm:"column:next_attempt_at"`
+		SentAt        *time.Time      `json:"sent_at" gorm:"column:sent_at"`
+	}
+
+	// EmailQueueAttachment attachment stored in object storage
+	EmailQueueAttachment struct {
+		Filename    string `json:"filename"`
+		ContentType string `json:"content_type"`
+		Key         string `json:"key"`
+	}
+)
+
+// Create func
+func (model *EmailQueue) Create() error {
+	return basemodel.Create(&model)
+}
+
+// Save func
+func (model *EmailQueue) Save() error {
+	return basemodel.Save(&model)
+}
+
+// FindbyID func
+func (model *EmailQueue) FindbyID(id uint64) error {
+	return basemodel.FindbyID(&model, id)
+}
+
+// PagedFindFilter func
+func (model *EmailQueue) PagedFindFilter(page int, rows int, orderby []string, sort []string, filter interface{}) (basemodel.PagedFindResult, error) {
+	queues := []EmailQueue{}
+
+	return basemodel.PagedFindFilter(&queues, page, rows, orderby, sort, filter)
+}
+
+// Message builds email message with attachments loaded from storage
+func (model *EmailQueue) Message() (msg email.Message, err error) {
+	msg = email.Message{
+		Subject: model.Subject,
+		HTML:    model.HTMLBody,
+		Text:    model.TextBody,
+	}
+
+	attachments := []EmailQueueAttachment{}
+	if model.Attachments != nil {
+		json.Unmarshal(model.Attachments.RawMessage, &attachments)
+	}
+	for _, attachment := range attachments {
+		b, err := asira.App.Storage.Get(attachment.Key)
+		if err != nil {
+			return msg, fmt.Errorf("attachment %v : %v", attachment.Filename, err)
+		}
+		msg.Attachments = append(msg.Attachments, email.Attachment{
+			Filename:    attachment.Filename,
+			ContentType: attachment.ContentType,
+			Data:        b,
+		})
+	}
+
+	return msg, nil
+}
+
+// Requeue puts failed email back to the queue with fresh attempts
+func (model *EmailQueue) Requeue() error {
+	model.Status = "pending"
+	model.Attempts = 0
+	model.LastError = ""
+	model.NextAttemptAt = time.Now()
+
+	return model.Save()
+}
+
+// QueueEmail stores message and its attachments to be delivered by the email worker
+func QueueEmail(event string, msg email.Message, recipients ...string) (queue EmailQueue, err error) {
+	if len(recipients) < 1 {
+		return queue, fmt.Errorf("no recipient")
+	}
+
+	attachments := []EmailQueueAttachment{}
+	for i, attachment := range msg.Attachments {
+		key := fmt.Sprintf("mail%d_%d%s", time.Now().UnixNano(), i, filepath.Ext(attachment.Filename))
+		if _, err = asira.App.Storage.Put(key, attachment.Data, attachment.ContentType); err != nil {
+			return queue, err
+		}
+		attachments = append(attachments, EmailQueueAttachment{
+			Filename:    attachment.Filename,
+			ContentType: attachment.ContentType,
+			Key:         key,
+		})
+	}
+	marshal, _ := json.Marshal(attachments)
+
+	queue = EmailQueue{
+		Event:         event,
+		Recipients:    pq.StringArray(recipients),
+		Subject:       msg.Subject,
+		TextBody:      msg.Text,
+		HTMLBody:      msg.HTML,
+		Attachments:   &postgres.Jsonb{RawMessage: marshal},
+		Status:        "pending",
+		NextAttemptAt: time.Now(),
+	}
+	err = queue.Create()
+
+	return queue, err
+}
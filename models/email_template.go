@@ -0,0 +1,92 @@
+package models
+
+import (
+	"asira_lender/email"
+
+	"github.com/ayannahindonesia/basemodel"
+)
+
+// EmailTemplate admin editable email template per event and language
+type EmailTemplate struct {
+	basemodel.BaseModel
+	Event       string `json:"event" gorm:"column:event;type:varchar(255)"`
+	Language    string `json:"language" gorm:"column:language;type:varchar(255)"`
+	Subject     string `json:"subject" gorm:"column:subject;type:text"`
+	HTMLBody    string `json:"html_body" gorm:"column:html_body;type:text"`
+	TextBody    string `json:"text_body" gorm:"column:text_body;type:text"`
+	Description string `json:"description" gorm:"column:description;type:text"`
+}
+
+// Create func
+func (model *EmailTemplate) Create() error {
+	return basemodel.Create(&model)
+}
+
+// Save func
+func (model *EmailTemplate) Save() error {
+	return basemodel.Save(&model)
+}
+
+// FindbyID func
+func (model *EmailTemplate) FindbyID(id uint64) error {
+	return basemodel.FindbyID(&model, id)
+}
+
+// SingleFindFilter func
+func (model *EmailTemplate) SingleFindFilter(filter interface{}) error {
+	return basemodel.SingleFindFilter(&model, filter)
+}
+
+// PagedFindFilter func
+func (model *EmailTemplate) PagedFindFilter(page int, rows int, orderby []string, sort []string, filter interface{}) (basemodel.PagedFindResult, error) {
+	templates := []EmailTemplate{}
+
+	return basemodel.PagedFindFilter(&templates, page, rows, orderby, sort, filter)
+}
+
+// Template email template source
+func (model *EmailTemplate) Template() email.Template {
+	return email.Template{
+		Subject: model.Subject,
+		HTML:    model.HTMLBody,
+		Text:    model.TextBody,
+	}
+}
+
+// FindEmailTemplate finds template of event in language, falls back to default language then to built in template
+func FindEmailTemplate(event string, language string) (email.Template, error) {
+	type Filter struct {
+		Event    string `json:"event"`
+		Language string `json:"language"`
+	}
+
+	for _, lang := range []string{language, email.Languages[0]} {
+		model := EmailTemplate{}
+		if err := model.SingleFindFilter(&Filter{Event: event, Language: lang}); err == nil {
+			return model.Template(), nil
+		}
+	}
+
+	return email.DefaultTemplate(event, language)
+}
+
+// SendEmail renders template of data's event and queues it for the email worker
+func SendEmail(language string, data email.TemplateData, recipients ...string) error {
+	if len(language) < 1 {
+		language = email.DefaultLanguage()
+	}
+
+	template, err := FindEmailTemplate(data.Event(), language)
+	if err != nil {
+		return err
+	}
+
+	msg, err := template.Render(data)
+	if err != nil {
+		return err
+	}
+
+	_, err = QueueEmail(data.Event(), msg, recipients...)
+
+	return err
+}
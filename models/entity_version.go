@@ -0,0 +1,45 @@
+package models
+
+import (
+	"github.com/ayannahindonesia/basemodel"
+	"github.com/jinzhu/gorm"
+)
+
+// EntityVersion latest applied version per consumed entity
+type EntityVersion struct {
+	basemodel.BaseModel
+	Model    string `json:"model" gorm:"column:model;type:varchar(255)"`
+	EntityID uint64 `json:"entity_id" gorm:"column:entity_id;type:bigint"`
+	Version  int64  `json:"version" gorm:"column:version;type:bigint"`
+}
+
+// FindVersion get latest applied version of entity, 0 if never applied
+func (model *EntityVersion) FindVersion(name string, entityID uint64) (int64, error) {
+	return model.FindVersionTx(basemodel.DB, name, entityID)
+}
+
+// FindVersionTx get latest applied version of entity inside the given transaction, the version row is locked
+// so events of one entity are applied one after another
+func (model *EntityVersion) FindVersionTx(tx *gorm.DB, name string, entityID uint64) (int64, error) {
+	err := tx.Set("gorm:query_option", "FOR UPDATE").Where("model = ? AND entity_id = ?", name, entityID).First(&model).Error
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	return model.Version, nil
+}
+
+// Upsert store version, never lowering the existing one
+func (model *EntityVersion) Upsert() error {
+	return model.UpsertTx(basemodel.DB)
+}
+
+// UpsertTx store version inside the given transaction, never lowering the existing one
+func (model *EntityVersion) UpsertTx(tx *gorm.DB) error {
+	return tx.Exec(`INSERT INTO entity_versions (model, entity_id, version) VALUES (?, ?, ?)
+		ON CONFLICT (model, entity_id) DO UPDATE SET version = GREATEST(entity_versions.version, EXCLUDED.version), updated_at = CURRENT_TIMESTAMP`,
+		model.Model, model.EntityID, model.Version).Error
+}
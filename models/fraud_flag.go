@@ -0,0 +1,223 @@
+package models
+
+import (
+	"asira_lender/asira"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ayannahindonesia/basemodel"
+	"github.com/lib/pq"
+)
+
+// FraudFlag suspicious borrower data or agent activity waiting for review
+type FraudFlag struct {
+	basemodel.BaseModel
+	Borrower   uint64        `json:"borrower" gorm:"column:borrower;foreignkey"`
+	Loan       uint64        `json:"loan" gorm:"column:loan"`
+	Agent      uint64        `json:"agent" gorm:"column:agent"`
+	Kind       string        `json:"kind" gorm:"column:kind;type:varchar(255)"`
+	Matches    pq.Int64Array `json:"matches" gorm:"column:matches"` // other borrowers sharing the data, or loans of agent burst
+	Detail     string        `json:"detail" gorm:"column:detail;type:text"`
+	Status     string        `json:"status" gorm:"column:status;type:varchar(255)" sql:"DEFAULT:'open'"`
+	ReviewedBy uint64        `json:"reviewed_by" gorm:"column:reviewed_by"`
+	ReviewNote string        `json:"review_note" gorm:"column:review_note;type:text"`
+	ReviewedAt *time.Time    `json:"reviewed_at" gorm:"column:reviewed_at"`
+}
+
+// fraud flag kinds
+const (
+	FraudDuplicatePhone          = "duplicate_phone"
+	FraudDuplicateEmail          = "duplicate_email"
+	FraudDuplicateEmployerNumber = "duplicate_employer_number"
+	FraudDuplicateBankAccount    = "duplicate_bank_account"
+	FraudDuplicateRelatedPhone   = "duplicate_related_phone"
+	FraudDuplicateIdentity       = "duplicate_identity"
+	FraudAgentLoanBurst          = "agent_loan_burst"
+)
+
+// FraudFlagKinds every kind of fraud flag
+var FraudFlagKinds = []string{
+	FraudDuplicatePhone,
+	FraudDuplicateEmail,
+	FraudDuplicateEmployerNumber,
+	FraudDuplicateBankAccount,
+	FraudDuplicateRelatedPhone,
+	FraudDuplicateIdentity,
+	FraudAgentLoanBurst,
+}
+
+// Create func
+func (model *FraudFlag) Create() error {
+	return basemodel.Create(&model)
+}
+
+// Save func
+func (model *FraudFlag) Save() error {
+	return basemodel.Save(&model)
+}
+
+// FindbyID func
+func (model *FraudFlag) FindbyID(id uint64) error {
+	return basemodel.FindbyID(&model, id)
+}
+
+// PagedFindFilter func
+func (model *FraudFlag) PagedFindFilter(page int, rows int, orderby []string, sort []string, filter interface{}) (basemodel.PagedFindResult, error) {
+	flags := []FraudFlag{}
+
+	return basemodel.PagedFindFilter(&flags, page, rows, orderby, sort, filter)
+}
+
+// BorrowerFraudFlags flags of borrower not dismissed by reviewers
+func BorrowerFraudFlags(borrowerID uint64) (flags []FraudFlag, err error) {
+	err = asira.App.DB.Where("borrower = ? AND status <> ?", borrowerID, "dismissed").
+		Order("id asc").
+		Find(&flags).Error
+
+	return flags, err
+}
+
+// RaiseFraudFlag records flag, merging it into the latest flag of same kind on the same borrower, or the same agent
+// for agent bursts. dismissed flags are only reopened when new matches show up
+func RaiseFraudFlag(flag FraudFlag) (FraudFlag, error) {
+	existing := FraudFlag{}
+	db := asira.App.DB.Where("kind = ?", flag.Kind)
+	if flag.Kind == FraudAgentLoanBurst {
+		db = db.Where("agent = ? AND status = ?", flag.Agent, "open")
+	} else {
+		db = db.Where("borrower = ?", flag.Borrower)
+	}
+	if err := db.Order("id desc").First(&existing).Error; err != nil {
+		return flag, flag.Create()
+	}
+
+	known := map[int64]bool{}
+	for _, match := range existing.Matches {
+		known[match] = true
+	}
+	added := false
+	for _, match := range flag.Matches {
+		if !known[match] {
+			existing.Matches = append(existing.Matches, match)
+			added = true
+		}
+	}
+	if !added {
+		return existing, nil
+	}
+
+	if existing.Status == "dismissed" {
+		existing.Status = "open"
+		existing.ReviewedBy = 0
+		existing.ReviewNote = ""
+		existing.ReviewedAt = nil
+	}
+	if flag.Loan > 0 {
+		existing.Loan = flag.Loan
+	}
+	existing.Detail = flag.Detail
+
+	return existing, existing.Save()
+}
+
+// DetectBorrowerDuplicates flags borrower and every other borrower sharing phone, email, employer number,
+// bank account, related person phone, or mother name and birthday with it
+func DetectBorrowerDuplicates(borrower Borrower) (flags []FraudFlag, err error) {
+	type check struct {
+		kind  string
+		label string
+		where string
+		args  []interface{}
+	}
+	var checks []check
+	if phone := strings.TrimSpace(borrower.Phone); len(phone) > 0 {
+		checks = append(checks, check{FraudDuplicatePhone, "Nomor telepon", "TRIM(phone) = ?", []interface{}{phone}})
+	}
+	if email := strings.ToLower(strings.TrimSpace(borrower.Email)); len(email) > 0 {
+		checks = append(checks, check{FraudDuplicateEmail, "Email", "LOWER(TRIM(email)) = ?", []interface{}{email}})
+	}
+	if employerNumber := strings.TrimSpace(borrower.EmployerNumber); len(employerNumber) > 0 {
+		checks = append(checks, check{FraudDuplicateEmployerNumber, "Nomor telepon kantor", "TRIM(employer_number) = ?", []interface{}{employerNumber}})
+	}
+	if bankAccount := strings.TrimSpace(borrower.BankAccountNumber); len(bankAccount) > 0 && borrower.Bank.Valid {
+		checks = append(checks, check{FraudDuplicateBankAccount, "Rekening bank", "TRIM(bank_accountnumber) = ? AND bank = ?", []interface{}{bankAccount, borrower.Bank.Int64}})
+	}
+	if relatedPhone := strings.TrimSpace(borrower.RelatedPhoneNumber); len(relatedPhone) > 0 {
+		checks = append(checks, check{FraudDuplicateRelatedPhone, "Nomor telepon kerabat", "TRIM(related_phonenumber) = ?", []interface{}{relatedPhone}})
+	}
+	if motherName := strings.ToLower(strings.TrimSpace(borrower.MotherName)); len(motherName) > 0 && !borrower.Birthday.IsZero() {
+		checks = append(checks, check{FraudDuplicateIdentity, "Nama ibu kandung dan tanggal lahir", "LOWER(TRIM(mother_name)) = ? AND birthday::date = ?::date", []interface{}{motherName, borrower.Birthday.Format("2006-01-02")}})
+	}
+
+	for _, c := range checks {
+		var matches []int64
+		err = asira.App.DB.Model(&Borrower{}).
+			Where("id <> ?", borrower.ID).
+			Where(c.where, c.args...).
+			Order("id asc").
+			Pluck("id", &matches).Error
+		if err != nil {
+			return flags, err
+		}
+		if len(matches) < 1 {
+			continue
+		}
+
+		flag, err := RaiseFraudFlag(FraudFlag{
+			Borrower: borrower.ID,
+			Kind:     c.kind,
+			Matches:  pq.Int64Array(matches),
+			Detail:   fmt.Sprintf("%v sama dengan %v nasabah lain", c.label, len(matches)),
+		})
+		if err != nil {
+			return flags, err
+		}
+		flags = append(flags, flag)
+
+		for _, match := range matches {
+			_, err = RaiseFraudFlag(FraudFlag{
+				Borrower: uint64(match),
+				Kind:     c.kind,
+				Matches:  pq.Int64Array{int64(borrower.ID)},
+				Detail:   fmt.Sprintf("%v sama dengan nasabah lain", c.label),
+			})
+			if err != nil {
+				return flags, err
+			}
+		}
+	}
+
+	return flags, nil
+}
+
+// DetectAgentLoanBurst flags agent of loan borrower when the agent brought at least threshold loans within window
+func DetectAgentLoanBurst(loan Loan, window time.Duration, threshold int) (flag FraudFlag, flagged bool, err error) {
+	borrower := Borrower{}
+	if err = borrower.FindbyID(loan.Borrower); err != nil || !borrower.AgentReferral.Valid || threshold < 1 {
+		return flag, false, err
+	}
+
+	var loans []int64
+	err = asira.App.DB.Table("loans").
+		Joins("INNER JOIN borrowers b ON b.id = loans.borrower").
+		Where("loans.deleted_at IS NULL").
+		Where("b.agent_referral = ?", borrower.AgentReferral.Int64).
+		Where("loans.created_at >= ?", time.Now().Add(-window)).
+		Order("loans.id asc").
+		Pluck("loans.id", &loans).Error
+	if err != nil || len(loans) < threshold {
+		return flag, false, err
+	}
+
+	flag, err = RaiseFraudFlag(FraudFlag{
+		Borrower: borrower.ID,
+		Loan:     loan.ID,
+		Agent:    uint64(borrower.AgentReferral.Int64),
+		Kind:     FraudAgentLoanBurst,
+		Matches:  pq.Int64Array(loans),
+		Detail:   fmt.Sprintf("Agen %v mengajukan %v pinjaman dalam %v", borrower.AgentReferral.Int64, len(loans), window),
+	})
+
+	return flag, err == nil, err
+}
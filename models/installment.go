@@ -19,6 +19,7 @@ type Installment struct {
 	Penalty         float64    `json:"penalty" gorm:"column:penalty"`
 	DueDate         *time.Time `json:"due_date" gorm:"column:due_date"`
 	Note            string     `json:"note" gorm:"column:note"`
+	ClosedBy        uint64     `json:"closed_by" gorm:"column:closed_by"` // loan restructure replacing the installment
 }
 
 // Create func
@@ -12,30 +12,36 @@ type (
 	// Loan main struct
 	Loan struct {
 		basemodel.BaseModel
-		Borrower            uint64         `json:"borrower" gorm:"column:borrower;foreignkey"`
-		Status              string         `json:"status" gorm:"column:status;type:varchar(255)" sql:"DEFAULT:'processing'"`
-		LoanAmount          float64        `json:"loan_amount" gorm:"column:loan_amount;type:int;not null"`
-		Installment         int            `json:"installment" gorm:"column:installment;type:int;not null"` // plan of how long loan to be paid
-		InstallmentID       pq.Int64Array  `json:"installment_id" gorm:"column:installment_id"`
-		Fees                postgres.Jsonb `json:"fees" gorm:"column:fees;type:jsonb"`
-		Interest            float64        `json:"interest" gorm:"column:interest;type:int;not null"`
-		TotalLoan           float64        `json:"total_loan" gorm:"column:total_loan;type:int;not null"`
-		DisburseAmount      float64        `json:"disburse_amount" gorm:"column:disburse_amount;type:int;not null"`
-		DueDate             time.Time      `json:"due_date" gorm:"column:due_date"`
-		LayawayPlan         float64        `json:"layaway_plan" gorm:"column:layaway_plan;type:int;not null"` // how much borrower will pay per month
-		Product             uint64         `json:"product" gorm:"column:product;foreignkey"`                  // product and service is later to be discussed
-		LoanIntention       string         `json:"loan_intention" gorm:"column:loan_intention;type:varchar(255);not null"`
-		IntentionDetails    string         `json:"intention_details" gorm:"column:intention_details;type:text;not null"`
-		BorrowerInfo        postgres.Jsonb `json:"borrower_info" gorm:"column:borrower_info;type:jsonb"`
-		OTPverified         bool           `json:"otp_verified" gorm:"column:otp_verified;type:boolean" sql:"DEFAULT:FALSE"`
-		DisburseDate        time.Time      `json:"disburse_date" gorm:"column:disburse_date"`
-		DisburseDateChanged bool           `json:"disburse_date_changed" gorm:"column:disburse_date_changed"`
-		DisburseStatus      string         `json:"disburse_status" gorm:"column:disburse_status" sql:"DEFAULT:'processing'"`
-		ApprovalDate        time.Time      `json:"approval_date" gorm:"column:approval_date"`
-		RejectReason        string         `json:"reject_reason" gorm:"column:reject_reason"`
-		FormInfo            postgres.Jsonb `json:"form_info" gorm:"column:form_info;type:jsonb"`
-		PaymentStatus       string         `json:"payment_status" gorm:"column:payment_status" sql:"DEFAULT:'processing'"`
-		PaymentNote         string         `json:"payment_note" gorm:"column:payment_note"`
+		Borrower            uint64          `json:"borrower" gorm:"column:borrower;foreignkey"`
+		Status              string          `json:"status" gorm:"column:status;type:varchar(255)" sql:"DEFAULT:'processing'"`
+		LoanAmount          float64         `json:"loan_amount" gorm:"column:loan_amount;type:int;not null"`
+		Installment         int             `json:"installment" gorm:"column:installment;type:int;not null"` // plan of how long loan to be paid
+		InstallmentID       pq.Int64Array   `json:"installment_id" gorm:"column:installment_id"`
+		Fees                postgres.Jsonb  `json:"fees" gorm:"column:fees;type:jsonb"`
+		Interest            float64         `json:"interest" gorm:"column:interest;type:int;not null"`
+		TotalLoan           float64         `json:"total_loan" gorm:"column:total_loan;type:int;not null"`
+		DisburseAmount      float64         `json:"disburse_amount" gorm:"column:disburse_amount;type:int;not null"`
+		DueDate             time.Time       `json:"due_date" gorm:"column:due_date"`
+		LayawayPlan         float64         `json:"layaway_plan" gorm:"column:layaway_plan;type:int;not null"` // how much borrower will pay per month
+		Product             uint64          `json:"product" gorm:"column:product;foreignkey"`                  // product and service is later to be discussed
+		LoanIntention       string          `json:"loan_intention" gorm:"column:loan_intention;type:varchar(255);not null"`
+		IntentionDetails    string          `json:"intention_details" gorm:"column:intention_details;type:text;not null"`
+		BorrowerInfo        postgres.Jsonb  `json:"borrower_info" gorm:"column:borrower_info;type:jsonb"`
+		OTPverified         bool            `json:"otp_verified" gorm:"column:otp_verified;type:boolean" sql:"DEFAULT:FALSE"`
+		DisburseDate        time.Time       `json:"disburse_date" gorm:"column:disburse_date"`
+		DisburseDateChanged bool            `json:"disburse_date_changed" gorm:"column:disburse_date_changed"`
+		DisburseStatus      string          `json:"disburse_status" gorm:"column:disburse_status" sql:"DEFAULT:'processing'"`
+		ApprovalDate        time.Time       `json:"approval_date" gorm:"column:approval_date"`
+		RejectReason        string          `json:"reject_reason" gorm:"column:reject_reason"`
+		FormInfo            postgres.Jsonb  `json:"form_info" gorm:"column:form_info;type:jsonb"`
+		PaymentStatus       string          `json:"payment_status" gorm:"column:payment_status" sql:"DEFAULT:'processing'"`
+		PaymentNote         string          `json:"payment_note" gorm:"column:payment_note"`
+		Scorecard           uint64          `json:"scorecard" gorm:"column:scorecard"`
+		CreditScore         float64         `json:"credit_score" gorm:"column:credit_score;type:float8"`
+		RiskGrade           string          `json:"risk_grade" gorm:"column:risk_grade;type:varchar(255)"`
+		ScoreFactors        *postgres.Jsonb `json:"score_factors" gorm:"column:score_factors;type:jsonb"`
+		DTI                 float64         `json:"dti" gorm:"column:dti;type:float8"`
+		DTIExceeded         bool            `json:"dti_exceeded" gorm:"column:dti_exceeded;type:boolean"`
 	}
 
 	// LoanFee for loan fee
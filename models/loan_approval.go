@@ -0,0 +1,320 @@
+package models
+
+import (
+	"asira_lender/asira"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ayannahindonesia/basemodel"
+	"github.com/jinzhu/gorm"
+	"github.com/jinzhu/gorm/dialects/postgres"
+)
+
+type (
+	// ApprovalStep one level of approval chain, approved once enough users of the role approve
+	ApprovalStep struct {
+		Name      string `json:"name"`
+		Role      uint64 `json:"role"`
+		Approvers int    `json:"approvers"`
+	}
+
+	// ApprovalPolicy approval chain required for loans of bank within amount tier
+	ApprovalPolicy struct {
+		basemodel.BaseModel
+		Bank      uint64         `json:"bank" gorm:"column:bank;foreignkey"`
+		Product   uint64         `json:"product" gorm:"column:product"` // 0 applies to every product
+		Name      string         `json:"name" gorm:"column:name;type:varchar(255)"`
+		MinAmount float64        `json:"min_amount" gorm:"column:min_amount;type:bigint"`
+		MaxAmount float64        `json:"max_amount" gorm:"column:max_amount;type:bigint"` // 0 means no upper limit
+		Steps     postgres.Jsonb `json:"steps" gorm:"column:steps;type:jsonb"`
+		Status    string         `json:"status" gorm:"column:status;type:varchar(255)" sql:"DEFAULT:'active'"`
+	}
+
+	// LoanApproval progress of loan through the approval chain of its policy
+	LoanApproval struct {
+		basemodel.BaseModel
+		Loan         uint64         `json:"loan" gorm:"column:loan;foreignkey"`
+		Bank         uint64         `json:"bank" gorm:"column:bank;foreignkey"`
+		Policy       uint64         `json:"policy" gorm:"column:policy;foreignkey"`
+		Steps        postgres.Jsonb `json:"steps" gorm:"column:steps;type:jsonb"` // policy steps when approval started
+		CurrentStep  int            `json:"current_step" gorm:"column:current_step;type:int"`
+		StepRole     uint64         `json:"step_role" gorm:"column:step_role"`
+		Status       string         `json:"status" gorm:"column:status;type:varchar(255)" sql:"DEFAULT:'pending'"`
+		DisburseDate *time.Time     `json:"disburse_date" gorm:"column:disburse_date"`
+	}
+
+	// LoanApprovalAction decision of a user on one approval step
+	LoanApprovalAction struct {
+		basemodel.BaseModel
+		LoanApproval uint64 `json:"loan_approval" gorm:"column:loan_approval;foreignkey"`
+		Loan         uint64 `json:"loan" gorm:"column:loan;foreignkey"`
+		Step         int    `json:"step" gorm:"column:step;type:int"`
+		UserID       uint64 `json:"user_id" gorm:"column:user_id;foreignkey"`
+		Role         uint64 `json:"role" gorm:"column:role"`
+		Action       string `json:"action" gorm:"column:action;type:varchar(255)"`
+		Note         string `json:"note" gorm:"column:note;type:text"`
+	}
+
+	// ApprovalError decision not allowed by approval chain, loan affordability, watchlist or loan state
+	ApprovalError struct {
+		Message string
+	}
+)
+
+func (e ApprovalError) Error() string {
+	return e.Message
+}
+
+// Create func
+func (model *ApprovalPolicy) Create() error {
+	return basemodel.Create(&model)
+}
+
+// Save func
+func (model *ApprovalPolicy) Save() error {
+	return basemodel.Save(&model)
+}
+
+// Delete func
+func (model *ApprovalPolicy) Delete() error {
+	return basemodel.Delete(&model)
+}
+
+// SingleFindFilter func
+func (model *ApprovalPolicy) SingleFindFilter(filter interface{}) error {
+	return basemodel.SingleFindFilter(&model, filter)
+}
+
+// PagedFindFilter func
+func (model *ApprovalPolicy) PagedFindFilter(page int, rows int, orderby []string, sort []string, filter interface{}) (basemodel.PagedFindResult, error) {
+	policies := []ApprovalPolicy{}
+
+	return basemodel.PagedFindFilter(&policies, page, rows, orderby, sort, filter)
+}
+
+// StepList approval steps of policy
+func (model *ApprovalPolicy) StepList() (steps []ApprovalStep) {
+	json.Unmarshal(model.Steps.RawMessage, &steps)
+
+	return steps
+}
+
+// Create func
+func (model *LoanApproval) Create() error {
+	return basemodel.Create(&model)
+}
+
+// Save func
+func (model *LoanApproval) Save() error {
+	return basemodel.Save(&model)
+}
+
+// SingleFindFilter func
+func (model *LoanApproval) SingleFindFilter(filter interface{}) error {
+	return basemodel.SingleFindFilter(&model, filter)
+}
+
+// StepList approval steps of loan approval
+func (model *LoanApproval) StepList() (steps []ApprovalStep) {
+	json.Unmarshal(model.Steps.RawMessage, &steps)
+
+	return steps
+}
+
+// Actions decisions made on loan approval so far
+func (model *LoanApproval) Actions() (actions []LoanApprovalAction, err error) {
+	err = asira.App.DB.Where("loan_approval = ?", model.ID).Order("id asc").Find(&actions).Error
+
+	return actions, err
+}
+
+// FindApprovalPolicy finds active policy of bank covering loan product and amount.
+// product specific policies win over bank wide ones, then the highest tier wins
+func FindApprovalPolicy(bankID uint64, productID uint64, amount float64) (policy ApprovalPolicy, err error) {
+	err = asira.App.DB.Where("bank = ? AND status = ?", bankID, "active").
+		Where("product = ? OR product = 0", productID).
+		Where("min_amount <= ?", amount).
+		Where("max_amount = 0 OR max_amount >= ?", amount).
+		Order("product desc, min_amount desc").
+		First(&policy).Error
+
+	return policy, err
+}
+
+// StartLoanApproval finds approval of loan, starting it when loan falls under an approval policy of the bank.
+// required is false for loans no policy applies to, they can be decided directly
+func StartLoanApproval(loan Loan, bankID uint64) (approval LoanApproval, required bool, err error) {
+	type Filter struct {
+		Loan uint64 `json:"loan"`
+	}
+	if err = approval.SingleFindFilter(&Filter{Loan: loan.ID}); err == nil {
+		return approval, true, nil
+	}
+
+	policy, err := FindApprovalPolicy(bankID, loan.Product, loan.LoanAmount)
+	if gorm.IsRecordNotFoundError(err) {
+		return approval, false, nil
+	}
+	if err != nil {
+		return approval, false, err
+	}
+	steps := policy.StepList()
+	if len(steps) < 1 {
+		return approval, false, nil
+	}
+
+	approval = LoanApproval{
+		Loan:        loan.ID,
+		Bank:        bankID,
+		Policy:      policy.ID,
+		Steps:       policy.Steps,
+		CurrentStep: 0,
+		StepRole:    steps[0].Role,
+		Status:      "pending",
+	}
+	err = approval.Create()
+
+	return approval, true, err
+}
+
+// Act records user decision on current step. approving moves approval to next step once the step
+// has enough approvers, any rejection rejects the loan. final is true when loan is decided, the decided loan
+// and its kafka payload are then written through outbox in the same transaction as the approval
+func (model *LoanApproval) Act(user User, action string, note string, disburseDate *time.Time, loan *Loan, outbox OutboxWriter) (final bool, err error) {
+	tx := asira.App.DB.Begin()
+	if tx.Error != nil {
+		return false, tx.Error
+	}
+
+	// lock approval so concurrent decisions are applied one after another
+	err = tx.Set("gorm:query_option", "FOR UPDATE").First(model, model.ID).Error
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	if model.Status != "pending" {
+		tx.Rollback()
+		return false, ApprovalError{fmt.Sprintf("Persetujuan pinjaman %v sudah selesai dengan status %v", model.Loan, model.Status)}
+	}
+
+	var acted int
+	tx.Model(&LoanApprovalAction{}).Where("loan_approval = ? AND user_id = ?", model.ID, user.ID).Count(&acted)
+	if acted > 0 {
+		tx.Rollback()
+		return false, ApprovalError{fmt.Sprintf("Anda sudah memberikan keputusan untuk pinjaman %v", model.Loan)}
+	}
+
+	steps := model.StepList()
+	if model.CurrentStep >= len(steps) {
+		tx.Rollback()
+		return false, ApprovalError{fmt.Sprintf("Tahap persetujuan pinjaman %v tidak valid", model.Loan)}
+	}
+	step := steps[model.CurrentStep]
+
+	hasRole := false
+	for _, role := range user.Roles {
+		if uint64(role) == step.Role {
+			hasRole = true
+		}
+	}
+	if !hasRole {
+		tx.Rollback()
+		return false, ApprovalError{fmt.Sprintf("Tahap %v pinjaman %v membutuhkan keputusan dari role lain", step.Name, model.Loan)}
+	}
+
+	approvalAction := LoanApprovalAction{
+		LoanApproval: model.ID,
+		Loan:         model.Loan,
+		Step:         model.CurrentStep,
+		UserID:       user.ID,
+		Role:         step.Role,
+		Action:       action,
+		Note:         note,
+	}
+	if err = tx.Create(&approvalAction).Error; err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	if disburseDate != nil {
+		model.DisburseDate = disburseDate
+	}
+
+	switch action {
+	case "reject":
+		model.Status = "rejected"
+		final = true
+	case "approve":
+		var approvers int
+		tx.Model(&LoanApprovalAction{}).Where("loan_approval = ? AND step = ? AND action = ?", model.ID, model.CurrentStep, "approve").Count(&approvers)
+		if approvers >= step.Approvers {
+			model.CurrentStep++
+			if model.CurrentStep >= len(steps) {
+				model.Status = "approved"
+				final = true
+			} else {
+				model.StepRole = steps[model.CurrentStep].Role
+			}
+		}
+	}
+
+	if err = tx.Save(model).Error; err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	if final {
+		decidedDate := time.Time{}
+		if model.DisburseDate != nil {
+			decidedDate = *model.DisburseDate
+		}
+		if err = decideLoanTx(tx, loan, action, decidedDate, note, outbox); err != nil {
+			tx.Rollback()
+			return false, err
+		}
+	}
+
+	return final, tx.Commit().Error
+}
+
+// DecideLoan approves or rejects processing loan, its kafka payload is written through outbox in the same transaction
+func DecideLoan(loan *Loan, action string, disburseDate time.Time, reason string, outbox OutboxWriter) (err error) {
+	tx := asira.App.DB.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+
+	if err = decideLoanTx(tx, loan, action, disburseDate, reason, outbox); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// decideLoanTx locks loan and applies decision inside tx, loans no longer processing are rejected with ApprovalError
+func decideLoanTx(tx *gorm.DB, loan *Loan, action string, disburseDate time.Time, reason string, outbox OutboxWriter) (err error) {
+	if err = tx.Set("gorm:query_option", "FOR UPDATE").First(loan, loan.ID).Error; err != nil {
+		return err
+	}
+	if loan.Status != "processing" {
+		return ApprovalError{fmt.Sprintf("Pinjaman %v berstatus %v dan tidak dapat diproses", loan.ID, loan.Status)}
+	}
+
+	switch action {
+	case "approve":
+		loan.Status = "approved"
+		loan.DisburseDate = disburseDate
+	case "reject":
+		loan.Status = "rejected"
+		loan.RejectReason = reason
+	}
+	loan.ApprovalDate = time.Now()
+	if err = tx.Save(loan).Error; err != nil {
+		return err
+	}
+
+	return outbox(tx, *loan, "loan_update")
+}
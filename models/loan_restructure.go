@@ -0,0 +1,230 @@
+package models
+
+import (
+	"asira_lender/asira"
+	"asira_lender/custommodule/irate"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/ayannahindonesia/basemodel"
+	"github.com/lib/pq"
+)
+
+// LoanRestructure new schedule replacing unpaid installments of approved loan. grace period installments are
+// interest only, the remaining principal is then amortized over tenor installments at monthly interest percent
+type LoanRestructure struct {
+	basemodel.BaseModel
+	Loan              uint64        `json:"loan" gorm:"column:loan;foreignkey"`
+	Tenor             int           `json:"tenor" gorm:"column:tenor;type:int"`
+	Interest          float64       `json:"interest" gorm:"column:interest;type:float8"`
+	GracePeriod       int           `json:"grace_period" gorm:"column:grace_period;type:int"`
+	CapitaliseArrears bool          `json:"capitalise_arrears" gorm:"column:capitalise_arrears;type:boolean"`
+	FirstDueDate      time.Time     `json:"first_due_date" gorm:"column:first_due_date"`
+	Principal         float64       `json:"principal" gorm:"column:principal;type:float8"` // unpaid principal carried to new schedule
+	Arrears           float64       `json:"arrears" gorm:"column:arrears;type:float8"`     // overdue interest and penalty of closed installments
+	OldInstallments   pq.Int64Array `json:"old_installments" gorm:"column:old_installments"`
+	NewInstallments   pq.Int64Array `json:"new_installments" gorm:"column:new_installments"`
+	PreviousPlan      float64       `json:"previous_plan" gorm:"column:previous_plan;type:float8"`
+	NewPlan           float64       `json:"new_plan" gorm:"column:new_plan;type:float8"`
+	Reason            string        `json:"reason" gorm:"column:reason;type:text"`
+	RequestedBy       uint64        `json:"requested_by" gorm:"column:requested_by"`
+}
+
+// FindbyID func
+func (model *LoanRestructure) FindbyID(id uint64) error {
+	return basemodel.FindbyID(&model, id)
+}
+
+// PagedFindFilter func
+func (model *LoanRestructure) PagedFindFilter(page int, rows int, orderby []string, sort []string, filter interface{}) (basemodel.PagedFindResult, error) {
+	restructures := []LoanRestructure{}
+
+	return basemodel.PagedFindFilter(&restructures, page, rows, orderby, sort, filter)
+}
+
+// LoanInstallments installments of loan ordered by period
+func LoanInstallments(loan Loan) (installments []Installment, err error) {
+	if len(loan.InstallmentID) < 1 {
+		return installments, nil
+	}
+	err = asira.App.DB.Where("id IN (?)", []int64(loan.InstallmentID)).
+		Order("period asc, id asc").
+		Find(&installments).Error
+
+	return installments, err
+}
+
+// Schedule splits installments of loan into paid ones kept and unpaid ones to be closed, and computes the new
+// schedule for the unpaid principal. arrears not capitalised are due with the first new installment
+func (model *LoanRestructure) Schedule(installments []Installment, now time.Time) (paid []Installment, closed []Installment, schedule []Installment, err error) {
+	if model.Tenor < 1 {
+		return nil, nil, nil, ApprovalError{Message: "Tenor restrukturisasi minimal 1 bulan"}
+	}
+
+	lastPeriod := 0
+	model.Principal = 0
+	model.Arrears = 0
+	for _, installment := range installments {
+		if installment.PaidStatus {
+			paid = append(paid, installment)
+			if installment.Period > lastPeriod {
+				lastPeriod = installment.Period
+			}
+			continue
+		}
+		closed = append(closed, installment)
+		model.Principal += installment.LoanPayment + installment.Underpayment
+		if installment.DueDate != nil && installment.DueDate.Before(now) {
+			model.Arrears += installment.InterestPayment + installment.Penalty
+		}
+	}
+	if len(closed) < 1 {
+		return nil, nil, nil, ApprovalError{Message: "Pinjaman tidak memiliki cicilan yang belum dibayar"}
+	}
+
+	principal := model.Principal
+	carried := model.Arrears
+	if model.CapitaliseArrears {
+		principal += model.Arrears
+		carried = 0
+	}
+
+	rate := model.Interest / 100
+	period := 0
+	next := func(loanPayment float64, interestPayment float64) {
+		dueDate := model.FirstDueDate.AddDate(0, period, 0)
+		period++
+		schedule = append(schedule, Installment{
+			Period:          lastPeriod + period,
+			LoanPayment:     loanPayment,
+			InterestPayment: interestPayment,
+			DueDate:         &dueDate,
+			Note:            fmt.Sprintf("restrukturisasi cicilan %v", period),
+		})
+	}
+
+	for i := 0; i < model.GracePeriod; i++ {
+		next(0, math.Round(principal*rate))
+	}
+
+	pmt := -irate.PMT(rate, float64(model.Tenor), principal, 0)
+	remaining := principal
+	for i := 1; i <= model.Tenor; i++ {
+		loanPayment := math.Round(-irate.PPMT(rate, float64(i), float64(model.Tenor), principal, 0))
+		if i == model.Tenor {
+			loanPayment = remaining
+		}
+		remaining -= loanPayment
+		next(loanPayment, math.Max(math.Round(pmt)-loanPayment, 0))
+	}
+	schedule[0].InterestPayment += carried
+	model.NewPlan = math.Round(pmt)
+
+	return paid, closed, schedule, nil
+}
+
+// RestructureLoan closes unpaid installments of loan and stores the new schedule, updating loan plan to match. loan and
+// installments are locked, loan no longer approved and unpaid is rejected with ApprovalError. returns closed and created
+// installments, their kafka payloads are written through outbox in the same transaction
+func RestructureLoan(loan *Loan, restructure *LoanRestructure, outbox OutboxWriter) (closed []Installment, created []Installment, err error) {
+	tx := asira.App.DB.Begin()
+	if tx.Error != nil {
+		return nil, nil, tx.Error
+	}
+
+	// lock loan and its installments so a concurrent restructure, payment or settlement is applied one after another
+	if err = tx.Set("gorm:query_option", "FOR UPDATE").First(loan, loan.ID).Error; err != nil {
+		tx.Rollback()
+		return nil, nil, err
+	}
+	if loan.Status != "approved" || loan.PaymentStatus == "terbayar" || loan.PaymentStatus == "hapus_buku" {
+		tx.Rollback()
+		return nil, nil, ApprovalError{fmt.Sprintf("Pinjaman %v berstatus %v dan tidak dapat direstrukturisasi", loan.ID, loan.Status)}
+	}
+
+	installments := []Installment{}
+	if len(loan.InstallmentID) > 0 {
+		err = tx.Set("gorm:query_option", "FOR UPDATE").
+			Where("id IN (?)", []int64(loan.InstallmentID)).
+			Order("period asc, id asc").
+			Find(&installments).Error
+		if err != nil {
+			tx.Rollback()
+			return nil, nil, err
+		}
+	}
+	paid, closed, created, err := restructure.Schedule(installments, time.Now())
+	if err != nil {
+		tx.Rollback()
+		return nil, nil, err
+	}
+
+	restructure.Loan = loan.ID
+	restructure.PreviousPlan = loan.LayawayPlan
+	for _, installment := range closed {
+		restructure.OldInstallments = append(restructure.OldInstallments, int64(installment.ID))
+	}
+	if err = tx.Create(restructure).Error; err != nil {
+		tx.Rollback()
+		return nil, nil, err
+	}
+
+	for i := range closed {
+		closed[i].ClosedBy = restructure.ID
+		if err = tx.Save(&closed[i]).Error; err != nil {
+			tx.Rollback()
+			return nil, nil, err
+		}
+	}
+
+	installmentIDs := pq.Int64Array{}
+	totalLoan := 0.0
+	for _, installment := range paid {
+		installmentIDs = append(installmentIDs, int64(installment.ID))
+		totalLoan += installment.LoanPayment + installment.InterestPayment
+	}
+	for i := range created {
+		if err = tx.Create(&created[i]).Error; err != nil {
+			tx.Rollback()
+			return nil, nil, err
+		}
+		installmentIDs = append(installmentIDs, int64(created[i].ID))
+		restructure.NewInstallments = append(restructure.NewInstallments, int64(created[i].ID))
+		totalLoan += created[i].LoanPayment + created[i].InterestPayment
+	}
+	if err = tx.Save(restructure).Error; err != nil {
+		tx.Rollback()
+		return nil, nil, err
+	}
+
+	loan.InstallmentID = installmentIDs
+	loan.Installment = len(installmentIDs)
+	loan.Interest = restructure.Interest
+	loan.LayawayPlan = restructure.NewPlan
+	loan.TotalLoan = math.Round(totalLoan)
+	loan.DueDate = *created[len(created)-1].DueDate
+	if err = tx.Save(loan).Error; err != nil {
+		tx.Rollback()
+		return nil, nil, err
+	}
+
+	for _, installment := range closed {
+		if err = outbox(tx, installment, "installment_update"); err != nil {
+			tx.Rollback()
+			return nil, nil, err
+		}
+	}
+	for _, installment := range created {
+		if err = outbox(tx, installment, "installment_create"); err != nil {
+			tx.Rollback()
+			return nil, nil, err
+		}
+	}
+	if err = outbox(tx, *loan, "loan_update"); err != nil {
+		tx.Rollback()
+		return nil, nil, err
+	}
+
+	return closed, created, tx.Commit().Error
+}
@@ -0,0 +1,182 @@
+package models
+
+import (
+	"asira_lender/asira"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/ayannahindonesia/basemodel"
+	"github.com/lib/pq"
+)
+
+type (
+	// PayoffQuote amount settling loan in full at date. interest of installments due by date is accrued in full,
+	// interest of the running installment is accrued by days elapsed in its period
+	PayoffQuote struct {
+		Loan               uint64        `json:"loan" gorm:"column:loan"`
+		Date               time.Time     `json:"date" gorm:"column:date"`
+		RemainingPrincipal float64       `json:"remaining_principal" gorm:"column:remaining_principal;type:float8"`
+		AccruedInterest    float64       `json:"accrued_interest" gorm:"column:accrued_interest;type:float8"`
+		Penalties          float64       `json:"penalties" gorm:"column:penalties;type:float8"`
+		Underpayment       float64       `json:"underpayment" gorm:"column:underpayment;type:float8"`
+		SettlementFeeRate  float64       `json:"settlement_fee_rate" gorm:"column:settlement_fee_rate;type:float8"`
+		SettlementFee      float64       `json:"settlement_fee" gorm:"column:settlement_fee;type:float8"`
+		Total              float64       `json:"total" gorm:"column:total;type:float8"`
+		Installments       pq.Int64Array `json:"installments" gorm:"column:installments"` // remaining installments settled by the payoff
+	}
+
+	// LoanSettlement early settlement of loan and the quote it was paid against
+	LoanSettlement struct {
+		basemodel.BaseModel
+		PayoffQuote
+		PaidAmount float64 `json:"paid_amount" gorm:"column:paid_amount;type:float8"`
+		Note       string  `json:"note" gorm:"column:note;type:text"`
+		SettledBy  uint64  `json:"settled_by" gorm:"column:settled_by"`
+	}
+)
+
+// SingleFindFilter func
+func (model *LoanSettlement) SingleFindFilter(filter interface{}) error {
+	return basemodel.SingleFindFilter(&model, filter)
+}
+
+// LoanPayoffQuote quotes amount settling every unpaid installment of loan at date
+func LoanPayoffQuote(loan Loan, date time.Time) (quote PayoffQuote, err error) {
+	quote = PayoffQuote{
+		Loan:         loan.ID,
+		Date:         date,
+		Installments: pq.Int64Array{},
+	}
+
+	installments, err := LoanInstallments(loan)
+	if err != nil {
+		return quote, err
+	}
+
+	running := false
+	for _, installment := range installments {
+		quote.Underpayment += installment.Underpayment
+		if installment.PaidStatus || installment.ClosedBy > 0 {
+			continue
+		}
+		quote.Installments = append(quote.Installments, int64(installment.ID))
+		quote.RemainingPrincipal += installment.LoanPayment
+		quote.Penalties += installment.Penalty
+
+		switch {
+		case installment.DueDate == nil:
+		case !installment.DueDate.After(date):
+			quote.AccruedInterest += installment.InterestPayment
+		case !running:
+			running = true
+			start := installment.DueDate.AddDate(0, -1, 0)
+			if date.After(start) {
+				elapsed := date.Sub(start).Hours() / 24
+				period := installment.DueDate.Sub(start).Hours() / 24
+				quote.AccruedInterest += installment.InterestPayment * math.Floor(elapsed) / period
+			}
+		}
+	}
+
+	product := Product{}
+	if loan.Product > 0 {
+		product.FindbyID(loan.Product)
+	}
+	quote.SettlementFeeRate = product.EarlySettlementFee
+
+	quote.AccruedInterest = math.Round(quote.AccruedInterest)
+	quote.SettlementFee = math.Round(quote.RemainingPrincipal * quote.SettlementFeeRate / 100)
+	quote.Total = quote.RemainingPrincipal + quote.AccruedInterest + quote.Penalties + quote.Underpayment + quote.SettlementFee
+
+	return quote, nil
+}
+
+// SettleLoan marks remaining installments of quote paid at quote date and loan paid off, recording the settlement.
+// loan and installments are locked so a payment or settlement committed after the quote is rejected with ApprovalError.
+// returns settled installments, their kafka payloads are written through outbox in the same transaction
+func SettleLoan(loan *Loan, settlement *LoanSettlement, outbox OutboxWriter) (settled []Installment, err error) {
+	tx := asira.App.DB.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	if err = tx.Set("gorm:query_option", "FOR UPDATE").First(loan, loan.ID).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if loan.Status != "approved" || loan.PaymentStatus == "terbayar" || loan.PaymentStatus == "hapus_buku" {
+		tx.Rollback()
+		return nil, ApprovalError{fmt.Sprintf("Pinjaman %v tidak memiliki sisa pembayaran", loan.ID)}
+	}
+
+	var settlements int
+	if err = tx.Model(&LoanSettlement{}).Where("loan = ?", loan.ID).Count(&settlements).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if settlements > 0 {
+		tx.Rollback()
+		return nil, ApprovalError{fmt.Sprintf("Pinjaman %v sudah dilunasi", loan.ID)}
+	}
+
+	if err = tx.Set("gorm:query_option", "FOR UPDATE").Where("id IN (?)", []int64(settlement.Installments)).Order("period asc").Find(&settled).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if len(settled) != len(settlement.Installments) {
+		tx.Rollback()
+		return nil, ApprovalError{fmt.Sprintf("Cicilan pinjaman %v berubah, silakan minta penawaran pelunasan baru", loan.ID)}
+	}
+	for _, installment := range settled {
+		if installment.PaidStatus || installment.ClosedBy > 0 {
+			tx.Rollback()
+			return nil, ApprovalError{fmt.Sprintf("Cicilan pinjaman %v berubah, silakan minta penawaran pelunasan baru", loan.ID)}
+		}
+	}
+
+	paidDate := settlement.Date
+	for i := range settled {
+		settled[i].PaidStatus = true
+		settled[i].PaidDate = &paidDate
+		settled[i].PaidAmount = settled[i].LoanPayment + settled[i].Penalty
+		settled[i].Note = "pelunasan dipercepat"
+		if err = tx.Save(&settled[i]).Error; err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+	if len(settled) > 0 {
+		// interest, fee and underpayment are collected with the first settled installment
+		settled[0].PaidAmount += settlement.Total - settlement.RemainingPrincipal - settlement.Penalties
+		if err = tx.Save(&settled[0]).Error; err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	if err = tx.Create(settlement).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	loan.PaymentStatus = "terbayar"
+	loan.PaymentNote = settlement.Note
+	if err = tx.Save(loan).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	for _, installment := range settled {
+		if err = outbox(tx, installment, "installment_update"); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+	if err = outbox(tx, *loan, "loan_update"); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	return settled, tx.Commit().Error
+}
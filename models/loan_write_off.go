@@ -0,0 +1,254 @@
+package models
+
+import (
+	"asira_lender/asira"
+	"fmt"
+	"time"
+
+	"github.com/ayannahindonesia/basemodel"
+	"github.com/lib/pq"
+)
+
+type (
+	// LoanWriteOff removal of non performing loan from active portfolio, effective once approved by another user
+	LoanWriteOff struct {
+		basemodel.BaseModel
+		Loan         uint64        `json:"loan" gorm:"column:loan;foreignkey"`
+		Bank         uint64        `json:"bank" gorm:"column:bank;foreignkey"`
+		DaysPastDue  int           `json:"days_past_due" gorm:"column:days_past_due;type:int"`
+		Principal    float64       `json:"principal" gorm:"column:principal;type:float8"`
+		Interest     float64       `json:"interest" gorm:"column:interest;type:float8"`
+		Penalties    float64       `json:"penalties" gorm:"column:penalties;type:float8"` // penalties and underpayment of installments
+		Amount       float64       `json:"amount" gorm:"column:amount;type:float8"`
+		Recovered    float64       `json:"recovered" gorm:"column:recovered;type:float8"`
+		Installments pq.Int64Array `json:"installments" gorm:"column:installments"` // unpaid installments written off
+		Reason       string        `json:"reason" gorm:"column:reason;type:text"`
+		Status       string        `json:"status" gorm:"column:status;type:varchar(255)" sql:"DEFAULT:'pending'"`
+		RequestedBy  uint64        `json:"requested_by" gorm:"column:requested_by"`
+		DecidedBy    uint64        `json:"decided_by" gorm:"column:decided_by"`
+		DecisionNote string        `json:"decision_note" gorm:"column:decision_note;type:text"`
+		DecidedAt    *time.Time    `json:"decided_at" gorm:"column:decided_at"`
+	}
+
+	// LoanRecovery payment collected on written off loan
+	LoanRecovery struct {
+		basemodel.BaseModel
+		WriteOff   uint64    `json:"write_off" gorm:"column:write_off;foreignkey"`
+		Loan       uint64    `json:"loan" gorm:"column:loan;foreignkey"`
+		Amount     float64   `json:"amount" gorm:"column:amount;type:float8"`
+		Date       time.Time `json:"date" gorm:"column:date"`
+		Note       string    `json:"note" gorm:"column:note;type:text"`
+		RecordedBy uint64    `json:"recorded_by" gorm:"column:recorded_by"`
+	}
+)
+
+// Create func
+func (model *LoanWriteOff) Create() error {
+	return basemodel.Create(&model)
+}
+
+// Request stores pending write off, loan is locked so it never has two pending write offs
+func (model *LoanWriteOff) Request() (err error) {
+	tx := asira.App.DB.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+
+	loan := Loan{}
+	if err = tx.Set("gorm:query_option", "FOR UPDATE").First(&loan, model.Loan).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if loan.Status != "approved" || loan.PaymentStatus == "terbayar" || loan.PaymentStatus == "hapus_buku" {
+		tx.Rollback()
+		return ApprovalError{fmt.Sprintf("Pinjaman %v tidak dapat dihapus buku", loan.ID)}
+	}
+
+	var pending int
+	if err = tx.Model(&LoanWriteOff{}).Where("loan = ? AND status = ?", loan.ID, "pending").Count(&pending).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if pending > 0 {
+		tx.Rollback()
+		return ApprovalError{fmt.Sprintf("Pinjaman %v sudah menunggu persetujuan hapus buku", loan.ID)}
+	}
+
+	if err = tx.Create(model).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// SingleFindFilter func
+func (model *LoanWriteOff) SingleFindFilter(filter interface{}) error {
+	return basemodel.SingleFindFilter(&model, filter)
+}
+
+// PagedFindFilter func
+func (model *LoanWriteOff) PagedFindFilter(page int, rows int, orderby []string, sort []string, filter interface{}) (basemodel.PagedFindResult, error) {
+	writeOffs := []LoanWriteOff{}
+
+	return basemodel.PagedFindFilter(&writeOffs, page, rows, orderby, sort, filter)
+}
+
+// Recoveries payments collected on write off so far
+func (model *LoanWriteOff) Recoveries() (recoveries []LoanRecovery, err error) {
+	err = asira.App.DB.Where("write_off = ?", model.ID).Order("date asc, id asc").Find(&recoveries).Error
+
+	return recoveries, err
+}
+
+// LoanDaysPastDue days the oldest unpaid installment of loan is overdue at now, or the loan itself
+// when it has no installment schedule
+func LoanDaysPastDue(loan Loan, now time.Time) (dpd int, err error) {
+	installments, err := LoanInstallments(loan)
+	if err != nil {
+		return 0, err
+	}
+	for _, installment := range installments {
+		if installment.PaidStatus {
+			continue
+		}
+		if d := installmentDPD(installment, now); d > dpd {
+			dpd = d
+		}
+	}
+	if len(installments) < 1 {
+		dpd = daysPastDue(loan.DueDate, now)
+	}
+
+	return dpd, nil
+}
+
+// NewLoanWriteOff pending write off of everything unpaid on loan at now
+func NewLoanWriteOff(loan Loan, bankID uint64, now time.Time) (writeOff LoanWriteOff, err error) {
+	dpd, err := LoanDaysPastDue(loan, now)
+	if err != nil {
+		return writeOff, err
+	}
+	quote, err := LoanPayoffQuote(loan, now)
+	if err != nil {
+		return writeOff, err
+	}
+
+	writeOff = LoanWriteOff{
+		Loan:         loan.ID,
+		Bank:         bankID,
+		DaysPastDue:  dpd,
+		Principal:    quote.RemainingPrincipal,
+		Interest:     quote.AccruedInterest,
+		Penalties:    quote.Penalties + quote.Underpayment,
+		Installments: quote.Installments,
+		Status:       "pending",
+	}
+	writeOff.Amount = writeOff.Principal + writeOff.Interest + writeOff.Penalties
+
+	return writeOff, nil
+}
+
+// Decide approves or rejects pending write off. the requester can not decide its own write off.
+// approving writes loan off when it is still approved and unpaid, loan is locked and updated in place and its kafka payload
+// written through outbox in the same transaction
+func (model *LoanWriteOff) Decide(loan *Loan, userID uint64, action string, note string, outbox OutboxWriter) (err error) {
+	tx := asira.App.DB.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+
+	// lock write off so concurrent decisions are applied one after another
+	err = tx.Set("gorm:query_option", "FOR UPDATE").First(model, model.ID).Error
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if model.Status != "pending" {
+		tx.Rollback()
+		return ApprovalError{fmt.Sprintf("Hapus buku pinjaman %v sudah diputuskan dengan status %v", model.Loan, model.Status)}
+	}
+	if model.RequestedBy == userID {
+		tx.Rollback()
+		return ApprovalError{fmt.Sprintf("Hapus buku pinjaman %v harus diputuskan oleh user lain", model.Loan)}
+	}
+
+	now := time.Now()
+	model.DecidedBy = userID
+	model.DecisionNote = note
+	model.DecidedAt = &now
+
+	switch action {
+	case "approve":
+		model.Status = "approved"
+
+		if err = tx.Set("gorm:query_option", "FOR UPDATE").First(loan, model.Loan).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+		if loan.Status != "approved" || loan.PaymentStatus == "terbayar" || loan.PaymentStatus == "hapus_buku" {
+			tx.Rollback()
+			return ApprovalError{fmt.Sprintf("Pinjaman %v berstatus pembayaran %v dan tidak dapat dihapus buku", loan.ID, loan.PaymentStatus)}
+		}
+		err = tx.Model(loan).Updates(map[string]interface{}{
+			"payment_status": "hapus_buku",
+			"payment_note":   model.Reason,
+		}).Error
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err = outbox(tx, *loan, "loan_update"); err != nil {
+			tx.Rollback()
+			return err
+		}
+	case "reject":
+		model.Status = "rejected"
+	}
+
+	if err = tx.Save(model).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// Recover records recovery payment on approved write off, recoveries can not exceed the written off amount
+func (model *LoanWriteOff) Recover(recovery *LoanRecovery) (err error) {
+	tx := asira.App.DB.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+
+	err = tx.Set("gorm:query_option", "FOR UPDATE").First(model, model.ID).Error
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if model.Status != "approved" {
+		tx.Rollback()
+		return ApprovalError{fmt.Sprintf("Pinjaman %v belum dihapus buku", model.Loan)}
+	}
+	if remaining := model.Amount - model.Recovered; recovery.Amount > remaining {
+		tx.Rollback()
+		return ApprovalError{fmt.Sprintf("Pemulihan melebihi sisa hapus buku pinjaman %v sebesar %v", model.Loan, remaining)}
+	}
+
+	recovery.WriteOff = model.ID
+	recovery.Loan = model.Loan
+	if err = tx.Create(recovery).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	model.Recovered += recovery.Amount
+	if err = tx.Save(model).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"time"
+
+	"github.com/ayannahindonesia/basemodel"
+)
+
+type (
+	// Notification in-app notification of bank representative
+	Notification struct {
+		basemodel.BaseModel
+		UserID   uint64     `json:"user_id" gorm:"column:user_id"`
+		Bank     uint64     `json:"bank" gorm:"column:bank;foreignkey"`
+		Event    string     `json:"event" gorm:"column:event;type:varchar(255)"`
+		Entity   string     `json:"entity" gorm:"column:entity;type:varchar(255)"`
+		EntityID uint64     `json:"entity_id" gorm:"column:entity_id"`
+		Title    string     `json:"title" gorm:"column:title;type:varchar(255)"`
+		Message  string     `json:"message" gorm:"column:message;type:text"`
+		ReadAt   *time.Time `json:"read_at" gorm:"column:read_at"`
+	}
+
+	// NotificationPreference channels user receives for an event. missing preference means all channels
+	NotificationPreference struct {
+		basemodel.BaseModel
+		UserID uint64 `json:"user_id" gorm:"column:user_id"`
+		Event  string `json:"event" gorm:"column:event;type:varchar(255)"`
+		InApp  bool   `json:"in_app" gorm:"column:in_app;type:boolean"`
+		Email  bool   `json:"email" gorm:"column:email;type:boolean"`
+	}
+)
+
+// lender notification events
+const (
+	NotificationNewLoan     = "new_loan"
+	NotificationNewBorrower = "new_borrower"
+)
+
+// NotificationEvents events lender can subscribe to
+var NotificationEvents = []string{NotificationNewLoan, NotificationNewBorrower}
+
+// Create func
+func (model *Notification) Create() error {
+	return basemodel.Create(&model)
+}
+
+// Save func
+func (model *Notification) Save() error {
+	return basemodel.Save(&model)
+}
+
+// SingleFindFilter func
+func (model *Notification) SingleFindFilter(filter interface{}) error {
+	return basemodel.SingleFindFilter(&model, filter)
+}
+
+// PagedFindFilter func
+func (model *Notification) PagedFindFilter(page int, rows int, orderby []string, sort []string, filter interface{}) (basemodel.PagedFindResult, error) {
+	notifications := []Notification{}
+
+	return basemodel.PagedFindFilter(&notifications, page, rows, orderby, sort, filter)
+}
+
+// Create func
+func (model *NotificationPreference) Create() error {
+	return basemodel.Create(&model)
+}
+
+// Save func
+func (model *NotificationPreference) Save() error {
+	return basemodel.Save(&model)
+}
+
+// FindPreference finds user preference of event, defaults to all channels enabled
+func FindPreference(userID uint64, event string) NotificationPreference {
+	type Filter struct {
+		UserID uint64 `json:"user_id"`
+		Event  string `json:"event"`
+	}
+
+	preference := NotificationPreference{}
+	if err := basemodel.SingleFindFilter(&preference, &Filter{UserID: userID, Event: event}); err != nil {
+		return NotificationPreference{UserID: userID, Event: event, InApp: true, Email: true}
+	}
+
+	return preference
+}
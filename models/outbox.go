@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"github.com/ayannahindonesia/basemodel"
+	"github.com/jinzhu/gorm"
+	"github.com/jinzhu/gorm/dialects/postgres"
+)
+
+// Outbox kafka payload waiting to be relayed. written in the same transaction as the model change
+type Outbox struct {
+	basemodel.BaseModel
+	Topic     string         `json:"topic" gorm:"column:topic;type:varchar(255)"`
+	Model     string         `json:"model" gorm:"column:model;type:varchar(255)"`
+	Payload   postgres.Jsonb `json:"payload" gorm:"column:payload;type:jsonb"`
+	Status    string         `json:"status" gorm:"column:status;type:varchar(255)" sql:"DEFAULT:'pending'"`
+	Attempts  int            `json:"attempts" gorm:"column:attempts;type:int" sql:"DEFAULT:0"`
+	LastError string         `json:"last_error" gorm:"column:last_error;type:text"`
+	SentAt    *time.Time     `json:"sent_at" gorm:"column:sent_at"`
+}
+
+// OutboxWriter writes kafka payload of model to outbox inside transaction, see middlewares.SubmitKafkaPayloadTx
+type OutboxWriter func(tx *gorm.DB, i interface{}, model string) error
+
+// Create func
+func (model *Outbox) Create() error {
+	return basemodel.Create(&model)
+}
+
+// CreateTx create inside the given transaction
+func (model *Outbox) CreateTx(tx *gorm.DB) error {
+	return tx.Create(model).Error
+}
+
+// Save func
+func (model *Outbox) Save() error {
+	return basemodel.Save(&model)
+}
+
+// FindbyID func
+func (model *Outbox) FindbyID(id uint64) error {
+	return basemodel.FindbyID(&model, id)
+}
+
+// PagedFindFilter func
+func (model *Outbox) PagedFindFilter(page int, rows int, orderby []string, sort []string, filter interface{}) (basemodel.PagedFindResult, error) {
+	outboxes := []Outbox{}
+
+	return basemodel.PagedFindFilter(&outboxes, page, rows, orderby, sort, filter)
+}
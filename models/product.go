@@ -25,6 +25,9 @@ type Product struct {
 	Status                   string         `json:"status" gorm:"column:status;type:varchar(255)"`
 	Form                     postgres.Jsonb `json:"form" gorm:"column:form;type:text"`
 	Description     string         `json:"description" gorm:"column:description;type:text"`
+	MaxDTI                   float64        `json:"max_dti" gorm:"column:max_dti"`   // loans above it are flagged, 0 disables the check
+	HardDTI                  float64        `json:"hard_dti" gorm:"column:hard_dti"` // loans above it can not be approved, 0 disables the block
+	EarlySettlementFee       float64        `json:"early_settlement_fee" gorm:"column:early_settlement_fee"` // percent of remaining principal charged on early settlement
 }
 
 // Create func
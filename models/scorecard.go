@@ -0,0 +1,262 @@
+package models
+
+import (
+	"asira_lender/asira"
+	"encoding/json"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ayannahindonesia/basemodel"
+	"github.com/jinzhu/gorm"
+	"github.com/jinzhu/gorm/dialects/postgres"
+)
+
+type (
+	// ScoreBand points given when factor value falls in the band. numeric bands match min <= value < max,
+	// max 0 means no upper limit. text bands match any of values, case insensitive
+	ScoreBand struct {
+		Min    float64  `json:"min"`
+		Max    float64  `json:"max"`
+		Values []string `json:"values,omitempty"`
+		Points float64  `json:"points"`
+	}
+
+	// ScoreRule weighted factor of scorecard
+	ScoreRule struct {
+		Factor string      `json:"factor"`
+		Weight float64     `json:"weight"`
+		Bands  []ScoreBand `json:"bands"`
+	}
+
+	// ScoreGrade risk grade given to scores at least min_score
+	ScoreGrade struct {
+		Grade    string  `json:"grade"`
+		MinScore float64 `json:"min_score"`
+	}
+
+	// Scorecard weighted scoring rules of product
+	Scorecard struct {
+		basemodel.BaseModel
+		Product uint64         `json:"product" gorm:"column:product;foreignkey"`
+		Name    string         `json:"name" gorm:"column:name;type:varchar(255)"`
+		Rules   postgres.Jsonb `json:"rules" gorm:"column:rules;type:jsonb"`
+		Grades  postgres.Jsonb `json:"grades" gorm:"column:grades;type:jsonb"`
+		Status  string         `json:"status" gorm:"column:status;type:varchar(255)" sql:"DEFAULT:'active'"`
+	}
+
+	// ScoreFactor contribution of one rule to loan score
+	ScoreFactor struct {
+		Factor       string      `json:"factor"`
+		Value        interface{} `json:"value"`
+		Points       float64     `json:"points"`
+		Weight       float64     `json:"weight"`
+		Contribution float64     `json:"contribution"`
+	}
+)
+
+// ScoreNumericFactors borrower and loan factors scored by numeric bands
+var ScoreNumericFactors = []string{
+	"monthly_income",
+	"other_income",
+	"total_income",
+	"dependants",
+	"been_workingfor",
+	"lived_for",
+	"age",
+	"loan_amount",
+	"installment",
+	"layaway_plan",
+}
+
+// ScoreTextFactors borrower and loan factors scored by text bands
+var ScoreTextFactors = []string{
+	"home_ownership",
+	"marriage_status",
+	"last_education",
+	"field_of_work",
+	"occupation",
+	"gender",
+	"loan_intention",
+}
+
+// Create func
+func (model *Scorecard) Create() error {
+	return basemodel.Create(&model)
+}
+
+// Save func
+func (model *Scorecard) Save() error {
+	return basemodel.Save(&model)
+}
+
+// Delete func
+func (model *Scorecard) Delete() error {
+	return basemodel.Delete(&model)
+}
+
+// FindbyID func
+func (model *Scorecard) FindbyID(id uint64) error {
+	return basemodel.FindbyID(&model, id)
+}
+
+// PagedFindFilter func
+func (model *Scorecard) PagedFindFilter(page int, rows int, orderby []string, sort []string, filter interface{}) (basemodel.PagedFindResult, error) {
+	scorecards := []Scorecard{}
+
+	return basemodel.PagedFindFilter(&scorecards, page, rows, orderby, sort, filter)
+}
+
+// RuleList scoring rules of scorecard
+func (model *Scorecard) RuleList() (rules []ScoreRule) {
+	json.Unmarshal(model.Rules.RawMessage, &rules)
+
+	return rules
+}
+
+// GradeList risk grades of scorecard, highest min_score first
+func (model *Scorecard) GradeList() (grades []ScoreGrade) {
+	json.Unmarshal(model.Grades.RawMessage, &grades)
+	sort.SliceStable(grades, func(i, j int) bool {
+		return grades[i].MinScore > grades[j].MinScore
+	})
+
+	return grades
+}
+
+// FindProductScorecard finds latest active scorecard of product
+func FindProductScorecard(productID uint64) (scorecard Scorecard, err error) {
+	err = asira.App.DB.Where("product = ? AND status = ?", productID, "active").
+		Order("id desc").
+		First(&scorecard).Error
+
+	return scorecard, err
+}
+
+// ScoreLoan computes loan score, risk grade and contributing factors using active scorecard of its product.
+// scored is false when product has no active scorecard
+func ScoreLoan(loan *Loan) (scored bool, err error) {
+	scorecard, err := FindProductScorecard(loan.Product)
+	if gorm.IsRecordNotFoundError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	borrower := Borrower{}
+	if err = borrower.FindbyID(loan.Borrower); err != nil {
+		return false, err
+	}
+	// borrower info is the snapshot sent along with loan, it wins over current borrower data
+	if len(loan.BorrowerInfo.RawMessage) > 0 {
+		json.Unmarshal(loan.BorrowerInfo.RawMessage, &borrower)
+	}
+
+	score, factors := scorecard.Score(*loan, borrower)
+	factorsJSON, _ := json.Marshal(factors)
+
+	loan.Scorecard = scorecard.ID
+	loan.CreditScore = score
+	loan.RiskGrade = scorecard.Grade(score)
+	loan.ScoreFactors = &postgres.Jsonb{RawMessage: factorsJSON}
+
+	return true, nil
+}
+
+// Score weighted average of rule points for loan and borrower, along with contribution of each rule
+func (model *Scorecard) Score(loan Loan, borrower Borrower) (score float64, factors []ScoreFactor) {
+	values := scoreFactorValues(loan, borrower)
+
+	var totalWeight float64
+	for _, rule := range model.RuleList() {
+		value := values[rule.Factor]
+		factor := ScoreFactor{
+			Factor: rule.Factor,
+			Value:  value,
+			Weight: rule.Weight,
+		}
+		for _, band := range rule.Bands {
+			if band.matches(value) {
+				factor.Points = band.Points
+				break
+			}
+		}
+		factor.Contribution = factor.Points * factor.Weight
+
+		score += factor.Contribution
+		totalWeight += rule.Weight
+		factors = append(factors, factor)
+	}
+
+	if totalWeight > 0 {
+		score = math.Round(score/totalWeight*100) / 100
+		for i := range factors {
+			factors[i].Contribution = math.Round(factors[i].Contribution/totalWeight*100) / 100
+		}
+	}
+
+	return score, factors
+}
+
+// Grade risk grade of score. scores below every grade get the lowest one
+func (model *Scorecard) Grade(score float64) string {
+	grades := model.GradeList()
+	if len(grades) < 1 {
+		return ""
+	}
+	for _, grade := range grades {
+		if score >= grade.MinScore {
+			return grade.Grade
+		}
+	}
+
+	return grades[len(grades)-1].Grade
+}
+
+func (band ScoreBand) matches(value interface{}) bool {
+	switch v := value.(type) {
+	case float64:
+		return len(band.Values) < 1 && v >= band.Min && (band.Max == 0 || v < band.Max)
+	case string:
+		for _, bandValue := range band.Values {
+			if strings.EqualFold(strings.TrimSpace(bandValue), strings.TrimSpace(v)) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func scoreFactorValues(loan Loan, borrower Borrower) map[string]interface{} {
+	age := 0
+	if !borrower.Birthday.IsZero() {
+		now := time.Now()
+		age = now.Year() - borrower.Birthday.Year()
+		if now.YearDay() < borrower.Birthday.YearDay() {
+			age--
+		}
+	}
+
+	return map[string]interface{}{
+		"monthly_income":  float64(borrower.MonthlyIncome),
+		"other_income":    float64(borrower.OtherIncome),
+		"total_income":    float64(borrower.MonthlyIncome + borrower.OtherIncome),
+		"dependants":      float64(borrower.Dependants),
+		"been_workingfor": float64(borrower.BeenWorkingFor),
+		"lived_for":       float64(borrower.LivedFor),
+		"age":             float64(age),
+		"loan_amount":     loan.LoanAmount,
+		"installment":     float64(loan.Installment),
+		"layaway_plan":    loan.LayawayPlan,
+		"home_ownership":  borrower.HomeOwnership,
+		"marriage_status": borrower.MarriedStatus,
+		"last_education":  borrower.LastEducation,
+		"field_of_work":   borrower.FieldOfWork,
+		"occupation":      borrower.Occupation,
+		"gender":          borrower.Gender,
+		"loan_intention":  loan.LoanIntention,
+	}
+}
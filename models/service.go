@@ -2,15 +2,17 @@ package models
 
 import (
 	"github.com/ayannahindonesia/basemodel"
+	"github.com/jinzhu/gorm/dialects/postgres"
 )
 
 // Service main type
 type Service struct {
 	basemodel.BaseModel
-	Name        string `json:"name" gorm:"column:name;type:varchar(255)"`
-	Image       string `json:"image" gorm:"column:image"`
-	Status      string `json:"status" gorm:"column:status;type:varchar(255)"`
-	Description string `json:"description" gorm:"column:description;type:varchar(255)"`
+	Name        string          `json:"name" gorm:"column:name;type:varchar(255)"`
+	Image       string          `json:"image" gorm:"column:image"`
+	Thumbnails  *postgres.Jsonb `json:"thumbnails" gorm:"column:thumbnails;type:jsonb"`
+	Status      string          `json:"status" gorm:"column:status;type:varchar(255)"`
+	Description string          `json:"description" gorm:"column:description;type:varchar(255)"`
 }
 
 // Create func
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"asira_lender/asira"
+	"database/sql"
+	"strings"
+
+	"github.com/ayannahindonesia/basemodel"
+)
+
+// Watchlist id card number, phone number or bank account never to be approved. entries without bank apply to every bank
+type Watchlist struct {
+	basemodel.BaseModel
+	Bank   sql.NullInt64 `json:"bank" gorm:"column:bank"`
+	Kind   string        `json:"kind" gorm:"column:kind;type:varchar(255)"`
+	Value  string        `json:"value" gorm:"column:value;type:varchar(255)"`
+	Reason string        `json:"reason" gorm:"column:reason;type:text"`
+	Status string        `json:"status" gorm:"column:status;type:varchar(255)" sql:"DEFAULT:'active'"`
+}
+
+// WatchlistKinds kinds of borrower data checked against watchlist
+var WatchlistKinds = []string{
+	"id_card_number",
+	"phone",
+	"bank_account",
+}
+
+// Create func
+func (model *Watchlist) Create() error {
+	return basemodel.Create(&model)
+}
+
+// Save func
+func (model *Watchlist) Save() error {
+	return basemodel.Save(&model)
+}
+
+// Delete func
+func (model *Watchlist) Delete() error {
+	return basemodel.Delete(&model)
+}
+
+// FindbyID func
+func (model *Watchlist) FindbyID(id uint64) error {
+	return basemodel.FindbyID(&model, id)
+}
+
+// PagedFindFilter func
+func (model *Watchlist) PagedFindFilter(page int, rows int, orderby []string, sort []string, filter interface{}) (basemodel.PagedFindResult, error) {
+	watchlists := []Watchlist{}
+
+	return basemodel.PagedFindFilter(&watchlists, page, rows, orderby, sort, filter)
+}
+
+// NormalizeWatchlistValue strips separators so values match however they were typed
+func NormalizeWatchlistValue(value string) string {
+	return strings.NewReplacer(" ", "", "-", "", ".", "").Replace(strings.TrimSpace(value))
+}
+
+// WatchlistExists true when same entry is already listed for bank
+func WatchlistExists(bank sql.NullInt64, kind string, value string) bool {
+	var count int
+	db := asira.App.DB.Model(&Watchlist{}).Where("kind = ? AND value = ?", kind, NormalizeWatchlistValue(value))
+	if bank.Valid {
+		db = db.Where("bank = ?", bank.Int64)
+	} else {
+		db = db.Where("bank IS NULL")
+	}
+	db.Count(&count)
+
+	return count > 0
+}
+
+// WatchlistMatches active watchlist entries of borrower bank or every bank matching borrower data
+func WatchlistMatches(borrower Borrower) (matches []Watchlist, err error) {
+	values := map[string]string{
+		"id_card_number": NormalizeWatchlistValue(borrower.IdCardNumber),
+		"phone":          NormalizeWatchlistValue(borrower.Phone),
+		"bank_account":   NormalizeWatchlistValue(borrower.BankAccountNumber),
+	}
+
+	var (
+		conditions []string
+		args       []interface{}
+	)
+	for _, kind := range WatchlistKinds {
+		if len(values[kind]) < 1 {
+			continue
+		}
+		conditions = append(conditions, "(kind = ? AND value = ?)")
+		args = append(args, kind, values[kind])
+	}
+	if len(conditions) < 1 {
+		return matches, nil
+	}
+
+	err = asira.App.DB.Where("status = ?", "active").
+		Where("bank IS NULL OR bank = ?", borrower.Bank.Int64).
+		Where(strings.Join(conditions, " OR "), args...).
+		Order("id asc").
+		Find(&matches).Error
+
+	return matches, err
+}
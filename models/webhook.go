@@ -0,0 +1,162 @@
+package models
+
+import (
+	"asira_lender/asira"
+	"encoding/json"
+	"time"
+
+	"github.com/ayannahindonesia/basemodel"
+	"github.com/jinzhu/gorm/dialects/postgres"
+	"github.com/lib/pq"
+)
+
+type (
+	// Webhook bank system subscribed to loan events
+	Webhook struct {
+		basemodel.BaseModel
+		Bank        uint64         `json:"bank" gorm:"column:bank;foreignkey"`
+		URL         string         `json:"url" gorm:"column:url;type:text"`
+		Secret      string         `json:"secret" gorm:"column:secret;type:varchar(255)"`
+		Events      pq.StringArray `json:"events" gorm:"column:events"`
+		Description string         `json:"description" gorm:"column:description;type:text"`
+		Status      string         `json:"status" gorm:"column:status;type:varchar(255)" sql:"DEFAULT:'active'"`
+	}
+
+	// WebhookDelivery event delivery to a webhook and its latest response
+	WebhookDelivery struct {
+		basemodel.BaseModel
+		Webhook       uint64          `json:"webhook" gorm:"column:webhook;foreignkey"`
+		Bank          uint64          `json:"bank" gorm:"column:bank;foreignkey"`
+		Event         string          `json:"event" gorm:"column:event;type:varchar(255)"`
+		Reference     string          `json:"reference" gorm:"column:reference;type:varchar(255)"`
+		Payload       *postgres.Jsonb `json:"payload" gorm:"column:payload;type:jsonb"`
+		Status        string          `json:"status" gorm:"column:status;type:varchar(255)" sql:"DEFAULT:'pending'"`
+		Attempts      int             `json:"attempts" gorm:"column:attempts;type:int" sql:"DEFAULT:0"`
+		ResponseCode  int             `json:"response_code" gorm:"column:response_code;type:int"`
+		ResponseBody  string          `json:"response_body" gorm:"column:response_body;type:text"`
+		LastError     string          `json:"last_error" gorm:"column:last_error;type:text"`
+		NextAttemptAt time.Time       `json:"next_attempt_at" gorm:"column:next_attempt_at"`
+		DeliveredAt   *time.Time      `json:"delivered_at" gorm:"column:delivered_at"`
+	}
+)
+
+// webhook events sent to bank systems
+const (
+	WebhookLoanApproved  = "loan_approved"
+	WebhookLoanDisbursed = "loan_disbursed"
+	WebhookLoanPaid      = "loan_paid"
+	WebhookTest          = "webhook_test"
+)
+
+// WebhookEvents events webhook can subscribe to
+var WebhookEvents = []string{WebhookLoanApproved, WebhookLoanDisbursed, WebhookLoanPaid}
+
+// Create func
+func (model *Webhook) Create() error {
+	return basemodel.Create(&model)
+}
+
+// Save func
+func (model *Webhook) Save() error {
+	return basemodel.Save(&model)
+}
+
+// Delete func
+func (model *Webhook) Delete() error {
+	return basemodel.Delete(&model)
+}
+
+// FindbyID func
+func (model *Webhook) FindbyID(id uint64) error {
+	return basemodel.FindbyID(&model, id)
+}
+
+// SingleFindFilter func
+func (model *Webhook) SingleFindFilter(filter interface{}) error {
+	return basemodel.SingleFindFilter(&model, filter)
+}
+
+// PagedFindFilter func
+func (model *Webhook) PagedFindFilter(page int, rows int, orderby []string, sort []string, filter interface{}) (basemodel.PagedFindResult, error) {
+	webhooks := []Webhook{}
+
+	return basemodel.PagedFindFilter(&webhooks, page, rows, orderby, sort, filter)
+}
+
+// Create func
+func (model *WebhookDelivery) Create() error {
+	return basemodel.Create(&model)
+}
+
+// Save func
+func (model *WebhookDelivery) Save() error {
+	return basemodel.Save(&model)
+}
+
+// FindbyID func
+func (model *WebhookDelivery) FindbyID(id uint64) error {
+	return basemodel.FindbyID(&model, id)
+}
+
+// SingleFindFilter func
+func (model *WebhookDelivery) SingleFindFilter(filter interface{}) error {
+	return basemodel.SingleFindFilter(&model, filter)
+}
+
+// PagedFindFilter func
+func (model *WebhookDelivery) PagedFindFilter(page int, rows int, orderby []string, sort []string, filter interface{}) (basemodel.PagedFindResult, error) {
+	deliveries := []WebhookDelivery{}
+
+	return basemodel.PagedFindFilter(&deliveries, page, rows, orderby, sort, filter)
+}
+
+// NewWebhookDelivery builds pending delivery of event to webhook. reference identifies the entity state change
+func NewWebhookDelivery(webhook Webhook, event string, reference string, data interface{}) WebhookDelivery {
+	payload, _ := json.Marshal(map[string]interface{}{
+		"event":      event,
+		"bank":       webhook.Bank,
+		"data":       data,
+		"created_at": time.Now(),
+	})
+
+	return WebhookDelivery{
+		Webhook:       webhook.ID,
+		Bank:          webhook.Bank,
+		Event:         event,
+		Reference:     reference,
+		Payload:       &postgres.Jsonb{RawMessage: payload},
+		Status:        "pending",
+		NextAttemptAt: time.Now(),
+	}
+}
+
+// QueueWebhooks queues event for every active webhook of the bank subscribed to it.
+// a change seen by both loan handler and kafka consumer is queued once per reference
+func QueueWebhooks(bankID uint64, event string, reference string, data interface{}) (deliveries []WebhookDelivery, err error) {
+	var webhooks []Webhook
+
+	err = asira.App.DB.Where("bank = ? AND status = ?", bankID, "active").
+		Where("? = ANY(events)", event).
+		Find(&webhooks).Error
+	if err != nil {
+		return deliveries, err
+	}
+
+	for _, webhook := range webhooks {
+		var queued int
+		asira.App.DB.Model(&WebhookDelivery{}).
+			Where("webhook = ? AND event = ? AND reference = ?", webhook.ID, event, reference).
+			Count(&queued)
+		if queued > 0 {
+			continue
+		}
+
+		delivery := NewWebhookDelivery(webhook, event, reference, data)
+		if err = delivery.Create(); err != nil {
+			return deliveries, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, nil
+}
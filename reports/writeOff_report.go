@@ -0,0 +1,135 @@
+package reports
+
+import (
+	"asira_lender/asira"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ayannahindonesia/basemodel"
+	"github.com/labstack/echo"
+)
+
+// WriteOffReport for approved write offs and their recoveries
+func WriteOffReport(c echo.Context) error {
+	defer c.Request().Body.Close()
+	err := validatePermission(c, "write_off_report")
+	if err != nil {
+		return returnInvalidResponse(http.StatusForbidden, err, fmt.Sprintf("%s", err))
+	}
+
+	db := asira.App.DB
+
+	type WriteOffReport struct {
+		BankName     string    `json:"bank_name"`
+		ProductName  string    `json:"product_name"`
+		LoanID       string    `json:"loan_id"`
+		BorrowerName string    `json:"borrower_name"`
+		WrittenOffAt time.Time `json:"written_off_at"`
+		DaysPastDue  int       `json:"days_past_due"`
+		Principal    float64   `json:"principal"`
+		Interest     float64   `json:"interest"`
+		Penalties    float64   `json:"penalties"`
+		WrittenOff   float64   `json:"written_off"`
+		Recovered    float64   `json:"recovered"`
+		Outstanding  float64   `json:"outstanding"`
+		RecoveryRate float64   `json:"recovery_rate"`
+		Reason       string    `json:"reason"`
+	}
+	var results []WriteOffReport
+	var totalRows int
+	var offset int
+	var rows int
+	var page int
+	var lastPage int
+
+	// pagination parameters
+	rows, _ = strconv.Atoi(c.QueryParam("rows"))
+	if rows > 0 {
+		page, _ = strconv.Atoi(c.QueryParam("page"))
+		if page <= 0 {
+			page = 1
+		}
+		offset = (page * rows) - rows
+	}
+
+	db = db.Table("loan_write_offs").
+		Select("ba.name as bank_name, p.name as product_name, loan_write_offs.loan as loan_id, b.fullname as borrower_name, loan_write_offs.decided_at as written_off_at, loan_write_offs.days_past_due, loan_write_offs.principal, loan_write_offs.interest, loan_write_offs.penalties, loan_write_offs.amount as written_off, loan_write_offs.recovered, loan_write_offs.amount - loan_write_offs.recovered as outstanding, COALESCE(ROUND((loan_write_offs.recovered / NULLIF(loan_write_offs.amount, 0) * 100)::numeric, 2), 0) as recovery_rate, loan_write_offs.reason").
+		Joins("INNER JOIN loans l ON l.id = loan_write_offs.loan").
+		Joins("INNER JOIN borrowers b ON b.id = l.borrower").
+		Joins("INNER JOIN banks ba ON ba.id = loan_write_offs.bank").
+		Joins("LEFT JOIN products p ON p.id = l.product").
+		Where("loan_write_offs.status = ?", "approved")
+
+	// filters
+	if bankName := c.QueryParam("bank_name"); len(bankName) > 0 {
+		db = db.Where("LOWER(ba.name) = ?", strings.ToLower(bankName))
+	}
+	if productName := c.QueryParam("product_name"); len(productName) > 0 {
+		db = db.Where("LOWER(p.name) LIKE ?", "%"+strings.ToLower(productName)+"%")
+	}
+	if loanID := c.QueryParam("loan_id"); len(loanID) > 0 {
+		db = db.Where("loan_write_offs.loan = ?", loanID)
+	}
+	if borrowerName := c.QueryParam("borrower_name"); len(borrowerName) > 0 {
+		db = db.Where("LOWER(b.fullname) LIKE ?", "%"+strings.ToLower(borrowerName)+"%")
+	}
+	if recovered := c.QueryParam("recovered"); len(recovered) > 0 {
+		// fully recovered or still outstanding
+		if recovered == "true" {
+			db = db.Where("loan_write_offs.recovered >= loan_write_offs.amount")
+		} else {
+			db = db.Where("loan_write_offs.recovered < loan_write_offs.amount")
+		}
+	}
+	if startDate := c.QueryParam("start_date"); len(startDate) > 0 {
+		endDate := c.QueryParam("end_date")
+		if len(endDate) < 1 {
+			endDate = startDate
+		}
+		db = db.Where("loan_write_offs.decided_at::date BETWEEN ? AND ?", startDate, endDate)
+	}
+
+	tempDB := db
+	tempDB.Where("loan_write_offs.deleted_at IS NULL").Count(&totalRows)
+
+	if order := strings.Split(c.QueryParam("orderby"), ","); len(order) > 0 {
+		if sort := strings.Split(c.QueryParam("sort"), ","); len(sort) > 0 {
+			for k, v := range order {
+				q := v
+				if len(sort) > k {
+					value := sort[k]
+					if strings.ToUpper(value) == "ASC" || strings.ToUpper(value) == "DESC" {
+						q = v + " " + strings.ToUpper(value)
+					}
+				}
+				db = db.Order(q)
+			}
+		}
+	}
+
+	if rows > 0 {
+		db = db.Limit(rows).Offset(offset)
+		lastPage = int(math.Ceil(float64(totalRows) / float64(rows)))
+	}
+	err = db.Where("loan_write_offs.deleted_at IS NULL").Find(&results).Error
+	if err != nil {
+		log.Println(err)
+	}
+
+	response := basemodel.PagedFindResult{
+		TotalData:   totalRows,
+		Rows:        rows,
+		CurrentPage: page,
+		LastPage:    lastPage,
+		From:        offset + 1,
+		To:          offset + rows,
+		Data:        results,
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
@@ -19,6 +19,7 @@ func NewRouter() *echo.Echo {
 	}))
 
 	e.GET("/clientauth", handlers.ClientLogin)
+	e.GET("/files/*", handlers.StorageFile)
 
 	groups.AdminGroup(e)
 	groups.ClientGroup(e)
@@ -87,7 +87,7 @@ func TestAgentNew(t *testing.T) {
 		"phone":          "0812345567890",
 		"category":       "agent",
 		"agent_provider": 1,
-		"image":          "base64 very long string",
+		"image":          dummyImage(),
 		"banks":          []int{1},
 		"status":         "active",
 	}
@@ -97,6 +97,16 @@ func TestAgentNew(t *testing.T) {
 		Expect().
 		Status(http.StatusCreated).JSON().Object()
 	obj.ContainsKey("name").ValueEqual("name", "Test Agent")
+	obj.Value("thumbnails").Object().ContainsKey("128").ContainsKey("512")
+
+	// not an image
+	payload["username"] = "testagent2"
+	payload["email"] = "agent2@test.com"
+	payload["phone"] = "0812345567891"
+	payload["image"] = "aGVsbG8gd29ybGQ="
+	auth.POST("/admin/agents").WithJSON(payload).
+		Expect().
+		Status(http.StatusUnprocessableEntity).JSON().Object()
 
 	// test invalid
 	auth.POST("/admin/agents").WithJSON(map[string]interface{}{}).
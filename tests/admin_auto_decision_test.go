@@ -0,0 +1,226 @@
+package tests
+
+import (
+	"asira_lender/asira"
+	"asira_lender/models"
+	"asira_lender/router"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gavv/httpexpect"
+)
+
+func TestAutoDecisionRule(t *testing.T) {
+	RebuildData()
+
+	api := router.NewRouter()
+
+	server := httptest.NewServer(api)
+
+	defer server.Close()
+
+	e := httpexpect.New(t, server.URL)
+
+	auth := e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Basic "+clientBasicToken)
+	})
+
+	adminToken := getAdminLoginToken(e, auth, "1")
+
+	auth = e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Bearer "+adminToken)
+	})
+
+	// unsupported fact
+	auth.POST("/admin/auto_decision_rules").WithJSON(map[string]interface{}{
+		"product":    1,
+		"name":       "invalid",
+		"conditions": []map[string]interface{}{{"fact": "shoe_size", "operator": "lt", "value": 40}},
+		"action":     "approve",
+		"status":     "active",
+	}).
+		Expect().
+		Status(http.StatusUnprocessableEntity).JSON().Object()
+
+	// rejection needs reason
+	auth.POST("/admin/auto_decision_rules").WithJSON(map[string]interface{}{
+		"product":    1,
+		"name":       "no bank account",
+		"conditions": []map[string]interface{}{{"fact": "bank_account", "operator": "empty"}},
+		"action":     "reject",
+		"status":     "active",
+	}).
+		Expect().
+		Status(http.StatusUnprocessableEntity).JSON().Object()
+
+	obj := auth.POST("/admin/auto_decision_rules").WithJSON(map[string]interface{}{
+		"product":  1,
+		"name":     "small loan of returning borrower",
+		"priority": 10,
+		"conditions": []map[string]interface{}{
+			{"fact": "loan_amount", "operator": "lt", "value": 3000000},
+			{"fact": "paid_loans", "operator": "gte", "value": 1},
+		},
+		"action":          "approve",
+		"disburse_offset": 3,
+		"status":          "active",
+	}).
+		Expect().
+		Status(http.StatusCreated).JSON().Object()
+	obj.ContainsKey("action").ValueEqual("action", "approve")
+
+	auth.POST("/admin/auto_decision_rules").WithJSON(map[string]interface{}{
+		"product":       1,
+		"name":          "no bank account",
+		"priority":      1,
+		"conditions":    []map[string]interface{}{{"fact": "bank_account", "operator": "empty"}},
+		"action":        "reject",
+		"reject_reason": "rekening bank tidak ditemukan",
+		"status":        "active",
+	}).
+		Expect().
+		Status(http.StatusCreated).JSON().Object()
+
+	auth.GET("/admin/auto_decision_rules").WithQuery("product", 1).
+		Expect().
+		Status(http.StatusOK).JSON().Object().ValueEqual("total_data", 2)
+
+	// first time borrower is reviewed manually
+	obj = auth.POST("/admin/auto_decision_rules/test").WithJSON(map[string]interface{}{"loan_id": 2}).
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.ValueEqual("matched", false)
+	obj.Value("facts").Object().ValueEqual("paid_loans", 0)
+
+	// borrower with a fully paid loan
+	asira.App.DB.Model(&models.Loan{}).Where("id = ?", 3).Updates(map[string]interface{}{"status": "approved", "payment_status": "terbayar"})
+	obj = auth.POST("/admin/auto_decision_rules/test").WithJSON(map[string]interface{}{"loan_id": 2}).
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.ValueEqual("matched", true)
+	obj.Value("rule").Object().ValueEqual("id", 1)
+
+	auth.POST("/admin/auto_decision_rules/test").WithJSON(map[string]interface{}{"loan_id": 6}).
+		Expect().
+		Status(http.StatusOK).JSON().Object().ValueEqual("matched", false)
+
+	// higher priority rule wins
+	asira.App.DB.Model(&models.Borrower{}).Where("id = ?", 1).Update("bank_accountnumber", "")
+	obj = auth.POST("/admin/auto_decision_rules/test").WithJSON(map[string]interface{}{"loan_id": 2}).
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.Value("rule").Object().ValueEqual("id", 2).ValueEqual("action", "reject")
+
+	// inactive rules are skipped
+	auth.PATCH("/admin/auto_decision_rules/2").WithJSON(map[string]interface{}{"status": "inactive"}).
+		Expect().
+		Status(http.StatusOK).JSON().Object().ValueEqual("status", "inactive")
+	obj = auth.POST("/admin/auto_decision_rules/test").WithJSON(map[string]interface{}{"loan_id": 2}).
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.Value("rule").Object().ValueEqual("id", 1)
+
+	auth.GET("/admin/auto_decisions").WithQuery("loan", 2).
+		Expect().
+		Status(http.StatusOK).JSON().Object().ValueEqual("total_data", 0)
+
+	auth.DELETE("/admin/auto_decision_rules/1").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+
+	auth.GET("/admin/auto_decision_rules/1").
+		Expect().
+		Status(http.StatusNotFound).JSON().Object()
+}
+
+func TestAutoDecisionHeldForManualReview(t *testing.T) {
+	RebuildData()
+
+	api := router.NewRouter()
+
+	server := httptest.NewServer(api)
+
+	defer server.Close()
+
+	e := httpexpect.New(t, server.URL)
+
+	auth := e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Basic "+adminBasicToken)
+	})
+
+	adminToken := getAdminLoginToken(e, auth, "1")
+
+	auth = e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Bearer "+adminToken)
+	})
+
+	auth.POST("/admin/auto_decision_rules").WithJSON(map[string]interface{}{
+		"product":         1,
+		"name":            "small loan",
+		"conditions":      []map[string]interface{}{{"fact": "loan_amount", "operator": "lt", "value": 3000000}},
+		"action":          "approve",
+		"disburse_offset": 1,
+		"status":          "active",
+	}).
+		Expect().
+		Status(http.StatusCreated).JSON().Object()
+
+	// reviewed seed data does not hold approvals
+	asira.App.DB.Model(&models.FraudFlag{}).Update("status", "dismissed")
+
+	newLoan := func(id int) models.Loan {
+		asira.App.Bus.Publish("asira_borrower_to_lender", []byte(fmt.Sprintf(`loan:{"id":%v,"event_id":"loan-%v-1","version":1,"mode":"create","payload":{"id":%v,"borrower":1,"product":1,"loan_amount":2000000,"installment":2,"layaway_plan":100000,"otp_verified":true}}`, id, id, id)))
+
+		loan := models.Loan{}
+		loan.FindbyID(uint64(id))
+
+		return loan
+	}
+
+	// watchlisted borrower
+	watchlist := models.Watchlist{Kind: "bank_account", Value: "520384716", Status: "active"}
+	watchlist.Create()
+	if loan := newLoan(90); loan.Status != "processing" {
+		t.Errorf("expected watchlisted loan to stay processing, got %v", loan.Status)
+	}
+	asira.App.DB.Model(&watchlist).Update("status", "inactive")
+
+	// borrower with open fraud flag
+	flag := models.FraudFlag{Borrower: 1, Kind: models.FraudDuplicatePhone, Status: "open"}
+	flag.Create()
+	if loan := newLoan(91); loan.Status != "processing" {
+		t.Errorf("expected flagged loan to stay processing, got %v", loan.Status)
+	}
+	asira.App.DB.Model(&flag).Update("status", "dismissed")
+
+	// bank approval policy
+	auth.POST("/admin/banks/1/approval_policies").WithJSON(map[string]interface{}{
+		"name":   "all loans",
+		"steps":  []map[string]interface{}{{"name": "supervisor approval", "role": 3, "approvers": 1}},
+		"status": "active",
+	}).
+		Expect().
+		Status(http.StatusCreated).JSON().Object()
+	if loan := newLoan(92); loan.Status != "processing" {
+		t.Errorf("expected loan with approval policy to stay processing, got %v", loan.Status)
+	}
+	auth.GET("/admin/auto_decisions").
+		Expect().
+		Status(http.StatusOK).JSON().Object().ValueEqual("total_data", 0)
+
+	approval := models.LoanApproval{}
+	if err := asira.App.DB.Where("loan = ?", 92).First(&approval).Error; err != nil {
+		t.Errorf("expected loan 92 to enter approval queue : %v", err)
+	}
+
+	// nothing holds the approval
+	asira.App.DB.Model(&models.ApprovalPolicy{}).Update("status", "inactive")
+	if loan := newLoan(93); loan.Status != "approved" {
+		t.Errorf("expected loan 93 to be auto approved, got %v", loan.Status)
+	}
+	auth.GET("/admin/auto_decisions").WithQuery("loan", 93).
+		Expect().
+		Status(http.StatusOK).JSON().Object().ValueEqual("total_data", 1)
+}
@@ -2,6 +2,7 @@ package tests
 
 import (
 	"asira_lender/router"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -89,6 +90,20 @@ func TestNewBank(t *testing.T) {
 		Status(http.StatusCreated).JSON().Object()
 	obj.ContainsKey("name").ValueEqual("name", "Test New Bank")
 
+	// image is stored as object key and pre-signed on detail
+	payload["image"] = dummyImage()
+	obj = auth.POST("/admin/banks").WithJSON(payload).
+		Expect().
+		Status(http.StatusCreated).JSON().Object()
+	obj.Value("image").String().NotContains("/")
+	obj.Value("thumbnails").Object().Value("128").String().NotContains("/")
+
+	obj = auth.GET(fmt.Sprintf("/admin/banks/%v", int(obj.Value("id").Number().Raw()))).
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.Value("image").String().Contains("signature=")
+	obj.Value("thumbnails").Object().Value("128").String().Contains("signature=")
+
 	// test invalid
 	payload = map[string]interface{}{
 		"name": "",
@@ -0,0 +1,135 @@
+package tests
+
+import (
+	"asira_lender/router"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gavv/httpexpect"
+)
+
+func TestDocumentTypeList(t *testing.T) {
+	RebuildData()
+
+	api := router.NewRouter()
+
+	server := httptest.NewServer(api)
+
+	defer server.Close()
+
+	e := httpexpect.New(t, server.URL)
+
+	auth := e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Basic "+clientBasicToken)
+	})
+
+	adminToken := getAdminLoginToken(e, auth, "1")
+
+	auth = e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Bearer "+adminToken)
+	})
+
+	// valid response
+	obj := auth.GET("/admin/document_types").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.ContainsKey("total_data").ValueEqual("total_data", 3)
+
+	// test query found with part
+	obj = auth.GET("/admin/document_types").WithQuery("name", "gaji").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.ContainsKey("total_data").ValueEqual("total_data", 1)
+
+	// test query invalid
+	obj = auth.GET("/admin/document_types").WithQuery("name", "should not found this").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.ContainsKey("total_data").ValueEqual("total_data", 0)
+}
+
+func TestNewDocumentType(t *testing.T) {
+	RebuildData()
+
+	api := router.NewRouter()
+
+	server := httptest.NewServer(api)
+
+	defer server.Close()
+
+	e := httpexpect.New(t, server.URL)
+
+	auth := e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Basic "+clientBasicToken)
+	})
+
+	adminToken := getAdminLoginToken(e, auth, "1")
+
+	auth = e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Bearer "+adminToken)
+	})
+
+	payload := map[string]interface{}{
+		"name":        "Rekening Koran",
+		"description": "rekening koran 3 bulan terakhir",
+		"status":      "active",
+	}
+
+	// normal scenario
+	obj := auth.POST("/admin/document_types").WithJSON(payload).
+		Expect().
+		Status(http.StatusCreated).JSON().Object()
+	obj.ContainsKey("name").ValueEqual("name", "Rekening Koran")
+
+	// test invalid
+	payload = map[string]interface{}{
+		"name":   "",
+		"status": "unknown",
+	}
+	auth.POST("/admin/document_types").WithJSON(payload).
+		Expect().
+		Status(http.StatusUnprocessableEntity).JSON().Object()
+}
+
+func TestPatchDocumentType(t *testing.T) {
+	RebuildData()
+
+	api := router.NewRouter()
+
+	server := httptest.NewServer(api)
+
+	defer server.Close()
+
+	e := httpexpect.New(t, server.URL)
+
+	auth := e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Basic "+clientBasicToken)
+	})
+
+	adminToken := getAdminLoginToken(e, auth, "1")
+
+	auth = e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Bearer "+adminToken)
+	})
+
+	payload := map[string]interface{}{
+		"status": "inactive",
+	}
+
+	// valid response
+	obj := auth.PATCH("/admin/document_types/3").WithJSON(payload).
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.ContainsKey("status").ValueEqual("status", "inactive")
+
+	obj = auth.GET("/admin/document_types/3").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.ContainsKey("status").ValueEqual("status", "inactive")
+
+	// not found
+	auth.PATCH("/admin/document_types/9999").WithJSON(payload).
+		Expect().
+		Status(http.StatusNotFound).JSON().Object()
+}
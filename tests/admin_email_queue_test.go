@@ -0,0 +1,100 @@
+package tests
+
+import (
+	"asira_lender/router"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gavv/httpexpect"
+)
+
+func TestEmailQueueList(t *testing.T) {
+	RebuildData()
+
+	api := router.NewRouter()
+
+	server := httptest.NewServer(api)
+
+	defer server.Close()
+
+	e := httpexpect.New(t, server.URL)
+
+	auth := e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Basic "+clientBasicToken)
+	})
+
+	adminToken := getAdminLoginToken(e, auth, "1")
+
+	auth = e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Bearer "+adminToken)
+	})
+
+	// failed emails
+	obj := auth.GET("/admin/emails").WithQuery("status", "failed").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.ContainsKey("total_data").ValueEqual("total_data", 1)
+
+	// new user password is queued instead of sent in the request
+	auth.POST("/admin/users").WithJSON(map[string]interface{}{
+		"username": "queued user",
+		"email":    "queueduser@ayannah.id",
+		"phone":    "08119",
+		"bank":     1,
+		"status":   "active",
+		"roles":    []int{3},
+	}).
+		Expect().
+		Status(http.StatusCreated).JSON().Object()
+
+	obj = auth.GET("/admin/emails").WithQuery("status", "pending").WithQuery("event", "user_password").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.ContainsKey("total_data").ValueEqual("total_data", 1)
+	obj.Value("data").Array().First().Object().Value("recipients").Array().Contains("queueduser@ayannah.id")
+}
+
+func TestEmailQueueResend(t *testing.T) {
+	RebuildData()
+
+	api := router.NewRouter()
+
+	server := httptest.NewServer(api)
+
+	defer server.Close()
+
+	e := httpexpect.New(t, server.URL)
+
+	auth := e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Basic "+clientBasicToken)
+	})
+
+	adminToken := getAdminLoginToken(e, auth, "1")
+
+	auth = e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Bearer "+adminToken)
+	})
+
+	obj := auth.GET("/admin/emails/1").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.ContainsKey("status").ValueEqual("status", "failed")
+
+	// valid response
+	obj = auth.POST("/admin/emails/1/resend").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.ContainsKey("status").ValueEqual("status", "pending")
+	obj.ContainsKey("attempts").ValueEqual("attempts", 0)
+
+	// only failed email can be resent
+	auth.POST("/admin/emails/1/resend").
+		Expect().
+		Status(http.StatusUnprocessableEntity).JSON().Object()
+
+	// not found
+	auth.POST("/admin/emails/9999/resend").
+		Expect().
+		Status(http.StatusNotFound).JSON().Object()
+}
@@ -0,0 +1,145 @@
+package tests
+
+import (
+	"asira_lender/router"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gavv/httpexpect"
+)
+
+func TestEmailTemplateList(t *testing.T) {
+	RebuildData()
+
+	api := router.NewRouter()
+
+	server := httptest.NewServer(api)
+
+	defer server.Close()
+
+	e := httpexpect.New(t, server.URL)
+
+	auth := e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Basic "+clientBasicToken)
+	})
+
+	adminToken := getAdminLoginToken(e, auth, "1")
+
+	auth = e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Bearer "+adminToken)
+	})
+
+	// valid response
+	obj := auth.GET("/admin/email_templates").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.ContainsKey("total_data").ValueEqual("total_data", 8)
+
+	// filter by event and language
+	obj = auth.GET("/admin/email_templates").WithQuery("event", "reset_password").WithQuery("language", "en").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.ContainsKey("total_data").ValueEqual("total_data", 1)
+}
+
+func TestNewEmailTemplate(t *testing.T) {
+	RebuildData()
+
+	api := router.NewRouter()
+
+	server := httptest.NewServer(api)
+
+	defer server.Close()
+
+	e := httpexpect.New(t, server.URL)
+
+	auth := e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Basic "+clientBasicToken)
+	})
+
+	adminToken := getAdminLoginToken(e, auth, "1")
+
+	auth = e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Bearer "+adminToken)
+	})
+
+	// template of event and language already exists
+	payload := map[string]interface{}{
+		"event":     "reset_password",
+		"language":  "id",
+		"subject":   "Reset Password",
+		"html_body": "<a href=\"{{.Link}}\">reset</a>",
+		"text_body": "{{.Link}}",
+	}
+	auth.POST("/admin/email_templates").WithJSON(payload).
+		Expect().
+		Status(http.StatusUnprocessableEntity).JSON().Object()
+
+	// unknown event
+	payload["event"] = "unknown_event"
+	auth.POST("/admin/email_templates").WithJSON(payload).
+		Expect().
+		Status(http.StatusUnprocessableEntity).JSON().Object()
+
+	// unsupported language
+	payload["event"] = "reset_password"
+	payload["language"] = "fr"
+	auth.POST("/admin/email_templates").WithJSON(payload).
+		Expect().
+		Status(http.StatusUnprocessableEntity).JSON().Object()
+}
+
+func TestPatchEmailTemplate(t *testing.T) {
+	RebuildData()
+
+	api := router.NewRouter()
+
+	server := httptest.NewServer(api)
+
+	defer server.Close()
+
+	e := httpexpect.New(t, server.URL)
+
+	auth := e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Basic "+clientBasicToken)
+	})
+
+	adminToken := getAdminLoginToken(e, auth, "1")
+
+	auth = e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Bearer "+adminToken)
+	})
+
+	obj := auth.GET("/admin/email_templates").WithQuery("event", "user_password").WithQuery("language", "id").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	id := obj.Value("data").Array().First().Object().Value("id").Number().Raw()
+
+	// valid response
+	obj = auth.PATCH("/admin/email_templates/{id}", id).WithJSON(map[string]interface{}{
+		"subject": "Password {{.Username}}",
+	}).
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.ContainsKey("subject").ValueEqual("subject", "Password {{.Username}}")
+
+	// field not available in event data
+	auth.PATCH("/admin/email_templates/{id}", id).WithJSON(map[string]interface{}{
+		"html_body": "<p>{{.Link}}</p>",
+	}).
+		Expect().
+		Status(http.StatusUnprocessableEntity).JSON().Object()
+
+	// broken template syntax
+	auth.PATCH("/admin/email_templates/{id}", id).WithJSON(map[string]interface{}{
+		"text_body": "{{.Password",
+	}).
+		Expect().
+		Status(http.StatusUnprocessableEntity).JSON().Object()
+
+	// not found
+	auth.GET("/admin/email_templates/9999").
+		Expect().
+		Status(http.StatusNotFound).JSON().Object()
+}
@@ -0,0 +1,121 @@
+package tests
+
+import (
+	"asira_lender/asira"
+	"asira_lender/models"
+	"asira_lender/router"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gavv/httpexpect"
+)
+
+func TestFraudFlag(t *testing.T) {
+	RebuildData()
+
+	api := router.NewRouter()
+
+	server := httptest.NewServer(api)
+
+	defer server.Close()
+
+	e := httpexpect.New(t, server.URL)
+
+	auth := e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Basic "+clientBasicToken)
+	})
+
+	adminToken := getAdminLoginToken(e, auth, "1")
+	lenderToken := getLenderLoginToken(e, auth, "1")
+
+	admin := e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Bearer "+adminToken)
+	})
+	lender := e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Bearer "+lenderToken)
+	})
+
+	// borrower 1 and 2 share employer number and related person phone
+	arr := admin.POST("/admin/borrower/1/fraud_check").
+		Expect().
+		Status(http.StatusOK).JSON().Array()
+	arr.Length().Equal(2)
+	arr.Element(0).Object().ValueEqual("kind", "duplicate_employer_number")
+	arr.Element(0).Object().ValueEqual("matches", []int{2})
+	arr.Element(0).Object().ValueEqual("status", "open")
+	arr.Element(1).Object().ValueEqual("kind", "duplicate_related_phone")
+
+	// matched borrower is flagged too
+	obj := admin.GET("/admin/fraud_flags").WithQuery("borrower", 2).
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.ContainsKey("total_data").ValueEqual("total_data", 2)
+
+	// rescanning does not duplicate flags
+	admin.POST("/admin/borrower/1/fraud_check").
+		Expect().
+		Status(http.StatusOK).JSON().Array().Length().Equal(2)
+	obj = admin.GET("/admin/fraud_flags").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.ContainsKey("total_data").ValueEqual("total_data", 4)
+
+	asira.App.DB.Model(&models.Borrower{}).Where("id = ?", 2).Update("phone", "081234567890")
+	arr = admin.POST("/admin/borrower/1/fraud_check").
+		Expect().
+		Status(http.StatusOK).JSON().Array()
+	arr.Length().Equal(3)
+	arr.Element(0).Object().ValueEqual("kind", "duplicate_phone")
+
+	obj = admin.GET("/admin/fraud_flags").WithQuery("borrower", 1).WithQuery("kind", "duplicate_phone").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.ContainsKey("total_data").ValueEqual("total_data", 1)
+	flagID := int(obj.Value("data").Array().Element(0).Object().Value("id").Number().Raw())
+
+	admin.GET("/admin/fraud_flags/{id}", flagID).
+		Expect().
+		Status(http.StatusOK).JSON().Object().ValueEqual("borrower", 1)
+	admin.GET("/admin/fraud_flags/9999").
+		Expect().
+		Status(http.StatusNotFound).JSON().Object()
+	admin.POST("/admin/borrower/9999/fraud_check").
+		Expect().
+		Status(http.StatusNotFound).JSON().Object()
+
+	// lender sees open flags of its borrower
+	obj = lender.GET("/lender/borrower_list/1/detail").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.Value("fraud_flags").Array().Length().Equal(3)
+
+	// review
+	admin.PATCH("/admin/fraud_flags/{id}", flagID).WithJSON(map[string]interface{}{
+		"status": "open",
+	}).
+		Expect().
+		Status(http.StatusUnprocessableEntity).JSON().Object()
+	obj = admin.PATCH("/admin/fraud_flags/{id}", flagID).WithJSON(map[string]interface{}{
+		"status": "dismissed",
+		"note":   "family members sharing a phone",
+	}).
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.ValueEqual("status", "dismissed")
+	obj.ValueEqual("review_note", "family members sharing a phone")
+	obj.ValueEqual("reviewed_by", 1)
+
+	// dismissed flag stays dismissed without new matches
+	admin.POST("/admin/borrower/1/fraud_check").
+		Expect().
+		Status(http.StatusOK).JSON().Array().Element(0).Object().ValueEqual("status", "dismissed")
+	lender.GET("/lender/borrower_list/1/detail").
+		Expect().
+		Status(http.StatusOK).JSON().Object().Value("fraud_flags").Array().Length().Equal(2)
+
+	obj = admin.GET("/admin/fraud_flags").WithQuery("status", "dismissed").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.ContainsKey("total_data").ValueEqual("total_data", 1)
+}
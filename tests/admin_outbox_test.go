@@ -0,0 +1,75 @@
+package tests
+
+import (
+	"asira_lender/router"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gavv/httpexpect"
+)
+
+func TestOutboxMetrics(t *testing.T) {
+	RebuildData()
+
+	api := router.NewRouter()
+
+	server := httptest.NewServer(api)
+
+	defer server.Close()
+
+	e := httpexpect.New(t, server.URL)
+
+	auth := e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Basic "+adminBasicToken)
+	})
+
+	adminToken := getAdminLoginToken(e, auth, "1")
+
+	auth = e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Bearer "+adminToken)
+	})
+
+	// no outbox yet
+	obj := auth.GET("/admin/outbox/metrics").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.ContainsKey("pending").ValueEqual("pending", 0)
+
+	payload := map[string]interface{}{
+		"title":       "outbox faq",
+		"description": "<html>outbox</html>",
+	}
+	obj = auth.POST("/admin/faq").WithJSON(payload).
+		Expect().
+		Status(http.StatusCreated).JSON().Object()
+	faqID := int(obj.Value("id").Number().Raw())
+
+	// relay does not run in unit testing, row stays pending
+	obj = auth.GET("/admin/outbox/metrics").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.ContainsKey("pending").ValueEqual("pending", 1)
+	obj.ContainsKey("lag_seconds")
+
+	// update and delete are written locally with their outbox rows
+	auth.PATCH(fmt.Sprintf("/admin/faq/%v", faqID)).WithJSON(map[string]interface{}{"title": "outbox faq updated"}).
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	auth.GET(fmt.Sprintf("/admin/faq/%v", faqID)).
+		Expect().
+		Status(http.StatusOK).JSON().Object().ValueEqual("title", "outbox faq updated")
+
+	auth.DELETE(fmt.Sprintf("/admin/faq/%v", faqID)).
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	auth.GET(fmt.Sprintf("/admin/faq/%v", faqID)).
+		Expect().
+		Status(http.StatusNotFound).JSON().Object()
+
+	obj = auth.GET("/admin/outbox/metrics").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.ContainsKey("pending").ValueEqual("pending", 3)
+}
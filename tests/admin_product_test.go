@@ -180,6 +180,25 @@ func TestPatchProduct(t *testing.T) {
 		Status(http.StatusOK).JSON().Object()
 	obj.ContainsKey("name").ValueEqual("name", "Test Service Product Patch")
 
+	// dti limits and settlement fee can be set and reset to 0
+	obj = auth.PATCH("/admin/products/1").WithJSON(map[string]interface{}{
+		"max_dti":              30,
+		"hard_dti":             40,
+		"early_settlement_fee": 2,
+	}).
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.ValueEqual("max_dti", 30).ValueEqual("hard_dti", 40).ValueEqual("early_settlement_fee", 2)
+	obj = auth.PATCH("/admin/products/1").WithJSON(map[string]interface{}{
+		"max_dti":              0,
+		"hard_dti":             0,
+		"early_settlement_fee": 0,
+	}).
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.ValueEqual("max_dti", 0).ValueEqual("hard_dti", 0).ValueEqual("early_settlement_fee", 0).
+		ValueEqual("name", "Test Service Product Patch")
+
 	// valid response
 	payload = map[string]interface{}{
 		"status": "invalid",
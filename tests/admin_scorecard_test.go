@@ -0,0 +1,136 @@
+package tests
+
+import (
+	"asira_lender/router"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gavv/httpexpect"
+)
+
+func TestScorecard(t *testing.T) {
+	RebuildData()
+
+	api := router.NewRouter()
+
+	server := httptest.NewServer(api)
+
+	defer server.Close()
+
+	e := httpexpect.New(t, server.URL)
+
+	auth := e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Basic "+clientBasicToken)
+	})
+
+	adminToken := getAdminLoginToken(e, auth, "1")
+	lenderToken := getLenderLoginToken(e, auth, "1")
+
+	admin := e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Bearer "+adminToken)
+	})
+	lender := e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Bearer "+lenderToken)
+	})
+
+	payload := map[string]interface{}{
+		"product": 1,
+		"name":    "consumer loan",
+		"rules": []map[string]interface{}{
+			{
+				"factor": "monthly_income",
+				"weight": 2,
+				"bands": []map[string]interface{}{
+					{"min": 0, "max": 3000000, "points": 20},
+					{"min": 3000000, "points": 80},
+				},
+			},
+			{
+				"factor": "home_ownership",
+				"weight": 1,
+				"bands": []map[string]interface{}{
+					{"values": []string{"privately owned"}, "points": 100},
+					{"values": []string{"rent"}, "points": 40},
+				},
+			},
+		},
+		"grades": []map[string]interface{}{
+			{"grade": "A", "min_score": 80},
+			{"grade": "B", "min_score": 60},
+			{"grade": "C", "min_score": 0},
+		},
+		"status": "active",
+	}
+
+	// unsupported factor
+	invalid := map[string]interface{}{
+		"product": 1,
+		"name":    "invalid",
+		"rules":   []map[string]interface{}{{"factor": "shoe_size", "weight": 1, "bands": []map[string]interface{}{{"min": 0, "points": 1}}}},
+		"grades":  payload["grades"],
+		"status":  "active",
+	}
+	admin.POST("/admin/scorecards").WithJSON(invalid).
+		Expect().
+		Status(http.StatusUnprocessableEntity).JSON().Object()
+
+	// product without scorecard can not be scored
+	admin.POST("/admin/loan/1/score").
+		Expect().
+		Status(http.StatusUnprocessableEntity).JSON().Object()
+
+	obj := admin.POST("/admin/scorecards").WithJSON(payload).
+		Expect().
+		Status(http.StatusCreated).JSON().Object()
+	obj.ContainsKey("product").ValueEqual("product", 1)
+
+	admin.GET("/admin/scorecards").WithQuery("product", 1).
+		Expect().
+		Status(http.StatusOK).JSON().Object().ValueEqual("total_data", 1)
+
+	obj = admin.PATCH("/admin/scorecards/1").WithJSON(map[string]interface{}{
+		"name": "consumer loan v2",
+	}).
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.ValueEqual("name", "consumer loan v2")
+	obj.Value("rules").Array().Length().Equal(2)
+
+	// (2 * 80 + 1 * 100) / 3
+	obj = admin.POST("/admin/loan/1/score").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.ValueEqual("credit_score", 86.67).ValueEqual("risk_grade", "A").ValueEqual("scorecard", 1)
+
+	// lender sees contributing factors
+	obj = lender.GET("/lender/loanrequest_list/1/detail").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.ValueEqual("risk_grade", "A")
+	factors := obj.Value("score_factors").Array()
+	factors.Length().Equal(2)
+	factors.Element(0).Object().ValueEqual("factor", "monthly_income").ValueEqual("points", 80).ValueEqual("contribution", 53.33)
+
+	// filter and sort by score
+	lender.GET("/lender/loanrequest_list").WithQuery("risk_grade", "A,B").
+		Expect().
+		Status(http.StatusOK).JSON().Object().ValueEqual("total_data", 1)
+
+	lender.GET("/lender/loanrequest_list").WithQuery("min_score", 90).
+		Expect().
+		Status(http.StatusOK).JSON().Object().ValueEqual("total_data", 0)
+
+	obj = lender.GET("/lender/loanrequest_list").WithQuery("orderby", "credit_score").WithQuery("sort", "desc").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.Value("data").Array().Element(0).Object().ValueEqual("id", 1)
+
+	admin.DELETE("/admin/scorecards/1").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+
+	admin.GET("/admin/scorecards/1").
+		Expect().
+		Status(http.StatusNotFound).JSON().Object()
+}
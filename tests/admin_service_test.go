@@ -77,7 +77,7 @@ func TestNewService(t *testing.T) {
 
 	payload := map[string]interface{}{
 		"name":        "Test New Bank Service",
-		"image":       "base64 super long image string",
+		"image":       dummyImage(),
 		"status":      "active",
 		"description": "tenor minimum 3 bln, tenor maksimal 12 bln",
 	}
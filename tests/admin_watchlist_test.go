@@ -0,0 +1,131 @@
+package tests
+
+import (
+	"asira_lender/asira"
+	"asira_lender/router"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gavv/httpexpect"
+)
+
+func TestWatchlist(t *testing.T) {
+	RebuildData()
+
+	api := router.NewRouter()
+
+	server := httptest.NewServer(api)
+
+	defer server.Close()
+
+	e := httpexpect.New(t, server.URL)
+
+	auth := e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Basic "+clientBasicToken)
+	})
+
+	adminToken := getAdminLoginToken(e, auth, "1")
+	lenderToken := getLenderLoginToken(e, auth, "1")
+
+	admin := e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Bearer "+adminToken)
+	})
+	lender := e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Bearer "+lenderToken)
+	})
+
+	// unsupported kind
+	admin.POST("/admin/watchlists").WithJSON(map[string]interface{}{
+		"kind":   "email",
+		"value":  "emaila@domain.com",
+		"status": "active",
+	}).
+		Expect().
+		Status(http.StatusUnprocessableEntity).JSON().Object()
+
+	// borrower 1 id card number, separators are ignored
+	obj := admin.POST("/admin/watchlists").WithJSON(map[string]interface{}{
+		"bank":   1,
+		"kind":   "id_card_number",
+		"value":  "9876-1234-5123-4567-789",
+		"reason": "identity theft report",
+		"status": "active",
+	}).
+		Expect().
+		Status(http.StatusCreated).JSON().Object()
+	obj.ValueEqual("value", "9876123451234567789")
+	watchlistID := int(obj.Value("id").Number().Raw())
+
+	admin.POST("/admin/watchlists").WithJSON(map[string]interface{}{
+		"bank":   1,
+		"kind":   "id_card_number",
+		"value":  "9876123451234567789",
+		"status": "active",
+	}).
+		Expect().
+		Status(http.StatusUnprocessableEntity).JSON().Object()
+
+	// csv import skips listed entries and reports invalid rows
+	csv := "kind,value,reason\n" +
+		"bank_account,5123456789865,fraudulent account\n" +
+		"id_card_number,9876123451234567789,\n" +
+		"email,emaila@domain.com,\n" +
+		"phone,,\n"
+	obj = admin.POST("/admin/watchlists/import").WithMultipart().WithFileBytes("file", "watchlist.csv", []byte(csv)).
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.ValueEqual("imported", 1).ValueEqual("skipped", 1).ValueEqual("failed", 2)
+	obj.Value("errors").Array().Element(0).Object().ValueEqual("line", 4)
+
+	admin.POST("/admin/watchlists/import").WithMultipart().WithFileBytes("file", "watchlist.csv", []byte("name,number\nfoo,1\n")).
+		Expect().
+		Status(http.StatusUnprocessableEntity).JSON().Object()
+
+	obj = admin.GET("/admin/watchlists").WithQuery("kind", "bank_account").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.ContainsKey("total_data").ValueEqual("total_data", 1)
+
+	admin.GET("/admin/watchlists/{id}", watchlistID).
+		Expect().
+		Status(http.StatusOK).JSON().Object().ValueEqual("reason", "identity theft report")
+	admin.GET("/admin/watchlists/9999").
+		Expect().
+		Status(http.StatusNotFound).JSON().Object()
+
+	// listed borrower and loan can not be approved
+	lender.GET("/lender/loanrequest_list/1/detail/approve").WithQuery("disburse_date", "2019-10-11").
+		Expect().
+		Status(http.StatusUnprocessableEntity).JSON().Object()
+	lender.GET("/lender/borrower_list/2/approve").WithQuery("account_number", "5123456789865").
+		Expect().
+		Status(http.StatusUnprocessableEntity).JSON().Object()
+
+	// inactive entries are not checked
+	admin.PATCH("/admin/watchlists/{id}", watchlistID).WithJSON(map[string]interface{}{
+		"status": "inactive",
+	}).
+		Expect().
+		Status(http.StatusOK).JSON().Object().ValueEqual("status", "inactive")
+	lender.GET("/lender/loanrequest_list/1/detail/approve").WithQuery("disburse_date", "2019-10-11").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+
+	// override permission
+	asira.App.DB.Exec("UPDATE roles SET permissions = array_append(permissions, ?) WHERE name = ?", "lender_watchlist_override", "Banker")
+	lenderToken = getLenderLoginToken(e, auth, "1")
+	lender = e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Bearer "+lenderToken)
+	})
+	lender.GET("/lender/borrower_list/2/approve").WithQuery("account_number", "5123456789865").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+
+	admin.DELETE("/admin/watchlists/{id}", watchlistID).
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	admin.GET("/admin/watchlists/{id}", watchlistID).
+		Expect().
+		Status(http.StatusNotFound).JSON().Object()
+}
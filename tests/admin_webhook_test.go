@@ -0,0 +1,125 @@
+package tests
+
+import (
+	"asira_lender/middlewares"
+	"asira_lender/router"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gavv/httpexpect"
+)
+
+func TestWebhook(t *testing.T) {
+	RebuildData()
+
+	api := router.NewRouter()
+
+	server := httptest.NewServer(api)
+
+	defer server.Close()
+
+	e := httpexpect.New(t, server.URL)
+
+	auth := e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Basic "+clientBasicToken)
+	})
+
+	adminToken := getAdminLoginToken(e, auth, "1")
+
+	auth = e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Bearer "+adminToken)
+	})
+
+	// bank system receiving webhooks
+	var signature, expected string
+	bankSystem := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		signature = r.Header.Get("X-Asira-Signature")
+		expected = "sha256=" + middlewares.SignWebhook("testsecret", body)
+		w.Write([]byte("ok"))
+	}))
+	defer bankSystem.Close()
+
+	// valid response
+	obj := auth.GET("/admin/banks/1/webhooks").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.ContainsKey("total_data").ValueEqual("total_data", 1)
+
+	auth.GET("/admin/banks/2/webhooks").
+		Expect().
+		Status(http.StatusOK).JSON().Object().ValueEqual("total_data", 0)
+
+	// new webhook
+	payload := map[string]interface{}{
+		"url":    bankSystem.URL,
+		"secret": "testsecret",
+		"events": []string{"loan_approved", "loan_paid"},
+		"status": "active",
+	}
+	obj = auth.POST("/admin/banks/1/webhooks").WithJSON(payload).
+		Expect().
+		Status(http.StatusCreated).JSON().Object()
+	obj.ContainsKey("bank").ValueEqual("bank", 1)
+	webhookID := obj.Value("id").Number().Raw()
+
+	// unknown event
+	payload["events"] = []string{"loan_deleted"}
+	auth.POST("/admin/banks/1/webhooks").WithJSON(payload).
+		Expect().
+		Status(http.StatusUnprocessableEntity).JSON().Object()
+
+	// generated secret
+	delete(payload, "secret")
+	payload["events"] = []string{"loan_disbursed"}
+	obj = auth.POST("/admin/banks/2/webhooks").WithJSON(payload).
+		Expect().
+		Status(http.StatusCreated).JSON().Object()
+	obj.Value("secret").String().Length().Equal(64)
+
+	// webhook belongs to other bank
+	auth.GET("/admin/banks/2/webhooks/1").
+		Expect().
+		Status(http.StatusNotFound).JSON().Object()
+
+	// patch
+	obj = auth.PATCH("/admin/banks/1/webhooks/1").WithJSON(map[string]interface{}{
+		"status": "inactive",
+	}).
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.ContainsKey("status").ValueEqual("status", "inactive")
+
+	// test event is delivered signed
+	obj = auth.POST("/admin/banks/1/webhooks/{id}/test", webhookID).
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.ContainsKey("status").ValueEqual("status", "sent")
+	obj.ContainsKey("response_code").ValueEqual("response_code", 200)
+	if signature != expected {
+		t.Errorf("invalid webhook signature %v, expected %v", signature, expected)
+	}
+
+	// failed test event is logged
+	obj = auth.POST("/admin/banks/1/webhooks/1/test").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.ContainsKey("status").ValueEqual("status", "pending")
+	obj.Value("last_error").String().NotEmpty()
+
+	obj = auth.GET("/admin/banks/1/webhooks/{id}/deliveries", webhookID).
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.ContainsKey("total_data").ValueEqual("total_data", 1)
+
+	// delete
+	auth.DELETE("/admin/banks/1/webhooks/1").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+
+	auth.GET("/admin/banks/1/webhooks/1").
+		Expect().
+		Status(http.StatusNotFound).JSON().Object()
+}
@@ -5,8 +5,10 @@ import (
 	"asira_lender/migration"
 	"encoding/base64"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"os"
+	"strings"
 
 	"github.com/gavv/httpexpect"
 )
@@ -24,6 +26,13 @@ func init() {
 	}
 }
 
+// dummyImage base64 jpeg used for upload payloads
+func dummyImage() string {
+	image, _ := ioutil.ReadFile("../migration/image_dummy.txt")
+
+	return strings.TrimSpace(string(image))
+}
+
 func RebuildData() {
 	migration.Truncate([]string{"all"})
 	migration.TestSeed()
@@ -47,6 +56,11 @@ func getLenderLoginToken(e *httpexpect.Expect, auth *httpexpect.Expect, lender_i
 			"key":      "Banktoib",
 			"password": "password",
 		}
+	case "3":
+		payload = map[string]interface{}{
+			"key":      "Banktoid",
+			"password": "password",
+		}
 	}
 
 	obj = auth.POST("/client/lender_login").WithJSON(payload).
@@ -0,0 +1,133 @@
+package tests
+
+import (
+	"asira_lender/asira"
+	"asira_lender/cron"
+	"asira_lender/models"
+	"asira_lender/router"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gavv/httpexpect"
+)
+
+func TestKafkaRedeliveredEvent(t *testing.T) {
+	RebuildData()
+
+	api := router.NewRouter()
+
+	server := httptest.NewServer(api)
+
+	defer server.Close()
+
+	e := httpexpect.New(t, server.URL)
+
+	auth := e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Basic "+adminBasicToken)
+	})
+
+	adminToken := getAdminLoginToken(e, auth, "1")
+
+	auth = e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Bearer "+adminToken)
+	})
+
+	message := []byte(`faq:{"id":98,"event_id":"faq-98-1","version":1,"mode":"create","payload":{"id":98,"title":"bus faq","description":"from borrower"}}`)
+	asira.App.Bus.Publish("asira_borrower_to_lender", message)
+
+	auth.PATCH("/admin/faq/98").WithJSON(map[string]interface{}{"title": "local faq"}).
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+
+	// redelivered event is consumed once
+	asira.App.Bus.Publish("asira_borrower_to_lender", message)
+
+	auth.GET("/admin/faq/98").
+		Expect().
+		Status(http.StatusOK).JSON().Object().ValueEqual("title", "local faq")
+
+	var consumed int
+	asira.App.DB.Model(&models.ConsumedEvent{}).Where("event_id = ?", "faq-98-1").Count(&consumed)
+	if consumed != 1 {
+		t.Errorf("expected event faq-98-1 consumed once, got %v", consumed)
+	}
+}
+
+func TestKafkaStaleVersion(t *testing.T) {
+	RebuildData()
+
+	version := models.EntityVersion{Model: "faq", EntityID: 97, Version: 5}
+	if err := version.Upsert(); err != nil {
+		t.Fatal(err)
+	}
+
+	// older version never lowers the stored one
+	version = models.EntityVersion{Model: "faq", EntityID: 97, Version: 3}
+	if err := version.Upsert(); err != nil {
+		t.Fatal(err)
+	}
+	latest, err := (&models.EntityVersion{}).FindVersion("faq", 97)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest != 5 {
+		t.Errorf("expected version 5, got %v", latest)
+	}
+
+	// event older than stored version is ignored
+	asira.App.Bus.Publish("asira_borrower_to_lender", []byte(`faq:{"id":97,"event_id":"faq-97-4","version":4,"mode":"create","payload":{"id":97,"title":"stale faq","description":"from borrower"}}`))
+
+	faq := models.FAQ{}
+	if err = faq.FindbyID(97); err == nil {
+		t.Errorf("expected stale faq 97 not to be created")
+	}
+
+	var consumed int
+	asira.App.DB.Model(&models.ConsumedEvent{}).Where("event_id = ?", "faq-97-4").Count(&consumed)
+	if consumed != 0 {
+		t.Errorf("expected stale event not to be marked consumed, got %v", consumed)
+	}
+
+	// version is taken from payload updated_at, not from the version field
+	asira.App.Bus.Publish("asira_borrower_to_lender", []byte(`faq:{"id":96,"event_id":"faq-96-1","version":1,"mode":"create","payload":{"id":96,"title":"new faq","description":"from borrower","updated_at":"2020-03-02T00:00:00Z"}}`))
+	asira.App.Bus.Publish("asira_borrower_to_lender", []byte(`faq:{"id":96,"event_id":"faq-96-2","version":99999999999999999,"mode":"update","payload":{"id":96,"title":"old faq","description":"from borrower","updated_at":"2020-03-01T00:00:00Z"}}`))
+
+	faq = models.FAQ{}
+	if err = faq.FindbyID(96); err != nil || faq.Title != "new faq" {
+		t.Errorf("expected faq 96 to keep newer title, got %v : %v", faq.Title, err)
+	}
+	latest, _ = (&models.EntityVersion{}).FindVersion("faq", 96)
+	if expected := time.Date(2020, 3, 2, 0, 0, 0, 0, time.UTC).UnixNano(); latest != expected {
+		t.Errorf("expected faq 96 version %v, got %v", expected, latest)
+	}
+
+	// failed apply is not marked consumed
+	asira.App.Bus.Publish("asira_borrower_to_lender", []byte(`faq:{"id":95,"event_id":"faq-95-1","mode":"unknown","payload":{"id":95,"title":"faq"}}`))
+	asira.App.DB.Model(&models.ConsumedEvent{}).Where("event_id = ?", "faq-95-1").Count(&consumed)
+	if consumed != 0 {
+		t.Errorf("expected failed event not to be marked consumed, got %v", consumed)
+	}
+}
+
+func TestPurgeConsumedEvents(t *testing.T) {
+	RebuildData()
+
+	for _, eventID := range []string{"expired-1", "expired-2", "recent-1"} {
+		event := models.ConsumedEvent{EventID: eventID, Model: "faq"}
+		if err := event.Create(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	asira.App.DB.Model(&models.ConsumedEvent{}).Where("event_id LIKE ?", "expired-%").
+		Update("created_at", time.Now().Add(-48*time.Hour))
+
+	cron.PurgeConsumedEvents(24)()
+
+	var eventIDs []string
+	asira.App.DB.Model(&models.ConsumedEvent{}).Order("event_id asc").Pluck("event_id", &eventIDs)
+	if len(eventIDs) != 1 || eventIDs[0] != "recent-1" {
+		t.Errorf("expected only recent-1 to be kept, got %v", eventIDs)
+	}
+}
@@ -0,0 +1,176 @@
+package tests
+
+import (
+	"asira_lender/asira"
+	"asira_lender/middlewares"
+	"asira_lender/models"
+	"asira_lender/router"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gavv/httpexpect"
+)
+
+func TestLenderBankStatementReconciliation(t *testing.T) {
+	RebuildData()
+
+	api := router.NewRouter()
+
+	server := httptest.NewServer(api)
+
+	defer server.Close()
+
+	e := httpexpect.New(t, server.URL)
+
+	auth := e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Basic "+clientBasicToken)
+	})
+
+	lenderToken := getLenderLoginToken(e, auth, "1")
+
+	auth = e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Bearer "+lenderToken)
+	})
+
+	// loan 1 of borrower 1 account 520384716 has installments 1 to 5 of 1200000 each
+	asira.App.DB.Model(&models.Loan{}).Where("id = ?", 1).Update("status", "approved")
+
+	auth.POST("/lender/bank_statements").WithMultipart().WithFileBytes("file", "statement.csv", []byte("name,number\nfoo,1\n")).
+		Expect().
+		Status(http.StatusUnprocessableEntity).JSON().Object()
+
+	statement := "date,description,reference,account_number,type,amount\n" +
+		"2020-03-01,transfer,,520384716,CR,\"1.200.000,00\"\n" +
+		"2020-03-02,transfer,LOAN 1,,CR,1200000\n" +
+		"2020-03-03,transfer,,999999,CR,1200000\n" +
+		"2020-03-03,admin fee,,,DB,5000\n" +
+		"2020-03-04,transfer,LOAN 1,,CR,700000\n" +
+		"2020-03-04,transfer,,,CR,12345\n"
+
+	obj := auth.POST("/lender/bank_statements").WithMultipart().WithFileBytes("file", "statement.csv", []byte(statement)).
+		Expect().
+		Status(http.StatusCreated).JSON().Object()
+	obj.Value("statement").Object().
+		ValueEqual("format", "csv").
+		ValueEqual("credits", 5).
+		ValueEqual("matched", 2).
+		ValueEqual("ambiguous", 2).
+		ValueEqual("unmatched", 1)
+	lines := obj.Value("lines").Array()
+	lines.Length().Equal(5)
+	// account number and loan reference match consecutive installments
+	lines.Element(0).Object().ValueEqual("status", "matched").ValueEqual("installment", 1).ValueEqual("loan", 1)
+	lines.Element(1).Object().ValueEqual("status", "matched").ValueEqual("installment", 2)
+	// amount alone only suggests candidates
+	lines.Element(2).Object().ValueEqual("status", "ambiguous").ValueEqual("candidates", []int{3})
+	// loan reference with different amount
+	lines.Element(3).Object().ValueEqual("status", "ambiguous").ValueEqual("candidates", []int{3})
+	lines.Element(4).Object().ValueEqual("status", "unmatched")
+
+	statementID := int(obj.Value("statement").Object().Value("id").Number().Raw())
+	lineIDs := []int{}
+	for i := 0; i < 5; i++ {
+		lineIDs = append(lineIDs, int(lines.Element(i).Object().Value("id").Number().Raw()))
+	}
+
+	auth.POST(fmt.Sprintf("/lender/bank_statements/%v/confirm", statementID)).WithJSON(map[string]interface{}{}).
+		Expect().
+		Status(http.StatusUnprocessableEntity).JSON().Object()
+
+	obj = auth.POST(fmt.Sprintf("/lender/bank_statements/%v/confirm", statementID)).WithJSON(map[string]interface{}{
+		"lines": []map[string]interface{}{
+			{"line": lineIDs[0]},
+			{"line": lineIDs[1]},
+			{"line": lineIDs[3], "installment": 3},
+			{"line": lineIDs[4]},
+			// installment 1 is already paid by the first credit
+			{"line": lineIDs[2], "installment": 1},
+		},
+	}).
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.Value("lines").Array().Length().Equal(3)
+	obj.Value("installments").Array().Length().Equal(3)
+	obj.Value("installments").Array().Element(2).Object().
+		ValueEqual("id", 3).
+		ValueEqual("paid_status", true).
+		ValueEqual("paid_amount", 700000).
+		ValueEqual("underpayment", 500000)
+	obj.Value("errors").Array().Length().Equal(2)
+	obj.Value("statement").Object().ValueEqual("applied", 3).ValueEqual("status", "reviewing")
+
+	// line read before it was applied is not resolved again
+	staleStatement := models.BankStatement{}
+	staleStatement.FindbyID(uint64(statementID))
+	staleLine := models.BankStatementLine{}
+	asira.App.DB.First(&staleLine, lineIDs[0])
+	staleLine.Status = "matched"
+	err := staleStatement.ResolveLine(&staleLine, nil, middlewares.SubmitKafkaPayloadTx)
+	if _, ok := err.(models.ApprovalError); !ok {
+		t.Errorf("resolving applied line got %v, expected approval error", err)
+	}
+	asira.App.DB.First(&staleLine, lineIDs[0])
+	if staleLine.Status != "applied" {
+		t.Errorf("line %v status %v, expected applied", lineIDs[0], staleLine.Status)
+	}
+
+	// applied credits can not be confirmed again
+	obj = auth.POST(fmt.Sprintf("/lender/bank_statements/%v/confirm", statementID)).WithJSON(map[string]interface{}{
+		"lines": []map[string]interface{}{
+			{"line": lineIDs[0]},
+			{"line": lineIDs[2], "ignore": true},
+			{"line": lineIDs[4], "ignore": true},
+		},
+	}).
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.Value("errors").Array().Length().Equal(1)
+	obj.Value("statement").Object().ValueEqual("status", "completed")
+
+	// uploading the same statement again marks applied credits duplicate
+	obj = auth.POST("/lender/bank_statements").WithMultipart().WithFileBytes("file", "statement.csv", []byte(statement)).
+		Expect().
+		Status(http.StatusCreated).JSON().Object()
+	obj.Value("statement").Object().ValueEqual("duplicates", 3)
+
+	// mt940 credits, the next outstanding installment of loan 1 is installment 4
+	mt940 := ":20:STATEMENT1\r\n" +
+		":25:1234567890\r\n" +
+		":28C:1/1\r\n" +
+		":60F:C200301IDR0,00\r\n" +
+		":61:2003050305C1200000,00NTRFLOAN 1//B1\r\n" +
+		":86:SETORAN CICILAN\r\n" +
+		"BORROWER 1\r\n" +
+		":61:2003050305D5000,00NCHGNONREF\r\n" +
+		":86:BIAYA ADMIN\r\n" +
+		":62F:C200305IDR1195000,00\r\n" +
+		"-\r\n"
+	obj = auth.POST("/lender/bank_statements").WithMultipart().WithFileBytes("file", "statement.sta", []byte(mt940)).
+		Expect().
+		Status(http.StatusCreated).JSON().Object()
+	obj.Value("statement").Object().
+		ValueEqual("format", "mt940").
+		ValueEqual("account_number", "1234567890").
+		ValueEqual("credits", 1).
+		ValueEqual("matched", 1)
+	obj.Value("lines").Array().Element(0).Object().
+		ValueEqual("installment", 4).
+		ValueEqual("reference", "LOAN 1").
+		ValueEqual("description", "SETORAN CICILAN BORROWER 1")
+
+	obj = auth.GET("/lender/bank_statements").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.ValueEqual("total_data", 3)
+
+	obj = auth.GET(fmt.Sprintf("/lender/bank_statements/%v", statementID)).WithQuery("status", "applied").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.Value("lines").Array().Length().Equal(3)
+
+	auth.GET("/lender/bank_statements/999").
+		Expect().
+		Status(http.StatusNotFound).JSON().Object()
+}
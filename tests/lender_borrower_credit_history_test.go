@@ -0,0 +1,91 @@
+package tests
+
+import (
+	"asira_lender/asira"
+	"asira_lender/models"
+	"asira_lender/router"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gavv/httpexpect"
+)
+
+func TestBorrowerCreditHistory(t *testing.T) {
+	RebuildData()
+
+	api := router.NewRouter()
+
+	server := httptest.NewServer(api)
+
+	defer server.Close()
+
+	e := httpexpect.New(t, server.URL)
+
+	auth := e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Basic "+clientBasicToken)
+	})
+
+	adminToken := getAdminLoginToken(e, auth, "1")
+	lenderToken := getLenderLoginToken(e, auth, "1")
+
+	admin := e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Bearer "+adminToken)
+	})
+	lender := e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Bearer "+lenderToken)
+	})
+
+	// loan 1 has one installment paid on time and one paid 5 days late, loan 2 is paid off
+	asira.App.DB.Model(&models.Loan{}).Where("id = ?", 1).Updates(map[string]interface{}{"status": "approved", "due_date": time.Now().AddDate(1, 0, 0)})
+	asira.App.DB.Model(&models.Loan{}).Where("id = ?", 2).Updates(map[string]interface{}{"status": "approved", "payment_status": "terbayar"})
+	asira.App.DB.Model(&models.Installment{}).Where("id = ?", 1).Updates(map[string]interface{}{
+		"paid_status": true,
+		"paid_amount": 1200000,
+		"due_date":    time.Date(2019, 1, 10, 0, 0, 0, 0, time.UTC),
+		"paid_date":   time.Date(2019, 1, 8, 0, 0, 0, 0, time.UTC),
+	})
+	asira.App.DB.Model(&models.Installment{}).Where("id = ?", 2).Updates(map[string]interface{}{
+		"paid_status": true,
+		"paid_amount": 1200000,
+		"due_date":    time.Date(2019, 2, 10, 0, 0, 0, 0, time.UTC),
+		"paid_date":   time.Date(2019, 2, 15, 0, 0, 0, 0, time.UTC),
+	})
+
+	// same person registered to bank 2 with an approved loan 10 days overdue
+	asira.App.DB.Model(&models.Borrower{}).Where("id = ?", 2).Updates(map[string]interface{}{"id_card_number": "9876123451234567789", "bank": 2})
+	asira.App.DB.Model(&models.Loan{}).Where("id IN (?)", []int{5, 6}).Update("borrower", 2)
+	asira.App.DB.Model(&models.Loan{}).Where("id = ?", 5).Updates(map[string]interface{}{"status": "approved", "due_date": time.Now().AddDate(0, 0, -10)})
+
+	obj := lender.GET("/lender/borrower_list/1/credit_history").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.ValueEqual("borrower", 1).ValueEqual("loan_status", "active")
+	obj.ValueEqual("total_loans", 4).ValueEqual("approved_loans", 2).ValueEqual("rejected_loans", 0)
+	obj.ValueEqual("total_borrowed", 7000000).ValueEqual("total_repaid", 5400000).ValueEqual("current_exposure", 4100000)
+	obj.ValueEqual("paid_installments", 2).ValueEqual("on_time_installments", 1).ValueEqual("on_time_ratio", 50)
+	obj.ValueEqual("max_dpd", 5)
+	obj.Value("loans").Array().Length().Equal(4)
+	obj.NotContainsKey("other_banks")
+
+	// borrower of other bank
+	lender.GET("/lender/borrower_list/2/credit_history").
+		Expect().
+		Status(http.StatusNotFound).JSON().Object()
+
+	obj = admin.GET("/admin/borrower/1/credit_history").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.ValueEqual("total_loans", 4)
+	obj.Value("other_banks").Object().
+		ValueEqual("banks", 1).
+		ValueEqual("loans", 2).
+		ValueEqual("approved_loans", 1).
+		ValueEqual("active_loans", 1).
+		ValueEqual("max_dpd", 10)
+
+	admin.GET("/admin/borrower/9999/credit_history").
+		Expect().
+		Status(http.StatusNotFound).JSON().Object()
+}
@@ -0,0 +1,202 @@
+package tests
+
+import (
+	"asira_lender/router"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gavv/httpexpect"
+)
+
+func TestLenderBorrowerDocumentList(t *testing.T) {
+	RebuildData()
+
+	api := router.NewRouter()
+
+	server := httptest.NewServer(api)
+
+	defer server.Close()
+
+	e := httpexpect.New(t, server.URL)
+
+	auth := e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Basic "+clientBasicToken)
+	})
+
+	lendertoken := getLenderLoginToken(e, auth, "1")
+
+	auth = e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Bearer "+lendertoken)
+	})
+
+	// document type catalog
+	obj := auth.GET("/lender/document_types").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.ContainsKey("total_data").ValueEqual("total_data", 3)
+
+	// valid response
+	obj = auth.GET("/lender/borrower_list/1/documents").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.ContainsKey("total_data").ValueEqual("total_data", 1)
+
+	// filter by status
+	obj = auth.GET("/lender/borrower_list/1/documents").WithQuery("status", "verified").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.ContainsKey("total_data").ValueEqual("total_data", 0)
+
+	// borrower of other bank
+	auth.GET("/lender/borrower_list/9999/documents").
+		Expect().
+		Status(http.StatusNotFound).JSON().Object()
+}
+
+func TestLenderBorrowerDocumentNew(t *testing.T) {
+	RebuildData()
+
+	api := router.NewRouter()
+
+	server := httptest.NewServer(api)
+
+	defer server.Close()
+
+	e := httpexpect.New(t, server.URL)
+
+	auth := e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Basic "+clientBasicToken)
+	})
+
+	lendertoken := getLenderLoginToken(e, auth, "1")
+
+	auth = e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Bearer "+lendertoken)
+	})
+
+	// request document from borrower
+	payload := map[string]interface{}{
+		"document_type": 2,
+		"note":          "mohon lampirkan surat keterangan kerja",
+	}
+	obj := auth.POST("/lender/borrower_list/1/documents").WithJSON(payload).
+		Expect().
+		Status(http.StatusCreated).JSON().Object()
+	obj.ContainsKey("status").ValueEqual("status", "requested")
+
+	// upload document for loan
+	payload = map[string]interface{}{
+		"document_type": 3,
+		"loan":          1,
+		"file":          dummyImage(),
+	}
+	obj = auth.POST("/lender/borrower_list/1/documents").WithJSON(payload).
+		Expect().
+		Status(http.StatusCreated).JSON().Object()
+	obj.ContainsKey("status").ValueEqual("status", "pending")
+	obj.ContainsKey("loan").ValueEqual("loan", 1)
+
+	// loan of another borrower
+	payload["loan"] = 9999
+	auth.POST("/lender/borrower_list/1/documents").WithJSON(payload).
+		Expect().
+		Status(http.StatusUnprocessableEntity).JSON().Object()
+
+	// unsupported file
+	payload = map[string]interface{}{
+		"document_type": 3,
+		"file":          "aW52YWxpZCBkb2N1bWVudA==",
+	}
+	auth.POST("/lender/borrower_list/1/documents").WithJSON(payload).
+		Expect().
+		Status(http.StatusUnprocessableEntity).JSON().Object()
+
+	// invalid document type
+	payload = map[string]interface{}{
+		"document_type": 9999,
+	}
+	auth.POST("/lender/borrower_list/1/documents").WithJSON(payload).
+		Expect().
+		Status(http.StatusUnprocessableEntity).JSON().Object()
+}
+
+func TestLenderBorrowerDocumentVerify(t *testing.T) {
+	RebuildData()
+
+	api := router.NewRouter()
+
+	server := httptest.NewServer(api)
+
+	defer server.Close()
+
+	e := httpexpect.New(t, server.URL)
+
+	auth := e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Basic "+clientBasicToken)
+	})
+
+	lendertoken := getLenderLoginToken(e, auth, "1")
+
+	auth = e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Bearer "+lendertoken)
+	})
+
+	// requested document has no file to verify yet
+	auth.PATCH("/lender/borrower_list/1/documents/1/verify").WithJSON(map[string]interface{}{
+		"status": "verified",
+	}).
+		Expect().
+		Status(http.StatusUnprocessableEntity).JSON().Object()
+
+	// upload requested document
+	obj := auth.PATCH("/lender/borrower_list/1/documents/1").WithJSON(map[string]interface{}{
+		"file": dummyImage(),
+	}).
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.ContainsKey("status").ValueEqual("status", "pending")
+
+	// reject requires reason
+	auth.PATCH("/lender/borrower_list/1/documents/1/verify").WithJSON(map[string]interface{}{
+		"status": "rejected",
+	}).
+		Expect().
+		Status(http.StatusUnprocessableEntity).JSON().Object()
+
+	obj = auth.PATCH("/lender/borrower_list/1/documents/1/verify").WithJSON(map[string]interface{}{
+		"status":        "rejected",
+		"reject_reason": "slip gaji tidak terbaca",
+	}).
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.ContainsKey("status").ValueEqual("status", "rejected")
+	obj.ContainsKey("reject_reason").ValueEqual("reject_reason", "slip gaji tidak terbaca")
+
+	// reupload resets verification
+	obj = auth.PATCH("/lender/borrower_list/1/documents/1").WithJSON(map[string]interface{}{
+		"file": dummyImage(),
+	}).
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.ContainsKey("status").ValueEqual("status", "pending")
+	obj.ContainsKey("reject_reason").ValueEqual("reject_reason", "")
+
+	obj = auth.PATCH("/lender/borrower_list/1/documents/1/verify").WithJSON(map[string]interface{}{
+		"status": "verified",
+	}).
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.ContainsKey("status").ValueEqual("status", "verified")
+
+	// detail returns pre-signed file
+	obj = auth.GET("/lender/borrower_list/1/documents/1").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.Value("file").String().Contains("signature")
+
+	// document of other borrower
+	auth.GET("/lender/borrower_list/2/documents/1").
+		Expect().
+		Status(http.StatusNotFound).JSON().Object()
+}
@@ -1,6 +1,8 @@
 package tests
 
 import (
+	"asira_lender/asira"
+	"asira_lender/models"
 	"asira_lender/router"
 	"net/http"
 	"net/http/httptest"
@@ -76,6 +78,40 @@ func TestBorrowerGetDetail(t *testing.T) {
 		Status(http.StatusNotFound).JSON().Object()
 }
 
+func TestBorrowerGetDetailImage(t *testing.T) {
+	RebuildData()
+
+	api := router.NewRouter()
+
+	server := httptest.NewServer(api)
+
+	defer server.Close()
+
+	e := httpexpect.New(t, server.URL)
+
+	auth := e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Basic "+clientBasicToken)
+	})
+
+	lendertoken := getLenderLoginToken(e, auth, "1")
+
+	auth = e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Bearer "+lendertoken)
+	})
+
+	asira.App.DB.Model(&models.Borrower{}).Where("id = ?", 1).Updates(map[string]interface{}{
+		"idcard_image": "ktp1.jpeg",
+		"taxid_image":  "npwp1.jpeg",
+	})
+
+	// stored keys are returned as pre-signed urls
+	obj := auth.GET("/lender/borrower_list/1/detail").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.Value("idcard_image").String().Contains("ktp1.jpeg").Contains("signature=")
+	obj.Value("taxid_image").String().Contains("npwp1.jpeg").Contains("signature=")
+}
+
 func TestBorrowerApproveReject(t *testing.T) {
 	RebuildData()
 
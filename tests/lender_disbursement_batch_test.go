@@ -0,0 +1,145 @@
+package tests
+
+import (
+	"asira_lender/asira"
+	"asira_lender/models"
+	"asira_lender/router"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gavv/httpexpect"
+)
+
+func TestLenderDisbursementBatch(t *testing.T) {
+	RebuildData()
+
+	api := router.NewRouter()
+
+	server := httptest.NewServer(api)
+
+	defer server.Close()
+
+	e := httpexpect.New(t, server.URL)
+
+	auth := e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Basic "+clientBasicToken)
+	})
+
+	lenderToken := getLenderLoginToken(e, auth, "1")
+
+	auth = e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Bearer "+lenderToken)
+	})
+
+	// nothing to disburse today
+	auth.POST("/lender/disbursement_batches").WithJSON(map[string]interface{}{}).
+		Expect().
+		Status(http.StatusUnprocessableEntity).JSON().Object()
+
+	// loans 1 to 3 are approved for disbursement today, borrower 2 of loan 3 has no bank account
+	asira.App.DB.Model(&models.Loan{}).Where("id IN (?)", []int{1, 2, 3}).Updates(map[string]interface{}{"status": "approved", "disburse_date": time.Now()})
+	asira.App.DB.Model(&models.Loan{}).Where("id = ?", 1).Update("disburse_amount", 4800000)
+	asira.App.DB.Model(&models.Loan{}).Where("id = ?", 2).Update("disburse_amount", 1900000)
+	asira.App.DB.Model(&models.Loan{}).Where("id = ?", 3).Update("borrower", 2)
+
+	auth.POST("/lender/disbursement_batches").WithJSON(map[string]interface{}{
+		"format": "xml",
+	}).
+		Expect().
+		Status(http.StatusUnprocessableEntity).JSON().Object()
+
+	obj := auth.POST("/lender/disbursement_batches").WithJSON(map[string]interface{}{
+		"format": "csv",
+	}).
+		Expect().
+		Status(http.StatusCreated).JSON().Object()
+	obj.Value("batch").Object().ValueEqual("count", 2).ValueEqual("total_amount", 6700000).ValueEqual("status", "generated")
+	obj.Value("items").Array().Length().Equal(2)
+	obj.Value("skipped").Array().Equal([]uint64{3})
+	batchID := int(obj.Value("batch").Object().Value("id").Number().Raw())
+	firstRef := obj.Value("items").Array().Element(0).Object().Value("reference").String().Raw()
+	secondRef := obj.Value("items").Array().Element(1).Object().Value("reference").String().Raw()
+
+	loan := models.Loan{}
+	loan.FindbyID(1)
+	if loan.DisburseStatus != "in_batch" {
+		t.Errorf("loan 1 disburse status %v, expected in_batch", loan.DisburseStatus)
+	}
+
+	// loans in batch are not batched again
+	auth.POST("/lender/disbursement_batches").WithJSON(map[string]interface{}{}).
+		Expect().
+		Status(http.StatusUnprocessableEntity).JSON().Object()
+
+	body := auth.GET(fmt.Sprintf("/lender/disbursement_batches/%v/file", batchID)).
+		Expect().
+		Status(http.StatusOK).Body().Raw()
+	if !strings.HasPrefix(body, "reference,loan,account_number,account_name,amount") || !strings.Contains(body, firstRef+",1,520384716,") {
+		t.Errorf("unexpected batch file %v", body)
+	}
+
+	auth.POST(fmt.Sprintf("/lender/disbursement_batches/%v/result", batchID)).WithMultipart().WithFileBytes("file", "result.csv", []byte("foo,bar\n1,2\n")).
+		Expect().
+		Status(http.StatusUnprocessableEntity).JSON().Object()
+
+	result := fmt.Sprintf("reference,status,message\n%v,success,\n%v,failed,account closed\nUNKNOWN,success,\n", firstRef, secondRef)
+	obj = auth.POST(fmt.Sprintf("/lender/disbursement_batches/%v/result", batchID)).WithMultipart().WithFileBytes("file", "result.csv", []byte(result)).
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.Value("batch").Object().ValueEqual("confirmed", 1).ValueEqual("failed", 1).ValueEqual("status", "completed")
+	obj.Value("unmatched").Array().Length().Equal(1)
+	obj.Value("items").Array().Element(1).Object().ValueEqual("status", "failed").ValueEqual("message", "account closed")
+
+	loan = models.Loan{}
+	loan.FindbyID(1)
+	if loan.DisburseStatus != "confirmed" {
+		t.Errorf("loan 1 disburse status %v, expected confirmed", loan.DisburseStatus)
+	}
+	loan = models.Loan{}
+	loan.FindbyID(2)
+	if loan.DisburseStatus != "failed" {
+		t.Errorf("loan 2 disburse status %v, expected failed", loan.DisburseStatus)
+	}
+
+	// failed transfers can be batched again
+	obj = auth.POST("/lender/disbursement_batches").WithJSON(map[string]interface{}{
+		"format": "fixed_width",
+	}).
+		Expect().
+		Status(http.StatusCreated).JSON().Object()
+	obj.Value("batch").Object().ValueEqual("count", 1).ValueEqual("format", "fixed_width")
+	batchID = int(obj.Value("batch").Object().Value("id").Number().Raw())
+	retryRef := obj.Value("items").Array().Element(0).Object().Value("reference").String().Raw()
+
+	body = auth.GET(fmt.Sprintf("/lender/disbursement_batches/%v/file", batchID)).
+		Expect().
+		Status(http.StatusOK).Body().Raw()
+	lines := strings.Split(strings.TrimSpace(body), "\r\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "H") || !strings.HasPrefix(lines[1], "D"+retryRef) || lines[2] != "T000001000000001900000" {
+		t.Errorf("unexpected fixed width batch file %q", body)
+	}
+
+	result = fmt.Sprintf("H%-20s\r\nD%-20s00TRANSFER OK\r\nT000001\r\n", "RESULT", retryRef)
+	obj = auth.POST(fmt.Sprintf("/lender/disbursement_batches/%v/result", batchID)).WithMultipart().WithFileBytes("file", "result.txt", []byte(result)).
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.Value("batch").Object().ValueEqual("confirmed", 1).ValueEqual("status", "completed")
+
+	obj = auth.GET("/lender/disbursement_batches").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.ValueEqual("total_data", 2)
+
+	auth.GET(fmt.Sprintf("/lender/disbursement_batches/%v", batchID)).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("items").Array().Length().Equal(1)
+
+	auth.GET("/lender/disbursement_batches/999").
+		Expect().
+		Status(http.StatusNotFound).JSON().Object()
+}
@@ -0,0 +1,82 @@
+package tests
+
+import (
+	"asira_lender/middlewares"
+	"asira_lender/router"
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gavv/httpexpect"
+)
+
+func TestLenderEvents(t *testing.T) {
+	RebuildData()
+
+	api := router.NewRouter()
+
+	server := httptest.NewServer(api)
+
+	defer server.Close()
+
+	e := httpexpect.New(t, server.URL)
+
+	auth := e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Basic "+clientBasicToken)
+	})
+
+	lendertoken := getLenderLoginToken(e, auth, "1")
+
+	// test invalid token
+	e.GET("/lender/events").WithHeader("Authorization", "Bearer wrong token").
+		Expect().
+		Status(http.StatusUnauthorized).JSON().Object()
+
+	// events published while disconnected are replayed, other bank events are not
+	first := middlewares.PublishLiveEvent(1, "loan", "create", 1, map[string]interface{}{"id": 1})
+	middlewares.PublishLiveEvent(2, "loan", "create", 3, map[string]interface{}{"id": 3})
+	middlewares.PublishLiveEvent(1, "borrower", "update", 1, map[string]interface{}{"id": 1})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req, _ := http.NewRequest("GET", server.URL+"/lender/events", nil)
+	req = req.WithContext(ctx)
+	req.Header.Set("Authorization", "Bearer "+lendertoken)
+	req.Header.Set("Last-Event-ID", fmt.Sprint(first.ID-1))
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK || !strings.HasPrefix(res.Header.Get("Content-Type"), "text/event-stream") {
+		t.Fatalf("unexpected response %v %v", res.StatusCode, res.Header.Get("Content-Type"))
+	}
+
+	var events []string
+	scanner := bufio.NewScanner(res.Body)
+	for len(events) < 2 && scanner.Scan() {
+		if line := scanner.Text(); strings.HasPrefix(line, "event: ") {
+			events = append(events, strings.TrimPrefix(line, "event: "))
+		}
+	}
+	if len(events) != 2 || events[0] != "loan_create" || events[1] != "borrower_update" {
+		t.Fatalf("unexpected replayed events %v", events)
+	}
+
+	// event id from before restart or from another process asks for reload
+	for _, lastID := range []uint64{1, first.ID - uint64(time.Hour), uint64(time.Now().Add(time.Hour).UnixNano())} {
+		subscription, _, complete := middlewares.SubscribeLiveEvents(1, lastID)
+		subscription.Unsubscribe()
+		if complete {
+			t.Errorf("expected reload for last event id %v", lastID)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package tests
+
+import (
+	"asira_lender/asira"
+	"asira_lender/models"
+	"asira_lender/router"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gavv/httpexpect"
+)
+
+func TestLenderLoanAffordability(t *testing.T) {
+	RebuildData()
+
+	api := router.NewRouter()
+
+	server := httptest.NewServer(api)
+
+	defer server.Close()
+
+	e := httpexpect.New(t, server.URL)
+
+	auth := e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Basic "+clientBasicToken)
+	})
+
+	lenderToken := getLenderLoginToken(e, auth, "1")
+
+	auth = e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Bearer "+lenderToken)
+	})
+
+	// no dti limit on product
+	obj := auth.GET("/lender/loanrequest_list/1/detail").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	affordability := obj.Value("affordability").Object()
+	affordability.ValueEqual("income", 7000000).ValueEqual("dti", 7.14).ValueEqual("exceeded", false)
+	affordability.NotContainsKey("warning")
+
+	// borrower income is 7000000, approved unpaid loans 2 and 3 add 700000 installments
+	asira.App.DB.Model(&models.Product{}).Where("id = ?", 1).Updates(map[string]interface{}{"max_dti": 10, "hard_dti": 15})
+	asira.App.DB.Model(&models.Loan{}).Where("id IN (?)", []int{2, 3}).Update("status", "approved")
+
+	obj = auth.GET("/lender/loanrequest_list/1/detail").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	affordability = obj.Value("affordability").Object()
+	affordability.ValueEqual("other_installments", 700000).ValueEqual("dti", 17.14)
+	affordability.ValueEqual("exceeded", true).ValueEqual("blocked", true)
+	affordability.Value("warning").String().NotEmpty()
+
+	obj = auth.GET("/lender/loanrequest_list/4/detail").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.Value("affordability").Object().ValueEqual("dti", 12.86).ValueEqual("exceeded", true).ValueEqual("blocked", false)
+
+	// paid loans are no longer counted
+	asira.App.DB.Model(&models.Loan{}).Where("id = ?", 3).Update("payment_status", "terbayar")
+	obj = auth.GET("/lender/loanrequest_list/1/detail").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.Value("affordability").Object().ValueEqual("dti", 10).ValueEqual("exceeded", false)
+
+	// approval above hard limit is blocked
+	asira.App.DB.Model(&models.Loan{}).Where("id = ?", 3).Update("payment_status", "processing")
+	auth.GET("/lender/loanrequest_list/1/detail/approve").WithQuery("disburse_date", "2019-10-11").
+		Expect().
+		Status(http.StatusUnprocessableEntity).JSON().Object()
+
+	obj = auth.POST("/lender/loanrequest_list/approve_reject/bulk").WithJSON(map[string]interface{}{
+		"loan_ids":      []int{1, 4},
+		"action":        "approve",
+		"disburse_date": "2019-10-11",
+	}).
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.ValueEqual("succeeded", 1).ValueEqual("failed", 1)
+
+	// rejection is not limited
+	auth.GET("/lender/loanrequest_list/1/detail/reject").WithQuery("reason", "dti too high").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+
+	// flagged loans
+	asira.App.DB.Model(&models.Loan{}).Where("id = ?", 5).Update("dti_exceeded", true)
+	auth.GET("/lender/loanrequest_list").WithQuery("dti_exceeded", "true").
+		Expect().
+		Status(http.StatusOK).JSON().Object().ValueEqual("total_data", 1)
+}
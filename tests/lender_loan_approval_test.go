@@ -0,0 +1,145 @@
+package tests
+
+import (
+	"asira_lender/asira"
+	"asira_lender/middlewares"
+	"asira_lender/models"
+	"asira_lender/router"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gavv/httpexpect"
+)
+
+func TestLenderLoanApprovalChain(t *testing.T) {
+	RebuildData()
+
+	api := router.NewRouter()
+
+	server := httptest.NewServer(api)
+
+	defer server.Close()
+
+	e := httpexpect.New(t, server.URL)
+
+	auth := e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Basic "+clientBasicToken)
+	})
+
+	adminToken := getAdminLoginToken(e, auth, "1")
+	analystToken := getLenderLoginToken(e, auth, "1")
+	supervisorToken := getLenderLoginToken(e, auth, "3")
+
+	admin := e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Bearer "+adminToken)
+	})
+	analyst := e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Bearer "+analystToken)
+	})
+	supervisor := e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Bearer "+supervisorToken)
+	})
+
+	// invalid step role
+	admin.POST("/admin/banks/1/approval_policies").WithJSON(map[string]interface{}{
+		"name":   "invalid",
+		"steps":  []map[string]interface{}{{"name": "analyst", "role": 999, "approvers": 1}},
+		"status": "active",
+	}).
+		Expect().
+		Status(http.StatusUnprocessableEntity).JSON().Object()
+
+	// two level policy for every loan of bank 1
+	obj := admin.POST("/admin/banks/1/approval_policies").WithJSON(map[string]interface{}{
+		"name": "all loans",
+		"steps": []map[string]interface{}{
+			{"name": "analyst recommendation", "role": 3, "approvers": 1},
+			{"name": "supervisor approval", "role": 3, "approvers": 1},
+		},
+		"status": "active",
+	}).
+		Expect().
+		Status(http.StatusCreated).JSON().Object()
+	obj.ContainsKey("bank").ValueEqual("bank", 1)
+
+	admin.GET("/admin/banks/1/approval_policies").
+		Expect().
+		Status(http.StatusOK).JSON().Object().ValueEqual("total_data", 1)
+
+	// first approval only moves loan to the next step
+	obj = analyst.GET("/lender/loanrequest_list/1/detail/approve").WithQuery("disburse_date", "2019-10-11").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.Value("approval").Object().ValueEqual("current_step", 1).ValueEqual("status", "pending")
+
+	// same user can not act twice
+	analyst.GET("/lender/loanrequest_list/1/detail/approve").WithQuery("disburse_date", "2019-10-11").
+		Expect().
+		Status(http.StatusUnprocessableEntity).JSON().Object()
+
+	analyst.GET("/lender/approval_tasks").
+		Expect().
+		Status(http.StatusOK).JSON().Object().ValueEqual("total_data", 0)
+
+	obj = supervisor.GET("/lender/approval_tasks").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.ValueEqual("total_data", 1)
+	obj.Value("data").Array().Element(0).Object().ValueEqual("loan", 1)
+
+	// last approval decides the loan
+	obj = supervisor.GET("/lender/loanrequest_list/1/detail/approve").WithQuery("disburse_date", "2019-10-12").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.ValueEqual("message", "loan 1 is approved")
+
+	obj = analyst.GET("/lender/loanrequest_list/1/approval").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.Value("approval").Object().ValueEqual("status", "approved")
+	obj.Value("actions").Array().Length().Equal(2)
+
+	// decided loan and its outbox row are written with the final approval
+	loan := models.Loan{}
+	loan.FindbyID(1)
+	if loan.Status != "approved" || loan.DisburseDate.Format("2006-01-02") != "2019-10-12" {
+		t.Errorf("loan 1 status %v disburse date %v, expected approved on 2019-10-12", loan.Status, loan.DisburseDate)
+	}
+	var pending int
+	asira.App.DB.Model(&models.Outbox{}).Where("model = ? AND status = ?", "loan", "pending").Count(&pending)
+	if pending != 1 {
+		t.Errorf("found %v pending loan outbox rows, expected 1", pending)
+	}
+
+	// loan decided elsewhere meanwhile keeps its approval pending
+	analyst.GET("/lender/loanrequest_list/3/detail/approve").WithQuery("disburse_date", "2019-10-11").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	asira.App.DB.Model(&models.Loan{}).Where("id = ?", 3).Update("status", "rejected")
+
+	approval := models.LoanApproval{}
+	asira.App.DB.Where("loan = ?", 3).First(&approval)
+	user := models.User{}
+	asira.App.DB.Where("username = ?", "Banktoid").First(&user)
+	stale := models.Loan{}
+	stale.FindbyID(3)
+	stale.Status = "processing"
+	disburseDate := time.Now()
+	_, err := approval.Act(user, "approve", "", &disburseDate, &stale, middlewares.SubmitKafkaPayloadTx)
+	if _, ok := err.(models.ApprovalError); !ok {
+		t.Errorf("approving rejected loan 3 got %v, expected approval error", err)
+	}
+
+	obj = analyst.GET("/lender/loanrequest_list/3/approval").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.Value("approval").Object().ValueEqual("status", "pending").ValueEqual("current_step", 1)
+	obj.Value("actions").Array().Length().Equal(1)
+
+	// rejection on any step rejects the loan
+	analyst.GET("/lender/loanrequest_list/2/detail/reject").WithQuery("reason", "reject reason").
+		Expect().
+		Status(http.StatusOK).JSON().Object().ValueEqual("message", "loan 2 is rejected")
+}
@@ -1,6 +1,8 @@
 package tests
 
 import (
+	"asira_lender/asira"
+	"asira_lender/models"
 	"asira_lender/router"
 	"net/http"
 	"net/http/httptest"
@@ -108,6 +110,83 @@ func TestLenderApproveRejectLoan(t *testing.T) {
 	auth.GET("/lender/loanrequest_list/3/detail/reject").WithQuery("reason", "reject reason").
 		Expect().
 		Status(http.StatusOK).JSON().Object()
+
+	// decided loan can not be decided again
+	asira.App.DB.Model(&models.Loan{}).Where("id = ?", 4).Update("status", "approved")
+	auth.GET("/lender/loanrequest_list/4/detail/reject").WithQuery("reason", "reject reason").
+		Expect().
+		Status(http.StatusUnprocessableEntity).JSON().Object()
+
+	// not found
+	auth.GET("/lender/loanrequest_list/999/detail/approve").WithQuery("disburse_date", "2019-10-11").
+		Expect().
+		Status(http.StatusNotFound).JSON().Object()
+}
+
+func TestLenderApproveRejectLoanBulk(t *testing.T) {
+	RebuildData()
+
+	api := router.NewRouter()
+
+	server := httptest.NewServer(api)
+
+	defer server.Close()
+
+	e := httpexpect.New(t, server.URL)
+
+	auth := e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Basic "+clientBasicToken)
+	})
+
+	lendertoken := getLenderLoginToken(e, auth, "1")
+
+	auth = e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Bearer "+lendertoken)
+	})
+
+	asira.App.DB.Model(&models.Loan{}).Where("id = ?", 4).Update("status", "approved")
+
+	// valid bulk approve, already decided and unknown loans are reported
+	obj := auth.POST("/lender/loanrequest_list/approve_reject/bulk").WithJSON(map[string]interface{}{
+		"loan_ids":      []int{1, 2, 4, 999},
+		"action":        "approve",
+		"disburse_date": "2019-10-11",
+	}).
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.ValueEqual("total", 4)
+	obj.ValueEqual("succeeded", 2)
+	obj.ValueEqual("failed", 2)
+	results := obj.Value("results").Array()
+	results.Element(0).Object().ValueEqual("success", true).ValueEqual("status", "approved")
+	results.Element(2).Object().ValueEqual("success", false).ValueEqual("status", "approved")
+	results.Element(3).Object().ValueEqual("success", false)
+
+	// valid bulk reject
+	obj = auth.POST("/lender/loanrequest_list/approve_reject/bulk").WithJSON(map[string]interface{}{
+		"loan_ids": []int{3, 5},
+		"action":   "reject",
+		"reason":   "reject reason",
+	}).
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.ValueEqual("succeeded", 2)
+
+	// reject without reason
+	auth.POST("/lender/loanrequest_list/approve_reject/bulk").WithJSON(map[string]interface{}{
+		"loan_ids": []int{3},
+		"action":   "reject",
+	}).
+		Expect().
+		Status(http.StatusUnprocessableEntity).JSON().Object()
+
+	// invalid action
+	auth.POST("/lender/loanrequest_list/approve_reject/bulk").WithJSON(map[string]interface{}{
+		"loan_ids": []int{3},
+		"action":   "cancel",
+	}).
+		Expect().
+		Status(http.StatusUnprocessableEntity).JSON().Object()
 }
 
 func TestLenderChangeDisburseDate(t *testing.T) {
@@ -195,6 +274,18 @@ func TestLenderLoanInstallmentPatch(t *testing.T) {
 	auth.PATCH("/lender/loanrequest_list/1/detail/installment_approve/1").WithJSON(payload).
 		Expect().
 		Status(http.StatusOK).JSON().Object()
+
+	// installment is saved with its outbox row
+	installment := models.Installment{}
+	asira.App.DB.First(&installment, 1)
+	if !installment.PaidStatus || installment.Note != "this is note" {
+		t.Errorf("installment 1 paid status %v note %v, expected paid with note", installment.PaidStatus, installment.Note)
+	}
+	var pending int
+	asira.App.DB.Model(&models.Outbox{}).Where("model = ? AND status = ?", "installment", "pending").Count(&pending)
+	if pending != 1 {
+		t.Errorf("found %v pending installment outbox rows, expected 1", pending)
+	}
 }
 
 func TestLenderLoanInstallmentPatchBulk(t *testing.T) {
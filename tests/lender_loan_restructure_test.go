@@ -0,0 +1,148 @@
+package tests
+
+import (
+	"asira_lender/asira"
+	"asira_lender/middlewares"
+	"asira_lender/models"
+	"asira_lender/router"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gavv/httpexpect"
+)
+
+func TestLenderLoanRestructure(t *testing.T) {
+	RebuildData()
+
+	api := router.NewRouter()
+
+	server := httptest.NewServer(api)
+
+	defer server.Close()
+
+	e := httpexpect.New(t, server.URL)
+
+	auth := e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Basic "+clientBasicToken)
+	})
+
+	lenderToken := getLenderLoginToken(e, auth, "1")
+
+	auth = e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Bearer "+lenderToken)
+	})
+
+	firstDueDate := time.Now().AddDate(0, 1, 0).Format("2006-01-02")
+
+	// only approved loans
+	auth.POST("/lender/loanrequest_list/1/restructure").WithJSON(map[string]interface{}{
+		"tenor":          4,
+		"first_due_date": firstDueDate,
+		"reason":         "borrower lost job",
+	}).
+		Expect().
+		Status(http.StatusUnprocessableEntity).JSON().Object()
+
+	// loan 1 has installments 1 to 5 of 1000000 principal and 200000 interest, installment 1 is paid
+	// and installment 2 is overdue with 50000 penalty
+	asira.App.DB.Model(&models.Loan{}).Where("id = ?", 1).Update("status", "approved")
+	asira.App.DB.Model(&models.Installment{}).Where("id = ?", 1).Updates(map[string]interface{}{"paid_status": true, "paid_amount": 1200000})
+	asira.App.DB.Model(&models.Installment{}).Where("id = ?", 2).Updates(map[string]interface{}{"due_date": time.Now().AddDate(0, 0, -15), "penalty": 50000})
+
+	auth.POST("/lender/loanrequest_list/1/restructure").WithJSON(map[string]interface{}{
+		"tenor":          0,
+		"first_due_date": "2019-01-01",
+		"reason":         "borrower lost job",
+	}).
+		Expect().
+		Status(http.StatusUnprocessableEntity).JSON().Object()
+
+	// arrears not capitalised are due with first new installment
+	obj := auth.POST("/lender/loanrequest_list/1/restructure").WithJSON(map[string]interface{}{
+		"tenor":          4,
+		"interest":       0,
+		"first_due_date": firstDueDate,
+		"reason":         "borrower lost job",
+		"preview":        true,
+	}).
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.Value("restructure").Object().ValueEqual("principal", 4000000).ValueEqual("arrears", 250000).ValueEqual("new_plan", 1000000)
+	obj.Value("closed").Array().Length().Equal(4)
+	obj.Value("installments").Array().Length().Equal(4)
+	obj.Value("installments").Array().Element(0).Object().ValueEqual("period", 2).ValueEqual("interest_payment", 250000)
+
+	// nothing is changed by preview
+	obj = auth.GET("/lender/loanrequest_list/1/restructures").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.ContainsKey("total_data").ValueEqual("total_data", 0)
+
+	obj = auth.POST("/lender/loanrequest_list/1/restructure").WithJSON(map[string]interface{}{
+		"tenor":              4,
+		"interest":           0,
+		"grace_period":       1,
+		"capitalise_arrears": true,
+		"first_due_date":     firstDueDate,
+		"reason":             "borrower lost job",
+	}).
+		Expect().
+		Status(http.StatusCreated).JSON().Object()
+	restructure := obj.Value("restructure").Object()
+	restructure.ValueEqual("principal", 4000000).ValueEqual("arrears", 250000).ValueEqual("new_plan", 1062500)
+	restructure.ValueEqual("previous_plan", 500000)
+	restructure.ValueEqual("old_installments", []int{2, 3, 4, 5})
+	restructure.Value("new_installments").Array().Length().Equal(5)
+	installments := obj.Value("installments").Array()
+	installments.Length().Equal(5)
+	installments.Element(0).Object().ValueEqual("period", 2).ValueEqual("loan_payment", 0).ValueEqual("interest_payment", 0)
+	installments.Element(4).Object().ValueEqual("period", 6).ValueEqual("loan_payment", 1062500)
+	loan := obj.Value("loan").Object()
+	loan.ValueEqual("installment", 6).ValueEqual("layaway_plan", 1062500).ValueEqual("total_loan", 5450000)
+	loan.Value("installment_id").Array().Length().Equal(6)
+
+	closed := models.Installment{}
+	asira.App.DB.First(&closed, 2)
+	if closed.ClosedBy == 0 {
+		t.Errorf("installment 2 is not closed by restructure")
+	}
+
+	obj = auth.GET("/lender/loanrequest_list/1/restructures").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.ContainsKey("total_data").ValueEqual("total_data", 1)
+
+	// loan settled after it was read is not restructured again
+	stale := models.Loan{}
+	stale.FindbyID(1)
+	asira.App.DB.Model(&models.Loan{}).Where("id = ?", 1).Update("payment_status", "terbayar")
+	_, _, err := models.RestructureLoan(&stale, &models.LoanRestructure{Tenor: 4, FirstDueDate: time.Now()}, middlewares.SubmitKafkaPayloadTx)
+	if _, ok := err.(models.ApprovalError); !ok {
+		t.Errorf("restructuring settled loan 1 got %v, expected approval error", err)
+	}
+	var restructures int
+	asira.App.DB.Model(&models.LoanRestructure{}).Where("loan = ?", 1).Count(&restructures)
+	if restructures != 1 {
+		t.Errorf("loan 1 has %v restructures, expected 1", restructures)
+	}
+
+	// paid off loans can not be restructured
+	asira.App.DB.Model(&models.Loan{}).Where("id = ?", 2).Updates(map[string]interface{}{"status": "approved", "payment_status": "terbayar"})
+	auth.POST("/lender/loanrequest_list/2/restructure").WithJSON(map[string]interface{}{
+		"tenor":          4,
+		"first_due_date": firstDueDate,
+		"reason":         "borrower lost job",
+	}).
+		Expect().
+		Status(http.StatusUnprocessableEntity).JSON().Object()
+
+	auth.POST("/lender/loanrequest_list/9999/restructure").WithJSON(map[string]interface{}{
+		"tenor":          4,
+		"first_due_date": firstDueDate,
+		"reason":         "borrower lost job",
+	}).
+		Expect().
+		Status(http.StatusNotFound).JSON().Object()
+}
@@ -0,0 +1,128 @@
+package tests
+
+import (
+	"asira_lender/asira"
+	"asira_lender/middlewares"
+	"asira_lender/models"
+	"asira_lender/router"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gavv/httpexpect"
+)
+
+func TestLenderLoanEarlySettlement(t *testing.T) {
+	RebuildData()
+
+	api := router.NewRouter()
+
+	server := httptest.NewServer(api)
+
+	defer server.Close()
+
+	e := httpexpect.New(t, server.URL)
+
+	auth := e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Basic "+clientBasicToken)
+	})
+
+	lenderToken := getLenderLoginToken(e, auth, "1")
+
+	auth = e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Bearer "+lenderToken)
+	})
+
+	// only approved loans
+	auth.GET("/lender/loanrequest_list/1/payoff_quote").
+		Expect().
+		Status(http.StatusUnprocessableEntity).JSON().Object()
+
+	// loan 1 has installments 1 to 5 of 1000000 principal and 200000 interest, installment 1 is paid
+	// and installment 2 is overdue with 50000 penalty
+	asira.App.DB.Model(&models.Loan{}).Where("id = ?", 1).Update("status", "approved")
+	asira.App.DB.Model(&models.Installment{}).Where("id = ?", 1).Updates(map[string]interface{}{"paid_status": true, "paid_amount": 1200000})
+	asira.App.DB.Model(&models.Installment{}).Where("id = ?", 2).Updates(map[string]interface{}{"due_date": time.Now().AddDate(0, 0, -15), "penalty": 50000})
+	asira.App.DB.Model(&models.Product{}).Where("id = ?", 1).Update("early_settlement_fee", 2)
+
+	auth.GET("/lender/loanrequest_list/1/payoff_quote").WithQuery("date", "not a date").
+		Expect().
+		Status(http.StatusUnprocessableEntity).JSON().Object()
+
+	obj := auth.GET("/lender/loanrequest_list/1/payoff_quote").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.ValueEqual("remaining_principal", 4000000).
+		ValueEqual("accrued_interest", 200000).
+		ValueEqual("penalties", 50000).
+		ValueEqual("settlement_fee_rate", 2).
+		ValueEqual("settlement_fee", 80000).
+		ValueEqual("total", 4330000)
+	obj.Value("installments").Array().Length().Equal(4)
+
+	// paid amount must cover the quote
+	auth.POST("/lender/loanrequest_list/1/early_settlement").WithJSON(map[string]interface{}{
+		"paid_amount": 4000000,
+	}).
+		Expect().
+		Status(http.StatusUnprocessableEntity).JSON().Object()
+
+	auth.POST("/lender/loanrequest_list/1/early_settlement").WithJSON(map[string]interface{}{
+		"date":        time.Now().AddDate(0, 0, 3).Format("2006-01-02"),
+		"paid_amount": 4330000,
+	}).
+		Expect().
+		Status(http.StatusUnprocessableEntity).JSON().Object()
+
+	// quote taken before the settlement below goes stale
+	stale := models.Loan{}
+	stale.FindbyID(1)
+	staleQuote, _ := models.LoanPayoffQuote(stale, time.Now())
+
+	obj = auth.POST("/lender/loanrequest_list/1/early_settlement").WithJSON(map[string]interface{}{
+		"paid_amount": 4330000,
+		"note":        "borrower got bonus",
+	}).
+		Expect().
+		Status(http.StatusCreated).JSON().Object()
+	obj.Value("settlement").Object().ValueEqual("total", 4330000).ValueEqual("paid_amount", 4330000)
+	obj.Value("loan").Object().ValueEqual("payment_status", "terbayar")
+	obj.Value("installments").Array().Length().Equal(4)
+	obj.Value("installments").Array().Element(0).Object().ValueEqual("paid_status", true).ValueEqual("paid_amount", 1380000)
+
+	loan := models.Loan{}
+	loan.FindbyID(1)
+	if loan.PaymentStatus != "terbayar" {
+		t.Errorf("loan 1 payment status %v, expected terbayar", loan.PaymentStatus)
+	}
+
+	var unpaid int
+	asira.App.DB.Model(&models.Installment{}).Where("id IN (?) AND paid_status = ?", []int{1, 2, 3, 4, 5}, false).Count(&unpaid)
+	if unpaid != 0 {
+		t.Errorf("loan 1 has %v unpaid installments after settlement", unpaid)
+	}
+
+	// paid off loan can not be settled again
+	auth.POST("/lender/loanrequest_list/1/early_settlement").WithJSON(map[string]interface{}{
+		"paid_amount": 4330000,
+	}).
+		Expect().
+		Status(http.StatusUnprocessableEntity).JSON().Object()
+
+	// settling the stale quote is rejected inside the settlement transaction
+	_, err := models.SettleLoan(&stale, &models.LoanSettlement{PayoffQuote: staleQuote}, middlewares.SubmitKafkaPayloadTx)
+	if _, ok := err.(models.ApprovalError); !ok {
+		t.Errorf("settling stale quote of loan 1 got %v, expected approval error", err)
+	}
+	var settlements int
+	asira.App.DB.Model(&models.LoanSettlement{}).Where("loan = ?", 1).Count(&settlements)
+	if settlements != 1 {
+		t.Errorf("loan 1 has %v settlements, expected 1", settlements)
+	}
+
+	// other bank loan
+	auth.GET("/lender/loanrequest_list/99/payoff_quote").
+		Expect().
+		Status(http.StatusNotFound).JSON().Object()
+}
@@ -0,0 +1,177 @@
+package tests
+
+import (
+	"asira_lender/asira"
+	"asira_lender/models"
+	"asira_lender/router"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gavv/httpexpect"
+)
+
+func TestLenderLoanWriteOff(t *testing.T) {
+	RebuildData()
+
+	api := router.NewRouter()
+
+	server := httptest.NewServer(api)
+
+	defer server.Close()
+
+	e := httpexpect.New(t, server.URL)
+
+	auth := e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Basic "+clientBasicToken)
+	})
+
+	adminToken := getAdminLoginToken(e, auth, "1")
+	officerToken := getLenderLoginToken(e, auth, "1")
+	supervisorToken := getLenderLoginToken(e, auth, "3")
+
+	admin := e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Bearer "+adminToken)
+	})
+	officer := e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Bearer "+officerToken)
+	})
+	supervisor := e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Bearer "+supervisorToken)
+	})
+
+	// only approved loans
+	officer.POST("/lender/loanrequest_list/1/write_off").WithJSON(map[string]interface{}{
+		"reason": "borrower unreachable",
+	}).
+		Expect().
+		Status(http.StatusUnprocessableEntity).JSON().Object()
+
+	// loan that is not overdue long enough is still performing
+	asira.App.DB.Model(&models.Loan{}).Where("id = ?", 1).Update("status", "approved")
+	officer.POST("/lender/loanrequest_list/1/write_off").WithJSON(map[string]interface{}{
+		"reason": "borrower unreachable",
+	}).
+		Expect().
+		Status(http.StatusUnprocessableEntity).JSON().Object().
+		Value("details").Object().ContainsKey("days_past_due")
+
+	// loan 1 has installments 1 to 5 of 1000000 principal and 200000 interest, installment 1 is paid
+	// and installment 2 is 100 days overdue with 50000 penalty
+	asira.App.DB.Model(&models.Installment{}).Where("id = ?", 1).Updates(map[string]interface{}{"paid_status": true, "paid_amount": 1200000})
+	asira.App.DB.Model(&models.Installment{}).Where("id = ?", 2).Updates(map[string]interface{}{"due_date": time.Now().AddDate(0, 0, -100), "penalty": 50000})
+
+	officer.POST("/lender/loanrequest_list/1/write_off").WithJSON(map[string]interface{}{}).
+		Expect().
+		Status(http.StatusUnprocessableEntity).JSON().Object()
+
+	obj := officer.POST("/lender/loanrequest_list/1/write_off").WithJSON(map[string]interface{}{
+		"reason": "borrower unreachable",
+	}).
+		Expect().
+		Status(http.StatusCreated).JSON().Object()
+	obj.ValueEqual("status", "pending").
+		ValueEqual("principal", 4000000).
+		ValueEqual("interest", 200000).
+		ValueEqual("penalties", 50000).
+		ValueEqual("amount", 4250000)
+	obj.Value("days_past_due").Number().Ge(100)
+
+	// one pending write off per loan
+	officer.POST("/lender/loanrequest_list/1/write_off").WithJSON(map[string]interface{}{
+		"reason": "borrower unreachable",
+	}).
+		Expect().
+		Status(http.StatusUnprocessableEntity).JSON().Object()
+
+	// recoveries need approved write off
+	officer.POST("/lender/loanrequest_list/1/recoveries").WithJSON(map[string]interface{}{
+		"amount": 1000000,
+	}).
+		Expect().
+		Status(http.StatusUnprocessableEntity).JSON().Object()
+
+	// requester can not approve its own write off
+	officer.PATCH("/lender/loanrequest_list/1/write_off").WithJSON(map[string]interface{}{
+		"action": "approve",
+	}).
+		Expect().
+		Status(http.StatusUnprocessableEntity).JSON().Object()
+
+	supervisor.GET("/lender/write_offs").WithQuery("status", "pending").
+		Expect().
+		Status(http.StatusOK).JSON().Object().ValueEqual("total_data", 1)
+
+	// loan settled while its write off was pending is not written off
+	asira.App.DB.Model(&models.Loan{}).Where("id = ?", 1).Update("payment_status", "terbayar")
+	supervisor.PATCH("/lender/loanrequest_list/1/write_off").WithJSON(map[string]interface{}{
+		"action": "approve",
+	}).
+		Expect().
+		Status(http.StatusUnprocessableEntity).JSON().Object()
+	asira.App.DB.Model(&models.Loan{}).Where("id = ?", 1).Update("payment_status", "")
+
+	obj = supervisor.PATCH("/lender/loanrequest_list/1/write_off").WithJSON(map[string]interface{}{
+		"action": "approve",
+		"note":   "collection exhausted",
+	}).
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.Value("write_off").Object().ValueEqual("status", "approved")
+	obj.Value("loan").Object().ValueEqual("payment_status", "hapus_buku")
+
+	loan := models.Loan{}
+	loan.FindbyID(1)
+	if loan.PaymentStatus != "hapus_buku" {
+		t.Errorf("loan 1 payment status %v, expected hapus_buku", loan.PaymentStatus)
+	}
+
+	// written off loan is out of active portfolio
+	officer.POST("/lender/loanrequest_list/1/write_off").WithJSON(map[string]interface{}{
+		"reason": "borrower unreachable",
+	}).
+		Expect().
+		Status(http.StatusUnprocessableEntity).JSON().Object()
+	officer.GET("/lender/loanrequest_list/1/payoff_quote").
+		Expect().
+		Status(http.StatusUnprocessableEntity).JSON().Object()
+	officer.GET("/lender/borrower_list/1/credit_history").
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		ValueEqual("written_off_loans", 1)
+
+	obj = officer.POST("/lender/loanrequest_list/1/recoveries").WithJSON(map[string]interface{}{
+		"amount": 1000000,
+		"note":   "collateral sold",
+	}).
+		Expect().
+		Status(http.StatusCreated).JSON().Object()
+	obj.Value("recovery").Object().ValueEqual("amount", 1000000)
+	obj.Value("write_off").Object().ValueEqual("recovered", 1000000)
+
+	// recoveries can not exceed written off amount
+	officer.POST("/lender/loanrequest_list/1/recoveries").WithJSON(map[string]interface{}{
+		"amount": 3500000,
+	}).
+		Expect().
+		Status(http.StatusUnprocessableEntity).JSON().Object()
+
+	obj = officer.GET("/lender/loanrequest_list/1/write_off").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.Value("recoveries").Array().Length().Equal(1)
+
+	obj = admin.GET("/admin/reports/write_off").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.ValueEqual("total_data", 1)
+	obj.Value("data").Array().Element(0).Object().
+		ValueEqual("written_off", 4250000).
+		ValueEqual("recovered", 1000000).
+		ValueEqual("outstanding", 3250000)
+
+	admin.GET("/admin/reports/write_off").WithQuery("recovered", "true").
+		Expect().
+		Status(http.StatusOK).JSON().Object().ValueEqual("total_data", 0)
+}
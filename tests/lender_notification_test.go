@@ -0,0 +1,133 @@
+package tests
+
+import (
+	"asira_lender/asira"
+	"asira_lender/router"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gavv/httpexpect"
+)
+
+func TestLenderNotificationList(t *testing.T) {
+	RebuildData()
+
+	api := router.NewRouter()
+
+	server := httptest.NewServer(api)
+
+	defer server.Close()
+
+	e := httpexpect.New(t, server.URL)
+
+	auth := e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Basic "+clientBasicToken)
+	})
+
+	lendertoken := getLenderLoginToken(e, auth, "1")
+
+	auth = e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Bearer "+lendertoken)
+	})
+
+	// valid response
+	obj := auth.GET("/lender/notifications").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.ContainsKey("total_data").ValueEqual("total_data", 2)
+
+	obj = auth.GET("/lender/notifications/unread_count").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.ContainsKey("unread").ValueEqual("unread", 2)
+	obj.Value("events").Object().ValueEqual("new_loan", 1)
+
+	// mark as read
+	obj = auth.PATCH("/lender/notifications/1/read").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.Value("read_at").NotNull()
+
+	obj = auth.GET("/lender/notifications").WithQuery("unread", "true").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.ContainsKey("total_data").ValueEqual("total_data", 1)
+
+	// mark all as read
+	auth.PATCH("/lender/notifications/read_all").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+
+	obj = auth.GET("/lender/notifications/unread_count").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.ContainsKey("unread").ValueEqual("unread", 0)
+
+	// not found
+	auth.PATCH("/lender/notifications/9999/read").
+		Expect().
+		Status(http.StatusNotFound).JSON().Object()
+}
+
+func TestLenderNotificationPreference(t *testing.T) {
+	RebuildData()
+
+	api := router.NewRouter()
+
+	server := httptest.NewServer(api)
+
+	defer server.Close()
+
+	e := httpexpect.New(t, server.URL)
+
+	auth := e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Basic "+clientBasicToken)
+	})
+
+	lendertoken := getLenderLoginToken(e, auth, "1")
+
+	auth = e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Bearer "+lendertoken)
+	})
+
+	// every event is enabled by default
+	arr := auth.GET("/lender/notification_preferences").
+		Expect().
+		Status(http.StatusOK).JSON().Array()
+	arr.Length().Equal(2)
+	arr.First().Object().ValueEqual("in_app", true).ValueEqual("email", true)
+
+	// disable in-app new borrower notification
+	obj := auth.PATCH("/lender/notification_preferences").WithJSON(map[string]interface{}{
+		"event":  "new_borrower",
+		"in_app": false,
+	}).
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.ContainsKey("in_app").ValueEqual("in_app", false)
+	obj.ContainsKey("email").ValueEqual("email", true)
+
+	// invalid event
+	auth.PATCH("/lender/notification_preferences").WithJSON(map[string]interface{}{
+		"event": "unknown",
+	}).
+		Expect().
+		Status(http.StatusUnprocessableEntity).JSON().Object()
+
+	// new loan from borrower service is notified
+	asira.App.Bus.Publish("asira_borrower_to_lender", []byte(`loan:{"id":99,"event_id":"loan-99-1","version":1,"mode":"create","payload":{"id":99,"borrower":1,"loan_amount":1000000,"installment":6,"loan_intention":"a","intention_details":"b","product":1}}`))
+
+	obj = auth.GET("/lender/notifications/unread_count").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.Value("events").Object().ValueEqual("new_loan", 2)
+
+	// new borrower is not, in-app is disabled
+	asira.App.Bus.Publish("asira_borrower_to_lender", []byte(`borrower:{"id":99,"event_id":"borrower-99-1","version":1,"mode":"create","payload":{"id":99,"fullname":"Bus Borrower","phone":"0811223344","bank":{"Int64":1,"Valid":true}}}`))
+
+	obj = auth.GET("/lender/notifications/unread_count").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.Value("events").Object().ValueEqual("new_borrower", 1)
+}
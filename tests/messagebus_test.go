@@ -0,0 +1,57 @@
+package tests
+
+import (
+	"asira_lender/asira"
+	"asira_lender/router"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gavv/httpexpect"
+)
+
+func TestMessageBusConsume(t *testing.T) {
+	RebuildData()
+
+	api := router.NewRouter()
+
+	server := httptest.NewServer(api)
+
+	defer server.Close()
+
+	e := httpexpect.New(t, server.URL)
+
+	auth := e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Basic "+adminBasicToken)
+	})
+
+	adminToken := getAdminLoginToken(e, auth, "1")
+
+	auth = e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Bearer "+adminToken)
+	})
+
+	// message from borrower service
+	asira.App.Bus.Publish("asira_borrower_to_lender", []byte(`faq:{"id":99,"event_id":"faq-99-1","version":2,"mode":"create","payload":{"id":99,"title":"bus faq","description":"from borrower"}}`))
+
+	obj := auth.GET("/admin/faq/99").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.ContainsKey("title").ValueEqual("title", "bus faq")
+
+	// stale update is skipped
+	asira.App.Bus.Publish("asira_borrower_to_lender", []byte(`faq:{"id":99,"event_id":"faq-99-0","version":1,"mode":"update","payload":{"id":99,"title":"old faq","description":"from borrower"}}`))
+
+	obj = auth.GET("/admin/faq/99").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.ContainsKey("title").ValueEqual("title", "bus faq")
+
+	// newer update is applied
+	asira.App.Bus.Publish("asira_borrower_to_lender", []byte(`faq:{"id":99,"event_id":"faq-99-3","version":3,"mode":"update","payload":{"id":99,"title":"new faq","description":"from borrower"}}`))
+
+	obj = auth.GET("/admin/faq/99").
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.ContainsKey("title").ValueEqual("title", "new faq")
+}
@@ -0,0 +1,78 @@
+package tests
+
+import (
+	"asira_lender/asira"
+	"asira_lender/custommodule"
+	"asira_lender/router"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gavv/httpexpect"
+)
+
+func TestStorageFile(t *testing.T) {
+	RebuildData()
+
+	api := router.NewRouter()
+
+	server := httptest.NewServer(api)
+
+	defer server.Close()
+
+	e := httpexpect.New(t, server.URL)
+
+	auth := e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Basic "+clientBasicToken)
+	})
+
+	adminToken := getAdminLoginToken(e, auth, "1")
+
+	auth = e.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Bearer "+adminToken)
+	})
+
+	payload := map[string]interface{}{
+		"name":        "Storage Service",
+		"image":       dummyImage(),
+		"status":      "active",
+		"description": "service with jpeg image",
+	}
+
+	obj := auth.POST("/admin/services").WithJSON(payload).
+		Expect().
+		Status(http.StatusCreated).JSON().Object()
+	url := obj.Value("image").String().Contains(".jpeg").Raw()
+
+	// permanent url is not served without signature
+	e.GET(url[strings.Index(url, "/files/"):]).
+		Expect().
+		Status(http.StatusForbidden)
+
+	// pre-signed url is served from local filesystem storage
+	presigned, err := custommodule.PresignObject(asira.App.Storage, url, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.GET(presigned[strings.Index(presigned, "/files/"):]).
+		Expect().
+		Status(http.StatusOK).
+		ContentType("image/jpeg")
+
+	// tampered signature
+	e.GET(url[strings.Index(url, "/files/"):]).WithQuery("expires", "9999999999").WithQuery("signature", "invalid").
+		Expect().
+		Status(http.StatusForbidden)
+
+	// signature without expiry
+	e.GET(url[strings.Index(url, "/files/"):]).WithQuery("signature", "invalid").
+		Expect().
+		Status(http.StatusForbidden)
+
+	presigned, _ = custommodule.PresignObject(asira.App.Storage, "not_found.jpeg", time.Minute)
+	e.GET(presigned[strings.Index(presigned, "/files/"):]).
+		Expect().
+		Status(http.StatusNotFound)
+}